@@ -3,6 +3,7 @@ package analyzer
 import (
 	"fmt"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
@@ -21,16 +22,61 @@ type Analyzer struct {
 	tokenizer *llm.Tokenizer
 }
 
-func (a *Analyzer) readContentByType(path string, fileType types.FileType) (string, error) {
-	switch fileType {
+// readContentByType extracts the text of a file according to its type.
+// Extractors that find document metadata or findings record them on info.
+func (a *Analyzer) readContentByType(path string, info *types.FileInfo) (string, error) {
+	switch info.Type {
 	case types.TypePDF:
-		return a.detector.ReadPDFContent(path)
+		content, metadata, err := a.detector.ReadPDFContent(path)
+		if len(metadata) > 0 {
+			info.Metadata = metadata
+		}
+		return content, err
 	case types.TypeDOC:
 		return a.detector.ReadDOCContent(path)
 	case types.TypeDOCX:
 		return a.detector.ReadDOCXContent(path)
+	case types.TypeXLSX:
+		return a.detector.ReadXLSXContent(path)
+	case types.TypeODS:
+		return a.detector.ReadODSContent(path)
+	case types.TypePPTX:
+		return a.detector.ReadPPTXContent(path)
+	case types.TypeODT:
+		return a.detector.ReadODTContent(path)
 	case types.TypePCAP:
-		return a.detector.ReadPCAPContent(path)
+		content, findings, err := a.detector.ReadPCAPContent(path, a.config.PCAP)
+		for i := range findings {
+			findings[i].File = info.RelPath
+		}
+		info.Findings = findings
+		return content, err
+	case types.TypeLog:
+		return a.detector.ReadLogContent(path, a.config.Logs)
+	case types.TypeCSV:
+		content, findings, err := a.detector.ReadCSVContent(path, a.config.CSV, a.validator)
+		for i := range findings {
+			findings[i].File = info.RelPath
+		}
+		info.Findings = findings
+		return content, err
+	case types.TypeSQLite:
+		return a.detector.ReadSQLiteContent(path, a.config.SQLite)
+	case types.TypeExecutable:
+		return a.detector.ReadExecutableContent(path, a.config.Executables)
+	case types.TypeIaC:
+		content, findings, err := a.detector.ReadIaCContent(path)
+		for i := range findings {
+			findings[i].File = info.RelPath
+		}
+		info.Findings = findings
+		return content, err
+	case types.TypeNotebook:
+		content, metadata, err := a.detector.ReadNotebookContent(path)
+		if len(metadata) > 0 {
+			info.Metadata = metadata
+		}
+		return content, err
 	default:
 		return a.detector.ReadContent(path, 0)
 	}
@@ -73,29 +119,123 @@ func (a *Analyzer) AnalyzeFile(path string, rootPath string) (*types.FileInfo, e
 	}
 
 	// Skip if too large
-	if info.Size > int64(a.config.Agent.MaxFileSizeBytes) {
+	if maxSize, _ := a.config.MaxFileSize(path); info.Size > maxSize {
 		return info, nil
 	}
 
+	read := func() (string, error) {
+		return a.readContentByType(path, info)
+	}
+	chunkRaw := func() ([]types.FileChunk, error) {
+		return a.chunker.ChunkFile(path, info.Language)
+	}
+
+	if err := a.loadContent(info, read, chunkRaw); err != nil {
+		return info, err
+	}
+
+	return info, nil
+}
+
+// ReloadPCAP reads a packet capture again keeping only the packets that
+// match filterExpr, in addition to any configured filter. It returns a new
+// FileInfo and leaves info unchanged.
+func (a *Analyzer) ReloadPCAP(info *types.FileInfo, filterExpr string) (*types.FileInfo, error) {
+	if err := checkRereadablePCAP(info); err != nil {
+		return nil, err
+	}
+	if _, err := ParsePCAPFilter(filterExpr); err != nil {
+		return nil, err
+	}
+
+	cfg := *a.config
+	cfg.PCAP.Filter = CombinePCAPFilters(a.config.PCAP.Filter, filterExpr)
+	engine := NewAnalyzer(&cfg)
+
+	reloaded := *info
+	reloaded.Content, reloaded.Summary, reloaded.TokenCount = "", "", 0
+	reloaded.Chunks, reloaded.Findings, reloaded.Violations, reloaded.Metadata = nil, nil, nil, nil
+	read := func() (string, error) {
+		return engine.readContentByType(info.Path, &reloaded)
+	}
+	if err := engine.loadContent(&reloaded, read, nil); err != nil {
+		return nil, err
+	}
+	return &reloaded, nil
+}
+
+// ExportPCAP writes the flow, DNS, HTTP, protocol and timeline tables of a
+// scanned packet capture to dir as CSV and JSON (see Detector.ExportPCAP).
+// filterExpr narrows the configured filter like ReloadPCAP.
+func (a *Analyzer) ExportPCAP(info *types.FileInfo, dir, filterExpr string) ([]string, error) {
+	if err := checkRereadablePCAP(info); err != nil {
+		return nil, err
+	}
+	if _, err := ParsePCAPFilter(filterExpr); err != nil {
+		return nil, err
+	}
+
+	opts := a.config.PCAP
+	opts.Filter = CombinePCAPFilters(opts.Filter, filterExpr)
+	return a.detector.ExportPCAP(info.Path, dir, PCAPExportName(info.RelPath), opts)
+}
+
+// checkRereadablePCAP reports whether a scanned file is a packet capture that
+// can be read again from disk; archive members are only held during the scan
+func checkRereadablePCAP(info *types.FileInfo) error {
+	if info.Type != types.TypePCAP {
+		return fmt.Errorf("%s is not a packet capture", info.RelPath)
+	}
+	if IsArchiveMember(info.Path) {
+		return fmt.Errorf("%s is inside an archive; extract it to read it again", info.RelPath)
+	}
+	return nil
+}
+
+// FilterCaptures returns files with each packet capture replaced by a copy
+// read again keeping only the packets that match filterExpr
+func (a *Analyzer) FilterCaptures(files []*types.FileInfo, filterExpr string) ([]*types.FileInfo, error) {
+	filtered := make([]*types.FileInfo, 0, len(files))
+	for _, file := range files {
+		if file == nil || file.Type != types.TypePCAP {
+			filtered = append(filtered, file)
+			continue
+		}
+		reloaded, err := a.ReloadPCAP(file, filterExpr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to filter %s: %w", file.RelPath, err)
+		}
+		filtered = append(filtered, reloaded)
+	}
+	return filtered, nil
+}
+
+// loadContent reads a readable file's content according to its size
+// category. chunkRaw chunks the original file for large files that need no
+// extraction; when it is nil the extracted content is chunked instead.
+func (a *Analyzer) loadContent(info *types.FileInfo, read func() (string, error), chunkRaw func() ([]types.FileChunk, error)) error {
 	// Read content based on category
 	switch info.Category {
 	case types.CategorySmall:
 		// Read full content
-		content, err := a.readContentByType(path, info.Type)
+		content, err := read()
 
 		if err != nil {
-			return info, fmt.Errorf("failed to read content: %w", err)
+			return fmt.Errorf("failed to read content: %w", err)
 		}
 		info.Content = content
 		info.TokenCount = a.tokenizer.EstimateTokensSimple(content)
+		if len(info.Metadata) > 0 {
+			info.Summary = a.generateSummary(info)
+		}
 		a.flagViolations(info, content)
 
 	case types.CategoryMedium:
 		// Read full content but prepare for chunking
-		content, err := a.readContentByType(path, info.Type)
+		content, err := read()
 
 		if err != nil {
-			return info, fmt.Errorf("failed to read content: %w", err)
+			return fmt.Errorf("failed to read content: %w", err)
 		}
 		info.Content = content
 		info.TokenCount = a.tokenizer.EstimateTokensSimple(content)
@@ -108,29 +248,32 @@ func (a *Analyzer) AnalyzeFile(path string, rootPath string) (*types.FileInfo, e
 		// Skip large file content if token limit is too low
 		if a.config.Agent.TokenLimit < 8000 {
 			info.Summary = fmt.Sprintf("Large file skipped (AGENT_TOKEN_LIMIT=%d < 8000 required)", a.config.Agent.TokenLimit)
-			return info, nil
+			return nil
 		}
 
 		// Read full content for analysis
-		content, err := a.readContentByType(path, info.Type)
+		content, err := read()
 
 		if err != nil {
-			return info, fmt.Errorf("failed to read content: %w", err)
+			return fmt.Errorf("failed to read content: %w", err)
 		}
 		info.Content = content
 
 		// Generate summary
 		info.Summary = a.generateSummary(info)
 
-		// For formats requiring extraction (PDF/DOC/DOCX/PCAP), chunk extracted text; for others chunk raw file
+		// For formats requiring extraction (PDF/DOC/DOCX/PCAP) and archive members, chunk extracted text; for others chunk raw file.
+		// Office documents rendered as Markdown chunk by section.
 		var chunks []types.FileChunk
-		if info.Type == types.TypePDF || info.Type == types.TypeDOC || info.Type == types.TypeDOCX || info.Type == types.TypePCAP {
+		if hasSections(info.Type) {
+			chunks, err = a.chunker.ChunkSections(content)
+		} else if isExtractedType(info.Type) || chunkRaw == nil {
 			chunks, err = a.chunker.ChunkContent(content)
 		} else {
-			chunks, err = a.chunker.ChunkFile(path)
+			chunks, err = chunkRaw()
 		}
 		if err != nil {
-			return info, fmt.Errorf("failed to chunk file: %w", err)
+			return fmt.Errorf("failed to chunk file: %w", err)
 		}
 		info.Chunks = chunks
 
@@ -139,7 +282,7 @@ func (a *Analyzer) AnalyzeFile(path string, rootPath string) (*types.FileInfo, e
 		a.flagViolations(info, content)
 	}
 
-	return info, nil
+	return nil
 }
 
 // AnalyzeFiles analyzes multiple files concurrently
@@ -170,6 +313,53 @@ func (a *Analyzer) AnalyzeFiles(paths []string, rootPath string) ([]*types.FileI
 	return results, errors
 }
 
+// CollectFindings returns the findings recorded on files while their content
+// was read, such as PCAP indicators, in file order
+func CollectFindings(files []*types.FileInfo) []types.Finding {
+	var findings []types.Finding
+	for _, file := range files {
+		if file != nil {
+			findings = append(findings, file.Findings...)
+		}
+	}
+	return findings
+}
+
+// maxUnchangedListed caps the unchanged files named in UnchangedFilesNote
+const maxUnchangedListed = 100
+
+// UnchangedFilesNote lists the scanned files that are not in selected, for
+// requests limited to files changed in git, so the model knows what else the
+// repository holds. It is empty when every scanned file is selected.
+func UnchangedFilesNote(all []types.FileInfo, selected []*types.FileInfo) string {
+	chosen := make(map[string]bool, len(selected))
+	for _, file := range selected {
+		if file != nil {
+			chosen[file.RelPath] = true
+		}
+	}
+
+	var unchanged []string
+	for i := range all {
+		if !chosen[all[i].RelPath] {
+			unchanged = append(unchanged, filepath.ToSlash(all[i].RelPath))
+		}
+	}
+	if len(unchanged) == 0 {
+		return ""
+	}
+	sort.Strings(unchanged)
+
+	total := len(unchanged)
+	more := ""
+	if total > maxUnchangedListed {
+		more = fmt.Sprintf(", and %d more", total-maxUnchangedListed)
+		unchanged = unchanged[:maxUnchangedListed]
+	}
+	return fmt.Sprintf("\n\nOnly files changed in git are included. The %d other scanned files are unchanged and not shown: %s%s.",
+		total, strings.Join(unchanged, ", "), more)
+}
+
 // generateSummary creates a summary for a file
 func (a *Analyzer) generateSummary(info *types.FileInfo) string {
 	var parts []string
@@ -186,16 +376,27 @@ func (a *Analyzer) generateSummary(info *types.FileInfo) string {
 
 	// Try to detect language
 	lang := detectLanguage(info.Extension)
+	if lang == "" && info.Language != "" {
+		lang = languageName(info.Language)
+	}
 	if lang != "" {
 		parts = append(parts, fmt.Sprintf("Language: %s", lang))
 	}
 
+	// Mention encodings other than UTF-8, since the content was transcoded
+	if info.Encoding != "" && info.Encoding != encodingUTF8 {
+		parts = append(parts, fmt.Sprintf("Encoding: %s", info.Encoding))
+	}
+
 	// Add line count if text file
 	if info.Type == types.TypeText && info.Content != "" {
 		lineCount := strings.Count(info.Content, "\n") + 1
 		parts = append(parts, fmt.Sprintf("Lines: %d", lineCount))
 	}
 
+	// Add document metadata (title, author, page count, ...)
+	parts = append(parts, formatMetadata(info.Metadata)...)
+
 	// Add chunk information for large files
 	if len(info.Chunks) > 0 {
 		parts = append(parts, fmt.Sprintf("Chunks: %d", len(info.Chunks)))
@@ -204,6 +405,32 @@ func (a *Analyzer) generateSummary(info *types.FileInfo) string {
 	return strings.Join(parts, " | ")
 }
 
+// formatMetadata renders metadata as "Key: value" pairs, with the title,
+// author and page count first
+func formatMetadata(metadata map[string]string) []string {
+	preferred := []string{"Title", "Author", "Pages"}
+	var parts []string
+	seen := make(map[string]bool)
+	for _, key := range preferred {
+		if value, ok := metadata[key]; ok {
+			parts = append(parts, fmt.Sprintf("%s: %s", key, value))
+			seen[key] = true
+		}
+	}
+
+	var rest []string
+	for key := range metadata {
+		if !seen[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	for _, key := range rest {
+		parts = append(parts, fmt.Sprintf("%s: %s", key, metadata[key]))
+	}
+	return parts
+}
+
 // PrepareForLLM prepares file content for sending to LLM
 // Enforces maxTokens limit by including files until limit is reached
 func (a *Analyzer) PrepareForLLM(files []*types.FileInfo, maxTokens int) string {
@@ -217,6 +444,13 @@ func (a *Analyzer) PrepareForLLM(files []*types.FileInfo, maxTokens int) string
 		return a.validator.SanitizeContent(text)
 	}
 
+	// Minify text files when configured, so they count for what is sent
+	type minifiedContent struct {
+		content  string
+		numbered bool
+	}
+	minified := make(map[*types.FileInfo]minifiedContent)
+
 	// Determine which files can fit within token limit
 	var includedFiles []*types.FileInfo
 	var skippedFiles []*types.FileInfo
@@ -227,19 +461,42 @@ func (a *Analyzer) PrepareForLLM(files []*types.FileInfo, maxTokens int) string
 			continue
 		}
 
+		tokens := file.TokenCount
+		if content, numbered, ok := a.minifyContent(file, file.Content, 1); ok {
+			minified[file] = minifiedContent{content, numbered}
+			tokens = a.tokenizer.EstimateTokensSimple(content)
+		}
+
 		// Skip files that exceed token limit entirely
-		if file.TokenCount > maxTokens {
+		if tokens > maxTokens {
 			skippedFiles = append(skippedFiles, file)
 			continue
 		}
 
 		// Stop if adding this file would exceed limit (unless it's the first file)
-		if currentTokens+file.TokenCount > maxTokens && len(includedFiles) > 0 {
+		if currentTokens+tokens > maxTokens && len(includedFiles) > 0 {
 			break
 		}
 
 		includedFiles = append(includedFiles, file)
-		currentTokens += file.TokenCount
+		currentTokens += tokens
+	}
+
+	// writeMinifiedNote tells the LLM how to read minified content
+	writeMinifiedNote := func(numbered bool) {
+		if numbered {
+			builder.WriteString("[Minified: each line starts with its line number in the file; removed lines are noted with …]\n")
+		} else {
+			builder.WriteString("[Minified]\n")
+		}
+	}
+	// fileContent returns what is sent of a whole file
+	fileContent := func(file *types.FileInfo) string {
+		if m, ok := minified[file]; ok {
+			writeMinifiedNote(m.numbered)
+			return m.content
+		}
+		return file.Content
 	}
 
 	// Add summary header
@@ -269,9 +526,12 @@ func (a *Analyzer) PrepareForLLM(files []*types.FileInfo, maxTokens int) string
 		// Add content based on category
 		switch file.Category {
 		case types.CategorySmall, types.CategoryMedium:
+			if len(file.Metadata) > 0 {
+				builder.WriteString(fmt.Sprintf("[%s]\n", strings.Join(formatMetadata(file.Metadata), " | ")))
+			}
 			if file.Content != "" {
-				safeContent := sanitize(file.Content)
-				builder.WriteString(fmt.Sprintf("```%s\n%s\n```\n\n", getLanguageIdentifier(file.Extension), safeContent))
+				safeContent := sanitize(fileContent(file))
+				builder.WriteString(fenceBlock(safeContent, fenceLanguage(file)) + "\n\n")
 			} else {
 				builder.WriteString("[Empty file]\n\n")
 			}
@@ -279,15 +539,28 @@ func (a *Analyzer) PrepareForLLM(files []*types.FileInfo, maxTokens int) string
 		case types.CategoryLarge:
 			// For single file analysis, include full content
 			if len(includedFiles) == 1 && file.Content != "" {
-				safeContent := sanitize(file.Content)
-				builder.WriteString(fmt.Sprintf("```%s\n%s\n```\n\n", getLanguageIdentifier(file.Extension), safeContent))
+				safeContent := sanitize(fileContent(file))
+				builder.WriteString(fenceBlock(safeContent, fenceLanguage(file)) + "\n\n")
 			} else {
 				// For multi-file batches, show summary and first chunk
 				builder.WriteString(fmt.Sprintf("[Large file - %s]\n", file.Summary))
 				if len(file.Chunks) > 0 && file.Chunks[0].Content != "" {
-					safeContent := sanitize(file.Chunks[0].Content)
-					builder.WriteString(fmt.Sprintf("\n**Preview (Chunk 1/%d):**\n```%s\n%s\n```\n",
-						len(file.Chunks), getLanguageIdentifier(file.Extension), safeContent))
+					chunk := file.Chunks[0]
+					preview := chunk.Content
+					label := fmt.Sprintf("Chunk 1/%d", len(file.Chunks))
+					if chunk.Section != "" {
+						label += ", " + chunk.Section
+					}
+					if content, numbered, ok := a.minifyContent(file, chunk.Content, chunk.StartLine); ok {
+						preview = content
+						label += ", minified"
+						if numbered {
+							label += ", lines numbered as in the file"
+						}
+					}
+					safeContent := sanitize(preview)
+					builder.WriteString(fmt.Sprintf("\n**Preview (%s):**\n%s\n",
+						label, fenceBlock(safeContent, fenceLanguage(file))))
 				}
 			}
 			builder.WriteString("\n")
@@ -347,9 +620,25 @@ func detectLanguage(ext string) string {
 		".sql":    "SQL",
 		".doc":    "Word Document",
 		".docx":   "Word Document",
+		".xlsx":   "Excel Spreadsheet",
+		".ods":    "OpenDocument Spreadsheet",
+		".pptx":   "PowerPoint Presentation",
+		".odt":    "OpenDocument Text",
 		".pcap":   "Network Capture",
 		".pcapng": "Network Capture",
 		".cap":    "Network Capture",
+		".log":    "Log",
+		".ipynb":  "Jupyter Notebook",
+		".csv":    "CSV",
+		".tsv":    "TSV",
+		".sqlite": "SQLite Database",
+		".db":     "SQLite Database",
+		".exe":    "Executable",
+		".dll":    "Executable",
+		".so":     "Executable",
+		".dylib":  "Executable",
+		".o":      "Object File",
+		".tf":     "Terraform",
 	}
 
 	return languages[ext]
@@ -375,10 +664,26 @@ func getLanguageIdentifier(ext string) string {
 		".yml":    "yaml",
 		".xml":    "xml",
 		".doc":    "text",
-		".docx":   "text",
+		".docx":   "markdown",
+		".xlsx":   "markdown",
+		".ods":    "markdown",
+		".pptx":   "markdown",
+		".ipynb":  "markdown",
+		".csv":    "markdown",
+		".tsv":    "markdown",
+		".sqlite": "markdown",
+		".db":     "markdown",
+		".exe":    "markdown",
+		".dll":    "markdown",
+		".so":     "markdown",
+		".dylib":  "markdown",
+		".o":      "markdown",
+		".tf":     "markdown",
+		".odt":    "markdown",
 		".pcap":   "text",
 		".pcapng": "text",
 		".cap":    "text",
+		".log":    "markdown",
 	}
 
 	if id, ok := identifiers[ext]; ok {
@@ -386,3 +691,27 @@ func getLanguageIdentifier(ext string) string {
 	}
 	return ""
 }
+
+// fenceLanguage returns the language of the code fence around a file's
+// content. Kubernetes manifests keep their .yaml extension but are rendered
+// as Markdown, and text files without a telling extension use the language
+// detected from their name or content.
+func fenceLanguage(file *types.FileInfo) string {
+	if file.Type == types.TypeIaC {
+		return "markdown"
+	}
+	if id := getLanguageIdentifier(file.Extension); id != "" {
+		return id
+	}
+	return file.Language
+}
+
+// fenceBlock fences text with enough backticks that fences inside it, e.g.
+// in a string literal, do not close the block
+func fenceBlock(text, language string) string {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence + language + "\n" + text + "\n" + fence
+}
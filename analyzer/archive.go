@@ -0,0 +1,423 @@
+package analyzer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"local-agent/types"
+)
+
+// archiveFormat identifies how an archive is read
+type archiveFormat int
+
+const (
+	formatNone archiveFormat = iota
+	formatZip
+	formatTar
+	formatTarGzip
+	formatTarBzip2
+	formatGzip
+	formatBzip2
+)
+
+// errArchiveLimit stops an archive walk once a configured limit is reached
+var errArchiveLimit = errors.New("archive limit reached")
+
+// archiveFormatOf picks the archive format from a file or member name
+func archiveFormatOf(name string) archiveFormat {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return formatZip
+	case strings.HasSuffix(lower, ".tar"):
+		return formatTar
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return formatTarGzip
+	case strings.HasSuffix(lower, ".tar.bz2"), strings.HasSuffix(lower, ".tbz2"):
+		return formatTarBzip2
+	case strings.HasSuffix(lower, ".gz"):
+		return formatGzip
+	case strings.HasSuffix(lower, ".bz2"):
+		return formatBzip2
+	default:
+		return formatNone
+	}
+}
+
+// IsExpandableArchive reports whether the members of the archive at path can be read
+func IsExpandableArchive(path string) bool {
+	return archiveFormatOf(path) != formatNone
+}
+
+// IsArchiveMember reports whether path is the virtual path of an archive member
+func IsArchiveMember(path string) bool {
+	return strings.Contains(path, types.ArchiveSeparator)
+}
+
+// ArchiveScan collects what was found while expanding one archive
+type ArchiveScan struct {
+	Files    []*types.FileInfo
+	Excluded []types.FilterDecision
+	Errors   []types.ScanError
+	Size     int64 // uncompressed bytes read
+}
+
+// archiveWalker tracks the limits shared by an archive and everything nested in it
+type archiveWalker struct {
+	analyzer *Analyzer
+	rootPath string
+	evaluate func(string) types.FilterDecision
+	scan     *ArchiveScan
+	members  int
+}
+
+// ExpandArchives reads the members of every archive in the scan result and
+// adds them as virtual files. Members pass through evaluate (the scan filter)
+// before they are read.
+func (a *Analyzer) ExpandArchives(result *types.ScanResult, evaluate func(string) types.FilterDecision) {
+	if !a.config.Archives.Enabled {
+		return
+	}
+
+	var members []types.FileInfo
+	for i := range result.Files {
+		info := &result.Files[i]
+		if info.Type != types.TypeArchive || !IsExpandableArchive(info.Path) {
+			continue
+		}
+
+		scan := a.ExpandArchive(info.Path, result.RootPath, evaluate)
+		info.Summary = fmt.Sprintf("Archive: %d members analyzed, %d excluded, %s uncompressed",
+			len(scan.Files), len(scan.Excluded), formatFileSize(scan.Size))
+
+		for _, member := range scan.Files {
+			members = append(members, *member)
+			result.TotalFiles++
+			result.TotalSize += member.Size
+			result.Summary[string(member.Type)]++
+			result.Summary[string(member.Category)]++
+		}
+		result.FilteredFiles += len(scan.Excluded)
+		result.Excluded = append(result.Excluded, scan.Excluded...)
+		result.Errors = append(result.Errors, scan.Errors...)
+	}
+
+	result.Files = append(result.Files, members...)
+}
+
+// ExpandArchive reads the members of one archive without unpacking it to
+// disk. Member paths have the form <archive>!/<member>; nested archives are
+// opened up to the configured depth.
+func (a *Analyzer) ExpandArchive(archivePath, rootPath string, evaluate func(string) types.FilterDecision) *ArchiveScan {
+	walker := &archiveWalker{
+		analyzer: a,
+		rootPath: rootPath,
+		evaluate: evaluate,
+		scan:     &ArchiveScan{},
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		walker.addError(archivePath, err)
+		return walker.scan
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		walker.addError(archivePath, err)
+		return walker.scan
+	}
+
+	if err := walker.walk(archivePath, file, stat.Size(), archiveFormatOf(archivePath), 1); err != nil {
+		walker.addError(archivePath, err)
+	}
+
+	return walker.scan
+}
+
+// archiveSource is an archive held in a file or in memory
+type archiveSource interface {
+	io.Reader
+	io.ReaderAt
+}
+
+func (w *archiveWalker) walk(container string, src archiveSource, size int64, format archiveFormat, depth int) error {
+	switch format {
+	case formatZip:
+		reader, err := zip.NewReader(src, size)
+		if err != nil {
+			return fmt.Errorf("failed to open zip: %w", err)
+		}
+		for _, file := range reader.File {
+			if !file.Mode().IsRegular() {
+				continue
+			}
+			file := file
+			open := func() (io.ReadCloser, error) { return file.Open() }
+			if err := w.visit(container, file.Name, int64(file.UncompressedSize64), file.Modified, open, depth); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case formatTar:
+		return w.walkTar(container, src, depth)
+
+	case formatTarGzip:
+		gz, err := gzip.NewReader(src)
+		if err != nil {
+			return fmt.Errorf("failed to open gzip: %w", err)
+		}
+		defer gz.Close()
+		return w.walkTar(container, gz, depth)
+
+	case formatTarBzip2:
+		return w.walkTar(container, bzip2.NewReader(src), depth)
+
+	case formatGzip:
+		gz, err := gzip.NewReader(src)
+		if err != nil {
+			return fmt.Errorf("failed to open gzip: %w", err)
+		}
+		defer gz.Close()
+
+		name := gz.Name
+		if name == "" {
+			name = strings.TrimSuffix(filepath.Base(archiveBaseName(container)), filepath.Ext(container))
+		}
+		open := func() (io.ReadCloser, error) { return io.NopCloser(gz), nil }
+		return w.visit(container, name, -1, gz.ModTime, open, depth)
+
+	case formatBzip2:
+		name := strings.TrimSuffix(filepath.Base(archiveBaseName(container)), filepath.Ext(container))
+		open := func() (io.ReadCloser, error) { return io.NopCloser(bzip2.NewReader(src)), nil }
+		return w.visit(container, name, -1, time.Time{}, open, depth)
+	}
+
+	return fmt.Errorf("unsupported archive format")
+}
+
+func (w *archiveWalker) walkTar(container string, r io.Reader, depth int) error {
+	reader := tar.NewReader(r)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		open := func() (io.ReadCloser, error) { return io.NopCloser(reader), nil }
+		if err := w.visit(container, header.Name, header.Size, header.ModTime, open, depth); err != nil {
+			return err
+		}
+	}
+}
+
+// visit filters, reads and analyzes a single member. size is -1 when the
+// format does not record it.
+func (w *archiveWalker) visit(container, name string, size int64, modTime time.Time, open func() (io.ReadCloser, error), depth int) error {
+	limits := w.analyzer.config.Archives
+
+	w.members++
+	if w.members > limits.MaxMembers {
+		return fmt.Errorf("%w: more than %d members (max_members), remaining members skipped", errArchiveLimit, limits.MaxMembers)
+	}
+
+	memberName := cleanMemberName(name)
+	if memberName == "" {
+		return nil
+	}
+	virtualPath := container + types.ArchiveSeparator + memberName
+
+	decision := w.evaluate(virtualPath)
+	if !decision.Included {
+		w.scan.Excluded = append(w.scan.Excluded, decision)
+		return nil
+	}
+
+	nested := archiveFormatOf(memberName)
+
+	// Archives are read whole so they can be opened in turn; other members
+	// are bounded by the per-file size limit
+	maxSize, limitSource := w.analyzer.config.MaxFileSize(memberName)
+	if nested != formatNone {
+		maxSize, limitSource = int64(limits.MaxTotalBytes), "archives max_total_bytes"
+	}
+	if size > maxSize {
+		w.scan.Excluded = append(w.scan.Excluded, sizeDecision(decision.Path, limitSource, size, maxSize))
+		return nil
+	}
+
+	data, err := w.readMember(open, maxSize)
+	if err != nil {
+		if errors.Is(err, errArchiveLimit) {
+			return err
+		}
+		w.addError(virtualPath, err)
+		return nil
+	}
+	if int64(len(data)) > maxSize {
+		w.scan.Excluded = append(w.scan.Excluded, sizeDecision(decision.Path, limitSource, -1, maxSize))
+		return nil
+	}
+
+	info, err := w.analyzer.analyzeMember(virtualPath, w.rootPath, data, modTime)
+	if err != nil {
+		w.addError(virtualPath, err)
+	}
+	if info == nil {
+		return nil
+	}
+	w.scan.Files = append(w.scan.Files, info)
+
+	if nested != formatNone {
+		if depth >= limits.MaxDepth {
+			info.Summary = fmt.Sprintf("Nested archive not opened (max_depth %d)", limits.MaxDepth)
+		} else if err := w.walk(virtualPath, bytes.NewReader(data), int64(len(data)), nested, depth+1); err != nil {
+			if errors.Is(err, errArchiveLimit) {
+				return err
+			}
+			w.addError(virtualPath, err)
+		}
+	}
+
+	return nil
+}
+
+// readMember reads up to maxSize+1 bytes of a member, charging them to the
+// total uncompressed byte budget of the top-level archive
+func (w *archiveWalker) readMember(open func() (io.ReadCloser, error), maxSize int64) ([]byte, error) {
+	limit := int64(w.analyzer.config.Archives.MaxTotalBytes)
+	remaining := limit - w.scan.Size
+
+	rc, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	readLimit := maxSize + 1
+	if remaining+1 < readLimit {
+		readLimit = remaining + 1
+	}
+
+	data, err := io.ReadAll(io.LimitReader(rc, readLimit))
+	if err != nil {
+		return nil, err
+	}
+
+	w.scan.Size += int64(len(data))
+	if w.scan.Size > limit {
+		return nil, fmt.Errorf("%w: more than %d uncompressed bytes (max_total_bytes), remaining members skipped", errArchiveLimit, limit)
+	}
+
+	return data, nil
+}
+
+func sizeDecision(relPath, source string, size, maxSize int64) types.FilterDecision {
+	reason := fmt.Sprintf("member is larger than the %d byte limit, so its content is not read", maxSize)
+	if size >= 0 {
+		reason = fmt.Sprintf("member size (%d bytes) exceeds the %d byte limit, so its content is not read", size, maxSize)
+	}
+	return types.FilterDecision{
+		Path:   relPath,
+		Stage:  "size",
+		Source: source,
+		Reason: reason,
+	}
+}
+
+func (w *archiveWalker) addError(path string, err error) {
+	w.scan.Errors = append(w.scan.Errors, types.ScanError{Path: path, Error: err.Error(), Time: time.Now()})
+}
+
+// analyzeMember runs detection and extraction on an in-memory archive member
+func (a *Analyzer) analyzeMember(virtualPath, rootPath string, data []byte, modTime time.Time) (*types.FileInfo, error) {
+	info := a.detector.DetectBytes(virtualPath, data, modTime)
+
+	if a.config.Security.DetectSecrets && a.validator.DetectSensitiveFile(virtualPath) {
+		info.IsSensitive = true
+	}
+
+	relPath, err := filepath.Rel(rootPath, virtualPath)
+	if err != nil {
+		relPath = virtualPath
+	}
+	info.RelPath = relPath
+
+	if !info.IsReadable {
+		return info, nil
+	}
+
+	read := func() (string, error) {
+		return a.readMemberContent(info, data)
+	}
+
+	if err := a.loadContent(info, read, nil); err != nil {
+		return info, err
+	}
+
+	return info, nil
+}
+
+// readMemberContent extracts text from member bytes. Formats whose readers
+// need random access to a file are spooled to a temporary file first.
+func (a *Analyzer) readMemberContent(info *types.FileInfo, data []byte) (string, error) {
+	switch {
+	case isExtractedType(info.Type):
+		spool, err := os.CreateTemp("", "local-agent-member-*"+info.Extension)
+		if err != nil {
+			return "", fmt.Errorf("failed to spool archive member: %w", err)
+		}
+		defer os.Remove(spool.Name())
+
+		_, writeErr := spool.Write(data)
+		closeErr := spool.Close()
+		if writeErr != nil {
+			return "", fmt.Errorf("failed to spool archive member: %w", writeErr)
+		}
+		if closeErr != nil {
+			return "", fmt.Errorf("failed to spool archive member: %w", closeErr)
+		}
+
+		return a.readContentByType(spool.Name(), info)
+	default:
+		return a.detector.ReadContentFrom(bytes.NewReader(data), 0)
+	}
+}
+
+// cleanMemberName turns a member name into a relative slash path, dropping
+// absolute prefixes and ".." components
+func cleanMemberName(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	cleaned := strings.TrimPrefix(path.Clean("/"+name), "/")
+	if cleaned == "." {
+		return ""
+	}
+	return cleaned
+}
+
+// archiveBaseName returns the last component of a (possibly virtual) archive path
+func archiveBaseName(archivePath string) string {
+	if idx := strings.LastIndex(archivePath, types.ArchiveSeparator); idx >= 0 {
+		return archivePath[idx+len(types.ArchiveSeparator):]
+	}
+	return archivePath
+}
@@ -0,0 +1,286 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+)
+
+// Compound File Binary (OLE2) container, as used by legacy Office formats.
+// Only reading whole streams by name is supported.
+
+var cfbSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+const (
+	cfbHeaderSize   = 512
+	cfbDirEntrySize = 128
+
+	cfbEndOfChain = 0xFFFFFFFE
+	cfbFreeSect   = 0xFFFFFFFF
+	cfbMaxRegSect = 0xFFFFFFFA
+
+	cfbTypeStream = 2
+	cfbTypeRoot   = 5
+)
+
+// cfbFile is a parsed compound file held in memory
+type cfbFile struct {
+	data           []byte
+	sectorSize     int
+	miniSectorSize int
+	miniCutoff     uint32
+	fat            []uint32
+	miniFAT        []uint32
+	miniStream     []byte
+	entries        []cfbEntry
+}
+
+// cfbEntry is a directory entry
+type cfbEntry struct {
+	name  string
+	kind  byte
+	start uint32
+	size  uint64
+}
+
+// isCFB reports whether data starts with the compound file signature
+func isCFB(data []byte) bool {
+	return len(data) >= len(cfbSignature) && bytes.Equal(data[:len(cfbSignature)], cfbSignature)
+}
+
+// parseCFB reads the header, allocation tables and directory of a compound file
+func parseCFB(data []byte) (*cfbFile, error) {
+	if len(data) < cfbHeaderSize || !isCFB(data) {
+		return nil, fmt.Errorf("not an OLE2 compound file")
+	}
+
+	le := binary.LittleEndian
+	sectorShift := le.Uint16(data[0x1E:])
+	miniSectorShift := le.Uint16(data[0x20:])
+	if sectorShift != 9 && sectorShift != 12 {
+		return nil, fmt.Errorf("unsupported sector size 2^%d", sectorShift)
+	}
+	if miniSectorShift != 6 {
+		return nil, fmt.Errorf("unsupported mini sector size 2^%d", miniSectorShift)
+	}
+
+	f := &cfbFile{
+		data:           data,
+		sectorSize:     1 << sectorShift,
+		miniSectorSize: 1 << miniSectorShift,
+		miniCutoff:     le.Uint32(data[0x38:]),
+	}
+
+	numFATSectors := le.Uint32(data[0x2C:])
+	firstDirSector := le.Uint32(data[0x30:])
+	firstMiniFATSector := le.Uint32(data[0x3C:])
+	firstDIFATSector := le.Uint32(data[0x44:])
+	numDIFATSectors := le.Uint32(data[0x48:])
+
+	// A file can't hold more FAT or DIFAT sectors than it has sectors, so
+	// larger counts in the header are capped rather than trusted
+	maxSectors := uint32(len(data) / f.sectorSize)
+	if numFATSectors > maxSectors {
+		numFATSectors = maxSectors
+	}
+	if numDIFATSectors > maxSectors {
+		numDIFATSectors = maxSectors
+	}
+
+	// The header holds the first 109 FAT sector numbers; DIFAT sectors hold the rest
+	var fatSectors []uint32
+	for i := 0; i < 109 && uint32(len(fatSectors)) < numFATSectors; i++ {
+		fatSectors = append(fatSectors, le.Uint32(data[0x4C+i*4:]))
+	}
+
+	perSector := f.sectorSize/4 - 1
+	next := firstDIFATSector
+	visited := make(map[uint32]bool)
+	for i := uint32(0); i < numDIFATSectors && next <= cfbMaxRegSect; i++ {
+		if visited[next] {
+			return nil, fmt.Errorf("DIFAT chain loops")
+		}
+		visited[next] = true
+
+		sector, err := f.sector(next)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read DIFAT: %w", err)
+		}
+		for j := 0; j < perSector && uint32(len(fatSectors)) < numFATSectors; j++ {
+			fatSectors = append(fatSectors, le.Uint32(sector[j*4:]))
+		}
+		next = le.Uint32(sector[perSector*4:])
+	}
+
+	for _, sectorNum := range fatSectors {
+		sector, err := f.sector(sectorNum)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read FAT: %w", err)
+		}
+		for j := 0; j < f.sectorSize; j += 4 {
+			f.fat = append(f.fat, le.Uint32(sector[j:]))
+		}
+	}
+
+	dir, err := f.chain(firstDirSector, -1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+	for off := 0; off+cfbDirEntrySize <= len(dir); off += cfbDirEntrySize {
+		f.entries = append(f.entries, parseCFBEntry(dir[off:off+cfbDirEntrySize]))
+	}
+	if len(f.entries) == 0 || f.entries[0].kind != cfbTypeRoot {
+		return nil, fmt.Errorf("missing root directory entry")
+	}
+
+	if firstMiniFATSector <= cfbMaxRegSect {
+		miniFAT, err := f.chain(firstMiniFATSector, -1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read mini FAT: %w", err)
+		}
+		for j := 0; j+4 <= len(miniFAT); j += 4 {
+			f.miniFAT = append(f.miniFAT, le.Uint32(miniFAT[j:]))
+		}
+
+		// The root entry's stream is the container for all mini streams
+		root := f.entries[0]
+		f.miniStream, err = f.chain(root.start, int64(root.size))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read mini stream: %w", err)
+		}
+	}
+
+	return f, nil
+}
+
+func parseCFBEntry(raw []byte) cfbEntry {
+	le := binary.LittleEndian
+
+	nameLen := int(le.Uint16(raw[0x40:]))
+	if nameLen > 64 {
+		nameLen = 64
+	}
+	var units []uint16
+	for i := 0; i+1 < nameLen; i += 2 {
+		unit := le.Uint16(raw[i:])
+		if unit == 0 {
+			break
+		}
+		units = append(units, unit)
+	}
+
+	return cfbEntry{
+		name:  string(utf16.Decode(units)),
+		kind:  raw[0x42],
+		start: le.Uint32(raw[0x74:]),
+		// Version 3 files may leave garbage in the high half of the size
+		size: uint64(le.Uint32(raw[0x78:])),
+	}
+}
+
+// sector returns the bytes of a regular sector
+func (f *cfbFile) sector(n uint32) ([]byte, error) {
+	if n > cfbMaxRegSect {
+		return nil, fmt.Errorf("invalid sector number %#x", n)
+	}
+	start := (int64(n) + 1) * int64(f.sectorSize)
+	end := start + int64(f.sectorSize)
+	if end > int64(len(f.data)) {
+		// The last sector of a file is sometimes truncated
+		if start >= int64(len(f.data)) {
+			return nil, fmt.Errorf("sector %d beyond end of file", n)
+		}
+		padded := make([]byte, f.sectorSize)
+		copy(padded, f.data[start:])
+		return padded, nil
+	}
+	return f.data[start:end], nil
+}
+
+// chain concatenates the sectors of a FAT chain. size < 0 reads the whole chain.
+func (f *cfbFile) chain(start uint32, size int64) ([]byte, error) {
+	var out []byte
+	seen := 0
+	for n := start; n != cfbEndOfChain; {
+		if n == cfbFreeSect || int(n) >= len(f.fat) {
+			return nil, fmt.Errorf("broken sector chain at %#x", n)
+		}
+		// A chain can't be longer than the FAT; anything more is a loop
+		seen++
+		if seen > len(f.fat) {
+			return nil, fmt.Errorf("sector chain loops")
+		}
+
+		sector, err := f.sector(n)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, sector...)
+		if size >= 0 && int64(len(out)) >= size {
+			break
+		}
+		n = f.fat[n]
+	}
+
+	if size >= 0 {
+		if int64(len(out)) < size {
+			return nil, fmt.Errorf("stream shorter than its declared size")
+		}
+		out = out[:size]
+	}
+	return out, nil
+}
+
+// miniChain concatenates the mini sectors of a mini FAT chain
+func (f *cfbFile) miniChain(start uint32, size int64) ([]byte, error) {
+	var out []byte
+	seen := 0
+	for n := start; n != cfbEndOfChain && int64(len(out)) < size; {
+		if int(n) >= len(f.miniFAT) {
+			return nil, fmt.Errorf("broken mini sector chain at %#x", n)
+		}
+		seen++
+		if seen > len(f.miniFAT) {
+			return nil, fmt.Errorf("mini sector chain loops")
+		}
+
+		begin := int(n) * f.miniSectorSize
+		end := begin + f.miniSectorSize
+		if end > len(f.miniStream) {
+			return nil, fmt.Errorf("mini sector %d beyond mini stream", n)
+		}
+		out = append(out, f.miniStream[begin:end]...)
+		n = f.miniFAT[n]
+	}
+
+	if int64(len(out)) < size {
+		return nil, fmt.Errorf("stream shorter than its declared size")
+	}
+	return out[:size], nil
+}
+
+// stream returns the content of the named stream, or ok=false if it does not exist
+func (f *cfbFile) stream(name string) ([]byte, bool, error) {
+	for _, entry := range f.entries {
+		if entry.kind != cfbTypeStream || entry.name != name {
+			continue
+		}
+		if entry.size > uint64(len(f.data)) {
+			return nil, true, fmt.Errorf("stream %s is larger than the file", name)
+		}
+
+		var data []byte
+		var err error
+		if entry.size < uint64(f.miniCutoff) {
+			data, err = f.miniChain(entry.start, int64(entry.size))
+		} else {
+			data, err = f.chain(entry.start, int64(entry.size))
+		}
+		if err != nil {
+			return nil, true, fmt.Errorf("failed to read stream %s: %w", name, err)
+		}
+		return data, true, nil
+	}
+	return nil, false, nil
+}
@@ -0,0 +1,23 @@
+package analyzer
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestParseCFBDIFATLoop(t *testing.T) {
+	data := make([]byte, 2*512)
+	copy(data, cfbSignature)
+	le := binary.LittleEndian
+	le.PutUint16(data[0x1E:], 9)
+	le.PutUint16(data[0x20:], 6)
+	le.PutUint32(data[0x2C:], 0xFFFFFFFF) // FAT sectors
+	le.PutUint32(data[0x44:], 0)          // first DIFAT sector, which links to itself
+	le.PutUint32(data[0x48:], 0xFFFFFFFF) // DIFAT sectors
+
+	_, err := parseCFB(data)
+	if err == nil || !strings.Contains(err.Error(), "loops") {
+		t.Fatalf("parseCFB: got %v, want a DIFAT loop error", err)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"local-agent/config"
@@ -27,15 +29,17 @@ func NewChunker(cfg *config.ChunkingConfig) *Chunker {
 	}
 }
 
-// ChunkFile chunks a file according to the configured strategy
-func (c *Chunker) ChunkFile(path string) ([]types.FileChunk, error) {
+// ChunkFile chunks a file according to the configured strategy. The smart
+// strategy breaks at boundaries of the file's language, a code fence
+// identifier such as "makefile", when it has any.
+func (c *Chunker) ChunkFile(path, language string) ([]types.FileChunk, error) {
 	switch strings.ToLower(c.config.Strategy) {
 	case "lines":
 		return c.chunkByLines(path)
 	case "tokens":
 		return c.chunkByTokens(path)
 	case "smart":
-		return c.chunkSmart(path)
+		return c.chunkSmart(path, language)
 	default:
 		return c.chunkByLines(path)
 	}
@@ -94,6 +98,214 @@ func (c *Chunker) ChunkContent(content string) ([]types.FileChunk, error) {
 	return chunks, nil
 }
 
+// hasSections reports whether a file type's extracted content is split into
+// "## " sections that ChunkSections can cite
+func hasSections(fileType types.FileType) bool {
+	switch fileType {
+	case types.TypePDF, types.TypeDOCX, types.TypeXLSX, types.TypeODS, types.TypePPTX, types.TypeODT, types.TypePCAP, types.TypeLog, types.TypeNotebook, types.TypeCSV, types.TypeSQLite, types.TypeExecutable, types.TypeIaC:
+		return true
+	}
+	return false
+}
+
+// numberedHeadingPattern matches the section titles of extracted
+// presentations ("Slide 3: Agenda"), PDFs ("Page 14") and notebooks
+// ("Cell 7 (code, In [5])")
+var numberedHeadingPattern = regexp.MustCompile(`^(Slide|Page|Cell) (\d+)\b`)
+
+// ChunkSections chunks extracted Markdown content that is organised in "## "
+// sections (e.g. one per spreadsheet sheet). Chunks never span sections and
+// continuation chunks repeat the section heading and any table header, so
+// each chunk stands on its own. When a table's first column is "Row" (or
+// another of numberedColumns), the chunk's Section also names the row range
+// it covers.
+func (c *Chunker) ChunkSections(content string) ([]types.FileChunk, error) {
+	lines := strings.Split(content, "\n")
+
+	// Byte offset of each line start, plus the end of content
+	offsets := make([]int64, len(lines)+1)
+	for i, line := range lines {
+		offsets[i+1] = offsets[i] + int64(len(line)) + 1
+	}
+	offsets[len(lines)]--
+
+	var chunks []types.FileChunk
+	emit := func(title string, prefix []string, from, to int, rowUnit string) {
+		body := lines[from:to]
+		chunkContent := strings.TrimRight(strings.Join(append(append([]string(nil), prefix...), body...), "\n"), "\n")
+
+		section := title
+		if rowUnit != "" {
+			first, last := tableRowNumber(body, true), tableRowNumber(body, false)
+			if first != "" && first == last {
+				section = fmt.Sprintf("%s, %s %s", title, rowUnit, first)
+			} else if first != "" {
+				// Dates already contain hyphens
+				separator := "-"
+				if strings.Contains(first+last, "-") {
+					separator = " to "
+				}
+				section = fmt.Sprintf("%s, %ss %s%s%s", title, rowUnit, first, separator, last)
+			}
+		}
+
+		chunk := types.FileChunk{
+			Index:       len(chunks),
+			StartLine:   from + 1,
+			EndLine:     to,
+			StartOffset: offsets[from],
+			EndOffset:   offsets[to],
+			Content:     chunkContent,
+			TokenCount:  c.estimateTokens(chunkContent),
+			Section:     section,
+		}
+		if match := numberedHeadingPattern.FindStringSubmatch(title); match != nil {
+			number, _ := strconv.Atoi(match[2])
+			switch match[1] {
+			case "Slide":
+				chunk.Slide = number
+			case "Cell":
+				chunk.Cell = number
+			default:
+				chunk.Page = number
+			}
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	size := c.config.ChunkSize
+	if size < 1 {
+		size = 1
+	}
+
+	// "## " lines inside code blocks, e.g. notebook code comments, are not headings
+	fenced := fencedLines(lines)
+
+	for start := 0; start < len(lines); {
+		end := start + 1
+		for end < len(lines) && (fenced[end] || !strings.HasPrefix(lines[end], "## ")) {
+			end++
+		}
+		next := end
+		// Blank lines separating sections do not get chunks of their own
+		for end > start+1 && strings.TrimSpace(lines[end-1]) == "" {
+			end--
+		}
+
+		title := ""
+		if strings.HasPrefix(lines[start], "## ") {
+			title = strings.TrimSpace(strings.TrimPrefix(lines[start], "## "))
+		}
+
+		// Locate the table header (header row and |---| separator)
+		headerAt := -1
+		for i := start; i+1 < end; i++ {
+			if strings.HasPrefix(lines[i], "|") && strings.HasPrefix(lines[i+1], "|---") {
+				headerAt = i
+				break
+			}
+		}
+		rowUnit := ""
+		if headerAt >= 0 {
+			rowUnit = numberedColumnUnit(lines[headerAt])
+		}
+
+		var prefix []string
+		for from := start; from < end; {
+			to := from + size
+			if to > end {
+				to = end
+			}
+			// Keep the table header with the first rows rather than ending a chunk on it
+			if headerAt >= from && headerAt+2 > to && headerAt+2 <= end && from < headerAt {
+				to = headerAt
+			}
+
+			unit := ""
+			if to > headerAt+2 {
+				unit = rowUnit
+			}
+			emit(title, prefix, from, to, unit)
+			from = to
+
+			prefix = nil
+			if title != "" {
+				prefix = append(prefix, lines[start], "")
+			}
+			if headerAt >= 0 && from >= headerAt+2 {
+				prefix = append(prefix, lines[headerAt], lines[headerAt+1])
+			}
+		}
+
+		start = next
+	}
+
+	return chunks, nil
+}
+
+// fencedLines reports which lines are inside a fenced code block, fences
+// included. A block is closed by a backtick fence at least as long as the
+// one that opened it.
+func fencedLines(lines []string) []bool {
+	fenced := make([]bool, len(lines))
+	open := 0
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		run := len(trimmed) - len(strings.TrimLeft(trimmed, "`"))
+		switch {
+		case open == 0 && run >= 3:
+			open = run
+			fenced[i] = true
+		case open > 0:
+			fenced[i] = true
+			if run >= open && run == len(trimmed) {
+				open = 0
+			}
+		}
+	}
+	return fenced
+}
+
+// numberedColumns maps the first header cell of tables whose rows are
+// numbered or timed (spreadsheet rows, capture flows, capture timeline
+// windows) to the unit used in section labels
+var numberedColumns = map[string]string{
+	"| Row |":          "row",
+	"| Flow |":         "flow",
+	"| Window (UTC) |": "window",
+}
+
+// numberedColumnUnit returns the label unit for a numbered table header, or ""
+func numberedColumnUnit(header string) string {
+	for prefix, unit := range numberedColumns {
+		if strings.HasPrefix(header, prefix) {
+			return unit
+		}
+	}
+	return ""
+}
+
+// tableRowNumber returns the first-column value of the first (or last) table
+// body row among lines, skipping the header and separator
+func tableRowNumber(lines []string, first bool) string {
+	for i := range lines {
+		idx := i
+		if !first {
+			idx = len(lines) - 1 - i
+		}
+		line := lines[idx]
+		if !strings.HasPrefix(line, "|") || strings.HasPrefix(line, "|---") || numberedColumnUnit(line) != "" {
+			continue
+		}
+		cells := strings.SplitN(line, "|", 3)
+		if len(cells) < 3 {
+			continue
+		}
+		return strings.TrimSpace(cells[1])
+	}
+	return ""
+}
+
 // chunkByLines splits a file into chunks by line count
 func (c *Chunker) chunkByLines(path string) ([]types.FileChunk, error) {
 	file, err := os.Open(path)
@@ -102,7 +314,8 @@ func (c *Chunker) chunkByLines(path string) ([]types.FileChunk, error) {
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
+	text, _ := newTextReader(file)
+	scanner := bufio.NewScanner(text)
 	scanner.Buffer(make([]byte, 0, 1024*1024), 1024*1024) // allow large lines
 	var chunks []types.FileChunk
 	var currentLines []string
@@ -227,7 +440,7 @@ func (c *Chunker) chunkByTokens(path string) ([]types.FileChunk, error) {
 }
 
 // chunkSmart uses context-aware chunking (functions, classes, etc.)
-func (c *Chunker) chunkSmart(path string) ([]types.FileChunk, error) {
+func (c *Chunker) chunkSmart(path, language string) ([]types.FileChunk, error) {
 	// For now, use token-based chunking with smarter boundaries
 	// In a full implementation, this would parse the code structure
 	// and chunk at logical boundaries (function/class boundaries)
@@ -254,7 +467,7 @@ func (c *Chunker) chunkSmart(path string) ([]types.FileChunk, error) {
 		if shouldChunk {
 			// Try to chunk at logical boundaries
 			// Look ahead for a good break point (empty line, function definition, etc.)
-			if c.isLogicalBoundary(line) || (i < len(lines)-1 && c.isLogicalBoundary(lines[i+1])) {
+			if c.isLogicalBoundary(line, language) || (i < len(lines)-1 && c.isLogicalBoundary(lines[i+1], language)) {
 				chunkContent := strings.Join(currentChunk, "\n")
 				chunk := types.FileChunk{
 					Index:       len(chunks),
@@ -297,8 +510,24 @@ func (c *Chunker) chunkSmart(path string) ([]types.FileChunk, error) {
 	return chunks, nil
 }
 
+// languageBoundaries match the lines that start a new unit in languages
+// whose units the generic prefixes miss, e.g. build stages in a Dockerfile
+// or targets in a Makefile
+var languageBoundaries = map[string]*regexp.Regexp{
+	"dockerfile": regexp.MustCompile(`(?i)^FROM\s`),
+	"makefile":   regexp.MustCompile(`^(?:define\s|[^\s#=:][^=:]*::?(?:[^=]|$))`),
+	"bash":       regexp.MustCompile(`^(?:function\s+[\w.:-]+|[\w.:-]+\s*\(\s*\))`),
+	"zsh":        regexp.MustCompile(`^(?:function\s+[\w.:-]+|[\w.:-]+\s*\(\s*\))`),
+	"ruby":       regexp.MustCompile(`^\s*(?:def|module)\s`),
+	"groovy":     regexp.MustCompile(`^\s*(?:stage|node|pipeline|post)\s*[({]`),
+	"perl":       regexp.MustCompile(`^(?:sub|package)\s`),
+	"lua":        regexp.MustCompile(`^(?:local\s+)?function\s`),
+	"cmake":      regexp.MustCompile(`(?i)^(?:function|macro)\s*\(`),
+	"starlark":   regexp.MustCompile(`^[a-z_]+\($`),
+}
+
 // isLogicalBoundary checks if a line is a good place to chunk
-func (c *Chunker) isLogicalBoundary(line string) bool {
+func (c *Chunker) isLogicalBoundary(line, language string) bool {
 	trimmed := strings.TrimSpace(line)
 
 	// Empty line
@@ -306,6 +535,10 @@ func (c *Chunker) isLogicalBoundary(line string) bool {
 		return true
 	}
 
+	if pattern, ok := languageBoundaries[language]; ok && pattern.MatchString(line) {
+		return true
+	}
+
 	// Function/method definitions (Go, Python, JavaScript, etc.)
 	prefixes := []string{
 		"func ", "def ", "function ", "class ",
@@ -0,0 +1,533 @@
+package analyzer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"local-agent/config"
+	"local-agent/security"
+	"local-agent/types"
+)
+
+// Limits of the CSV/TSV profile
+const (
+	maxCSVColumns    = 100   // columns profiled per table
+	maxCSVDistinct   = 10000 // distinct values counted exactly per column
+	maxCSVTopValues  = 50    // columns with more distinct values list no top values
+	csvPIISampleSize = 1000  // non-null values per column scanned for PII
+	csvPIIMinShare   = 0.1   // share of sampled values a PII pattern must match
+)
+
+// csvNullValues are the cell values counted as missing, compared lowercased
+var csvNullValues = map[string]bool{
+	"": true, "na": true, "n/a": true, "#n/a": true, "null": true, "none": true, "nan": true, "nil": true,
+}
+
+// csvDelimiters are the delimiters sniffed from the first line of a .csv
+var csvDelimiters = []rune{',', ';', '\t', '|'}
+
+// csvDelimiterNames names the delimiters in the profile
+var csvDelimiterNames = map[rune]string{',': "comma", ';': "semicolon", '\t': "tab", '|': "pipe"}
+
+// csvTimeLayouts are the date and time formats recognized in cells
+var csvTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02",
+	"01/02/2006",
+	"02.01.2006",
+}
+
+// csvPIIColumnPattern matches column names that suggest personal data even
+// when the values match no PII pattern
+var csvPIIColumnPattern = regexp.MustCompile(`(?i)(^|[_\s.-])(e-?mail|phone|mobile|ssn|social_?security|(first|last|full|middle|sur)_?name|surname|address|street|zip_?code|postal_?code|dob|birth_?date|date_?of_?birth|passport|iban|card_?number|national_?id|ip_?address)($|[_\s.-])`)
+
+// csvPIISeverity ranks the PII patterns reported by security.Validator
+var csvPIISeverity = map[string]types.Severity{
+	"ssn":         types.SeverityHigh,
+	"credit_card": types.SeverityHigh,
+	"email":       types.SeverityMedium,
+	"phone":       types.SeverityMedium,
+}
+
+// csvColumn accumulates the profile of one column
+type csvColumn struct {
+	name   string
+	values int // non-null cells
+	nulls  int
+
+	ints, floats, bools, dates int // cells parsing as each type; floats include ints
+	datesWithTime              int
+
+	min, max, sum    float64 // over numeric cells
+	minTime, maxTime time.Time
+	minLen, maxLen   int // in runes, over non-null cells
+
+	distinct map[uint64]struct{} // hashes of the values, up to maxCSVDistinct
+	overflow bool                // more than maxCSVDistinct distinct values
+	top      map[string]int      // value counts; nil above maxCSVTopValues distinct values
+
+	piiSample []string
+}
+
+// csvPIIMatch is a PII pattern matched in a column's sampled values
+type csvPIIMatch struct {
+	pattern string
+	share   float64
+}
+
+// csvProfile accumulates the statistics of a table
+type csvProfile struct {
+	opts      config.CSVConfig
+	delimiter rune
+	header    bool
+	columns   []*csvColumn
+	width     int // fields in the header (or first) row
+
+	rows      int // data rows
+	ragged    int // rows whose field count differs from the header
+	malformed int // rows the CSV reader rejected
+	preview   []sheetRow
+}
+
+// ReadCSVContent profiles a CSV or TSV file in one pass: the type, null
+// rate, cardinality and range of each column, which columns look like PII
+// (per security.Validator.ScanForPII on a sample of each column), and the
+// first rows as a Markdown table. Files up to types.SmallFileSizeBytes are
+// shown in full. PII columns are also returned as findings.
+func (d *Detector) ReadCSVContent(path string, opts config.CSVConfig, validator *security.Validator) (string, []types.Finding, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to open table: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to stat table: %w", err)
+	}
+
+	text, _ := newTextReader(file)
+	reader := bufio.NewReaderSize(text, 64*1024)
+
+	profile := &csvProfile{opts: opts, delimiter: '\t'}
+	if !strings.EqualFold(filepath.Ext(path), ".tsv") {
+		first, _ := reader.Peek(64 * 1024)
+		profile.delimiter = sniffCSVDelimiter(first)
+	}
+	previewRows := opts.PreviewRows
+	if stat.Size() <= types.SmallFileSizeBytes {
+		previewRows = maxSheetRows
+	}
+
+	records := csv.NewReader(reader)
+	records.Comma = profile.delimiter
+	records.FieldsPerRecord = -1
+	records.LazyQuotes = true
+
+	for num := 1; ; num++ {
+		record, err := records.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			profile.malformed++
+			continue
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read table: %w", err)
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		if profile.columns == nil {
+			profile.start(record)
+			if profile.header {
+				continue
+			}
+		}
+		profile.add(record)
+		if len(profile.preview) < previewRows {
+			profile.preview = append(profile.preview, sheetRow{num: num, cells: append([]string(nil), record...)})
+		}
+	}
+
+	if profile.columns == nil {
+		return "", nil, fmt.Errorf("no rows found in table")
+	}
+
+	pii := make([][]csvPIIMatch, len(profile.columns))
+	var findings []types.Finding
+	for i, column := range profile.columns {
+		pii[i] = column.scanPII(validator, path)
+
+		// Column names alone are a hint in the profile, not a finding
+		severity := types.SeverityLow
+		var patterns []string
+		for _, match := range pii[i] {
+			if match.share == 0 {
+				continue
+			}
+			if rank, ok := csvPIISeverity[match.pattern]; ok && severityRanks[rank] < severityRanks[severity] {
+				severity = rank
+			}
+			patterns = append(patterns, fmt.Sprintf("%s in %.0f%% of sampled values", match.pattern, match.share*100))
+		}
+		if len(patterns) == 0 {
+			continue
+		}
+		findings = append(findings, types.Finding{
+			Severity:    severity,
+			Category:    "pii",
+			Description: fmt.Sprintf("Column %q looks like personal data: %s", column.name, strings.Join(patterns, ", ")),
+			Suggestion:  "Check that this data may be stored here; mask, hash or drop the column if it is not needed",
+		})
+	}
+
+	return profile.render(pii), findings, nil
+}
+
+// sniffCSVDelimiter picks the delimiter occurring most often outside quotes
+// in the first line, defaulting to a comma
+func sniffCSVDelimiter(data []byte) rune {
+	line := string(data)
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	counts := make(map[rune]int)
+	quoted := false
+	for _, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		} else if !quoted {
+			counts[r]++
+		}
+	}
+	best := ','
+	for _, delimiter := range csvDelimiters {
+		if counts[delimiter] > counts[best] {
+			best = delimiter
+		}
+	}
+	return best
+}
+
+// start sets up the columns from the first row, which is taken as a header
+// unless one of its cells is a number
+func (p *csvProfile) start(record []string) {
+	p.header = true
+	for _, cell := range record {
+		if _, err := strconv.ParseFloat(strings.TrimSpace(cell), 64); err == nil {
+			p.header = false
+			break
+		}
+	}
+
+	p.width = len(record)
+	for col := 0; col < min(len(record), maxCSVColumns); col++ {
+		name := ""
+		if p.header {
+			name = strings.TrimSpace(record[col])
+		}
+		if name == "" {
+			name = columnName(col)
+		}
+		p.columns = append(p.columns, &csvColumn{name: name, distinct: make(map[uint64]struct{}), top: make(map[string]int)})
+	}
+}
+
+// add profiles one data row; missing fields count as nulls
+func (p *csvProfile) add(record []string) {
+	p.rows++
+	if len(record) != p.width {
+		p.ragged++
+	}
+	for col, column := range p.columns {
+		value := ""
+		if col < len(record) {
+			value = record[col]
+		}
+		column.add(value)
+	}
+}
+
+func (c *csvColumn) add(raw string) {
+	value := strings.TrimSpace(raw)
+	if csvNullValues[strings.ToLower(value)] {
+		c.nulls++
+		return
+	}
+	c.values++
+
+	length := len([]rune(value))
+	if c.values == 1 || length < c.minLen {
+		c.minLen = length
+	}
+	if length > c.maxLen {
+		c.maxLen = length
+	}
+
+	if number, err := strconv.ParseFloat(value, 64); err == nil && !math.IsInf(number, 0) {
+		if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+			c.ints++
+		}
+		if c.floats == 0 || number < c.min {
+			c.min = number
+		}
+		if c.floats == 0 || number > c.max {
+			c.max = number
+		}
+		c.floats++
+		c.sum += number
+	} else if lower := strings.ToLower(value); lower == "true" || lower == "false" || lower == "yes" || lower == "no" {
+		c.bools++
+	} else if ts, layout, ok := parseCSVTime(value); ok {
+		c.dates++
+		if len(layout) > len("2006-01-02") {
+			c.datesWithTime++
+		}
+		if c.minTime.IsZero() || ts.Before(c.minTime) {
+			c.minTime = ts
+		}
+		if ts.After(c.maxTime) {
+			c.maxTime = ts
+		}
+	}
+
+	if !c.overflow {
+		hash := fnv.New64a()
+		hash.Write([]byte(value))
+		c.distinct[hash.Sum64()] = struct{}{}
+		if len(c.distinct) > maxCSVDistinct {
+			c.overflow = true
+			c.distinct = nil
+		}
+	}
+	if c.top != nil {
+		c.top[value]++
+		if len(c.top) > maxCSVTopValues {
+			c.top = nil
+		}
+	}
+
+	if len(c.piiSample) < csvPIISampleSize {
+		c.piiSample = append(c.piiSample, strings.ReplaceAll(truncateRunes(value, maxCellLength), "\n", " "))
+	}
+}
+
+// parseCSVTime parses a date or timestamp cell, returning the layout matched
+func parseCSVTime(value string) (time.Time, string, bool) {
+	if len(value) < 8 || len(value) > 35 || value[0] < '0' || value[0] > '9' {
+		return time.Time{}, "", false
+	}
+	for _, layout := range csvTimeLayouts {
+		if ts, err := time.Parse(layout, value); err == nil {
+			return ts, layout, true
+		}
+	}
+	return time.Time{}, "", false
+}
+
+// kind names the inferred type of the column. Columns with a few values
+// that break the type are text, with the share of the dominant type noted.
+func (c *csvColumn) kind() string {
+	switch {
+	case c.values == 0:
+		return "empty"
+	case c.ints == c.values:
+		return "integer"
+	case c.floats == c.values:
+		return "float"
+	case c.bools == c.values:
+		return "boolean"
+	case c.dates == c.values && c.datesWithTime > 0:
+		return "datetime"
+	case c.dates == c.values:
+		return "date"
+	}
+
+	for _, typed := range []struct {
+		name  string
+		count int
+	}{{"numeric", c.floats}, {"date", c.dates}, {"boolean", c.bools}} {
+		if share := float64(typed.count) / float64(c.values); share >= 0.8 {
+			return fmt.Sprintf("text (%.0f%% %s)", math.Floor(share*100), typed.name)
+		}
+	}
+	return "text"
+}
+
+// scanPII runs the PII patterns over the column's sampled values and
+// returns those matching at least csvPIIMinShare of them. Plain integers
+// are not reported as phone numbers, which IDs would match. A column whose
+// name suggests personal data is reported as "column name".
+func (c *csvColumn) scanPII(validator *security.Validator, path string) []csvPIIMatch {
+	var matches []csvPIIMatch
+	if validator != nil && len(c.piiSample) > 0 {
+		lines := make(map[string]map[int]bool)
+		for _, violation := range validator.ScanForPII(strings.Join(c.piiSample, "\n"), path) {
+			if lines[violation.Pattern] == nil {
+				lines[violation.Pattern] = make(map[int]bool)
+			}
+			lines[violation.Pattern][violation.Line] = true
+		}
+		for pattern, matched := range lines {
+			if pattern == "phone" && c.kind() == "integer" {
+				continue
+			}
+			if share := float64(len(matched)) / float64(len(c.piiSample)); share >= csvPIIMinShare {
+				matches = append(matches, csvPIIMatch{pattern: pattern, share: share})
+			}
+		}
+		sort.Slice(matches, func(i, j int) bool {
+			if matches[i].share != matches[j].share {
+				return matches[i].share > matches[j].share
+			}
+			return matches[i].pattern < matches[j].pattern
+		})
+	}
+	if len(matches) == 0 && csvPIIColumnPattern.MatchString(c.name) {
+		matches = append(matches, csvPIIMatch{pattern: "column name"})
+	}
+	return matches
+}
+
+// render writes the profile: a Summary, the Columns table and the first rows
+func (p *csvProfile) render(pii [][]csvPIIMatch) string {
+	var builder strings.Builder
+
+	builder.WriteString("## Summary\n\n")
+	builder.WriteString(fmt.Sprintf("- Delimiter: %s\n", csvDelimiterNames[p.delimiter]))
+	if p.header {
+		builder.WriteString(fmt.Sprintf("- Rows: %d, plus a header row\n", p.rows))
+	} else {
+		builder.WriteString(fmt.Sprintf("- Rows: %d (no header row; columns are named by letter)\n", p.rows))
+	}
+	builder.WriteString(fmt.Sprintf("- Columns: %d\n", p.width))
+	if p.width > len(p.columns) {
+		builder.WriteString(fmt.Sprintf("- Columns profiled: first %d\n", len(p.columns)))
+	}
+	if p.ragged > 0 {
+		builder.WriteString(fmt.Sprintf("- Rows with a different number of fields than the first row: %d\n", p.ragged))
+	}
+	if p.malformed > 0 {
+		builder.WriteString(fmt.Sprintf("- Malformed rows skipped: %d\n", p.malformed))
+	}
+	var piiColumns []string
+	for i, column := range p.columns {
+		if len(pii[i]) > 0 {
+			piiColumns = append(piiColumns, column.name)
+		}
+	}
+	if len(piiColumns) > 0 {
+		builder.WriteString(fmt.Sprintf("- Possible PII columns: %s\n", strings.Join(piiColumns, ", ")))
+	}
+
+	builder.WriteString("\n## Columns\n\n")
+	builder.WriteString("Nulls are empty cells and NA, N/A, null, None, NaN or nil. PII is checked on the first ")
+	builder.WriteString(fmt.Sprintf("%d values of each column.\n\n", csvPIISampleSize))
+	builder.WriteString("| # | Column | Type | Nulls | Distinct | Range | Mean | Top values | PII |\n")
+	builder.WriteString("|---|---|---|---|---|---|---|---|---|\n")
+	for i, column := range p.columns {
+		nulls := "0%"
+		if share := float64(column.nulls) / float64(column.values+column.nulls) * 100; share >= 0.1 {
+			nulls = fmt.Sprintf("%.1f%%", share)
+		} else if column.nulls > 0 {
+			nulls = "<0.1%"
+		}
+		distinct := strconv.Itoa(len(column.distinct))
+		if column.overflow {
+			distinct = fmt.Sprintf(">%d", maxCSVDistinct)
+		}
+
+		kind := column.kind()
+		rangeText, mean := "", ""
+		switch {
+		case kind == "integer" || kind == "float":
+			rangeText = formatDecimal(column.min) + " to " + formatDecimal(column.max)
+			mean = strconv.FormatFloat(column.sum/float64(column.floats), 'g', 6, 64)
+		case kind == "date":
+			rangeText = column.minTime.Format("2006-01-02") + " to " + column.maxTime.Format("2006-01-02")
+		case kind == "datetime":
+			rangeText = formatRecordTime(column.minTime) + " to " + formatRecordTime(column.maxTime)
+		case kind != "boolean" && column.values > 0:
+			rangeText = fmt.Sprintf("length %d to %d", column.minLen, column.maxLen)
+		}
+
+		var piiText []string
+		for _, match := range pii[i] {
+			if match.share > 0 {
+				piiText = append(piiText, fmt.Sprintf("%s (%.0f%%)", match.pattern, match.share*100))
+			} else {
+				piiText = append(piiText, match.pattern)
+			}
+		}
+
+		builder.WriteString(fmt.Sprintf("| %d | %s | %s | %s | %s | %s | %s | %s | %s |\n",
+			i+1, escapeTableCell(column.name), kind, nulls, distinct, rangeText, mean,
+			escapeTableCell(column.topValues(kind)), strings.Join(piiText, ", ")))
+	}
+
+	if len(p.preview) > 0 {
+		builder.WriteString("\n## Rows\n\n")
+		if len(p.preview) < p.rows {
+			builder.WriteString(fmt.Sprintf("First %d of %d rows.\n\n", len(p.preview), p.rows))
+		}
+		builder.WriteString("| Row |")
+		for _, column := range p.columns {
+			builder.WriteString(" " + escapeTableCell(column.name) + " |")
+		}
+		builder.WriteString("\n|---|" + strings.Repeat("---|", len(p.columns)) + "\n")
+		for _, row := range p.preview {
+			builder.WriteString(fmt.Sprintf("| %d |", row.num))
+			for col := range p.columns {
+				value := ""
+				if col < len(row.cells) {
+					value = escapeTableCell(row.cells[col])
+				}
+				builder.WriteString(" " + value + " |")
+			}
+			builder.WriteString("\n")
+		}
+	}
+
+	return strings.TrimSpace(builder.String())
+}
+
+// topValues lists the three most frequent values of a low-cardinality text
+// or boolean column with their shares, e.g. "active 60%, closed 40%"
+func (c *csvColumn) topValues(kind string) string {
+	if c.top == nil || c.values == 0 || (kind != "boolean" && !strings.HasPrefix(kind, "text")) {
+		return ""
+	}
+	var parts []string
+	for _, entry := range sortedCounts(c.top, 3) {
+		parts = append(parts, fmt.Sprintf("%s %.0f%%", truncateRunes(entry.key, 40), float64(entry.value)/float64(c.values)*100))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatCSVNumber prints a number without exponent for common magnitudes
+func formatDecimal(value float64) string {
+	if math.Abs(value) < 1e15 {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(value, 'g', 6, 64)
+}
@@ -0,0 +1,342 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+
+	"local-agent/types"
+)
+
+const (
+	// minHashSize is the number of hash functions in a MinHash signature
+	minHashSize = 128
+	// minHashBands split signatures for locality-sensitive hashing; files
+	// agreeing on all rows of any band are compared
+	minHashBands = 32
+	// shingleWords is the number of words in a shingle
+	shingleWords = 5
+	// minShingles is the number of shingles below which files are only
+	// grouped when identical, as short files look alike too easily
+	minShingles = 20
+	// maxListedDuplicates caps the members named in a duplicate note
+	maxListedDuplicates = 10
+)
+
+// GroupDuplicates hashes the content of the scanned files and groups those
+// that are identical or, per MinHash over word shingles, near-identical.
+// The representative of each group is the file outside archives and vendored
+// directories with the shortest path; the other members point to it through
+// DuplicateOf and are not analyzed.
+func (a *Analyzer) GroupDuplicates(result *types.ScanResult) {
+	if !a.config.Dedupe.Enabled {
+		return
+	}
+
+	var candidates []int
+	for i := range result.Files {
+		file := &result.Files[i]
+		file.ContentHash, file.DuplicateOf, file.Duplicates = "", "", nil
+		if !file.IsReadable || file.IsSensitive || file.Content == "" {
+			continue
+		}
+		sum := sha256.Sum256([]byte(file.Content))
+		file.ContentHash = hex.EncodeToString(sum[:])
+		candidates = append(candidates, i)
+	}
+
+	parent := make(map[int]int, len(candidates))
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i, j int) {
+		if ri, rj := find(i), find(j); ri != rj {
+			parent[rj] = ri
+		}
+	}
+
+	// Identical files share their hash
+	byHash := make(map[string]int)
+	var distinct []int
+	for _, i := range candidates {
+		parent[i] = i
+		if first, ok := byHash[result.Files[i].ContentHash]; ok {
+			union(first, i)
+			continue
+		}
+		byHash[result.Files[i].ContentHash] = i
+		distinct = append(distinct, i)
+	}
+
+	// Near-identical files share a band of their MinHash signatures
+	threshold := a.config.Dedupe.Similarity
+	signatures := make(map[int][]uint64)
+	if threshold < 1 {
+		buckets := make(map[string][]int)
+		for _, i := range distinct {
+			signature := minHashSignature(result.Files[i].Content)
+			if signature == nil {
+				continue
+			}
+			signatures[i] = signature
+			rows := minHashSize / minHashBands
+			for band := 0; band < minHashBands; band++ {
+				key := make([]byte, 0, 2+rows*8)
+				key = binary.LittleEndian.AppendUint16(key, uint16(band))
+				for _, value := range signature[band*rows : (band+1)*rows] {
+					key = binary.LittleEndian.AppendUint64(key, value)
+				}
+				buckets[string(key)] = append(buckets[string(key)], i)
+			}
+		}
+
+		for _, bucket := range buckets {
+			for x := 0; x < len(bucket); x++ {
+				for y := x + 1; y < len(bucket); y++ {
+					if find(bucket[x]) != find(bucket[y]) && signatureSimilarity(signatures[bucket[x]], signatures[bucket[y]]) >= threshold {
+						union(bucket[x], bucket[y])
+					}
+				}
+			}
+		}
+	}
+
+	groups := make(map[int][]int)
+	for _, i := range candidates {
+		root := find(i)
+		groups[root] = append(groups[root], i)
+	}
+
+	// Banding links files transitively, so a group can hold files far apart
+	// from each other. Each group is split until every member is identical
+	// or similar enough to its own representative.
+	result.Duplicates = nil
+	for _, members := range groups {
+		sort.Slice(members, func(x, y int) bool {
+			return representativeBefore(&result.Files[members[x]], &result.Files[members[y]])
+		})
+
+		for len(members) > 1 {
+			representative := &result.Files[members[0]]
+			group := types.DuplicateGroup{
+				Representative: representative.RelPath,
+				Identical:      true,
+				Similarity:     1,
+			}
+
+			var rest []int
+			for _, i := range members[1:] {
+				member := &result.Files[i]
+				similarity := 1.0
+				if member.ContentHash != representative.ContentHash {
+					similarity = signatureSimilarity(minHashOf(signatures, result.Files, members[0]), minHashOf(signatures, result.Files, i))
+					if similarity < threshold {
+						rest = append(rest, i)
+						continue
+					}
+					group.Identical = false
+				}
+
+				member.DuplicateOf = representative.RelPath
+				representative.Duplicates = append(representative.Duplicates, member.RelPath)
+				group.Members = append(group.Members, member.RelPath)
+				group.TokensSaved += member.TokenCount
+				group.Similarity = min(group.Similarity, similarity)
+			}
+
+			if len(group.Members) > 0 {
+				result.Duplicates = append(result.Duplicates, group)
+			}
+			members = rest
+		}
+	}
+
+	sort.Slice(result.Duplicates, func(x, y int) bool {
+		if result.Duplicates[x].TokensSaved != result.Duplicates[y].TokensSaved {
+			return result.Duplicates[x].TokensSaved > result.Duplicates[y].TokensSaved
+		}
+		return result.Duplicates[x].Representative < result.Duplicates[y].Representative
+	})
+}
+
+// minHashOf returns the signature of file i, computing it for files that
+// were grouped by hash and never signed
+func minHashOf(signatures map[int][]uint64, files []types.FileInfo, i int) []uint64 {
+	if signature, ok := signatures[i]; ok {
+		return signature
+	}
+	for j, signature := range signatures {
+		if files[j].ContentHash == files[i].ContentHash {
+			return signature
+		}
+	}
+	return minHashSignature(files[i].Content)
+}
+
+// representativeBefore orders the members of a duplicate group: files on
+// disk before archive members, files outside vendored directories first,
+// then shallower and shorter paths
+func representativeBefore(x, y *types.FileInfo) bool {
+	if ax, ay := IsArchiveMember(x.Path), IsArchiveMember(y.Path); ax != ay {
+		return !ax
+	}
+	if vx, vy := isVendoredPath(x.RelPath), isVendoredPath(y.RelPath); vx != vy {
+		return !vx
+	}
+	if dx, dy := strings.Count(x.RelPath, "/"), strings.Count(y.RelPath, "/"); dx != dy {
+		return dx < dy
+	}
+	if len(x.RelPath) != len(y.RelPath) {
+		return len(x.RelPath) < len(y.RelPath)
+	}
+	return x.RelPath < y.RelPath
+}
+
+// isVendoredPath reports whether a path is in a directory of third-party code
+func isVendoredPath(path string) bool {
+	for _, dir := range strings.Split(strings.ReplaceAll(path, "\\", "/"), "/") {
+		switch dir {
+		case "vendor", "node_modules", "third_party", "third-party", "external", "deps":
+			return true
+		}
+	}
+	return false
+}
+
+// minHashSignature returns the MinHash signature of the word shingles of
+// text, or nil when it has too few shingles to compare
+func minHashSignature(text string) []uint64 {
+	words := strings.Fields(text)
+	if len(words) < shingleWords+minShingles-1 {
+		return nil
+	}
+
+	signature := make([]uint64, minHashSize)
+	for i := range signature {
+		signature[i] = ^uint64(0)
+	}
+
+	hasher := fnv.New64a()
+	for start := 0; start+shingleWords <= len(words); start++ {
+		hasher.Reset()
+		for _, word := range words[start : start+shingleWords] {
+			hasher.Write([]byte(word))
+			hasher.Write([]byte{0})
+		}
+		shingle := hasher.Sum64()
+		for i := range signature {
+			if value := mixHash(shingle + uint64(i)*0x9E3779B97F4A7C15); value < signature[i] {
+				signature[i] = value
+			}
+		}
+	}
+	return signature
+}
+
+// mixHash is the splitmix64 finalizer, which turns one shingle hash into
+// independent hashes for each position of the signature
+func mixHash(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xBF58476D1CE4E5B9
+	x ^= x >> 27
+	x *= 0x94D049BB133111EB
+	x ^= x >> 31
+	return x
+}
+
+// signatureSimilarity estimates the Jaccard similarity of two shingle sets
+// from their signatures
+func signatureSimilarity(x, y []uint64) float64 {
+	if len(x) != minHashSize || len(y) != minHashSize {
+		return 0
+	}
+	same := 0
+	for i := range x {
+		if x[i] == y[i] {
+			same++
+		}
+	}
+	return float64(same) / minHashSize
+}
+
+// SkipDuplicates drops the files whose representative is also in files and
+// will be analyzed, since its analysis applies to them. A representative is
+// analyzed when it is readable and within tokenLimit; near-duplicates can be
+// smaller than their representative, so the members of a group whose
+// representative is skipped are kept.
+func SkipDuplicates(files []*types.FileInfo, tokenLimit int) (kept, skipped []*types.FileInfo) {
+	analyzed := make(map[string]bool, len(files))
+	for _, file := range files {
+		if file != nil && file.IsReadable && file.TokenCount <= tokenLimit {
+			analyzed[file.RelPath] = true
+		}
+	}
+
+	for _, file := range files {
+		if file != nil && file.DuplicateOf != "" && analyzed[file.DuplicateOf] {
+			skipped = append(skipped, file)
+			continue
+		}
+		kept = append(kept, file)
+	}
+	return kept, skipped
+}
+
+// DuplicateNote attributes the analysis of the files in batch to their
+// duplicates. It is empty when they have none.
+func (a *Analyzer) DuplicateNote(batch []*types.FileInfo) string {
+	var notes []string
+	for _, file := range batch {
+		if file == nil || len(file.Duplicates) == 0 {
+			continue
+		}
+		listed := file.Duplicates
+		more := ""
+		if len(listed) > maxListedDuplicates {
+			more = fmt.Sprintf(" and %d more", len(listed)-maxListedDuplicates)
+			listed = listed[:maxListedDuplicates]
+		}
+		notes = append(notes, fmt.Sprintf("♻️  Also applies to %d %s of %s: %s%s",
+			len(file.Duplicates), plural(len(file.Duplicates), "duplicate", "duplicates"), file.RelPath, strings.Join(listed, ", "), more))
+	}
+	if len(notes) == 0 {
+		return ""
+	}
+	return "\n\n" + strings.Join(notes, "\n")
+}
+
+// FormatDuplicateGroups describes the duplicate groups of a scan, one line
+// per group after a header, listing at most limit groups (0 = all)
+func FormatDuplicateGroups(groups []types.DuplicateGroup, limit int) []string {
+	if len(groups) == 0 {
+		return nil
+	}
+
+	files, tokens := 0, 0
+	for _, group := range groups {
+		files += len(group.Members)
+		tokens += group.TokensSaved
+	}
+	lines := []string{fmt.Sprintf("♻️  Duplicate groups: %d (%d %s not sent, %d tokens saved)", len(groups), files, plural(files, "file", "files"), tokens)}
+
+	for i, group := range groups {
+		if limit > 0 && i >= limit {
+			lines = append(lines, fmt.Sprintf("… and %d more %s", len(groups)-limit, plural(len(groups)-limit, "group", "groups")))
+			break
+		}
+		kind := "identical"
+		if !group.Identical {
+			kind = fmt.Sprintf("≥%.0f%% similar", group.Similarity*100)
+		}
+		lines = append(lines, fmt.Sprintf("%s ← %s (%s)", group.Representative, strings.Join(group.Members, ", "), kind))
+	}
+	return lines
+}
@@ -0,0 +1,98 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"local-agent/config"
+	"local-agent/types"
+)
+
+func TestGroupDuplicatesNotTransitive(t *testing.T) {
+	words := make([]string, 1000)
+	for i := range words {
+		words[i] = fmt.Sprintf("w%d", i)
+	}
+
+	// Each file changes two more words than the one before, so neighbours
+	// are near-duplicates but the ends of the chain are not
+	var files []types.FileInfo
+	for n := 0; n <= 10; n++ {
+		for i := 0; i < n*2; i++ {
+			words[i*45] = fmt.Sprintf("changed%d", i)
+		}
+		files = append(files, types.FileInfo{
+			Path:       fmt.Sprintf("/src/file%02d.txt", n),
+			RelPath:    fmt.Sprintf("file%02d.txt", n),
+			Content:    strings.Join(words, " "),
+			IsReadable: true,
+		})
+	}
+	files = append(files, types.FileInfo{
+		Path:       "/src/copy/file10.txt",
+		RelPath:    "copy/file10.txt",
+		Content:    files[10].Content,
+		IsReadable: true,
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Dedupe.Similarity = 0.9
+	result := &types.ScanResult{Files: files}
+	NewAnalyzer(cfg).GroupDuplicates(result)
+
+	if len(result.Duplicates) < 2 {
+		t.Fatalf("got %d duplicate groups, want the chain split into several", len(result.Duplicates))
+	}
+
+	byPath := make(map[string]*types.FileInfo)
+	for i := range result.Files {
+		byPath[result.Files[i].RelPath] = &result.Files[i]
+	}
+	for _, group := range result.Duplicates {
+		if group.Similarity < cfg.Dedupe.Similarity {
+			t.Errorf("group of %s has similarity %.2f", group.Representative, group.Similarity)
+		}
+		representative := minHashSignature(byPath[group.Representative].Content)
+		for _, member := range group.Members {
+			if s := signatureSimilarity(representative, minHashSignature(byPath[member].Content)); s < cfg.Dedupe.Similarity {
+				t.Errorf("%s grouped with %s at similarity %.2f", member, group.Representative, s)
+			}
+		}
+	}
+
+	// Identical files always end up together
+	copied, original := byPath["copy/file10.txt"], byPath["file10.txt"]
+	if copied.DuplicateOf != original.RelPath && copied.DuplicateOf != original.DuplicateOf {
+		t.Errorf("copy/file10.txt is a duplicate of %q, file10.txt of %q", copied.DuplicateOf, original.DuplicateOf)
+	}
+}
+
+func TestSkipDuplicates(t *testing.T) {
+	files := []*types.FileInfo{
+		{RelPath: "small.txt", IsReadable: true, TokenCount: 10},
+		{RelPath: "small-copy.txt", IsReadable: true, TokenCount: 10, DuplicateOf: "small.txt"},
+		{RelPath: "large.txt", IsReadable: true, TokenCount: 500},
+		{RelPath: "large-trimmed.txt", IsReadable: true, TokenCount: 90, DuplicateOf: "large.txt"},
+		{RelPath: "broken.txt", TokenCount: 10},
+		{RelPath: "broken-copy.txt", IsReadable: true, TokenCount: 10, DuplicateOf: "broken.txt"},
+		{RelPath: "orphan.txt", IsReadable: true, TokenCount: 10, DuplicateOf: "elsewhere.txt"},
+	}
+
+	kept, skipped := SkipDuplicates(files, 100)
+
+	var keptPaths, skippedPaths []string
+	for _, file := range kept {
+		keptPaths = append(keptPaths, file.RelPath)
+	}
+	for _, file := range skipped {
+		skippedPaths = append(skippedPaths, file.RelPath)
+	}
+	wantKept := "small.txt large.txt large-trimmed.txt broken.txt broken-copy.txt orphan.txt"
+	if got := strings.Join(keptPaths, " "); got != wantKept {
+		t.Errorf("kept = %s, want %s", got, wantKept)
+	}
+	if got := strings.Join(skippedPaths, " "); got != "small-copy.txt" {
+		t.Errorf("skipped = %s, want small-copy.txt", got)
+	}
+}
@@ -1,25 +1,19 @@
 package analyzer
 
 import (
-	"archive/zip"
 	"bufio"
 	"bytes"
-	"encoding/xml"
+	"encoding/binary"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"sort"
 	"strings"
-	"unicode/utf8"
+	"time"
 
 	"local-agent/types"
-
-	"github.com/google/gopacket"
-	"github.com/google/gopacket/layers"
-	"github.com/google/gopacket/pcapgo"
-	"github.com/michalswi/pdf-reader/pdf"
 )
 
 // Detector detects file metadata and content
@@ -37,14 +31,50 @@ func (d *Detector) DetectFile(path string) (*types.FileInfo, error) {
 		return nil, fmt.Errorf("failed to stat file: %w", err)
 	}
 
-	ext := strings.ToLower(filepath.Ext(path))
-	size := info.Size()
+	fileInfo := newFileInfo(path, info.Size(), info.ModTime())
+
+	// Detect file type
+	fileType, err := d.detectFileType(path, fileInfo.Extension)
+	if err != nil {
+		fileInfo.IsReadable = false
+		fileInfo.Type = types.TypeUnknown
+		return fileInfo, nil
+	}
+
+	setFileType(fileInfo, fileType)
+	if isTextContentType(fileType) {
+		head, _ := readFileHead(path, 1024)
+		setTextDetails(fileInfo, head)
+	}
+	return fileInfo, nil
+}
+
+// DetectBytes analyzes in-memory content, such as an archive member, the
+// same way DetectFile analyzes a file on disk
+func (d *Detector) DetectBytes(path string, data []byte, modTime time.Time) *types.FileInfo {
+	fileInfo := newFileInfo(path, int64(len(data)), modTime)
+
+	fileType, ok := fileTypeByExtension(fileInfo.Extension)
+	if !ok {
+		fileType = fileTypeByContent(data)
+	} else if isYAMLExtension(fileInfo.Extension) && isKubernetesManifest(data[:min(len(data), 4096)]) {
+		fileType = types.TypeIaC
+	}
+
+	setFileType(fileInfo, fileType)
+	if isTextContentType(fileType) {
+		setTextDetails(fileInfo, data[:min(len(data), 1024)])
+	}
+	return fileInfo
+}
 
+// newFileInfo fills in the metadata that only depends on path and size
+func newFileInfo(path string, size int64, modTime time.Time) *types.FileInfo {
 	fileInfo := &types.FileInfo{
 		Path:      path,
 		Size:      size,
-		Extension: ext,
-		ModTime:   info.ModTime(),
+		Extension: strings.ToLower(filepath.Ext(path)),
+		ModTime:   modTime,
 	}
 
 	// Determine category based on size
@@ -56,22 +86,102 @@ func (d *Detector) DetectFile(path string) (*types.FileInfo, error) {
 		fileInfo.Category = types.CategoryLarge
 	}
 
-	// Detect file type
-	fileType, err := d.detectFileType(path, ext)
-	if err != nil {
-		fileInfo.IsReadable = false
-		fileInfo.Type = types.TypeUnknown
-		return fileInfo, nil
-	}
+	return fileInfo
+}
 
+func setFileType(fileInfo *types.FileInfo, fileType types.FileType) {
 	fileInfo.Type = fileType
-	fileInfo.IsReadable = (fileType == types.TypeText || fileType == types.TypePDF || fileType == types.TypeDOC || fileType == types.TypeDOCX || fileType == types.TypePCAP)
+	fileInfo.IsReadable = fileType == types.TypeText || isExtractedType(fileType)
+}
 
-	return fileInfo, nil
+// isTextContentType reports whether files of a type are read as text, in
+// whatever encoding, before any extraction
+func isTextContentType(fileType types.FileType) bool {
+	switch fileType {
+	case types.TypeText, types.TypeLog, types.TypeCSV, types.TypeIaC:
+		return true
+	}
+	return false
+}
+
+// setTextDetails records the encoding of a text file and, for plain text,
+// its language, from the head of its content
+func setTextDetails(fileInfo *types.FileInfo, head []byte) {
+	encoding, ok := detectEncoding(head[:min(len(head), 512)])
+	if !ok {
+		encoding = encodingUTF8
+	}
+	fileInfo.Encoding = encoding
+
+	if fileInfo.Type == types.TypeText {
+		text := []byte(decodeText(head, encoding))
+		fileInfo.Language = detectTextLanguage(fileInfo.Path, fileInfo.Extension, text)
+	}
+}
+
+// isExtractedType reports whether a file type needs a dedicated extractor
+// rather than being read as text
+func isExtractedType(fileType types.FileType) bool {
+	switch fileType {
+	case types.TypePDF, types.TypeDOC, types.TypeDOCX, types.TypeXLSX, types.TypeODS, types.TypePPTX, types.TypeODT, types.TypePCAP, types.TypeLog, types.TypeNotebook, types.TypeCSV, types.TypeSQLite, types.TypeExecutable, types.TypeIaC:
+		return true
+	}
+	return false
 }
 
 // detectFileType determines the type of a file
 func (d *Detector) detectFileType(path, ext string) (types.FileType, error) {
+	if fileType, ok := fileTypeByExtension(ext); ok {
+		if isYAMLExtension(ext) {
+			// Kubernetes manifests are told from other YAML by content
+			if head, err := readFileHead(path, 4096); err == nil && isKubernetesManifest(head) {
+				return types.TypeIaC, nil
+			}
+		}
+		return fileType, nil
+	}
+
+	// Try to detect by content
+	file, err := os.Open(path)
+	if err != nil {
+		return types.TypeUnknown, err
+	}
+	defer file.Close()
+
+	// Read first 512 bytes
+	buffer := make([]byte, 512)
+	n, err := file.Read(buffer)
+	if err != nil && err != io.EOF {
+		return types.TypeUnknown, err
+	}
+
+	return fileTypeByContent(buffer[:n]), nil
+}
+
+// isYAMLExtension reports whether ext is a YAML extension
+func isYAMLExtension(ext string) bool {
+	ext = strings.ToLower(ext)
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// readFileHead reads up to n bytes from the start of a file
+func readFileHead(path string, n int) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	buffer := make([]byte, n)
+	read, err := io.ReadFull(file, buffer)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return buffer[:read], nil
+}
+
+// fileTypeByExtension maps well-known extensions to a file type
+func fileTypeByExtension(ext string) (types.FileType, bool) {
 	ext = strings.ToLower(ext)
 
 	// Check by extension first
@@ -83,29 +193,31 @@ func (d *Detector) detectFileType(path, ext string) (types.FileType, error) {
 
 	for _, textExt := range textExts {
 		if ext == textExt {
-			return types.TypeText, nil
+			return types.TypeText, true
 		}
 	}
 
-	// Check for binary types
+	// Check for binary types. Executables and libraries (.exe, .dll, .so,
+	// .dylib, .o) are detected by content, which tells ELF, PE and Mach-O
+	// files from other data using those extensions.
 	binaryExts := []string{
-		".exe", ".dll", ".so", ".dylib", ".bin", ".o", ".a",
+		".bin", ".a",
 	}
 
 	for _, binExt := range binaryExts {
 		if ext == binExt {
-			return types.TypeBinary, nil
+			return types.TypeBinary, true
 		}
 	}
 
 	// Check for archives
 	archiveExts := []string{
-		".zip", ".tar", ".gz", ".bz2", ".xz", ".7z", ".rar",
+		".zip", ".tar", ".gz", ".tgz", ".bz2", ".tbz2", ".xz", ".7z", ".rar",
 	}
 
 	for _, archExt := range archiveExts {
 		if ext == archExt {
-			return types.TypeArchive, nil
+			return types.TypeArchive, true
 		}
 	}
 
@@ -116,23 +228,39 @@ func (d *Detector) detectFileType(path, ext string) (types.FileType, error) {
 
 	for _, imgExt := range imageExts {
 		if ext == imgExt {
-			return types.TypeImage, nil
+			return types.TypeImage, true
 		}
 	}
 
 	// Check for PDF files
 	if ext == ".pdf" {
-		return types.TypePDF, nil
+		return types.TypePDF, true
 	}
 
 	// Check for DOC files
 	if ext == ".doc" {
-		return types.TypeDOC, nil
+		return types.TypeDOC, true
 	}
 
 	// Check for DOCX files
 	if ext == ".docx" {
-		return types.TypeDOCX, nil
+		return types.TypeDOCX, true
+	}
+
+	// Check for spreadsheets
+	if ext == ".xlsx" {
+		return types.TypeXLSX, true
+	}
+	if ext == ".ods" {
+		return types.TypeODS, true
+	}
+
+	// Check for presentations and OpenDocument text
+	if ext == ".pptx" {
+		return types.TypePPTX, true
+	}
+	if ext == ".odt" {
+		return types.TypeODT, true
 	}
 
 	// Check for PCAP files
@@ -142,205 +270,79 @@ func (d *Detector) detectFileType(path, ext string) (types.FileType, error) {
 
 	for _, pcapExt := range pcapExts {
 		if ext == pcapExt {
-			return types.TypePCAP, nil
+			return types.TypePCAP, true
 		}
 	}
 
-	// Try to detect by content
-	file, err := os.Open(path)
-	if err != nil {
-		return types.TypeUnknown, err
-	}
-	defer file.Close()
-
-	// Read first 512 bytes
-	buffer := make([]byte, 512)
-	n, err := file.Read(buffer)
-	if err != nil && err != io.EOF {
-		return types.TypeUnknown, err
-	}
-
-	// Check if content is valid UTF-8 text
-	if utf8.Valid(buffer[:n]) {
-		// Further validate it's text (not binary with valid UTF-8 sequences)
-		textCount := 0
-		for _, b := range buffer[:n] {
-			if b == '\n' || b == '\r' || b == '\t' || (b >= 32 && b < 127) {
-				textCount++
-			}
-		}
-
-		// If more than 90% of characters are text, consider it text
-		if n > 0 && float64(textCount)/float64(n) > 0.9 {
-			return types.TypeText, nil
-		}
+	// Check for log files
+	if ext == ".log" {
+		return types.TypeLog, true
 	}
 
-	return types.TypeBinary, nil
-}
-
-// ReadPDFContent extracts text from a PDF file
-func (d *Detector) ReadPDFContent(path string) (string, error) {
-	f, reader, err := pdf.Open(path)
-	if err != nil {
-		return "", fmt.Errorf("failed to open PDF: %w", err)
+	// Check for Jupyter notebooks
+	if ext == ".ipynb" {
+		return types.TypeNotebook, true
 	}
-	defer f.Close()
 
-	textReader, err := reader.GetPlainText()
-	if err != nil {
-		return "", fmt.Errorf("failed to extract text from PDF: %w", err)
+	// Check for delimited tables
+	if ext == ".csv" || ext == ".tsv" {
+		return types.TypeCSV, true
 	}
 
-	var buf bytes.Buffer
-	_, err = buf.ReadFrom(textReader)
-	if err != nil {
-		return "", fmt.Errorf("failed to read extracted text: %w", err)
+	// Check for Terraform configurations
+	if ext == ".tf" {
+		return types.TypeIaC, true
 	}
 
-	return buf.String(), nil
+	return types.TypeUnknown, false
 }
 
-// ReadDOCXContent extracts text from a DOCX file.
-func (d *Detector) ReadDOCXContent(path string) (string, error) {
-	reader, err := zip.OpenReader(path)
-	if err != nil {
-		return "", fmt.Errorf("failed to open DOCX: %w", err)
+// fileTypeByContent tells text from binary by looking at the first 512 bytes
+func fileTypeByContent(data []byte) types.FileType {
+	buffer := data
+	if len(buffer) > 512 {
+		buffer = buffer[:512]
 	}
-	defer reader.Close()
 
-	var parts []string
-	for _, file := range reader.File {
-		name := strings.ToLower(file.Name)
-		if isDOCXTextPart(name) {
-			parts = append(parts, file.Name)
-		}
+	// SQLite databases are recognized by their header whatever the extension
+	if isSQLite(buffer) {
+		return types.TypeSQLite
 	}
 
-	if len(parts) == 0 {
-		return "", fmt.Errorf("no readable document XML parts found in DOCX")
+	// So are ELF, PE and Mach-O executables
+	if isExecutable(buffer) {
+		return types.TypeExecutable
 	}
 
-	sort.Strings(parts)
-
-	var content strings.Builder
-	for _, partName := range parts {
-		var partFile *zip.File
-		for _, file := range reader.File {
-			if file.Name == partName {
-				partFile = file
-				break
-			}
-		}
-		if partFile == nil {
-			continue
-		}
-
-		rc, err := partFile.Open()
-		if err != nil {
-			return "", fmt.Errorf("failed to open DOCX part %s: %w", partName, err)
-		}
-
-		partText, parseErr := extractDOCXText(rc)
-		closeErr := rc.Close()
-		if parseErr != nil {
-			return "", fmt.Errorf("failed to parse DOCX part %s: %w", partName, parseErr)
-		}
-		if closeErr != nil {
-			return "", fmt.Errorf("failed to close DOCX part %s: %w", partName, closeErr)
-		}
-
-		partText = strings.TrimSpace(partText)
-		if partText == "" {
-			continue
-		}
-
-		if content.Len() > 0 {
-			content.WriteString("\n\n")
-		}
-		content.WriteString(partText)
+	// Other formats are told by their magic bytes
+	if fileType, ok := fileTypeByMimeType(contentMimeType(buffer)); ok {
+		return fileType
 	}
 
-	finalText := strings.TrimSpace(content.String())
-	if finalText == "" {
-		return "", fmt.Errorf("no text content extracted from DOCX")
+	// Text may be UTF-8, UTF-16 or a legacy single-byte encoding
+	if _, ok := detectEncoding(buffer); ok {
+		return types.TypeText
 	}
 
-	return finalText, nil
+	return types.TypeBinary
 }
 
-func isDOCXTextPart(name string) bool {
-	if name == "word/document.xml" ||
-		name == "word/footnotes.xml" ||
-		name == "word/endnotes.xml" ||
-		name == "word/comments.xml" {
-		return true
-	}
-
-	if strings.HasPrefix(name, "word/header") && strings.HasSuffix(name, ".xml") {
-		return true
-	}
-
-	if strings.HasPrefix(name, "word/footer") && strings.HasSuffix(name, ".xml") {
-		return true
-	}
-
-	return false
-}
-
-func extractDOCXText(r io.Reader) (string, error) {
-	decoder := xml.NewDecoder(r)
-	var builder strings.Builder
-	lastWasNewline := true
-
-	appendNewline := func() {
-		if !lastWasNewline {
-			builder.WriteString("\n")
-			lastWasNewline = true
-		}
-	}
-
-	for {
-		tok, err := decoder.Token()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return "", err
-		}
-
-		switch t := tok.(type) {
-		case xml.StartElement:
-			switch strings.ToLower(t.Name.Local) {
-			case "t":
-				var text string
-				if err := decoder.DecodeElement(&text, &t); err != nil {
-					return "", err
-				}
-				if text != "" {
-					builder.WriteString(text)
-					lastWasNewline = false
-				}
-			case "tab":
-				builder.WriteString("\t")
-				lastWasNewline = false
-			case "br", "cr":
-				appendNewline()
-			}
+// ReadDOCContent extracts text from a legacy DOC file.
+// It uses the built-in Word 97-2003 reader and falls back to
+// platform/system converters in order.
+func (d *Detector) ReadDOCContent(path string) (string, error) {
+	var failures []string
 
-		case xml.EndElement:
-			if strings.ToLower(t.Name.Local) == "p" {
-				appendNewline()
-			}
-		}
+	text, err := readDOCNative(path)
+	switch {
+	case err != nil:
+		failures = append(failures, fmt.Sprintf("native reader failed: %v", err))
+	case text == "":
+		failures = append(failures, "native reader found no text")
+	default:
+		return text, nil
 	}
 
-	return strings.TrimSpace(builder.String()), nil
-}
-
-// ReadDOCContent extracts text from a legacy DOC file.
-// It tries platform/system converters in order.
-func (d *Detector) ReadDOCContent(path string) (string, error) {
 	converters := []struct {
 		name string
 		args []string
@@ -349,7 +351,6 @@ func (d *Detector) ReadDOCContent(path string) (string, error) {
 		{name: "antiword", args: []string{path}},
 	}
 
-	var failures []string
 	for _, c := range converters {
 		output, err := exec.Command(c.name, c.args...).CombinedOutput()
 		if err != nil {
@@ -369,207 +370,6 @@ func (d *Detector) ReadDOCContent(path string) (string, error) {
 	return "", fmt.Errorf("failed to extract text from DOC: %s", strings.Join(failures, "; "))
 }
 
-// ReadPCAPContent extracts information from a PCAP/PCAPNG file
-func (d *Detector) ReadPCAPContent(path string) (string, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return "", fmt.Errorf("failed to open PCAP file: %w", err)
-	}
-	defer f.Close()
-
-	var packetSource *gopacket.PacketSource
-	if strings.ToLower(filepath.Ext(path)) == ".pcapng" {
-		ngReader, err := pcapgo.NewNgReader(f, pcapgo.DefaultNgReaderOptions)
-		if err != nil {
-			return "", fmt.Errorf("failed to create PCAPNG reader: %w", err)
-		}
-		packetSource = gopacket.NewPacketSource(ngReader, ngReader.LinkType())
-	} else {
-		reader, err := pcapgo.NewReader(f)
-		if err != nil {
-			return "", fmt.Errorf("failed to create PCAP reader: %w", err)
-		}
-		packetSource = gopacket.NewPacketSource(reader, reader.LinkType())
-	}
-
-	var builder strings.Builder
-	builder.WriteString("=== PCAP File Analysis ===\n\n")
-	return d.buildPCAPSummary(&builder, packetSource)
-}
-
-// buildPCAPSummary processes packets from a PacketSource and writes a summary
-func (d *Detector) buildPCAPSummary(builder *strings.Builder, packetSource *gopacket.PacketSource) (string, error) {
-
-	// Track statistics
-	totalPackets := 0
-	protocolCount := make(map[string]int)
-	srcIPs := make(map[string]int)
-	dstIPs := make(map[string]int)
-	srcPorts := make(map[string]int)
-	dstPorts := make(map[string]int)
-	var firstTimestamp, lastTimestamp string
-
-	// Sample first few packets for detailed analysis
-	const maxDetailedPackets = 10
-	var detailedPackets []string
-
-	for packet := range packetSource.Packets() {
-		totalPackets++
-
-		// Capture timestamp
-		if totalPackets == 1 {
-			firstTimestamp = packet.Metadata().Timestamp.String()
-		}
-		lastTimestamp = packet.Metadata().Timestamp.String()
-
-		// Analyze network layer
-		if networkLayer := packet.NetworkLayer(); networkLayer != nil {
-			if ipLayer := packet.Layer(layers.LayerTypeIPv4); ipLayer != nil {
-				ip, _ := ipLayer.(*layers.IPv4)
-				srcIP := ip.SrcIP.String()
-				dstIP := ip.DstIP.String()
-				srcIPs[srcIP]++
-				dstIPs[dstIP]++
-				protocolCount["IPv4"]++
-			} else if ipLayer := packet.Layer(layers.LayerTypeIPv6); ipLayer != nil {
-				ip, _ := ipLayer.(*layers.IPv6)
-				srcIP := ip.SrcIP.String()
-				dstIP := ip.DstIP.String()
-				srcIPs[srcIP]++
-				dstIPs[dstIP]++
-				protocolCount["IPv6"]++
-			}
-		}
-
-		// Analyze transport layer
-		if transportLayer := packet.TransportLayer(); transportLayer != nil {
-			switch transportLayer.LayerType() {
-			case layers.LayerTypeTCP:
-				tcp, _ := transportLayer.(*layers.TCP)
-				srcPorts[fmt.Sprintf("%d", tcp.SrcPort)]++
-				dstPorts[fmt.Sprintf("%d", tcp.DstPort)]++
-				protocolCount["TCP"]++
-			case layers.LayerTypeUDP:
-				udp, _ := transportLayer.(*layers.UDP)
-				srcPorts[fmt.Sprintf("%d", udp.SrcPort)]++
-				dstPorts[fmt.Sprintf("%d", udp.DstPort)]++
-				protocolCount["UDP"]++
-			}
-		}
-
-		// Analyze application layer
-		if appLayer := packet.ApplicationLayer(); appLayer != nil {
-			if packet.Layer(layers.LayerTypeDNS) != nil {
-				protocolCount["DNS"]++
-			} else if packet.Layer(layers.LayerTypeTLS) != nil {
-				protocolCount["TLS"]++
-			} else if tcpLayer := packet.Layer(layers.LayerTypeTCP); tcpLayer != nil {
-				// Check for HTTP on common ports
-				tcp, _ := tcpLayer.(*layers.TCP)
-				if tcp.DstPort == 80 || tcp.SrcPort == 80 || tcp.DstPort == 8080 || tcp.SrcPort == 8080 {
-					protocolCount["HTTP"]++
-				}
-			}
-		}
-
-		// Capture detailed view of first few packets
-		if totalPackets <= maxDetailedPackets {
-			detailedPackets = append(detailedPackets, fmt.Sprintf("Packet #%d: %s", totalPackets, packet.String()))
-		}
-
-		// Limit processing for very large captures
-		if totalPackets >= 100000 {
-			builder.WriteString("⚠️  Large capture detected. Processing first 100,000 packets only.\n\n")
-			break
-		}
-	}
-
-	// Build summary
-	builder.WriteString("📊 Summary:\n")
-	builder.WriteString(fmt.Sprintf("- Total Packets: %d\n", totalPackets))
-	builder.WriteString(fmt.Sprintf("- First Packet: %s\n", firstTimestamp))
-	builder.WriteString(fmt.Sprintf("- Last Packet: %s\n\n", lastTimestamp))
-
-	// Protocol breakdown
-	builder.WriteString("📦 Protocols:\n")
-	for proto, count := range protocolCount {
-		percentage := float64(count) / float64(totalPackets) * 100
-		builder.WriteString(fmt.Sprintf("- %s: %d packets (%.2f%%)\n", proto, count, percentage))
-	}
-	builder.WriteString("\n")
-
-	// Top source IPs
-	builder.WriteString("🌐 Top Source IPs:\n")
-	topCount := 5
-	for ip, count := range topN(srcIPs, topCount) {
-		builder.WriteString(fmt.Sprintf("- %s: %d packets\n", ip, count))
-	}
-	builder.WriteString("\n")
-
-	// Top destination IPs
-	builder.WriteString("🎯 Top Destination IPs:\n")
-	for ip, count := range topN(dstIPs, topCount) {
-		builder.WriteString(fmt.Sprintf("- %s: %d packets\n", ip, count))
-	}
-	builder.WriteString("\n")
-
-	// Top source ports
-	builder.WriteString("🔌 Top Source Ports:\n")
-	for port, count := range topN(srcPorts, topCount) {
-		builder.WriteString(fmt.Sprintf("- Port %s: %d packets\n", port, count))
-	}
-	builder.WriteString("\n")
-
-	// Top destination ports
-	builder.WriteString("🚪 Top Destination Ports:\n")
-	for port, count := range topN(dstPorts, topCount) {
-		builder.WriteString(fmt.Sprintf("- Port %s: %d packets\n", port, count))
-	}
-	builder.WriteString("\n")
-
-	// Sample packets
-	if len(detailedPackets) > 0 {
-		builder.WriteString("📋 Sample Packets (first 10):\n")
-		for i, pkt := range detailedPackets {
-			if i >= 3 { // Only show first 3 in detail to keep it concise
-				break
-			}
-			builder.WriteString(fmt.Sprintf("\n%s\n", pkt))
-		}
-	}
-
-	return builder.String(), nil
-}
-
-// topN returns the top N items from a map by value
-func topN(m map[string]int, n int) map[string]int {
-	type kv struct {
-		key   string
-		value int
-	}
-
-	var sorted []kv
-	for k, v := range m {
-		sorted = append(sorted, kv{k, v})
-	}
-
-	// Simple bubble sort for top N
-	for i := 0; i < len(sorted) && i < n; i++ {
-		for j := i + 1; j < len(sorted); j++ {
-			if sorted[j].value > sorted[i].value {
-				sorted[i], sorted[j] = sorted[j], sorted[i]
-			}
-		}
-	}
-
-	result := make(map[string]int)
-	for i := 0; i < len(sorted) && i < n; i++ {
-		result[sorted[i].key] = sorted[i].value
-	}
-
-	return result
-}
-
 // ReadContent reads the content of a file
 func (d *Detector) ReadContent(path string, maxLines int) (string, error) {
 	file, err := os.Open(path)
@@ -578,8 +378,15 @@ func (d *Detector) ReadContent(path string, maxLines int) (string, error) {
 	}
 	defer file.Close()
 
+	return d.ReadContentFrom(file, maxLines)
+}
+
+// ReadContentFrom reads text content from r, transcoding it to UTF-8 and
+// normalizing line endings the same way ReadContent does for files
+func (d *Detector) ReadContentFrom(r io.Reader, maxLines int) (string, error) {
 	var builder strings.Builder
-	scanner := bufio.NewScanner(file)
+	text, _ := newTextReader(r)
+	scanner := bufio.NewScanner(text)
 	scanner.Buffer(make([]byte, 0, 1024*1024), 1024*1024) // allow large lines
 	lineCount := 0
 
@@ -610,7 +417,8 @@ func (d *Detector) CountLines(path string) (int, error) {
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
+	text, _ := newTextReader(file)
+	scanner := bufio.NewScanner(text)
 	scanner.Buffer(make([]byte, 0, 1024*1024), 1024*1024)
 	lineCount := 0
 
@@ -635,7 +443,8 @@ func (d *Detector) IsBinary(path string) (bool, error) {
 	return fileType == types.TypeBinary, nil
 }
 
-// GetMimeType attempts to determine MIME type of a file
+// GetMimeType attempts to determine MIME type of a file. Well-known
+// extensions are trusted, other files are sniffed by content.
 func (d *Detector) GetMimeType(path string) string {
 	ext := strings.ToLower(filepath.Ext(path))
 
@@ -651,21 +460,98 @@ func (d *Detector) GetMimeType(path string) string {
 		".yml":    "application/yaml",
 		".html":   "text/html",
 		".css":    "text/css",
+		".csv":    "text/csv",
+		".tsv":    "text/tab-separated-values",
+		".log":    "text/plain",
+		".ipynb":  "application/x-ipynb+json",
 		".jpg":    "image/jpeg",
 		".png":    "image/png",
 		".gif":    "image/gif",
 		".pdf":    "application/pdf",
 		".doc":    "application/msword",
 		".docx":   "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		".xlsx":   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		".pptx":   "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+		".odt":    "application/vnd.oasis.opendocument.text",
+		".ods":    "application/vnd.oasis.opendocument.spreadsheet",
 		".zip":    "application/zip",
 		".pcap":   "application/vnd.tcpdump.pcap",
 		".pcapng": "application/x-pcapng",
 		".cap":    "application/vnd.tcpdump.pcap",
+		".sqlite": "application/vnd.sqlite3",
 	}
 
 	if mime, ok := mimeTypes[ext]; ok {
 		return mime
 	}
 
+	head, err := readFileHead(path, 512)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	if mime := contentMimeType(head); mime != "" {
+		return mime
+	}
+	if fileTypeByContent(head) == types.TypeText {
+		return "text/plain"
+	}
 	return "application/octet-stream"
 }
+
+// contentMimeType identifies a binary format by its magic bytes. It returns
+// "" for text and for data it does not recognize.
+func contentMimeType(data []byte) string {
+	switch {
+	case isSQLite(data):
+		return "application/vnd.sqlite3"
+	case isExecutable(data):
+		return "application/x-executable"
+	case bytes.HasPrefix(data, pcapngMagic):
+		return "application/x-pcapng"
+	case len(data) >= 4 && isPCAPMagic(binary.LittleEndian.Uint32(data)):
+		return "application/vnd.tcpdump.pcap"
+	case bytes.HasPrefix(data, []byte("BZh")) && len(data) >= 4 && data[3] >= '1' && data[3] <= '9':
+		return "application/x-bzip2"
+	case bytes.HasPrefix(data, []byte("\xfd7zXZ\x00")):
+		return "application/x-xz"
+	case bytes.HasPrefix(data, []byte("7z\xbc\xaf\x27\x1c")):
+		return "application/x-7z-compressed"
+	case len(data) >= 262 && bytes.Equal(data[257:262], []byte("ustar")):
+		return "application/x-tar"
+	}
+
+	mime, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	if strings.HasPrefix(mime, "text/") || mime == "application/octet-stream" {
+		return ""
+	}
+	return mime
+}
+
+// isPCAPMagic reports whether a little-endian word is a classic PCAP magic
+// number, in either byte order and with micro- or nanosecond timestamps
+func isPCAPMagic(magic uint32) bool {
+	switch magic {
+	case 0xa1b2c3d4, 0xd4c3b2a1, 0xa1b23c4d, 0x4d3cb2a1:
+		return true
+	}
+	return false
+}
+
+// fileTypeByMimeType maps a sniffed MIME type to the file type that handles it
+func fileTypeByMimeType(mime string) (types.FileType, bool) {
+	switch {
+	case mime == "":
+		return types.TypeUnknown, false
+	case mime == "application/pdf":
+		return types.TypePDF, true
+	case strings.HasPrefix(mime, "image/"):
+		return types.TypeImage, true
+	case mime == "application/vnd.tcpdump.pcap", mime == "application/x-pcapng":
+		return types.TypePCAP, true
+	case mime == "application/zip", mime == "application/x-gzip", mime == "application/x-bzip2",
+		mime == "application/x-xz", mime == "application/x-7z-compressed", mime == "application/x-rar-compressed",
+		mime == "application/x-tar":
+		return types.TypeArchive, true
+	}
+	return types.TypeUnknown, false
+}
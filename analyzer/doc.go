@@ -0,0 +1,353 @@
+package analyzer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf16"
+)
+
+// Word 97-2003 binary documents keep their text in the WordDocument stream of
+// a compound file. The piece table (Clx) in the 0Table/1Table stream maps
+// character positions to byte ranges, each stored as cp1252 or UTF-16LE.
+
+const (
+	wordIdent = 0xA5EC
+
+	fibFlagWhichTblStm = 0x0200
+	fibFlagEncrypted   = 0x0100
+	fibFlagObfuscated  = 0x8000
+
+	pcdCompressed = 0x40000000
+
+	fieldBegin     = 0x13
+	fieldSeparator = 0x14
+	fieldEnd       = 0x15
+
+	// fcClx is the 34th fc/lcb pair in FibRgFcLcb97
+	fibClxIndex = 33
+)
+
+// cp1252 maps bytes 0x80-0x9F of Windows-1252 to Unicode. The remaining
+// bytes coincide with Latin-1. Undefined positions map to U+FFFD.
+var cp1252 = [32]rune{
+	'€', '\uFFFD', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '\uFFFD', 'Ž', '\uFFFD',
+	'\uFFFD', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '\uFFFD', 'ž', 'Ÿ',
+}
+
+// decodeCP1252 converts a single Windows-1252 byte to a rune
+func decodeCP1252(b byte) rune {
+	if b >= 0x80 && b <= 0x9F {
+		return cp1252[b-0x80]
+	}
+	return rune(b)
+}
+
+// wordStories are the text ranges that follow each other in the CP space,
+// in the order of the ccp* counters in FibRgLw97
+var wordStories = []struct {
+	name  string
+	index int // position of the ccp counter in FibRgLw97
+}{
+	{"", 3},                   // ccpText: main document
+	{"Footnotes", 4},          // ccpFtn
+	{"Headers", 5},            // ccpHdd
+	{"Comments", 7},           // ccpAtn
+	{"Endnotes", 8},           // ccpEdn
+	{"Text boxes", 9},         // ccpTxbx
+	{"Header text boxes", 10}, // ccpHdrTxbx
+}
+
+// readDOCNative extracts text from a Word 97-2003 document without external tools
+func readDOCNative(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read DOC file: %w", err)
+	}
+	return extractDOCText(data)
+}
+
+// extractDOCText extracts the document text from the bytes of a .doc file
+func extractDOCText(data []byte) (string, error) {
+	cfb, err := parseCFB(data)
+	if err != nil {
+		return "", err
+	}
+
+	word, ok, err := cfb.stream("WordDocument")
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("no WordDocument stream (not a Word document)")
+	}
+
+	fib, err := parseFIB(word)
+	if err != nil {
+		return "", err
+	}
+
+	tableName := "0Table"
+	if fib.flags&fibFlagWhichTblStm != 0 {
+		tableName = "1Table"
+	}
+	table, ok, err := cfb.stream(tableName)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("missing %s stream", tableName)
+	}
+
+	pieces, err := parsePieceTable(table, fib.fcClx, fib.lcbClx)
+	if err != nil {
+		return "", err
+	}
+
+	var sections []string
+	cp := uint32(0)
+	for i, story := range wordStories {
+		count := fib.ccp[i]
+		if count == 0 {
+			continue
+		}
+		text := cleanWordText(pieces.text(word, cp, cp+count))
+		cp += count
+
+		if text == "" {
+			continue
+		}
+		if story.name != "" {
+			text = "## " + story.name + "\n\n" + text
+		}
+		sections = append(sections, text)
+	}
+
+	return strings.Join(sections, "\n\n"), nil
+}
+
+// wordFIB holds the parts of the File Information Block the extractor needs
+type wordFIB struct {
+	flags  uint16
+	ccp    []uint32 // one counter per entry of wordStories
+	fcClx  uint32
+	lcbClx uint32
+}
+
+// parseFIB reads the FIB at the start of the WordDocument stream. The FIB is
+// variable length; csw, cslw and cbRgFcLcb give the size of each part.
+func parseFIB(word []byte) (*wordFIB, error) {
+	le := binary.LittleEndian
+	if len(word) < 34 || le.Uint16(word) != wordIdent {
+		return nil, fmt.Errorf("invalid Word file information block")
+	}
+
+	nFib := le.Uint16(word[2:])
+	if nFib < 101 {
+		return nil, fmt.Errorf("Word 6/95 documents are not supported (nFib %d)", nFib)
+	}
+
+	fib := &wordFIB{flags: le.Uint16(word[0x0A:])}
+	if fib.flags&(fibFlagEncrypted|fibFlagObfuscated) != 0 {
+		return nil, fmt.Errorf("encrypted DOC files are not supported")
+	}
+
+	pos := 32
+	csw := int(le.Uint16(word[pos:]))
+	pos += 2 + csw*2
+
+	if pos+2 > len(word) {
+		return nil, fmt.Errorf("truncated Word file information block")
+	}
+	cslw := int(le.Uint16(word[pos:]))
+	pos += 2
+	rgLw := pos
+	pos += cslw * 4
+
+	if pos+2 > len(word) {
+		return nil, fmt.Errorf("truncated Word file information block")
+	}
+	cbRgFcLcb := int(le.Uint16(word[pos:]))
+	pos += 2
+	rgFcLcb := pos
+
+	for _, story := range wordStories {
+		var count uint32
+		if story.index < cslw {
+			count = le.Uint32(word[rgLw+story.index*4:])
+		}
+		fib.ccp = append(fib.ccp, count)
+	}
+
+	if fibClxIndex >= cbRgFcLcb || rgFcLcb+(fibClxIndex+1)*8 > len(word) {
+		return nil, fmt.Errorf("Word file information block has no piece table")
+	}
+	fib.fcClx = le.Uint32(word[rgFcLcb+fibClxIndex*8:])
+	fib.lcbClx = le.Uint32(word[rgFcLcb+fibClxIndex*8+4:])
+
+	return fib, nil
+}
+
+// wordPiece maps a run of character positions to bytes in the WordDocument stream
+type wordPiece struct {
+	cpStart    uint32
+	cpEnd      uint32
+	offset     uint32
+	compressed bool // cp1252, one byte per character; otherwise UTF-16LE
+}
+
+type pieceTable []wordPiece
+
+// parsePieceTable reads the PlcPcd from the Clx, skipping any Prc entries
+// (property modifiers) that precede it
+func parsePieceTable(table []byte, fcClx, lcbClx uint32) (pieceTable, error) {
+	le := binary.LittleEndian
+	if lcbClx == 0 || uint64(fcClx)+uint64(lcbClx) > uint64(len(table)) {
+		return nil, fmt.Errorf("piece table out of range")
+	}
+	clx := table[fcClx : fcClx+lcbClx]
+
+	pos := 0
+	for pos < len(clx) && clx[pos] == 0x01 {
+		if pos+3 > len(clx) {
+			return nil, fmt.Errorf("truncated piece table")
+		}
+		grpprl := int(int16(le.Uint16(clx[pos+1:])))
+		if grpprl < 0 {
+			return nil, fmt.Errorf("invalid piece table")
+		}
+		pos += 3 + grpprl
+	}
+	if pos+5 > len(clx) || clx[pos] != 0x02 {
+		return nil, fmt.Errorf("piece table not found")
+	}
+
+	size := int(le.Uint32(clx[pos+1:]))
+	pos += 5
+	if size < 4 || pos+size > len(clx) {
+		return nil, fmt.Errorf("truncated piece table")
+	}
+	plc := clx[pos : pos+size]
+
+	// A PlcPcd is n+1 character positions followed by n 8-byte descriptors
+	n := (size - 4) / 12
+	pieces := make(pieceTable, 0, n)
+	for i := 0; i < n; i++ {
+		fc := le.Uint32(plc[(n+1)*4+i*8+2:])
+		piece := wordPiece{
+			cpStart:    le.Uint32(plc[i*4:]),
+			cpEnd:      le.Uint32(plc[(i+1)*4:]),
+			compressed: fc&pcdCompressed != 0,
+		}
+		fc &^= pcdCompressed
+		if piece.compressed {
+			fc /= 2
+		}
+		piece.offset = fc
+		pieces = append(pieces, piece)
+	}
+
+	return pieces, nil
+}
+
+// text returns the raw characters in [cpStart, cpEnd), including control
+// characters and field codes
+func (pt pieceTable) text(word []byte, cpStart, cpEnd uint32) []rune {
+	var out []rune
+	for _, piece := range pt {
+		from, to := max(piece.cpStart, cpStart), min(piece.cpEnd, cpEnd)
+		if from >= to {
+			continue
+		}
+
+		skip := from - piece.cpStart
+		if piece.compressed {
+			start := uint64(piece.offset) + uint64(skip)
+			for i := uint64(0); i < uint64(to-from) && start+i < uint64(len(word)); i++ {
+				out = append(out, decodeCP1252(word[start+i]))
+			}
+			continue
+		}
+
+		start := uint64(piece.offset) + uint64(skip)*2
+		var units []uint16
+		for i := uint64(0); i < uint64(to-from) && start+i*2+1 < uint64(len(word)); i++ {
+			units = append(units, binary.LittleEndian.Uint16(word[start+i*2:]))
+		}
+		out = append(out, utf16.Decode(units)...)
+	}
+	return out
+}
+
+// cleanWordText turns Word's control characters into plain text and keeps
+// only the displayed result of fields (e.g. the link text of a HYPERLINK)
+func cleanWordText(chars []rune) string {
+	var b strings.Builder
+	// One entry per open field: true while still inside its instructions
+	var fields []bool
+	var prev rune
+
+	for _, r := range chars {
+		switch r {
+		case fieldBegin:
+			fields = append(fields, true)
+			continue
+		case fieldSeparator:
+			if len(fields) > 0 {
+				fields[len(fields)-1] = false
+			}
+			continue
+		case fieldEnd:
+			if len(fields) > 0 {
+				fields = fields[:len(fields)-1]
+			}
+			continue
+		}
+
+		hidden := false
+		for _, inCode := range fields {
+			if inCode {
+				hidden = true
+				break
+			}
+		}
+		if hidden {
+			continue
+		}
+
+		switch {
+		case r == '\r', r == 0x0B, r == 0x0C, r == 0x0E:
+			// Paragraph, line, page/section and column breaks
+			b.WriteByte('\n')
+		case r == 0x07 && prev == 0x07:
+			// A second mark ends the table row
+			b.WriteByte('\n')
+		case r == 0x07:
+			// End of table cell
+			b.WriteByte('\t')
+		case r == 0x1E:
+			b.WriteByte('-') // non-breaking hyphen
+		case r == 0xA0:
+			b.WriteByte(' ')
+		case r == '\t':
+			b.WriteByte('\t')
+		case r < 0x20, r == 0x1F, r == '\uFFFD':
+			// Object anchors, footnote references, optional hyphens
+		default:
+			b.WriteRune(r)
+		}
+		prev = r
+	}
+
+	// Tidy whitespace left behind by table marks and empty paragraphs
+	lines := strings.Split(b.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	text := strings.Join(lines, "\n")
+	for strings.Contains(text, "\n\n\n") {
+		text = strings.ReplaceAll(text, "\n\n\n", "\n\n")
+	}
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,86 @@
+package analyzer
+
+import "testing"
+
+// The fixtures in testdata are minimal Word 97-2003 documents: a compound
+// file with a WordDocument stream and a 0Table/1Table stream holding the
+// piece table.
+func TestReadDOCNative(t *testing.T) {
+	tests := []struct {
+		file string
+		want string
+	}{
+		{
+			// One cp1252 piece stored in the mini stream
+			file: "ansi.doc",
+			want: "Quarterly “report” – €5 million\nSecond paragraph",
+		},
+		{
+			// A cp1252 piece and a UTF-16 piece stored in reverse order behind
+			// a Prc entry in 1Table, followed by a footnote story
+			file: "unicode.doc",
+			want: "Intro in ANSI. Zażółć gęślą jaźń 日本\n\n## Footnotes\n\nA footnote",
+		},
+		{
+			// A hyperlink, nested fields and a two-row table
+			file: "fields.doc",
+			want: "See the site and page 7.\nName\tRole\nAlice\tAdmin\nAfter the table",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got, err := readDOCNative("testdata/" + tt.file)
+			if err != nil {
+				t.Fatalf("readDOCNative: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPieceTableText(t *testing.T) {
+	word := []byte("....abcdef" + "x\x00y\x00z\x00")
+	pieces := pieceTable{
+		{cpStart: 0, cpEnd: 6, offset: 4, compressed: true},
+		{cpStart: 6, cpEnd: 9, offset: 10},
+	}
+
+	tests := []struct {
+		from, to uint32
+		want     string
+	}{
+		{0, 9, "abcdefxyz"},
+		{2, 4, "cd"},
+		{4, 8, "efxy"},
+		{7, 9, "yz"},
+		{9, 12, ""},
+	}
+	for _, tt := range tests {
+		if got := string(pieces.text(word, tt.from, tt.to)); got != tt.want {
+			t.Errorf("text(%d, %d) = %q, want %q", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestCleanWordText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"field result", "a \x13 HYPERLINK \"x\" \x14link\x15 b", "a link b"},
+		{"field without result", "a\x13 TOC \\o \x15b", "ab"},
+		{"nested field", "\x13 IF \x13 PAGE \x14" + "1\x15 \x14yes\x15", "yes"},
+		{"table", "a\x07b\x07\x07c\x07d\x07\x07", "a\tb\nc\td"},
+		{"breaks", "one\rtwo\x0Bthree\x0Cfour", "one\ntwo\nthree\nfour"},
+		{"special characters", "non\x1Ebreaking\u00A0space\x1Fx\x01", "non-breaking spacex"},
+	}
+	for _, tt := range tests {
+		if got := cleanWordText([]rune(tt.in)); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
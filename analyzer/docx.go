@@ -0,0 +1,626 @@
+package analyzer
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DOCX files are rendered as Markdown: headings come from paragraph styles,
+// w:tbl becomes a table, numbered and bulleted paragraphs become list items,
+// and tracked changes and comments are kept with their authors. Heading
+// levels are shifted down by one (Title is "#", Heading 1 is "##") so that
+// top-level headings start sections the chunker can cite.
+
+// docxItem is a footnote, endnote or comment collected from its part
+type docxItem struct {
+	id     string
+	author string
+	text   string
+}
+
+// docxStyles maps paragraph style IDs to heading levels (0 for Title)
+type docxStyles map[string]int
+
+// docxNumbering records which list levels are ordered, by numId and ilvl
+type docxNumbering map[string]map[int]bool
+
+// ReadDOCXContent extracts a DOCX file as Markdown
+func (d *Detector) ReadDOCXContent(filePath string) (string, error) {
+	reader, err := zip.OpenReader(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open DOCX: %w", err)
+	}
+	defer reader.Close()
+
+	files := make(map[string]*zip.File, len(reader.File))
+	for _, file := range reader.File {
+		files[strings.ToLower(file.Name)] = file
+	}
+
+	document := files["word/document.xml"]
+	if document == nil {
+		return "", fmt.Errorf("no readable document XML parts found in DOCX")
+	}
+
+	styles, err := readDOCXStyles(files["word/styles.xml"])
+	if err != nil {
+		return "", fmt.Errorf("failed to read DOCX styles: %w", err)
+	}
+	numbering, err := readDOCXNumbering(files["word/numbering.xml"])
+	if err != nil {
+		return "", fmt.Errorf("failed to read DOCX numbering: %w", err)
+	}
+
+	convert := func(file *zip.File) (*docxConverter, error) {
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open DOCX part %s: %w", file.Name, err)
+		}
+		defer rc.Close()
+
+		conv := newDOCXConverter(styles, numbering)
+		if err := conv.run(rc); err != nil {
+			return nil, fmt.Errorf("failed to parse DOCX part %s: %w", file.Name, err)
+		}
+		return conv, nil
+	}
+
+	body, err := convert(document)
+	if err != nil {
+		return "", err
+	}
+	sections := []string{body.markdown()}
+
+	// Headers and footers repeat on every page; list each distinct one once
+	var furniture []string
+	var furnitureNames []string
+	for name := range files {
+		base := strings.TrimPrefix(name, "word/")
+		if base != name && !strings.Contains(base, "/") && strings.HasSuffix(base, ".xml") &&
+			(strings.HasPrefix(base, "header") || strings.HasPrefix(base, "footer")) {
+			furnitureNames = append(furnitureNames, name)
+		}
+	}
+	sort.Strings(furnitureNames)
+	seen := make(map[string]bool)
+	for _, name := range furnitureNames {
+		part, err := convert(files[name])
+		if err != nil {
+			return "", err
+		}
+		text := part.markdown()
+		if text != "" && !seen[text] {
+			seen[text] = true
+			furniture = append(furniture, text)
+		}
+	}
+	if len(furniture) > 0 {
+		sections = append(sections, "## Headers and footers\n\n"+strings.Join(furniture, "\n\n"))
+	}
+
+	for _, notes := range []struct{ part, title, prefix string }{
+		{"word/footnotes.xml", "Footnotes", ""},
+		{"word/endnotes.xml", "Endnotes", "e"},
+	} {
+		file := files[notes.part]
+		if file == nil {
+			continue
+		}
+		part, err := convert(file)
+		if err != nil {
+			return "", err
+		}
+		var lines []string
+		for _, item := range part.items {
+			lines = append(lines, fmt.Sprintf("[^%s%s]: %s", notes.prefix, item.id, item.text))
+		}
+		if len(lines) > 0 {
+			sections = append(sections, "## "+notes.title+"\n\n"+strings.Join(lines, "\n"))
+		}
+	}
+
+	if file := files["word/comments.xml"]; file != nil {
+		part, err := convert(file)
+		if err != nil {
+			return "", err
+		}
+		var lines []string
+		for _, item := range part.items {
+			line := "- "
+			if item.author != "" {
+				line += "**" + item.author + "**"
+			} else {
+				line += "**Unknown author**"
+			}
+			if anchor := strings.TrimSpace(body.anchors[item.id]); anchor != "" {
+				line += fmt.Sprintf(" on %q", anchor)
+			}
+			lines = append(lines, line+": "+item.text)
+		}
+		if len(lines) > 0 {
+			sections = append(sections, "## Comments\n\n"+strings.Join(lines, "\n"))
+		}
+	}
+
+	var nonEmpty []string
+	for _, section := range sections {
+		if strings.TrimSpace(section) != "" {
+			nonEmpty = append(nonEmpty, section)
+		}
+	}
+	finalText := strings.TrimSpace(strings.Join(nonEmpty, "\n\n"))
+	if finalText == "" {
+		return "", fmt.Errorf("no text content extracted from DOCX")
+	}
+
+	return finalText, nil
+}
+
+var headingStylePattern = regexp.MustCompile(`(?i)^heading\s*([1-9])$`)
+
+// readDOCXStyles finds the paragraph styles that act as headings, either by
+// their name ("heading 2", "Title") or by an outline level
+func readDOCXStyles(file *zip.File) (docxStyles, error) {
+	styles := make(docxStyles)
+	if file == nil {
+		return styles, nil
+	}
+
+	var doc struct {
+		Styles []struct {
+			ID   string `xml:"styleId,attr"`
+			Type string `xml:"type,attr"`
+			Name struct {
+				Val string `xml:"val,attr"`
+			} `xml:"name"`
+			Outline *struct {
+				Val int `xml:"val,attr"`
+			} `xml:"pPr>outlineLvl"`
+		} `xml:"style"`
+	}
+	if err := decodeZipXML(file, &doc); err != nil {
+		return nil, err
+	}
+
+	for _, style := range doc.Styles {
+		if style.Type != "" && style.Type != "paragraph" {
+			continue
+		}
+		switch {
+		case strings.EqualFold(style.Name.Val, "title"):
+			styles[style.ID] = 0
+		case headingStylePattern.MatchString(style.Name.Val):
+			level, _ := strconv.Atoi(headingStylePattern.FindStringSubmatch(style.Name.Val)[1])
+			styles[style.ID] = level
+		case style.Outline != nil && style.Outline.Val < 9:
+			styles[style.ID] = style.Outline.Val + 1
+		}
+	}
+	return styles, nil
+}
+
+// headingLevel returns the heading level of a paragraph style, falling back
+// to the usual style IDs when styles.xml is missing
+func (s docxStyles) headingLevel(styleID string) (int, bool) {
+	if level, ok := s[styleID]; ok {
+		return level, true
+	}
+	if strings.EqualFold(styleID, "title") {
+		return 0, true
+	}
+	if match := headingStylePattern.FindStringSubmatch(styleID); match != nil {
+		level, _ := strconv.Atoi(match[1])
+		return level, true
+	}
+	return 0, false
+}
+
+// readDOCXNumbering reads which numbering levels use numbers rather than bullets
+func readDOCXNumbering(file *zip.File) (docxNumbering, error) {
+	numbering := make(docxNumbering)
+	if file == nil {
+		return numbering, nil
+	}
+
+	type level struct {
+		Ilvl   int `xml:"ilvl,attr"`
+		NumFmt struct {
+			Val string `xml:"val,attr"`
+		} `xml:"numFmt"`
+	}
+	var doc struct {
+		Abstract []struct {
+			ID     string  `xml:"abstractNumId,attr"`
+			Levels []level `xml:"lvl"`
+		} `xml:"abstractNum"`
+		Nums []struct {
+			ID       string `xml:"numId,attr"`
+			Abstract struct {
+				Val string `xml:"val,attr"`
+			} `xml:"abstractNumId"`
+		} `xml:"num"`
+	}
+	if err := decodeZipXML(file, &doc); err != nil {
+		return nil, err
+	}
+
+	abstract := make(map[string]map[int]bool)
+	for _, a := range doc.Abstract {
+		levels := make(map[int]bool)
+		for _, lvl := range a.Levels {
+			levels[lvl.Ilvl] = lvl.NumFmt.Val != "" && lvl.NumFmt.Val != "bullet" && lvl.NumFmt.Val != "none"
+		}
+		abstract[a.ID] = levels
+	}
+	for _, num := range doc.Nums {
+		numbering[num.ID] = abstract[num.Abstract.Val]
+	}
+	return numbering, nil
+}
+
+// docxTable collects the rows of a w:tbl being parsed
+type docxTable struct {
+	rows [][]string
+	row  []string
+	cell []string // paragraphs of the current cell
+	span int
+}
+
+// docxConverter turns one WordprocessingML part into Markdown
+type docxConverter struct {
+	styles    docxStyles
+	numbering docxNumbering
+
+	blocks  []string          // rendered top-level paragraphs and tables
+	items   []docxItem        // footnotes, endnotes or comments in this part
+	anchors map[string]string // comment id -> commented text
+
+	paragraph strings.Builder
+	pDepth    int
+	styleID   string
+	outline   int
+	numID     string
+	ilvl      int
+	inPPr     bool
+
+	insAuthor string
+	delAuthor string
+	changed   strings.Builder // text of the open w:ins/w:del
+
+	openAnchors map[string]*strings.Builder
+	tables      []*docxTable
+
+	item      *docxItem
+	itemStart int // len(blocks) when the current item started
+}
+
+func newDOCXConverter(styles docxStyles, numbering docxNumbering) *docxConverter {
+	return &docxConverter{
+		styles:      styles,
+		numbering:   numbering,
+		anchors:     make(map[string]string),
+		openAnchors: make(map[string]*strings.Builder),
+		outline:     -1,
+	}
+}
+
+// markdown returns the rendered blocks of the part
+func (c *docxConverter) markdown() string {
+	return strings.TrimSpace(strings.Join(c.blocks, "\n\n"))
+}
+
+func attrValue(el xml.StartElement, local string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func (c *docxConverter) run(r io.Reader) error {
+	decoder := xml.NewDecoder(r)
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if err := c.start(decoder, t); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			c.end(t)
+		}
+	}
+}
+
+func (c *docxConverter) start(decoder *xml.Decoder, t xml.StartElement) error {
+	switch t.Name.Local {
+	case "footnote", "endnote", "comment":
+		// Separator and continuation notes hold no text
+		if kind := attrValue(t, "type"); kind != "" && kind != "normal" {
+			return decoder.Skip()
+		}
+		c.item = &docxItem{id: attrValue(t, "id"), author: attrValue(t, "author")}
+		c.itemStart = len(c.blocks)
+
+	case "p":
+		if c.pDepth == 0 {
+			c.paragraph.Reset()
+			c.styleID, c.numID, c.ilvl, c.outline = "", "", 0, -1
+		} else {
+			c.paragraph.WriteString(" ") // text boxes inside a run
+		}
+		c.pDepth++
+	case "pPr":
+		c.inPPr = true
+	case "pStyle":
+		if c.pDepth == 1 {
+			c.styleID = attrValue(t, "val")
+		}
+	case "outlineLvl":
+		if c.pDepth == 1 {
+			c.outline, _ = strconv.Atoi(attrValue(t, "val"))
+		}
+	case "numId":
+		if c.pDepth == 1 {
+			c.numID = attrValue(t, "val")
+		}
+	case "ilvl":
+		if c.pDepth == 1 {
+			c.ilvl, _ = strconv.Atoi(attrValue(t, "val"))
+		}
+
+	case "t":
+		var text string
+		if err := decoder.DecodeElement(&text, &t); err != nil {
+			return err
+		}
+		c.write(text)
+	case "delText":
+		var text string
+		if err := decoder.DecodeElement(&text, &t); err != nil {
+			return err
+		}
+		c.changed.WriteString(text)
+	case "instrText":
+		// Field instructions (e.g. HYPERLINK "...") are not document text
+		return decoder.Skip()
+	case "tab":
+		if !c.inPPr {
+			c.write(" ")
+		}
+	case "br", "cr":
+		if len(c.tables) > 0 {
+			c.write(" ")
+		} else {
+			c.write("  \n")
+		}
+	case "noBreakHyphen":
+		c.write("-")
+
+	case "ins":
+		c.flushChange()
+		c.insAuthor = authorOrUnknown(attrValue(t, "author"))
+	case "del":
+		c.flushChange()
+		c.delAuthor = authorOrUnknown(attrValue(t, "author"))
+
+	case "footnoteReference":
+		c.write("[^" + attrValue(t, "id") + "]")
+	case "endnoteReference":
+		c.write("[^e" + attrValue(t, "id") + "]")
+
+	case "commentRangeStart":
+		c.openAnchors[attrValue(t, "id")] = &strings.Builder{}
+	case "commentRangeEnd":
+		id := attrValue(t, "id")
+		if anchor, ok := c.openAnchors[id]; ok {
+			c.anchors[id] = anchor.String()
+			delete(c.openAnchors, id)
+		}
+
+	case "tbl":
+		c.tables = append(c.tables, &docxTable{})
+	case "tr":
+		if table := c.currentTable(); table != nil {
+			table.row = nil
+		}
+	case "tc":
+		if table := c.currentTable(); table != nil {
+			table.cell = nil
+			table.span = 1
+		}
+	case "gridSpan":
+		if table := c.currentTable(); table != nil {
+			if n, err := strconv.Atoi(attrValue(t, "val")); err == nil && n > 1 {
+				table.span = n
+			}
+		}
+	}
+	return nil
+}
+
+func (c *docxConverter) end(t xml.EndElement) {
+	switch t.Name.Local {
+	case "pPr":
+		c.inPPr = false
+	case "p":
+		c.pDepth--
+		if c.pDepth > 0 {
+			c.paragraph.WriteString(" ")
+			return
+		}
+		c.flushChange()
+		c.finishParagraph()
+
+	case "ins", "del":
+		c.flushChange()
+
+	case "tc":
+		if table := c.currentTable(); table != nil {
+			table.row = append(table.row, escapeTableCell(strings.Join(table.cell, " ")))
+			// Merged cells keep the columns aligned with empty placeholders
+			for i := 1; i < table.span; i++ {
+				table.row = append(table.row, "")
+			}
+		}
+	case "tr":
+		if table := c.currentTable(); table != nil {
+			table.rows = append(table.rows, table.row)
+		}
+	case "tbl":
+		if len(c.tables) == 0 {
+			return
+		}
+		table := c.tables[len(c.tables)-1]
+		c.tables = c.tables[:len(c.tables)-1]
+
+		if outer := c.currentTable(); outer != nil {
+			// Nested tables are flattened into the enclosing cell
+			var rows []string
+			for _, row := range table.rows {
+				rows = append(rows, strings.Join(row, " / "))
+			}
+			outer.cell = append(outer.cell, strings.Join(rows, "; "))
+			return
+		}
+		if rendered := renderDOCXTable(table.rows); rendered != "" {
+			c.blocks = append(c.blocks, rendered)
+		}
+
+	case "footnote", "endnote", "comment":
+		if c.item == nil {
+			return
+		}
+		c.item.text = strings.Join(c.blocks[c.itemStart:], " ")
+		c.blocks = c.blocks[:c.itemStart]
+		c.items = append(c.items, *c.item)
+		c.item = nil
+	}
+}
+
+func (c *docxConverter) currentTable() *docxTable {
+	if len(c.tables) == 0 {
+		return nil
+	}
+	return c.tables[len(c.tables)-1]
+}
+
+// write appends run text to the paragraph, or to the open tracked change
+func (c *docxConverter) write(text string) {
+	if c.insAuthor != "" || c.delAuthor != "" {
+		c.changed.WriteString(text)
+	} else {
+		c.paragraph.WriteString(text)
+	}
+	if c.delAuthor == "" {
+		for _, anchor := range c.openAnchors {
+			anchor.WriteString(text)
+		}
+	}
+}
+
+// flushChange writes the text of a finished w:ins/w:del with its author
+func (c *docxConverter) flushChange() {
+	text := c.changed.String()
+	c.changed.Reset()
+
+	// Keep surrounding spaces outside the brackets
+	trimmed := strings.TrimSpace(text)
+	leading := text[:strings.Index(text, trimmed)]
+	trailing := text[len(leading)+len(trimmed):]
+
+	switch {
+	case trimmed == "":
+	case c.delAuthor != "":
+		c.paragraph.WriteString(fmt.Sprintf("%s[deleted by %s: %s]%s", leading, c.delAuthor, trimmed, trailing))
+	case c.insAuthor != "":
+		c.paragraph.WriteString(fmt.Sprintf("%s[inserted by %s: %s]%s", leading, c.insAuthor, trimmed, trailing))
+	}
+	c.insAuthor, c.delAuthor = "", ""
+}
+
+func authorOrUnknown(author string) string {
+	if author == "" {
+		return "unknown"
+	}
+	return author
+}
+
+// finishParagraph renders the paragraph as a heading, list item or plain text
+func (c *docxConverter) finishParagraph() {
+	text := strings.TrimSpace(c.paragraph.String())
+	c.paragraph.Reset()
+	if text == "" {
+		return
+	}
+
+	if table := c.currentTable(); table != nil {
+		table.cell = append(table.cell, text)
+		return
+	}
+
+	level, isHeading := c.styles.headingLevel(c.styleID)
+	if !isHeading && c.outline >= 0 && c.outline < 9 {
+		level, isHeading = c.outline+1, true
+	}
+
+	switch {
+	case isHeading:
+		text = strings.Repeat("#", min(level+1, 6)) + " " + strings.ReplaceAll(text, "  \n", " ")
+	case c.numID != "" && c.numID != "0":
+		marker := "- "
+		if c.numbering[c.numID][c.ilvl] {
+			marker = "1. "
+		}
+		text = strings.Repeat("  ", c.ilvl) + marker + text
+		// Consecutive list items form one block
+		if n := len(c.blocks); n > c.itemStart && isListBlock(c.blocks[n-1]) {
+			c.blocks[n-1] += "\n" + text
+			return
+		}
+	}
+
+	c.blocks = append(c.blocks, text)
+}
+
+func isListBlock(block string) bool {
+	last := block[strings.LastIndex(block, "\n")+1:]
+	last = strings.TrimLeft(last, " ")
+	return strings.HasPrefix(last, "- ") || strings.HasPrefix(last, "1. ")
+}
+
+// renderDOCXTable renders rows as a Markdown table with the first row as header
+func renderDOCXTable(rows [][]string) string {
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	if width == 0 {
+		return ""
+	}
+
+	var lines []string
+	for i, row := range rows {
+		cells := make([]string, width)
+		copy(cells, row)
+		lines = append(lines, "| "+strings.Join(cells, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat("---|", width))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
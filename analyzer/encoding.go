@@ -0,0 +1,241 @@
+package analyzer
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Text encodings told apart by detectEncoding. Everything is transcoded to
+// UTF-8 when read.
+const (
+	encodingUTF8        = "utf-8"
+	encodingUTF16LE     = "utf-16le"
+	encodingUTF16BE     = "utf-16be"
+	encodingWindows1252 = "windows-1252"
+	encodingLatin1      = "iso-8859-1"
+)
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// detectEncoding guesses the encoding of the start of a file. A byte order
+// mark decides; otherwise UTF-16 is recognized by the zero bytes of its
+// ASCII characters, then UTF-8 is tried and finally Windows-1252/Latin-1.
+// It reports false when the data does not look like text in any of them.
+func detectEncoding(data []byte) (string, bool) {
+	switch {
+	case bytes.HasPrefix(data, utf8BOM):
+		return encodingUTF8, true
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE, 0x00, 0x00}):
+		// UTF-32LE, which is not supported
+		return "", false
+	case bytes.HasPrefix(data, utf16LEBOM):
+		return encodingUTF16LE, true
+	case bytes.HasPrefix(data, utf16BEBOM):
+		return encodingUTF16BE, true
+	case len(data) == 0:
+		return "", false
+	}
+
+	if encoding := sniffUTF16(data); encoding != "" {
+		return encoding, true
+	}
+
+	// The head may end in the middle of a character
+	if head := trimIncompleteRune(data); utf8.Valid(head) {
+		return encodingUTF8, isMostlyText(string(head))
+	}
+
+	// Legacy single-byte text has no NUL bytes and mostly ASCII
+	high, c1 := 0, false
+	for _, b := range data {
+		if b == 0 {
+			return "", false
+		}
+		if b >= 0x80 {
+			high++
+			c1 = c1 || b <= 0x9F
+		}
+	}
+	if float64(high)/float64(len(data)) > 0.3 || !isMostlyText(decodeText(data, encodingWindows1252)) {
+		return "", false
+	}
+	if c1 {
+		return encodingWindows1252, true
+	}
+	return encodingLatin1, true
+}
+
+// sniffUTF16 recognizes UTF-16 without a byte order mark by the zero high
+// bytes of ASCII characters, which all fall on odd offsets in little endian
+// and on even offsets in big endian
+func sniffUTF16(data []byte) string {
+	pairs := len(data) / 2
+	if pairs < 2 {
+		return ""
+	}
+
+	zeroEven, zeroOdd := 0, 0
+	for i := 0; i+1 < len(data); i += 2 {
+		if data[i] == 0 {
+			zeroEven++
+		}
+		if data[i+1] == 0 {
+			zeroOdd++
+		}
+	}
+
+	var encoding string
+	switch {
+	case float64(zeroOdd) >= 0.4*float64(pairs) && float64(zeroEven) <= 0.05*float64(pairs):
+		encoding = encodingUTF16LE
+	case float64(zeroEven) >= 0.4*float64(pairs) && float64(zeroOdd) <= 0.05*float64(pairs):
+		encoding = encodingUTF16BE
+	default:
+		return ""
+	}
+
+	if !isMostlyText(decodeText(data, encoding)) {
+		return ""
+	}
+	return encoding
+}
+
+// trimIncompleteRune drops a UTF-8 sequence cut off at the end of data
+func trimIncompleteRune(data []byte) []byte {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return data[:i]
+			}
+			break
+		}
+	}
+	return data
+}
+
+// isMostlyText reports whether more than 90% of the characters of s are
+// printable or whitespace
+func isMostlyText(s string) bool {
+	total, text := 0, 0
+	for _, r := range s {
+		total++
+		if r == '\n' || r == '\r' || r == '\t' || r == '\f' || (r != utf8.RuneError && unicode.IsPrint(r)) {
+			text++
+		}
+	}
+	return total > 0 && float64(text)/float64(total) > 0.9
+}
+
+// decodeText converts data in encoding to UTF-8
+func decodeText(data []byte, encoding string) string {
+	out, _ := transcode(data, encoding, true)
+	return string(out)
+}
+
+// transcode converts a block of data in encoding to UTF-8. Unless final,
+// trailing bytes that may belong to a character continued in the next block
+// are returned as rest.
+func transcode(data []byte, encoding string, final bool) (out, rest []byte) {
+	switch encoding {
+	case encodingUTF16LE, encodingUTF16BE:
+		units := make([]uint16, 0, len(data)/2)
+		for i := 0; i+1 < len(data); i += 2 {
+			if encoding == encodingUTF16LE {
+				units = append(units, uint16(data[i])|uint16(data[i+1])<<8)
+			} else {
+				units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+			}
+		}
+		if !final && len(units) > 0 && units[len(units)-1] >= 0xD800 && units[len(units)-1] < 0xDC00 {
+			// A high surrogate waits for its low half
+			units = units[:len(units)-1]
+		}
+		rest = data[len(units)*2:]
+
+		out = make([]byte, 0, len(data))
+		for _, r := range utf16.Decode(units) {
+			out = utf8.AppendRune(out, r)
+		}
+		if final && len(rest) > 0 {
+			out = utf8.AppendRune(out, utf8.RuneError)
+			rest = nil
+		}
+		return out, rest
+
+	case encodingWindows1252:
+		out = make([]byte, 0, len(data))
+		for _, b := range data {
+			out = utf8.AppendRune(out, decodeCP1252(b))
+		}
+		return out, nil
+
+	case encodingLatin1:
+		out = make([]byte, 0, len(data))
+		for _, b := range data {
+			out = utf8.AppendRune(out, rune(b))
+		}
+		return out, nil
+	}
+
+	return data, nil
+}
+
+// textReader transcodes a stream to UTF-8 block by block
+type textReader struct {
+	src      io.Reader
+	encoding string
+	buf      []byte
+	rest     []byte // bytes of a character split across blocks
+	out      []byte // transcoded bytes not yet read
+	err      error
+}
+
+func (t *textReader) Read(p []byte) (int, error) {
+	for len(t.out) == 0 {
+		if t.err != nil {
+			return 0, t.err
+		}
+		n, err := t.src.Read(t.buf)
+		data := append(append([]byte(nil), t.rest...), t.buf[:n]...)
+		t.out, t.rest = transcode(data, t.encoding, err != nil)
+		t.err = err
+	}
+
+	n := copy(p, t.out)
+	t.out = t.out[n:]
+	return n, nil
+}
+
+// newTextReader detects the encoding of r from its first 512 bytes and
+// returns a reader of its content as UTF-8, without byte order mark, along
+// with the encoding. Data that does not look like text is passed through
+// and reported as "".
+func newTextReader(r io.Reader) (io.Reader, string) {
+	reader := bufio.NewReaderSize(r, 64*1024)
+	head, _ := reader.Peek(512)
+	encoding, ok := detectEncoding(head)
+	if !ok {
+		return reader, ""
+	}
+
+	switch encoding {
+	case encodingUTF8:
+		if bytes.HasPrefix(head, utf8BOM) {
+			reader.Discard(len(utf8BOM))
+		}
+		return reader, encoding
+	case encodingUTF16LE, encodingUTF16BE:
+		if bytes.HasPrefix(head, utf16LEBOM) || bytes.HasPrefix(head, utf16BEBOM) {
+			reader.Discard(2)
+		}
+	}
+	return &textReader{src: reader, encoding: encoding, buf: make([]byte, 32*1024)}, encoding
+}
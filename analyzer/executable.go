@@ -0,0 +1,892 @@
+package analyzer
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"debug/buildinfo"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"local-agent/config"
+)
+
+// Limits on what is read from an executable
+const (
+	maxSectionEntropyBytes = 16 * 1024 * 1024 // data of a section read for its entropy
+	maxExecutableStringLen = 200              // longer strings are truncated
+	maxSourcePaths         = 10               // compiler-embedded source file paths listed
+)
+
+// sourcePathPattern matches the source file paths compilers embed for
+// panics and debug info; a few show the build machine, the rest is noise
+var sourcePathPattern = regexp.MustCompile(`\.(?:go|s|c|h|cc|cpp|rs)$`)
+
+// executableStringPatterns classify the strings worth listing first, in the
+// order they are tried
+var executableStringPatterns = []struct {
+	kind    string
+	pattern *regexp.Regexp
+}{
+	{"URL", regexp.MustCompile(`(?i)\b(?:https?|ftp|wss?)://(?:localhost|[\w-]+(?:\.[\w-]+)+)[^\s"'<>]*`)},
+	{"Email", regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}\b`)},
+	// Not part of a longer dotted name, such as Go's func1.1.1.1
+	{"IP address", regexp.MustCompile(`(?:^|[^\w.])(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)(?:[^\w.]|$)`)},
+	{"Registry key", regexp.MustCompile(`(?i)\b(?:HKEY_[A-Z_]+|HK(?:LM|CU|CR|U)\b|SOFTWARE\\(?:Microsoft|Classes|Policies))`)},
+	{"Command", regexp.MustCompile(`(?i)\b(?:cmd(?:\.exe)?\s+/c|powershell(?:\.exe)?\b|/bin/(?:ba)?sh\b|bash\s+-c|curl\s+-|wget\s+http|chmod\s+[+0-7])`)},
+	{"Path", regexp.MustCompile(`(?:\b[A-Z]:\\[\w$][^\s"'<>]*|(?:^|\s)/(?:etc|usr|bin|sbin|tmp|var|home|root|dev|proc|opt|lib|Library|Users|System)/[^\s"'<>]*)`)},
+}
+
+// executableFormat is what the format-specific readers report
+type executableFormat struct {
+	facts     [][2]string // rows of the Binary section, in order
+	sections  []executableSection
+	libraries []string
+	imports   map[string][]string // imported symbols by library, "" when unknown
+	exports   []string
+}
+
+// executableSection is one row of the Sections table
+type executableSection struct {
+	name    string
+	size    uint64
+	flags   string
+	entropy float64
+	hasData bool
+}
+
+// isExecutable reports whether data starts with an ELF, PE or Mach-O header
+func isExecutable(data []byte) bool {
+	if bytes.HasPrefix(data, []byte(elf.ELFMAG)) {
+		return true
+	}
+	if len(data) >= 0x40 && data[0] == 'M' && data[1] == 'Z' {
+		// The DOS stub points at the PE signature; scripts and text files
+		// starting with "MZ" do not have one
+		offset := int(binary.LittleEndian.Uint32(data[0x3c:]))
+		return offset+4 <= len(data) && bytes.Equal(data[offset:offset+4], []byte("PE\x00\x00"))
+	}
+	if len(data) < 8 {
+		return false
+	}
+	switch binary.BigEndian.Uint32(data) {
+	case macho.Magic32, macho.Magic64, 0xcefaedfe, 0xcffaedfe:
+		return true
+	case macho.MagicFat:
+		// Java class files share the magic; their version number, where a
+		// universal binary has its small architecture count, is 45 or more
+		return binary.BigEndian.Uint32(data[4:]) < 20
+	}
+	return false
+}
+
+// ReadExecutableContent summarizes an ELF, PE or Mach-O executable, shared
+// library or object file: architecture and hardening flags, sections with
+// their entropy, imported libraries and symbols, exports, Go build info and
+// the printable strings most useful for triage. The code itself is never
+// disassembled or run.
+func (d *Detector) ReadExecutableContent(path string, opts config.ExecutableConfig) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open executable: %w", err)
+	}
+	defer file.Close()
+
+	header := make([]byte, 4096)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("failed to read executable: %w", err)
+	}
+	header = header[:n]
+
+	var format *executableFormat
+	switch {
+	case bytes.HasPrefix(header, []byte(elf.ELFMAG)):
+		format, err = readELF(file)
+	case bytes.HasPrefix(header, []byte("MZ")):
+		format, err = readPE(file)
+	case isExecutable(header):
+		format, err = readMachO(file)
+	default:
+		return "", fmt.Errorf("not an ELF, PE or Mach-O file")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	strs, digest, err := scanExecutableStrings(file, opts)
+	if err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	builder.WriteString("## Binary\n\n")
+	for _, fact := range format.facts {
+		fmt.Fprintf(&builder, "- %s: %s\n", fact[0], fact[1])
+	}
+	fmt.Fprintf(&builder, "- SHA-256: %s\n", digest)
+
+	info, infoErr := buildinfo.ReadFile(path)
+	if infoErr == nil {
+		fmt.Fprintf(&builder, "- Go: %s\n", info.GoVersion)
+	}
+
+	if len(format.sections) > 0 {
+		builder.WriteString("\n## Sections\n\n")
+		builder.WriteString("| Name | Size | Flags | Entropy |\n|---|---|---|---|\n")
+		for _, section := range format.sections {
+			entropy := "-"
+			if section.hasData {
+				entropy = fmt.Sprintf("%.2f", section.entropy)
+			}
+			fmt.Fprintf(&builder, "| %s | %d | %s | %s |\n", escapeTableCell(section.name), section.size, section.flags, entropy)
+		}
+		builder.WriteString("\nEntropy is in bits per byte; above 7.2 usually means compressed, encrypted or packed data.\n")
+	}
+
+	writeExecutableImports(&builder, format, opts.MaxSymbols)
+
+	if len(format.exports) > 0 {
+		fmt.Fprintf(&builder, "\n## Exports\n\n%d exported symbols", len(format.exports))
+		sort.Strings(format.exports)
+		writeSymbolList(&builder, format.exports, opts.MaxSymbols)
+	}
+
+	if infoErr == nil {
+		writeGoBuildInfo(&builder, info)
+	}
+
+	if len(strs) > 0 {
+		builder.WriteString("\n## Strings\n\n")
+		builder.WriteString("| Kind | String |\n|---|---|\n")
+		for _, s := range strs {
+			fmt.Fprintf(&builder, "| %s | %s |\n", s[0], escapeTableCell(s[1]))
+		}
+	}
+
+	return strings.TrimSpace(builder.String()), nil
+}
+
+// readELF reads the headers, sections and dynamic symbols of an ELF file
+func readELF(r io.ReaderAt) (*executableFormat, error) {
+	f, err := elf.NewFile(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ELF file: %w", err)
+	}
+	defer f.Close()
+
+	format := &executableFormat{imports: make(map[string][]string)}
+	bits := "32-bit"
+	if f.Class == elf.ELFCLASS64 {
+		bits = "64-bit"
+	}
+	endian := "little-endian"
+	if f.Data == elf.ELFDATA2MSB {
+		endian = "big-endian"
+	}
+	format.facts = append(format.facts, [2]string{"Format", fmt.Sprintf("ELF %s %s", bits, endian)})
+	format.facts = append(format.facts, [2]string{"Architecture", elfMachineName(f.Machine)})
+
+	var interpreter string
+	var nxStack, relro bool
+	for _, prog := range f.Progs {
+		switch prog.Type {
+		case elf.PT_INTERP:
+			data, err := io.ReadAll(io.LimitReader(prog.Open(), 4096))
+			if err == nil {
+				interpreter = strings.TrimRight(string(data), "\x00")
+			}
+		case elf.PT_GNU_STACK:
+			nxStack = prog.Flags&elf.PF_X == 0
+		case elf.PT_GNU_RELRO:
+			relro = true
+		}
+	}
+
+	kind := map[elf.Type]string{
+		elf.ET_EXEC: "executable",
+		elf.ET_DYN:  "shared object",
+		elf.ET_REL:  "relocatable object",
+		elf.ET_CORE: "core dump",
+	}[f.Type]
+	if f.Type == elf.ET_DYN && interpreter != "" {
+		kind = "position-independent executable"
+	}
+	if kind == "" {
+		kind = f.Type.String()
+	}
+	format.facts = append(format.facts, [2]string{"Type", kind})
+	if f.OSABI != elf.ELFOSABI_NONE {
+		format.facts = append(format.facts, [2]string{"OS ABI", strings.TrimPrefix(f.OSABI.String(), "ELFOSABI_")})
+	}
+	if f.Entry != 0 {
+		format.facts = append(format.facts, [2]string{"Entry point", fmt.Sprintf("0x%x", f.Entry)})
+	}
+	if interpreter != "" {
+		format.facts = append(format.facts, [2]string{"Interpreter", interpreter})
+	}
+
+	_, symErr := f.Symbols()
+	format.facts = append(format.facts, [2]string{"Stripped", yesNo(errors.Is(symErr, elf.ErrNoSymbols))})
+
+	for _, section := range f.Sections {
+		if section.Type == elf.SHT_NULL {
+			continue
+		}
+		flags := ""
+		if section.Flags&elf.SHF_ALLOC != 0 {
+			flags += "R"
+		}
+		if section.Flags&elf.SHF_WRITE != 0 {
+			flags += "W"
+		}
+		if section.Flags&elf.SHF_EXECINSTR != 0 {
+			flags += "X"
+		}
+		row := executableSection{name: section.Name, size: section.Size, flags: orDash(flags)}
+		if section.Type != elf.SHT_NOBITS && section.Size > 0 {
+			row.entropy, row.hasData = sectionEntropy(section.Open())
+		}
+		format.sections = append(format.sections, row)
+	}
+
+	format.libraries, _ = f.ImportedLibraries()
+	symbols, _ := f.ImportedSymbols()
+	canary := false
+	for _, symbol := range symbols {
+		name := symbol.Name
+		if symbol.Version != "" {
+			name += "@" + symbol.Version
+		}
+		format.imports[symbol.Library] = append(format.imports[symbol.Library], name)
+		if symbol.Name == "__stack_chk_fail" {
+			canary = true
+		}
+	}
+
+	if f.Type == elf.ET_DYN && interpreter == "" {
+		dynamic, _ := f.DynamicSymbols()
+		for _, symbol := range dynamic {
+			bind := elf.ST_BIND(symbol.Info)
+			kind := elf.ST_TYPE(symbol.Info)
+			if symbol.Section != elf.SHN_UNDEF && symbol.Section != elf.SHN_ABS && (bind == elf.STB_GLOBAL || bind == elf.STB_WEAK) &&
+				(kind == elf.STT_FUNC || kind == elf.STT_OBJECT) {
+				format.exports = append(format.exports, symbol.Name)
+			}
+		}
+	}
+
+	if f.Type == elf.ET_EXEC || f.Type == elf.ET_DYN {
+		var hardening []string
+		if f.Type == elf.ET_DYN && interpreter != "" {
+			hardening = append(hardening, "PIE")
+		}
+		if nxStack {
+			hardening = append(hardening, "NX stack")
+		}
+		if relro {
+			bindNow := false
+			if flags, err := f.DynValue(elf.DT_FLAGS); err == nil && len(flags) > 0 && flags[0]&uint64(elf.DF_BIND_NOW) != 0 {
+				bindNow = true
+			}
+			if flags, err := f.DynValue(elf.DT_FLAGS_1); err == nil && len(flags) > 0 && flags[0]&uint64(elf.DF_1_NOW) != 0 {
+				bindNow = true
+			}
+			if bindNow {
+				hardening = append(hardening, "full RELRO")
+			} else {
+				hardening = append(hardening, "partial RELRO")
+			}
+		}
+		if canary {
+			hardening = append(hardening, "stack canary")
+		}
+		format.facts = append(format.facts, [2]string{"Hardening", orNone(hardening)})
+	}
+
+	return format, nil
+}
+
+// readPE reads the headers, sections and import table of a PE file
+func readPE(r io.ReaderAt) (*executableFormat, error) {
+	f, err := pe.NewFile(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PE file: %w", err)
+	}
+	defer f.Close()
+
+	format := &executableFormat{imports: make(map[string][]string)}
+	var (
+		bits               string
+		subsystem          uint16
+		dllCharacteristics uint16
+		entry              uint32
+		imageBase          uint64
+		directories        []pe.DataDirectory
+	)
+	switch header := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		bits, subsystem, dllCharacteristics = "PE32", header.Subsystem, header.DllCharacteristics
+		entry, imageBase, directories = header.AddressOfEntryPoint, uint64(header.ImageBase), header.DataDirectory[:header.NumberOfRvaAndSizes]
+	case *pe.OptionalHeader64:
+		bits, subsystem, dllCharacteristics = "PE32+", header.Subsystem, header.DllCharacteristics
+		entry, imageBase, directories = header.AddressOfEntryPoint, header.ImageBase, header.DataDirectory[:header.NumberOfRvaAndSizes]
+	default:
+		bits = "COFF object"
+	}
+	directory := func(index int) bool {
+		return index < len(directories) && directories[index].VirtualAddress != 0 && directories[index].Size != 0
+	}
+
+	format.facts = append(format.facts, [2]string{"Format", bits})
+	format.facts = append(format.facts, [2]string{"Architecture", peMachineName(f.Machine)})
+
+	kind := "executable"
+	if f.Characteristics&pe.IMAGE_FILE_DLL != 0 {
+		kind = "DLL"
+	} else if f.OptionalHeader == nil {
+		kind = "object file"
+	}
+	if directory(pe.IMAGE_DIRECTORY_ENTRY_COM_DESCRIPTOR) {
+		kind += " (.NET assembly)"
+	}
+	format.facts = append(format.facts, [2]string{"Type", kind})
+	if f.OptionalHeader != nil {
+		format.facts = append(format.facts, [2]string{"Subsystem", peSubsystemName(subsystem)})
+		format.facts = append(format.facts, [2]string{"Entry point", fmt.Sprintf("0x%x (image base 0x%x)", entry, imageBase)})
+	}
+	if f.TimeDateStamp != 0 {
+		// Reproducible builds store a hash here, which shows as a date far
+		// in the past or future
+		format.facts = append(format.facts, [2]string{"Link time", time.Unix(int64(f.TimeDateStamp), 0).UTC().Format(time.RFC3339)})
+	}
+	if f.OptionalHeader != nil {
+		var hardening []string
+		if dllCharacteristics&pe.IMAGE_DLLCHARACTERISTICS_DYNAMIC_BASE != 0 {
+			hardening = append(hardening, "ASLR")
+		}
+		if dllCharacteristics&pe.IMAGE_DLLCHARACTERISTICS_HIGH_ENTROPY_VA != 0 {
+			hardening = append(hardening, "high-entropy ASLR")
+		}
+		if dllCharacteristics&pe.IMAGE_DLLCHARACTERISTICS_NX_COMPAT != 0 {
+			hardening = append(hardening, "DEP")
+		}
+		if dllCharacteristics&pe.IMAGE_DLLCHARACTERISTICS_GUARD_CF != 0 {
+			hardening = append(hardening, "Control Flow Guard")
+		}
+		format.facts = append(format.facts, [2]string{"Hardening", orNone(hardening)})
+		format.facts = append(format.facts, [2]string{"Authenticode signature", yesNo(directory(pe.IMAGE_DIRECTORY_ENTRY_SECURITY))})
+	}
+
+	for _, section := range f.Sections {
+		flags := ""
+		if section.Characteristics&pe.IMAGE_SCN_MEM_READ != 0 {
+			flags += "R"
+		}
+		if section.Characteristics&pe.IMAGE_SCN_MEM_WRITE != 0 {
+			flags += "W"
+		}
+		if section.Characteristics&pe.IMAGE_SCN_MEM_EXECUTE != 0 {
+			flags += "X"
+		}
+		size := uint64(section.VirtualSize)
+		if size == 0 {
+			size = uint64(section.Size)
+		}
+		row := executableSection{name: section.Name, size: size, flags: orDash(flags)}
+		if section.Size > 0 {
+			row.entropy, row.hasData = sectionEntropy(section.Open())
+		}
+		format.sections = append(format.sections, row)
+	}
+
+	format.libraries, _ = f.ImportedLibraries()
+	symbols, _ := f.ImportedSymbols()
+	for _, symbol := range symbols {
+		// Symbols are reported as "function:library"
+		name, library, _ := strings.Cut(symbol, ":")
+		format.imports[library] = append(format.imports[library], name)
+	}
+	if len(format.libraries) == 0 {
+		for library := range format.imports {
+			if library != "" {
+				format.libraries = append(format.libraries, library)
+			}
+		}
+		sort.Strings(format.libraries)
+	}
+
+	return format, nil
+}
+
+// readMachO reads the headers, sections and symbols of a Mach-O file, or of
+// the first architecture of a universal binary
+func readMachO(r io.ReaderAt) (*executableFormat, error) {
+	var arches []string
+	f, err := macho.NewFile(r)
+	if err != nil {
+		fat, fatErr := macho.NewFatFile(r)
+		if fatErr != nil {
+			return nil, fmt.Errorf("failed to parse Mach-O file: %w", err)
+		}
+		defer fat.Close()
+		for _, arch := range fat.Arches {
+			arches = append(arches, machoCPUName(arch.Cpu))
+		}
+		f = fat.Arches[0].File
+	} else {
+		defer f.Close()
+	}
+
+	format := &executableFormat{imports: make(map[string][]string)}
+	bits := "32-bit"
+	if f.Magic == macho.Magic64 {
+		bits = "64-bit"
+	}
+	if len(arches) > 0 {
+		format.facts = append(format.facts, [2]string{"Format", fmt.Sprintf("Mach-O universal binary (%s); the first architecture is summarized", strings.Join(arches, ", "))})
+	} else {
+		format.facts = append(format.facts, [2]string{"Format", "Mach-O " + bits})
+	}
+	format.facts = append(format.facts, [2]string{"Architecture", machoCPUName(f.Cpu)})
+
+	kind := map[macho.Type]string{
+		macho.TypeExec:   "executable",
+		macho.TypeDylib:  "dynamic library",
+		macho.TypeBundle: "bundle",
+		macho.TypeObj:    "object file",
+	}[f.Type]
+	if kind == "" {
+		kind = fmt.Sprintf("type %d", f.Type)
+	}
+	format.facts = append(format.facts, [2]string{"Type", kind})
+	if f.Type == macho.TypeExec {
+		var hardening []string
+		if f.Flags&macho.FlagPIE != 0 {
+			hardening = append(hardening, "PIE")
+		}
+		if f.Flags&macho.FlagAllowStackExecution == 0 {
+			hardening = append(hardening, "NX stack")
+		}
+		format.facts = append(format.facts, [2]string{"Hardening", orNone(hardening)})
+	}
+	format.facts = append(format.facts, [2]string{"Stripped", yesNo(f.Symtab == nil || len(f.Symtab.Syms) == 0)})
+
+	for _, section := range f.Sections {
+		flags := ""
+		if seg := f.Segment(section.Seg); seg != nil {
+			// Segment protections use the VM_PROT bits: 1 read, 2 write, 4 execute
+			for _, bit := range []struct {
+				mask uint32
+				flag string
+			}{{1, "R"}, {2, "W"}, {4, "X"}} {
+				if seg.Prot&bit.mask != 0 {
+					flags += bit.flag
+				}
+			}
+		}
+		row := executableSection{name: section.Seg + "," + section.Name, size: section.Size, flags: orDash(flags)}
+		// Zero-fill sections have no data in the file
+		if section.Offset != 0 && section.Size > 0 {
+			row.entropy, row.hasData = sectionEntropy(section.Open())
+		}
+		format.sections = append(format.sections, row)
+	}
+
+	format.libraries, _ = f.ImportedLibraries()
+	symbols, _ := f.ImportedSymbols()
+	format.imports[""] = symbols
+
+	if f.Symtab != nil && f.Type == macho.TypeDylib {
+		for _, symbol := range f.Symtab.Syms {
+			// N_EXT symbols of type N_SECT are defined here and visible to others
+			if symbol.Type&0x01 != 0 && symbol.Type&0x0e == 0x0e {
+				format.exports = append(format.exports, symbol.Name)
+			}
+		}
+	}
+
+	return format, nil
+}
+
+// writeExecutableImports renders the Imports section: the libraries, then
+// the imported symbols grouped by library, at most maxSymbols in total
+func writeExecutableImports(builder *strings.Builder, format *executableFormat, maxSymbols int) {
+	total := 0
+	for _, symbols := range format.imports {
+		total += len(symbols)
+	}
+	if len(format.libraries) == 0 && total == 0 {
+		return
+	}
+
+	builder.WriteString("\n## Imports\n\n")
+	if len(format.libraries) > 0 {
+		fmt.Fprintf(builder, "Libraries (%d): %s\n", len(format.libraries), strings.Join(format.libraries, ", "))
+	}
+	if total == 0 {
+		return
+	}
+
+	var libraries []string
+	for library := range format.imports {
+		libraries = append(libraries, library)
+	}
+	sort.Strings(libraries)
+
+	fmt.Fprintf(builder, "\n%d imported symbols", total)
+	if total > maxSymbols {
+		fmt.Fprintf(builder, " (first %d shown)", maxSymbols)
+	}
+	builder.WriteString(":\n")
+	shown := 0
+	for _, library := range libraries {
+		symbols := format.imports[library]
+		if len(symbols) == 0 || shown == maxSymbols {
+			continue
+		}
+		sort.Strings(symbols)
+		if len(symbols) > maxSymbols-shown {
+			symbols = symbols[:maxSymbols-shown]
+		}
+		shown += len(symbols)
+		if library == "" {
+			fmt.Fprintf(builder, "\n- %s\n", strings.Join(symbols, ", "))
+		} else {
+			fmt.Fprintf(builder, "\n- %s: %s\n", library, strings.Join(symbols, ", "))
+		}
+	}
+}
+
+// writeSymbolList finishes a "N symbols" line with at most limit names
+func writeSymbolList(builder *strings.Builder, symbols []string, limit int) {
+	if len(symbols) > limit {
+		fmt.Fprintf(builder, " (first %d shown)", limit)
+		symbols = symbols[:limit]
+	}
+	fmt.Fprintf(builder, ":\n\n%s\n", strings.Join(symbols, ", "))
+}
+
+// writeGoBuildInfo renders the module, settings and dependencies that the Go
+// toolchain embeds in binaries it builds
+func writeGoBuildInfo(builder *strings.Builder, info *buildinfo.BuildInfo) {
+	builder.WriteString("\n## Go build info\n\n")
+	fmt.Fprintf(builder, "- Go version: %s\n", info.GoVersion)
+	if info.Path != "" {
+		fmt.Fprintf(builder, "- Package: %s\n", info.Path)
+	}
+	if info.Main.Path != "" {
+		fmt.Fprintf(builder, "- Main module: %s %s\n", info.Main.Path, info.Main.Version)
+	}
+	for _, setting := range info.Settings {
+		if setting.Value != "" {
+			fmt.Fprintf(builder, "- %s: %s\n", setting.Key, setting.Value)
+		}
+	}
+
+	if len(info.Deps) == 0 {
+		return
+	}
+	fmt.Fprintf(builder, "\nDependencies (%d):\n\n| Module | Version |\n|---|---|\n", len(info.Deps))
+	for _, dep := range info.Deps {
+		version := dep.Version
+		if dep.Replace != nil {
+			version += " => " + strings.TrimSpace(dep.Replace.Path+" "+dep.Replace.Version)
+		}
+		fmt.Fprintf(builder, "| %s | %s |\n", dep.Path, version)
+	}
+}
+
+// scanExecutableStrings reads the whole file once, hashing it and collecting
+// its printable ASCII and UTF-16LE strings. It returns at most
+// opts.MaxStrings (kind, string) pairs: strings matching
+// executableStringPatterns first, grouped by kind, then other strings that
+// look like words, in file order.
+func scanExecutableStrings(file *os.File, opts config.ExecutableConfig) ([][2]string, string, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, "", fmt.Errorf("failed to read executable: %w", err)
+	}
+
+	hash := sha256.New()
+	reader := bufio.NewReaderSize(io.TeeReader(file, hash), 64*1024)
+
+	type rankedString struct {
+		rank  int
+		value [2]string
+	}
+	var interesting []rankedString
+	var other [][2]string
+	seen := make(map[string]bool)
+	sourcePaths := 0
+	add := func(run []byte) {
+		if len(run) < opts.MinStringLength {
+			return
+		}
+		s := strings.TrimSpace(string(run))
+		if len(s) < opts.MinStringLength {
+			return
+		}
+		for rank, candidate := range executableStringPatterns {
+			loc := candidate.pattern.FindStringIndex(s)
+			if loc == nil {
+				continue
+			}
+			// Compilers pack string constants together; keep the part
+			// around the match
+			s = clipAround(s, loc[0])
+			if seen[s] {
+				return
+			}
+			if candidate.kind == "Path" && sourcePathPattern.MatchString(s) {
+				if sourcePaths == maxSourcePaths {
+					return
+				}
+				sourcePaths++
+			}
+			if len(interesting) < opts.MaxStrings {
+				seen[s] = true
+				interesting = append(interesting, rankedString{rank, [2]string{candidate.kind, s}})
+			}
+			return
+		}
+		s = clipAround(s, 0)
+		if len(other) < opts.MaxStrings && !seen[s] && looksLikeWords(s) {
+			seen[s] = true
+			other = append(other, [2]string{"Text", s})
+		}
+	}
+
+	// ascii collects runs of printable bytes; wide collects UTF-16LE runs,
+	// whose high bytes are zero, as the Windows API uses them. pending is the
+	// low byte of the character being read.
+	var ascii, wide []byte
+	var pending byte
+	hasPending := false
+	for {
+		b, err := reader.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read executable: %w", err)
+		}
+
+		if isPrintableByte(b) {
+			ascii = append(ascii, b)
+		} else {
+			add(ascii)
+			ascii = ascii[:0]
+		}
+
+		switch {
+		case !hasPending:
+			pending, hasPending = b, true
+		case b == 0 && isPrintableByte(pending):
+			wide = append(wide, pending)
+			hasPending = false
+		default:
+			// The next run may start at this byte
+			add(wide)
+			wide = wide[:0]
+			pending = b
+		}
+	}
+	add(ascii)
+	add(wide)
+
+	// Interesting strings are grouped by kind, in file order within a kind
+	sort.SliceStable(interesting, func(i, j int) bool {
+		return interesting[i].rank < interesting[j].rank
+	})
+	var result [][2]string
+	for _, s := range interesting {
+		result = append(result, s.value)
+	}
+	for _, s := range other {
+		if len(result) == opts.MaxStrings {
+			break
+		}
+		result = append(result, s)
+	}
+	return result, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// clipAround shortens s to maxExecutableStringLen bytes starting a little
+// before offset, marking what was cut with "..."
+func clipAround(s string, offset int) string {
+	if len(s) <= maxExecutableStringLen {
+		return s
+	}
+	start := max(0, offset-40)
+	end := min(len(s), start+maxExecutableStringLen)
+	clipped := s[start:end]
+	if start > 0 {
+		clipped = "..." + clipped
+	}
+	if end < len(s) {
+		clipped += "..."
+	}
+	return clipped
+}
+
+// isPrintableByte reports whether b is printable ASCII or a tab
+func isPrintableByte(b byte) bool {
+	return (b >= 0x20 && b < 0x7f) || b == '\t'
+}
+
+// looksLikeWords filters out the runs of printable bytes found in machine
+// code and compressed data: a string is kept when at least two words of
+// three or more letters, each lowercase or capitalized, make up half of it
+func looksLikeWords(s string) bool {
+	words, covered := 0, 0
+	for _, word := range strings.FieldsFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z')
+	}) {
+		if len(word) >= 3 && strings.ToLower(word[1:]) == word[1:] {
+			words++
+			covered += len(word)
+		}
+	}
+	return words >= 2 && covered*2 >= len(s)
+}
+
+// sectionEntropy returns the Shannon entropy in bits per byte of the first
+// maxSectionEntropyBytes of a section, and false when it cannot be read
+func sectionEntropy(r io.Reader) (float64, bool) {
+	var counts [256]int
+	buffer := make([]byte, 64*1024)
+	total := 0
+	limited := io.LimitReader(r, maxSectionEntropyBytes)
+	for {
+		n, err := limited.Read(buffer)
+		for _, b := range buffer[:n] {
+			counts[b]++
+		}
+		total += n
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, false
+		}
+	}
+	if total == 0 {
+		return 0, false
+	}
+
+	entropy := 0.0
+	for _, count := range counts {
+		if count > 0 {
+			p := float64(count) / float64(total)
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy, true
+}
+
+// elfMachineName names the common ELF architectures
+func elfMachineName(machine elf.Machine) string {
+	names := map[elf.Machine]string{
+		elf.EM_X86_64:    "x86-64",
+		elf.EM_386:       "x86",
+		elf.EM_AARCH64:   "arm64",
+		elf.EM_ARM:       "arm",
+		elf.EM_RISCV:     "riscv",
+		elf.EM_MIPS:      "mips",
+		elf.EM_PPC64:     "ppc64",
+		elf.EM_PPC:       "ppc",
+		elf.EM_S390:      "s390x",
+		elf.EM_LOONGARCH: "loong64",
+		elf.EM_BPF:       "eBPF",
+	}
+	if name, ok := names[machine]; ok {
+		return name
+	}
+	return strings.TrimPrefix(machine.String(), "EM_")
+}
+
+// peMachineName names the common PE architectures
+func peMachineName(machine uint16) string {
+	switch machine {
+	case pe.IMAGE_FILE_MACHINE_AMD64:
+		return "x86-64"
+	case pe.IMAGE_FILE_MACHINE_I386:
+		return "x86"
+	case pe.IMAGE_FILE_MACHINE_ARM64:
+		return "arm64"
+	case pe.IMAGE_FILE_MACHINE_ARMNT, pe.IMAGE_FILE_MACHINE_ARM, pe.IMAGE_FILE_MACHINE_THUMB:
+		return "arm"
+	case pe.IMAGE_FILE_MACHINE_IA64:
+		return "ia64"
+	case pe.IMAGE_FILE_MACHINE_RISCV64:
+		return "riscv64"
+	}
+	return fmt.Sprintf("machine 0x%x", machine)
+}
+
+// peSubsystemName names the PE subsystems
+func peSubsystemName(subsystem uint16) string {
+	switch subsystem {
+	case pe.IMAGE_SUBSYSTEM_WINDOWS_GUI:
+		return "Windows GUI"
+	case pe.IMAGE_SUBSYSTEM_WINDOWS_CUI:
+		return "Windows console"
+	case pe.IMAGE_SUBSYSTEM_NATIVE:
+		return "native (driver)"
+	case pe.IMAGE_SUBSYSTEM_EFI_APPLICATION, pe.IMAGE_SUBSYSTEM_EFI_BOOT_SERVICE_DRIVER, pe.IMAGE_SUBSYSTEM_EFI_RUNTIME_DRIVER:
+		return "EFI"
+	}
+	return fmt.Sprintf("%d", subsystem)
+}
+
+// machoCPUName names the common Mach-O architectures
+func machoCPUName(cpu macho.Cpu) string {
+	switch cpu {
+	case macho.CpuAmd64:
+		return "x86-64"
+	case macho.Cpu386:
+		return "x86"
+	case macho.CpuArm64:
+		return "arm64"
+	case macho.CpuArm:
+		return "arm"
+	case macho.CpuPpc64:
+		return "ppc64"
+	case macho.CpuPpc:
+		return "ppc"
+	}
+	return cpu.String()
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func orNone(items []string) string {
+	if len(items) == 0 {
+		return "none"
+	}
+	return strings.Join(items, ", ")
+}
@@ -0,0 +1,591 @@
+package analyzer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"local-agent/types"
+)
+
+// iacCategory is the category of the findings of infrastructure checks
+const iacCategory = "misconfiguration"
+
+var (
+	// kubernetesAPIVersionPattern and kubernetesKindPattern match the
+	// top-level fields every Kubernetes resource has
+	kubernetesAPIVersionPattern = regexp.MustCompile(`(?m)^apiVersion:[ \t]*\S`)
+	kubernetesKindPattern       = regexp.MustCompile(`(?m)^kind:[ \t]*[A-Z]`)
+	// yamlDocumentStart matches the "---" line between YAML documents
+	yamlDocumentStart = regexp.MustCompile(`^---(\s|$)`)
+)
+
+// podSpecPaths locates the pod spec in the workload kinds
+var podSpecPaths = map[string][]string{
+	"Pod":                   {"spec"},
+	"Deployment":            {"spec", "template", "spec"},
+	"StatefulSet":           {"spec", "template", "spec"},
+	"DaemonSet":             {"spec", "template", "spec"},
+	"ReplicaSet":            {"spec", "template", "spec"},
+	"ReplicationController": {"spec", "template", "spec"},
+	"Job":                   {"spec", "template", "spec"},
+	"CronJob":               {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// dangerousCapabilities are the Linux capabilities that amount to root on
+// the node
+var dangerousCapabilities = map[string]bool{
+	"ALL": true, "SYS_ADMIN": true, "NET_ADMIN": true, "SYS_PTRACE": true, "SYS_MODULE": true,
+}
+
+// iacResource is one section of an infrastructure summary
+type iacResource struct {
+	heading  string
+	facts    [][2]string
+	table    string // Markdown table shown below the facts, e.g. containers
+	findings []types.Finding
+	source   string
+	language string // fence language of source
+}
+
+// isKubernetesManifest reports whether YAML data holds Kubernetes resources.
+// Helm templates are left as text: they are not YAML until rendered.
+func isKubernetesManifest(data []byte) bool {
+	return kubernetesAPIVersionPattern.Match(data) && kubernetesKindPattern.Match(data) &&
+		!bytes.Contains(data, []byte("{{"))
+}
+
+// ReadIaCContent summarizes Kubernetes manifests and Terraform files: one
+// "## " section per resource with its key settings and source, after a
+// summary. Deterministic misconfiguration checks, such as privileged
+// containers, images without a pinned tag, missing resource limits and
+// ingress open to 0.0.0.0/0, are returned as findings with line numbers
+// and listed under the resource they concern.
+func (d *Detector) ReadIaCContent(path string) (string, []types.Finding, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	if strings.ToLower(filepath.Ext(path)) == ".tf" {
+		resources, format := terraformResources(string(data))
+		content, findings := renderIaC(format, resources)
+		return content, findings, nil
+	}
+	resources, format := kubernetesResources(string(data))
+	content, findings := renderIaC(format, resources)
+	return content, findings, nil
+}
+
+// renderIaC writes the summary and the resource sections, and collects the
+// findings of all resources
+func renderIaC(format []string, resources []iacResource) (string, []types.Finding) {
+	var findings []types.Finding
+	for _, resource := range resources {
+		sort.SliceStable(resource.findings, func(i, j int) bool {
+			return resource.findings[i].Line < resource.findings[j].Line
+		})
+		findings = append(findings, resource.findings...)
+	}
+
+	var builder strings.Builder
+	builder.WriteString("## Summary\n\n")
+	for _, line := range format {
+		builder.WriteString("- " + line + "\n")
+	}
+	if len(findings) == 0 {
+		builder.WriteString("- Misconfigurations: none found by the built-in checks\n")
+	} else {
+		bySeverity := make(map[types.Severity]int)
+		for _, finding := range findings {
+			bySeverity[finding.Severity]++
+		}
+		var parts []string
+		for _, severity := range []types.Severity{types.SeverityCritical, types.SeverityHigh, types.SeverityMedium, types.SeverityLow, types.SeverityInfo} {
+			if bySeverity[severity] > 0 {
+				parts = append(parts, fmt.Sprintf("%d %s", bySeverity[severity], severity))
+			}
+		}
+		fmt.Fprintf(&builder, "- Misconfigurations: %d (%s), listed under each resource\n", len(findings), strings.Join(parts, ", "))
+	}
+
+	for _, resource := range resources {
+		fmt.Fprintf(&builder, "\n## %s\n\n", resource.heading)
+		for _, fact := range resource.facts {
+			fmt.Fprintf(&builder, "- %s: %s\n", fact[0], fact[1])
+		}
+		if resource.table != "" {
+			builder.WriteString("\n" + resource.table)
+		}
+		if len(resource.findings) > 0 {
+			builder.WriteString("\nMisconfigurations:\n\n")
+			for _, finding := range resource.findings {
+				fmt.Fprintf(&builder, "- [%s] line %d: %s\n", finding.Severity, finding.Line, finding.Description)
+			}
+		}
+		if strings.TrimSpace(resource.source) != "" {
+			builder.WriteString("\n" + fenceBlock(strings.TrimRight(resource.source, "\n"), resource.language) + "\n")
+		}
+	}
+	return strings.TrimSpace(builder.String()), findings
+}
+
+// kubernetesResources splits a manifest into its documents and summarizes
+// the resources they hold. Lists, as written by kubectl get -o yaml, are
+// expanded into their items.
+func kubernetesResources(source string) ([]iacResource, []string) {
+	var resources []iacResource
+	kinds := make(map[string]int)
+	documents := 0
+
+	lines := strings.Split(source, "\n")
+	start := 0
+	flush := func(end int) {
+		text := strings.Join(lines[start:end], "\n")
+		offset := start
+		start = end + 1
+		if strings.TrimSpace(stripYAMLComments(text)) == "" {
+			return
+		}
+		documents++
+
+		var document yaml.Node
+		if err := yaml.Unmarshal([]byte(text), &document); err != nil || len(document.Content) == 0 {
+			resources = append(resources, iacResource{
+				heading:  fmt.Sprintf("Document %d", documents),
+				facts:    [][2]string{{"Lines", fmt.Sprintf("%d-%d", offset+1, end)}, {"Note", "not valid YAML, shown as is"}},
+				source:   text,
+				language: "yaml",
+			})
+			return
+		}
+		root := document.Content[0]
+
+		items := yamlField(root, "items")
+		if kind := yamlString(root, "kind"); strings.HasSuffix(kind, "List") && items != nil && items.Kind == yaml.SequenceNode {
+			for _, item := range items.Content {
+				resource := kubernetesResource(item, offset, documents)
+				resource.facts = append([][2]string{{"Line", strconv.Itoa(offset + item.Line)}}, resource.facts...)
+				if data, err := yaml.Marshal(item); err == nil {
+					resource.source = string(data)
+				}
+				resources = append(resources, resource)
+				kinds[yamlString(item, "kind")]++
+			}
+			return
+		}
+
+		resource := kubernetesResource(root, offset, documents)
+		resource.facts = append([][2]string{{"Lines", fmt.Sprintf("%d-%d", offset+1, end)}}, resource.facts...)
+		resource.source = text
+		resources = append(resources, resource)
+		kinds[yamlString(root, "kind")]++
+	}
+	for i, line := range lines {
+		if yamlDocumentStart.MatchString(line) {
+			flush(i)
+		}
+	}
+	flush(len(lines))
+
+	format := []string{fmt.Sprintf("Format: Kubernetes manifest (%d %s)", documents, plural(documents, "document", "documents"))}
+	if summary := formatKindCounts(kinds); summary != "" {
+		format = append(format, "Resources: "+summary)
+	}
+	return resources, format
+}
+
+// kubernetesResource summarizes one resource and runs the checks that apply
+// to its kind. offset is the line of the document in the file.
+func kubernetesResource(node *yaml.Node, offset, document int) iacResource {
+	kind := yamlString(node, "kind")
+	name := yamlString(node, "metadata", "name")
+	if name == "" && yamlString(node, "metadata", "generateName") != "" {
+		name = yamlString(node, "metadata", "generateName") + "*"
+	}
+
+	resource := iacResource{language: "yaml"}
+	switch {
+	case kind != "" && name != "":
+		resource.heading = kind + ": " + name
+	case kind != "":
+		resource.heading = kind
+	default:
+		resource.heading = fmt.Sprintf("Document %d", document)
+	}
+	label := strings.TrimSpace(fmt.Sprintf("%s %q", kind, name))
+
+	fact := func(key, value string) {
+		if value != "" {
+			resource.facts = append(resource.facts, [2]string{key, value})
+		}
+	}
+	finding := func(at *yaml.Node, severity types.Severity, description, suggestion string) {
+		line := offset
+		if at != nil {
+			line += at.Line
+		}
+		resource.findings = append(resource.findings, types.Finding{
+			Line:        line,
+			Severity:    severity,
+			Category:    iacCategory,
+			Description: label + ": " + description,
+			Suggestion:  suggestion,
+		})
+	}
+
+	fact("API version", yamlString(node, "apiVersion"))
+	fact("Namespace", yamlString(node, "metadata", "namespace"))
+
+	if path, ok := podSpecPaths[kind]; ok {
+		fact("Replicas", yamlString(node, "spec", "replicas"))
+		fact("Schedule", yamlString(node, "spec", "schedule"))
+		if pod := yamlField(node, path...); pod != nil {
+			resource.table = summarizePodSpec(pod, fact, finding)
+		}
+		return resource
+	}
+
+	switch kind {
+	case "Service":
+		serviceType := yamlString(node, "spec", "type")
+		if serviceType == "" {
+			serviceType = "ClusterIP"
+		}
+		fact("Type", serviceType)
+		var ports []string
+		for _, port := range yamlItems(yamlField(node, "spec", "ports")) {
+			text := yamlString(port, "port")
+			if target := yamlString(port, "targetPort"); target != "" && target != text {
+				text += "->" + target
+			}
+			if nodePort := yamlString(port, "nodePort"); nodePort != "" {
+				text += " (node port " + nodePort + ")"
+			}
+			ports = append(ports, text+"/"+orDefault(yamlString(port, "protocol"), "TCP"))
+		}
+		fact("Ports", strings.Join(ports, ", "))
+		fact("Selector", formatYAMLMap(yamlField(node, "spec", "selector")))
+
+		ranges := yamlField(node, "spec", "loadBalancerSourceRanges")
+		for _, cidr := range yamlItems(ranges) {
+			if isAnyAddress(cidr.Value) {
+				finding(cidr, types.SeverityMedium, fmt.Sprintf("load balancer accepts traffic from %s", cidr.Value),
+					"Limit loadBalancerSourceRanges to the client networks that need access")
+			}
+		}
+		if serviceType == "LoadBalancer" && ranges == nil {
+			finding(yamlField(node, "spec", "type"), types.SeverityLow, "load balancer accepts traffic from any address",
+				"Set loadBalancerSourceRanges unless the service is meant to be public")
+		}
+	case "Ingress":
+		var rules []string
+		for _, rule := range yamlItems(yamlField(node, "spec", "rules")) {
+			host := orDefault(yamlString(rule, "host"), "*")
+			for _, path := range yamlItems(yamlField(rule, "http", "paths")) {
+				backend := yamlString(path, "backend", "service", "name")
+				port := yamlString(path, "backend", "service", "port", "number") + yamlString(path, "backend", "service", "port", "name")
+				if backend == "" {
+					// networking.k8s.io/v1beta1
+					backend, port = yamlString(path, "backend", "serviceName"), yamlString(path, "backend", "servicePort")
+				}
+				rules = append(rules, fmt.Sprintf("%s%s -> %s:%s", host, orDefault(yamlString(path, "path"), "/"), backend, port))
+			}
+		}
+		fact("Rules", strings.Join(rules, ", "))
+		var tlsHosts []string
+		for _, tls := range yamlItems(yamlField(node, "spec", "tls")) {
+			for _, host := range yamlItems(yamlField(tls, "hosts")) {
+				tlsHosts = append(tlsHosts, host.Value)
+			}
+		}
+		fact("TLS hosts", strings.Join(tlsHosts, ", "))
+	case "ConfigMap", "Secret":
+		// Only key names; values stay in the source below, where secrets
+		// are redacted before anything is sent
+		fact("Type", yamlString(node, "type"))
+		var keys []string
+		for _, field := range []string{"data", "stringData", "binaryData"} {
+			keys = append(keys, yamlKeys(yamlField(node, field))...)
+		}
+		sort.Strings(keys)
+		fact("Keys", strings.Join(keys, ", "))
+	case "NetworkPolicy":
+		var policyTypes []string
+		for _, policyType := range yamlItems(yamlField(node, "spec", "policyTypes")) {
+			policyTypes = append(policyTypes, policyType.Value)
+		}
+		fact("Policy types", strings.Join(policyTypes, ", "))
+		fact("Pod selector", orDefault(formatYAMLMap(yamlField(node, "spec", "podSelector", "matchLabels")), "all pods"))
+		for _, rule := range yamlItems(yamlField(node, "spec", "ingress")) {
+			for _, peer := range yamlItems(yamlField(rule, "from")) {
+				if cidr := yamlField(peer, "ipBlock", "cidr"); cidr != nil && isAnyAddress(cidr.Value) {
+					finding(cidr, types.SeverityMedium, fmt.Sprintf("allows ingress from %s", cidr.Value),
+						"Narrow the ipBlock to the networks that need access")
+				}
+			}
+		}
+	default:
+		fact("Spec fields", strings.Join(yamlKeys(yamlField(node, "spec")), ", "))
+	}
+	return resource
+}
+
+// summarizePodSpec records the pod-level settings as facts, checks the pod
+// and its containers, and returns the container table
+func summarizePodSpec(pod *yaml.Node, fact func(key, value string), finding func(*yaml.Node, types.Severity, string, string)) string {
+	fact("Service account", yamlString(pod, "serviceAccountName"))
+
+	for _, field := range []string{"hostNetwork", "hostPID", "hostIPC"} {
+		if value := yamlField(pod, field); value != nil && value.Value == "true" {
+			fact(field, "true")
+			finding(value, types.SeverityHigh, fmt.Sprintf("pod shares the node's namespaces (%s: true)", field),
+				"Remove "+field+" unless the workload is a node agent that needs it")
+		}
+	}
+	for _, volume := range yamlItems(yamlField(pod, "volumes")) {
+		if hostPath := yamlField(volume, "hostPath", "path"); hostPath != nil {
+			finding(hostPath, types.SeverityMedium, fmt.Sprintf("volume %q mounts the node path %s", yamlString(volume, "name"), hostPath.Value),
+				"Use a persistent volume, ConfigMap or emptyDir instead of a hostPath")
+		}
+	}
+	podUser := yamlField(pod, "securityContext", "runAsUser")
+	if podUser != nil && podUser.Value == "0" {
+		finding(podUser, types.SeverityMedium, "pod runs its containers as root by default (runAsUser: 0)",
+			"Run as a non-root user and set runAsNonRoot: true")
+	}
+
+	var table strings.Builder
+	table.WriteString("| Container | Image | Ports | Requests | Limits | Security context |\n|---|---|---|---|---|---|\n")
+	for _, group := range []string{"initContainers", "containers"} {
+		for _, container := range yamlItems(yamlField(pod, group)) {
+			name := yamlString(container, "name")
+			label := fmt.Sprintf("container %q", name)
+			if group == "initContainers" {
+				label = fmt.Sprintf("init container %q", name)
+			}
+
+			var ports []string
+			for _, port := range yamlItems(yamlField(container, "ports")) {
+				ports = append(ports, yamlString(port, "containerPort")+"/"+orDefault(yamlString(port, "protocol"), "TCP"))
+			}
+
+			image := yamlField(container, "image")
+			if image != nil {
+				checkImageTag(image, label, finding)
+			}
+
+			limits := yamlField(container, "resources", "limits")
+			var missing []string
+			for _, resource := range []string{"cpu", "memory"} {
+				if yamlField(limits, resource) == nil {
+					missing = append(missing, resource)
+				}
+			}
+			if len(missing) > 0 {
+				severity := types.SeverityLow
+				if yamlField(limits, "memory") == nil {
+					severity = types.SeverityMedium
+				}
+				finding(container, severity, fmt.Sprintf("%s has no %s limit", label, strings.Join(missing, " or ")),
+					"Set resources.limits so one container cannot starve the node")
+			}
+
+			security := yamlField(container, "securityContext")
+			var settings []string
+			if privileged := yamlField(security, "privileged"); privileged != nil && privileged.Value == "true" {
+				settings = append(settings, "privileged")
+				finding(privileged, types.SeverityHigh, label+" runs privileged",
+					"Remove privileged: true and grant only the capabilities the container needs")
+			}
+			if escalation := yamlField(security, "allowPrivilegeEscalation"); escalation != nil {
+				settings = append(settings, "allowPrivilegeEscalation "+escalation.Value)
+			}
+			if user := yamlField(security, "runAsUser"); user != nil {
+				settings = append(settings, "runAsUser "+user.Value)
+				if user.Value == "0" {
+					finding(user, types.SeverityMedium, label+" runs as root (runAsUser: 0)",
+						"Run as a non-root user and set runAsNonRoot: true")
+				}
+			} else if podUser != nil {
+				settings = append(settings, "runAsUser "+podUser.Value+" (pod)")
+			}
+			if nonRoot := yamlString(security, "runAsNonRoot"); nonRoot != "" {
+				settings = append(settings, "runAsNonRoot "+nonRoot)
+			}
+			if readOnly := yamlString(security, "readOnlyRootFilesystem"); readOnly != "" {
+				settings = append(settings, "readOnlyRootFilesystem "+readOnly)
+			}
+			for _, capability := range yamlItems(yamlField(security, "capabilities", "add")) {
+				settings = append(settings, "adds "+capability.Value)
+				if dangerousCapabilities[strings.TrimPrefix(strings.ToUpper(capability.Value), "CAP_")] {
+					finding(capability, types.SeverityHigh, fmt.Sprintf("%s adds the %s capability", label, capability.Value),
+						"Drop the capability; it gives the container control over the node")
+				}
+			}
+
+			fmt.Fprintf(&table, "| %s | %s | %s | %s | %s | %s |\n",
+				escapeTableCell(name), escapeTableCell(yamlString(container, "image")), strings.Join(ports, ", "),
+				escapeTableCell(formatYAMLMap(yamlField(container, "resources", "requests"))),
+				escapeTableCell(formatYAMLMap(limits)), escapeTableCell(strings.Join(settings, ", ")))
+		}
+	}
+	return table.String()
+}
+
+// checkImageTag flags images that follow the mutable "latest" tag
+func checkImageTag(image *yaml.Node, label string, finding func(*yaml.Node, types.Severity, string, string)) {
+	tag, ok := imageTag(image.Value)
+	if !ok {
+		return
+	}
+	switch tag {
+	case "latest":
+		finding(image, types.SeverityMedium, fmt.Sprintf("%s uses the mutable \"latest\" tag (%s)", label, image.Value),
+			"Pin the image to a version tag or digest so deployments are reproducible")
+	case "":
+		finding(image, types.SeverityMedium, fmt.Sprintf("%s image %s has no tag, so it pulls \"latest\"", label, image.Value),
+			"Pin the image to a version tag or digest so deployments are reproducible")
+	}
+}
+
+// imageTag returns the tag of an image reference, "" when it has none. ok
+// is false for digests and templated references, which are not checked.
+func imageTag(image string) (string, bool) {
+	if image == "" || strings.Contains(image, "@") || strings.ContainsAny(image, "${}") {
+		return "", false
+	}
+	name := image[strings.LastIndex(image, "/")+1:]
+	if _, tag, found := strings.Cut(name, ":"); found {
+		return tag, true
+	}
+	return "", true
+}
+
+// isAnyAddress reports whether a CIDR or address prefix covers every address
+func isAnyAddress(value string) bool {
+	switch strings.TrimSpace(value) {
+	case "0.0.0.0/0", "::/0", "*", "Internet", "Any":
+		return true
+	}
+	return false
+}
+
+// yamlField follows a path of mapping keys from node, or returns nil
+func yamlField(node *yaml.Node, path ...string) *yaml.Node {
+	for _, key := range path {
+		if node != nil && node.Kind == yaml.AliasNode {
+			node = node.Alias
+		}
+		if node == nil || node.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				next = node.Content[i+1]
+				break
+			}
+		}
+		node = next
+	}
+	if node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+// yamlString returns the scalar at path, or ""
+func yamlString(node *yaml.Node, path ...string) string {
+	node = yamlField(node, path...)
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return node.Value
+}
+
+// yamlItems returns the items of a sequence node
+func yamlItems(node *yaml.Node) []*yaml.Node {
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return node.Content
+}
+
+// yamlKeys returns the keys of a mapping node in document order
+func yamlKeys(node *yaml.Node) []string {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	var keys []string
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keys = append(keys, node.Content[i].Value)
+	}
+	return keys
+}
+
+// formatYAMLMap renders a mapping of scalars as "key=value, ..."
+func formatYAMLMap(node *yaml.Node) string {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return ""
+	}
+	var pairs []string
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		pairs = append(pairs, node.Content[i].Value+"="+node.Content[i+1].Value)
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// stripYAMLComments drops comment lines, so documents holding only
+// comments are skipped
+func stripYAMLComments(text string) string {
+	var kept []string
+	for _, line := range strings.Split(text, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "#") {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// formatKindCounts lists resource kinds by count, e.g. "2 Deployment, 1 Service"
+func formatKindCounts(kinds map[string]int) string {
+	var names []string
+	for kind := range kinds {
+		if kind != "" {
+			names = append(names, kind)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if kinds[names[i]] != kinds[names[j]] {
+			return kinds[names[i]] > kinds[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	var parts []string
+	for _, name := range names {
+		parts = append(parts, strconv.Itoa(kinds[name])+" "+name)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func plural(n int, singular, pluralForm string) string {
+	if n == 1 {
+		return singular
+	}
+	return pluralForm
+}
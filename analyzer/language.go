@@ -0,0 +1,340 @@
+package analyzer
+
+import (
+	"bytes"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// languageNames maps the code fence identifiers found by file name or
+// content to the name shown in summaries
+var languageNames = map[string]string{
+	"applescript": "AppleScript",
+	"awk":         "AWK",
+	"bash":        "Shell",
+	"c":           "C",
+	"cmake":       "CMake",
+	"cpp":         "C++",
+	"dockerfile":  "Dockerfile",
+	"elixir":      "Elixir",
+	"erlang":      "Erlang",
+	"fish":        "Fish",
+	"go":          "Go",
+	"groovy":      "Groovy",
+	"haskell":     "Haskell",
+	"hcl":         "HCL",
+	"html":        "HTML",
+	"ini":         "INI",
+	"java":        "Java",
+	"javascript":  "JavaScript",
+	"json":        "JSON",
+	"julia":       "Julia",
+	"just":        "Just",
+	"kotlin":      "Kotlin",
+	"lisp":        "Lisp",
+	"lua":         "Lua",
+	"makefile":    "Makefile",
+	"markdown":    "Markdown",
+	"nginx":       "Nginx",
+	"perl":        "Perl",
+	"php":         "PHP",
+	"powershell":  "PowerShell",
+	"python":      "Python",
+	"r":           "R",
+	"ruby":        "Ruby",
+	"rust":        "Rust",
+	"scala":       "Scala",
+	"scheme":      "Scheme",
+	"sql":         "SQL",
+	"starlark":    "Starlark",
+	"swift":       "Swift",
+	"tcl":         "Tcl",
+	"toml":        "TOML",
+	"typescript":  "TypeScript",
+	"vim":         "Vim script",
+	"xml":         "XML",
+	"yaml":        "YAML",
+	"zsh":         "Zsh",
+}
+
+// fileNameLanguages are naming conventions for files whose extension does
+// not tell their language, matched in order against the lower-cased base name
+var fileNameLanguages = []struct {
+	pattern  string
+	language string
+}{
+	{"dockerfile", "dockerfile"},
+	{"dockerfile.*", "dockerfile"},
+	{"*.dockerfile", "dockerfile"},
+	{"containerfile", "dockerfile"},
+	{"containerfile.*", "dockerfile"},
+	{"makefile", "makefile"},
+	{"makefile.*", "makefile"},
+	{"gnumakefile", "makefile"},
+	{"*.mk", "makefile"},
+	{"*.mak", "makefile"},
+	{"jenkinsfile", "groovy"},
+	{"jenkinsfile.*", "groovy"},
+	{"*.groovy", "groovy"},
+	{"*.gradle", "groovy"},
+	{"vagrantfile", "ruby"},
+	{"gemfile", "ruby"},
+	{"rakefile", "ruby"},
+	{"podfile", "ruby"},
+	{"fastfile", "ruby"},
+	{"brewfile", "ruby"},
+	{"guardfile", "ruby"},
+	{"capfile", "ruby"},
+	{"*.gemspec", "ruby"},
+	{"*.rake", "ruby"},
+	{"cmakelists.txt", "cmake"},
+	{"*.cmake", "cmake"},
+	{"build", "starlark"},
+	{"build.bazel", "starlark"},
+	{"workspace", "starlark"},
+	{"workspace.bazel", "starlark"},
+	{"module.bazel", "starlark"},
+	{"tiltfile", "starlark"},
+	{"*.bzl", "starlark"},
+	{"*.star", "starlark"},
+	{"justfile", "just"},
+	{".justfile", "just"},
+	{".bashrc", "bash"},
+	{".bash_profile", "bash"},
+	{".bash_aliases", "bash"},
+	{".bash_logout", "bash"},
+	{".profile", "bash"},
+	{".envrc", "bash"},
+	{"*.bash", "bash"},
+	{".zshrc", "zsh"},
+	{".zshenv", "zsh"},
+	{".zprofile", "zsh"},
+	{"*.zsh", "zsh"},
+	{"*.fish", "fish"},
+	{".vimrc", "vim"},
+	{"*.vim", "vim"},
+	{"nginx.conf", "nginx"},
+	{"*.pl", "perl"},
+	{"*.pm", "perl"},
+	{"*.lua", "lua"},
+	{"*.ps1", "powershell"},
+	{"*.psm1", "powershell"},
+	{"*.toml", "toml"},
+	{"*.ini", "ini"},
+	{"*.hcl", "hcl"},
+	{"*.tfvars", "hcl"},
+	{"*.html", "html"},
+	{"*.htm", "html"},
+	{"*.hpp", "cpp"},
+	{"*.cc", "cpp"},
+	{"*.h", "c"},
+}
+
+// interpreterLanguages maps shebang interpreters, without version suffix,
+// to a language
+var interpreterLanguages = map[string]string{
+	"sh":         "bash",
+	"bash":       "bash",
+	"dash":       "bash",
+	"ash":        "bash",
+	"ksh":        "bash",
+	"zsh":        "zsh",
+	"fish":       "fish",
+	"python":     "python",
+	"pypy":       "python",
+	"node":       "javascript",
+	"nodejs":     "javascript",
+	"bun":        "javascript",
+	"deno":       "typescript",
+	"ts-node":    "typescript",
+	"tsx":        "typescript",
+	"ruby":       "ruby",
+	"perl":       "perl",
+	"php":        "php",
+	"lua":        "lua",
+	"luajit":     "lua",
+	"rscript":    "r",
+	"pwsh":       "powershell",
+	"powershell": "powershell",
+	"awk":        "awk",
+	"gawk":       "awk",
+	"mawk":       "awk",
+	"tclsh":      "tcl",
+	"wish":       "tcl",
+	"expect":     "tcl",
+	"groovy":     "groovy",
+	"make":       "makefile",
+	"osascript":  "applescript",
+	"julia":      "julia",
+	"elixir":     "elixir",
+	"escript":    "erlang",
+	"swift":      "swift",
+	"kotlin":     "kotlin",
+	"scala":      "scala",
+	"runghc":     "haskell",
+	"runhaskell": "haskell",
+	"guile":      "scheme",
+	"sbcl":       "lisp",
+	"go":         "go",
+}
+
+// modelineLanguages maps vim file types and Emacs modes that differ from
+// the code fence identifier
+var modelineLanguages = map[string]string{
+	"sh":             "bash",
+	"shell-script":   "bash",
+	"make":           "makefile",
+	"makefile-gmake": "makefile",
+	"js":             "javascript",
+	"cperl":          "perl",
+	"c++":            "cpp",
+	"emacs-lisp":     "lisp",
+	"terraform":      "hcl",
+	"ps1":            "powershell",
+	"conf":           "ini",
+	"dosini":         "ini",
+	"bzl":            "starlark",
+	"jenkinsfile":    "groovy",
+}
+
+var (
+	// vimModeline matches "vim: set ft=python:" and "vi: filetype=sh"
+	vimModeline = regexp.MustCompile(`(?:^|\s)(?:vim?|ex):.*?\b(?:ft|filetype|syn|syntax)=([\w+-]+)`)
+	// emacsModeline matches "-*- mode: python; coding: utf-8 -*-" and "-*- lisp -*-"
+	emacsModeline = regexp.MustCompile(`-\*-(.*?)-\*-`)
+	emacsMode     = regexp.MustCompile(`(?i)(?:^|;)\s*mode:\s*([\w+-]+)`)
+	// interpreterVersion is the version suffix of e.g. python3.12
+	interpreterVersion = regexp.MustCompile(`[\d.]+$`)
+)
+
+// detectTextLanguage returns the code fence identifier of a text file's
+// language. The extension wins, then what the file declares itself in a
+// shebang line or a vim or Emacs modeline, then file naming conventions,
+// and finally signatures at the start of the content. It returns "" when
+// none of them tells.
+func detectTextLanguage(name, ext string, head []byte) string {
+	if id := getLanguageIdentifier(ext); id != "" {
+		return id
+	}
+
+	head = bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))
+	if lang := declaredLanguage(head); lang != "" {
+		return lang
+	}
+	if lang := languageByFileName(name); lang != "" {
+		return lang
+	}
+	return languageBySignature(head)
+}
+
+// languageByFileName matches a file's base name against naming conventions
+func languageByFileName(name string) string {
+	base := strings.ToLower(path.Base(strings.ReplaceAll(name, "\\", "/")))
+	for _, convention := range fileNameLanguages {
+		if ok, _ := path.Match(convention.pattern, base); ok {
+			return convention.language
+		}
+	}
+	return ""
+}
+
+// declaredLanguage looks at the first lines of a file for a shebang or a
+// modeline. Vim also reads modelines at the end of a file, but only the head
+// is looked at.
+func declaredLanguage(head []byte) string {
+	lines := strings.Split(string(head), "\n")
+	if len(lines) > 5 {
+		lines = lines[:5]
+	}
+
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "#!") {
+		if lang := shebangLanguage(lines[0]); lang != "" {
+			return lang
+		}
+	}
+
+	for _, line := range lines {
+		if lang := modelineLanguage(line); lang != "" {
+			return lang
+		}
+	}
+
+	return ""
+}
+
+// languageBySignature recognizes markup by how it starts
+func languageBySignature(head []byte) string {
+	trimmed := strings.TrimSpace(string(head))
+	lower := strings.ToLower(trimmed[:min(len(trimmed), 64)])
+	switch {
+	case strings.HasPrefix(lower, "<?php"):
+		return "php"
+	case strings.HasPrefix(lower, "<!doctype html"), strings.HasPrefix(lower, "<html"):
+		return "html"
+	case strings.HasPrefix(lower, "<?xml"):
+		return "xml"
+	}
+	return ""
+}
+
+// shebangLanguage returns the language of the interpreter named in a
+// shebang line, looking through env and its options
+func shebangLanguage(line string) string {
+	fields := strings.Fields(strings.TrimPrefix(line, "#!"))
+	if len(fields) == 0 {
+		return ""
+	}
+
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, field := range fields[1:] {
+			// Skip options such as -S and variable assignments
+			if strings.HasPrefix(field, "-") || strings.Contains(field, "=") {
+				continue
+			}
+			interpreter = path.Base(field)
+			break
+		}
+	}
+
+	interpreter = strings.ToLower(interpreter)
+	if lang, ok := interpreterLanguages[interpreter]; ok {
+		return lang
+	}
+	return interpreterLanguages[interpreterVersion.ReplaceAllString(interpreter, "")]
+}
+
+// modelineLanguage returns the language set by a vim or Emacs modeline
+func modelineLanguage(line string) string {
+	var mode string
+	if m := vimModeline.FindStringSubmatch(line); m != nil {
+		mode = m[1]
+	} else if m := emacsModeline.FindStringSubmatch(line); m != nil {
+		vars := strings.TrimSpace(m[1])
+		if !strings.Contains(vars, ":") {
+			mode = vars
+		} else if m := emacsMode.FindStringSubmatch(vars); m != nil {
+			mode = m[1]
+		}
+	}
+
+	mode = strings.ToLower(mode)
+	if lang, ok := modelineLanguages[mode]; ok {
+		return lang
+	}
+	if _, ok := languageNames[mode]; ok {
+		return mode
+	}
+	return ""
+}
+
+// languageName returns the name shown in summaries for a code fence
+// identifier
+func languageName(id string) string {
+	if name, ok := languageNames[id]; ok {
+		return name
+	}
+	return id
+}
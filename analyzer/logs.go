@@ -0,0 +1,895 @@
+package analyzer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"local-agent/config"
+	"local-agent/types"
+)
+
+const (
+	// logSampleLines are read ahead to detect the log format
+	logSampleLines = 100
+	// maxLogLineBytes truncates longer lines
+	maxLogLineBytes = 64 * 1024
+	// maxLogTemplates bounds the distinct message templates kept per log
+	maxLogTemplates = 10000
+	// maxTemplateBytes and maxSampleBytes bound what is shown of a message
+	maxTemplateBytes = 300
+	maxSampleBytes   = 500
+	// maxSampleContinuation is how many continuation lines (e.g. of a stack
+	// trace) are kept with a sample
+	maxSampleContinuation = 5
+)
+
+// logFormat is the line format detected for a log file
+type logFormat int
+
+const (
+	logPlain logFormat = iota
+	logJSON
+	logLogfmt
+	logSyslog
+	logAccess
+)
+
+var logFormatNames = map[logFormat]string{
+	logPlain:  "plain text",
+	logJSON:   "JSON lines",
+	logLogfmt: "logfmt",
+	logSyslog: "syslog",
+	logAccess: "web access log (common/combined)",
+}
+
+// logLevelRanks orders the normalized levels, most severe first; "-" is
+// entries without a level
+var logLevelRanks = map[string]int{"FATAL": 0, "ERROR": 1, "WARN": 2, "INFO": 3, "DEBUG": 4, "TRACE": 5, "-": 6}
+
+// logLevelAliases maps level names found in logs to the normalized levels
+var logLevelAliases = map[string]string{
+	"fatal": "FATAL", "panic": "FATAL", "crit": "FATAL", "critical": "FATAL", "emerg": "FATAL", "emergency": "FATAL", "alert": "FATAL",
+	"error": "ERROR", "err": "ERROR", "severe": "ERROR",
+	"warn": "WARN", "warning": "WARN",
+	"info": "INFO", "notice": "INFO", "information": "INFO", "informational": "INFO",
+	"debug": "DEBUG", "dbug": "DEBUG", "fine": "DEBUG",
+	"trace": "TRACE", "finer": "TRACE", "finest": "TRACE", "verbose": "TRACE",
+}
+
+// Well-known keys of structured (JSON and logfmt) entries
+var (
+	logTimeKeys    = []string{"time", "timestamp", "ts", "@timestamp", "t", "date", "datetime", "asctime"}
+	logLevelKeys   = []string{"level", "lvl", "severity", "loglevel", "log.level", "levelname"}
+	logMessageKeys = []string{"msg", "message", "event", "log"}
+	logErrorKeys   = []string{"error", "err", "exception"}
+)
+
+var (
+	// Jan  2 15:04:05 host program[pid]: message (RFC 3164, optional <PRI>)
+	syslog3164Pattern = regexp.MustCompile(`^(?:<(\d{1,3})>)?([A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}) (\S+) ([^\s:\[]+)(?:\[\d+\])?: ?(.*)$`)
+	// <PRI>1 timestamp host app procid msgid [structured-data] message (RFC 5424)
+	syslog5424Pattern = regexp.MustCompile(`^<(\d{1,3})>1 (\S+) \S+ (\S+) \S+ \S+ (?:-|(?:\[[^\]]*\])+) ?(.*)$`)
+	// host ident user [time] "METHOD target protocol" status size ...
+	accessLogPattern = regexp.MustCompile(`^\S+ \S+ \S+ \[([^\]]+)\] "(\S+) (\S+)[^"]*" (\d{3}) (?:\d+|-)`)
+	// key=value or key="quoted value"
+	logfmtPairPattern = regexp.MustCompile(`([A-Za-z_][\w.\-]*)=("(?:[^"\\]|\\.)*"|[^\s"]*)`)
+	// A leading ISO-like timestamp, optionally in brackets
+	plainTimePattern = regexp.MustCompile(`^\[?(\d{4}[-/]\d{2}[-/]\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z| ?[+-]\d{2}:?\d{2})?)\]?\s*`)
+	// Double-quoted strings in messages
+	quotedPattern = regexp.MustCompile(`"[^"]*"`)
+)
+
+// logTimeLayouts are tried in order for timestamps; fractional seconds are
+// accepted after any seconds field
+var logTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05-0700",
+	"2006-01-02 15:04:05",
+	"2006/01/02 15:04:05",
+	"02/Jan/2006:15:04:05 -0700",
+	"Jan _2 15:04:05",
+}
+
+// logEntry is one parsed log entry
+type logEntry struct {
+	ts      time.Time
+	level   string // normalized, "" when unknown
+	message string
+	detail  string   // other fields of structured entries, for samples
+	extra   []string // first continuation lines, for samples
+}
+
+// logTemplateRecord counts the entries sharing a level and message template
+type logTemplateRecord struct {
+	level    string
+	template string
+	count    int
+	first    time.Time
+	last     time.Time
+	samples  []string
+	messages map[string]bool // sampled messages, to keep samples distinct
+}
+
+// logAnalysis accumulates statistics over the lines of a log
+type logAnalysis struct {
+	opts    config.LogConfig
+	modTime time.Time // dates syslog timestamps, which have no year
+
+	format      logFormat
+	timestamped bool // plain text lines start with a timestamp
+
+	lines        int
+	truncated    int // lines cut at maxLogLineBytes
+	entries      int
+	continuation int // lines attached to the entry above
+	unparsed     int // lines not in the detected format
+	untimed      int // entries without a timestamp
+	first        time.Time
+	last         time.Time
+
+	levels    map[string]int
+	templates map[string]*logTemplateRecord
+	overflow  int // entries beyond maxLogTemplates
+	timeline  *timeline
+
+	pending *logEntry
+}
+
+// ReadLogContent summarizes a log file instead of passing it on verbatim. It
+// detects the line format (JSON lines, logfmt, syslog, web access logs, or
+// plain text with a leading timestamp), parses timestamps and levels, and
+// clusters messages into templates whose variable parts (numbers, IDs,
+// quoted strings, key=value values) are replaced by <*>. The summary lists
+// error and warning templates with counts, first/last occurrence and sample
+// messages, the other templates, and a per-window timeline of entries by
+// level, each in a "## " section so large logs chunk by section. Lines are
+// streamed, so memory does not grow with the log. Logs small enough to be
+// read whole are also included verbatim.
+func (d *Detector) ReadLogContent(path string, opts config.LogConfig) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	text, _ := newTextReader(f)
+	reader := bufio.NewReaderSize(text, 64*1024)
+	analysis := newLogAnalysis(opts, stat.ModTime())
+
+	// Detect the format on the first lines, then process them with the rest
+	var sample []string
+	for len(sample) < logSampleLines {
+		line, truncated, err := readLogLine(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read log file: %w", err)
+		}
+		if truncated {
+			analysis.truncated++
+		}
+		sample = append(sample, line)
+	}
+	analysis.detect(sample)
+	for _, line := range sample {
+		analysis.addLine(line)
+	}
+	for {
+		line, truncated, err := readLogLine(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read log file: %w", err)
+		}
+		if truncated {
+			analysis.truncated++
+		}
+		analysis.addLine(line)
+	}
+	analysis.commit()
+
+	content := analysis.render()
+	if stat.Size() <= types.SmallFileSizeBytes {
+		raw, err := d.ReadContent(path, 0)
+		if err != nil {
+			return "", err
+		}
+		content += "\n\n## Full log\n\n```\n" + strings.TrimRight(raw, "\n") + "\n```"
+	}
+	return content, nil
+}
+
+func newLogAnalysis(opts config.LogConfig, modTime time.Time) *logAnalysis {
+	return &logAnalysis{
+		opts:      opts,
+		modTime:   modTime,
+		levels:    make(map[string]int),
+		templates: make(map[string]*logTemplateRecord),
+		timeline:  newLogTimeline(),
+	}
+}
+
+// readLogLine reads one line without its line ending, keeping at most
+// maxLogLineBytes of it
+func readLogLine(r *bufio.Reader) (string, bool, error) {
+	var line []byte
+	truncated := false
+	for {
+		chunk, isPrefix, err := r.ReadLine()
+		if err != nil {
+			if err == io.EOF && line != nil {
+				break
+			}
+			return "", false, err
+		}
+		if room := maxLogLineBytes - len(line); room < len(chunk) {
+			chunk = chunk[:max(room, 0)]
+			truncated = true
+		}
+		line = append(line, chunk...)
+		if !isPrefix {
+			break
+		}
+	}
+	return string(line), truncated, nil
+}
+
+// detect picks the format matched by at least half of the non-blank sample
+// lines, or plain text
+func (a *logAnalysis) detect(sample []string) {
+	counts := make(map[logFormat]int)
+	timed, total := 0, 0
+	for _, line := range sample {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		total++
+		switch {
+		case strings.HasPrefix(trimmed, "{") && json.Valid([]byte(trimmed)):
+			counts[logJSON]++
+		case accessLogPattern.MatchString(line):
+			counts[logAccess]++
+		case syslog3164Pattern.MatchString(line) || syslog5424Pattern.MatchString(line):
+			counts[logSyslog]++
+		case isLogfmtLine(line):
+			counts[logLogfmt]++
+		}
+		if plainTimePattern.MatchString(line) {
+			timed++
+		}
+	}
+
+	best, bestCount := logPlain, 0
+	for _, format := range []logFormat{logJSON, logAccess, logSyslog, logLogfmt} {
+		if counts[format] > bestCount {
+			best, bestCount = format, counts[format]
+		}
+	}
+	if total > 0 && bestCount*2 >= total {
+		a.format = best
+	}
+	a.timestamped = total > 0 && timed*2 >= total
+}
+
+// isLogfmtLine reports whether a line starts with a key=value pair and has
+// one of the well-known keys
+func isLogfmtLine(line string) bool {
+	pairs := logfmtPairPattern.FindAllStringSubmatchIndex(line, -1)
+	if len(pairs) < 2 || pairs[0][0] != 0 {
+		return false
+	}
+	for _, pair := range pairs {
+		key := strings.ToLower(line[pair[2]:pair[3]])
+		for _, keys := range [][]string{logTimeKeys, logLevelKeys, logMessageKeys} {
+			for _, known := range keys {
+				if key == known {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// addLine parses one line into a new entry, or attaches it to the pending
+// entry as a continuation (such as a stack trace line)
+func (a *logAnalysis) addLine(line string) {
+	a.lines++
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+
+	entry, ok := a.parse(line)
+	if !ok {
+		structured := a.format != logPlain || a.timestamped
+		if a.pending != nil && (structured || startsWithSpace(line)) {
+			a.continuation++
+			if len(a.pending.extra) < maxSampleContinuation {
+				a.pending.extra = append(a.pending.extra, truncateRunes(line, maxSampleBytes))
+			}
+			return
+		}
+		entry, _ = parsePlainLine(line)
+		if a.format != logPlain {
+			a.unparsed++
+		}
+	}
+
+	a.commit()
+	a.pending = &entry
+}
+
+// parse reads a line in the detected format
+func (a *logAnalysis) parse(line string) (logEntry, bool) {
+	switch a.format {
+	case logJSON:
+		return parseJSONLine(line)
+	case logLogfmt:
+		if !isLogfmtLine(line) {
+			return logEntry{}, false
+		}
+		return parseLogfmtLine(line), true
+	case logSyslog:
+		return a.parseSyslogLine(line)
+	case logAccess:
+		return parseAccessLine(line)
+	}
+
+	if startsWithSpace(line) {
+		return logEntry{}, false
+	}
+	entry, timed := parsePlainLine(line)
+	if a.timestamped && !timed {
+		return logEntry{}, false
+	}
+	return entry, true
+}
+
+// commit counts the pending entry
+func (a *logAnalysis) commit() {
+	if a.pending == nil {
+		return
+	}
+	entry := a.pending
+	a.pending = nil
+	a.entries++
+
+	level := entry.level
+	if level == "" {
+		level = "-"
+	}
+	a.levels[level]++
+
+	if entry.ts.IsZero() {
+		a.untimed++
+	} else {
+		if a.first.IsZero() || entry.ts.Before(a.first) {
+			a.first = entry.ts
+		}
+		if entry.ts.After(a.last) {
+			a.last = entry.ts
+		}
+		a.timeline.add(entry.ts, len(entry.message), level)
+	}
+
+	template := logTemplate(entry.message)
+	key := level + "\x00" + template
+	record, ok := a.templates[key]
+	if !ok {
+		if len(a.templates) >= maxLogTemplates {
+			a.overflow++
+			return
+		}
+		record = &logTemplateRecord{level: level, template: template, messages: make(map[string]bool)}
+		a.templates[key] = record
+	}
+	record.count++
+	if !entry.ts.IsZero() {
+		if record.first.IsZero() || entry.ts.Before(record.first) {
+			record.first = entry.ts
+		}
+		if entry.ts.After(record.last) {
+			record.last = entry.ts
+		}
+	}
+
+	// Samples are only shown for errors and warnings
+	if logLevelRanks[level] <= logLevelRanks["WARN"] && len(record.samples) < a.opts.Samples && !record.messages[entry.message] {
+		record.messages[entry.message] = true
+		sample := entry.message
+		if entry.detail != "" {
+			sample += " " + entry.detail
+		}
+		sample = truncateRunes(sample, maxSampleBytes)
+		if len(entry.extra) > 0 {
+			sample += "\n" + strings.Join(entry.extra, "\n")
+		}
+		record.samples = append(record.samples, sample)
+	}
+}
+
+// parseJSONLine reads a JSON object entry
+func parseJSONLine(line string) (logEntry, bool) {
+	var object map[string]any
+	if err := json.Unmarshal([]byte(strings.TrimSpace(line)), &object); err != nil {
+		return logEntry{}, false
+	}
+
+	fields := make(map[string]string, len(object))
+	for key, value := range object {
+		switch v := value.(type) {
+		case string:
+			fields[key] = v
+		case float64:
+			fields[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			fields[key] = strconv.FormatBool(v)
+		case nil:
+			fields[key] = ""
+		default:
+			encoded, _ := json.Marshal(v)
+			fields[key] = string(encoded)
+		}
+	}
+	return structuredEntry(fields), true
+}
+
+// parseLogfmtLine reads a key=value entry
+func parseLogfmtLine(line string) logEntry {
+	fields := make(map[string]string)
+	for _, match := range logfmtPairPattern.FindAllStringSubmatch(line, -1) {
+		value := match[2]
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		fields[match[1]] = value
+	}
+	return structuredEntry(fields)
+}
+
+// structuredEntry takes the time, level and message from well-known keys.
+// Without a message key the remaining fields become the message, so entries
+// with the same keys share a template; otherwise they are kept as the detail
+// shown in samples.
+func structuredEntry(fields map[string]string) logEntry {
+	var entry logEntry
+	used := make(map[string]bool)
+	lookup := func(keys []string) (string, bool) {
+		for _, key := range keys {
+			for field, value := range fields {
+				if strings.EqualFold(field, key) {
+					used[field] = true
+					return value, true
+				}
+			}
+		}
+		return "", false
+	}
+
+	if value, ok := lookup(logTimeKeys); ok {
+		entry.ts, _ = parseLogTime(value, time.Time{})
+	}
+	if value, ok := lookup(logLevelKeys); ok {
+		entry.level = normalizeLogLevel(value)
+	}
+	message, hasMessage := lookup(logMessageKeys)
+	if errText, ok := lookup(logErrorKeys); ok && hasMessage && errText != "" {
+		message += ": " + errText
+	}
+	var keys []string
+	for key := range fields {
+		if !used[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+fields[key])
+	}
+	if hasMessage {
+		entry.detail = strings.Join(pairs, " ")
+	} else {
+		message = strings.Join(pairs, " ")
+	}
+	entry.message = strings.TrimSpace(message)
+	return entry
+}
+
+// parseSyslogLine reads an RFC 3164 or RFC 5424 entry. The program name is
+// kept in the message so templates are per program.
+func (a *logAnalysis) parseSyslogLine(line string) (logEntry, bool) {
+	var entry logEntry
+	var pri string
+	if match := syslog5424Pattern.FindStringSubmatch(line); match != nil {
+		pri = match[1]
+		entry.ts, _ = parseLogTime(match[2], a.modTime)
+		entry.message = match[3] + ": " + match[4]
+	} else if match := syslog3164Pattern.FindStringSubmatch(line); match != nil {
+		pri = match[1]
+		entry.ts, _ = parseLogTime(match[2], a.modTime)
+		entry.message = match[4] + ": " + match[5]
+	} else {
+		return logEntry{}, false
+	}
+
+	if value, err := strconv.Atoi(pri); err == nil {
+		entry.level = syslogSeverityLevel(value % 8)
+	} else {
+		_, entry.level, _ = splitLogLevel(strings.TrimSpace(entry.message[strings.Index(entry.message, ":")+1:]))
+	}
+	return entry, true
+}
+
+// syslogSeverityLevel maps a syslog severity (0 emergency to 7 debug)
+func syslogSeverityLevel(severity int) string {
+	switch {
+	case severity <= 2:
+		return "FATAL"
+	case severity == 3:
+		return "ERROR"
+	case severity == 4:
+		return "WARN"
+	case severity <= 6:
+		return "INFO"
+	default:
+		return "DEBUG"
+	}
+}
+
+// parseAccessLine reads a common or combined log format entry. The level
+// follows the status code, and the message is the request without its
+// query string.
+func parseAccessLine(line string) (logEntry, bool) {
+	match := accessLogPattern.FindStringSubmatch(line)
+	if match == nil {
+		return logEntry{}, false
+	}
+
+	var entry logEntry
+	entry.ts, _ = parseLogTime(match[1], time.Time{})
+	target, _, _ := strings.Cut(match[3], "?")
+	entry.message = match[2] + " " + target + " " + match[4]
+	switch status := match[4]; {
+	case status >= "500":
+		entry.level = "ERROR"
+	case status >= "400":
+		entry.level = "WARN"
+	default:
+		entry.level = "INFO"
+	}
+	return entry, true
+}
+
+// parsePlainLine reads a free-form line: an optional leading timestamp, then
+// a level among the first words. It reports whether a timestamp was found.
+func parsePlainLine(line string) (logEntry, bool) {
+	var entry logEntry
+	rest := line
+	timed := false
+	if match := plainTimePattern.FindStringSubmatch(line); match != nil {
+		if ts, ok := parseLogTime(match[1], time.Time{}); ok {
+			entry.ts = ts
+			timed = true
+		}
+		rest = line[len(match[0]):]
+	}
+	entry.message, entry.level, _ = splitLogLevel(rest)
+	entry.message = strings.TrimSpace(entry.message)
+	return entry, timed
+}
+
+// splitLogLevel looks for a level among the first three words of text, as
+// in "[main] ERROR c.Foo - message" or "ERROR:root:message". It returns the
+// text after the level, or all of it when there is none.
+func splitLogLevel(text string) (string, string, bool) {
+	rest := text
+	for i := 0; i < 3; i++ {
+		rest = strings.TrimLeft(rest, " \t")
+		end := strings.IndexAny(rest, " \t")
+		if end < 0 {
+			end = len(rest)
+		}
+		word := rest[:end]
+		candidate := strings.Trim(word, "[]()<>|,-")
+		candidate, after, hasColon := strings.Cut(candidate, ":")
+		if level := normalizeLogLevel(candidate); level != "" {
+			message := rest[end:]
+			if hasColon && after != "" {
+				message = after + message
+			}
+			return strings.TrimLeft(message, " \t:-|"), level, true
+		}
+		if end == len(rest) {
+			break
+		}
+		rest = rest[end:]
+	}
+	return text, "", false
+}
+
+// normalizeLogLevel maps a level name or a numeric (pino/bunyan) level to
+// FATAL, ERROR, WARN, INFO, DEBUG or TRACE, or "" when unknown
+func normalizeLogLevel(value string) string {
+	if level, ok := logLevelAliases[strings.ToLower(strings.TrimSpace(value))]; ok {
+		return level
+	}
+	if number, err := strconv.Atoi(value); err == nil {
+		switch {
+		case number >= 60:
+			return "FATAL"
+		case number >= 50:
+			return "ERROR"
+		case number >= 40:
+			return "WARN"
+		case number >= 30:
+			return "INFO"
+		case number >= 20:
+			return "DEBUG"
+		case number >= 10:
+			return "TRACE"
+		}
+	}
+	return ""
+}
+
+// parseLogTime parses the timestamp formats of logTimeLayouts and Unix
+// epochs in seconds, milliseconds, microseconds or nanoseconds. Timestamps
+// without a year (syslog) take it from ref, the file's modification time.
+func parseLogTime(value string, ref time.Time) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+	if epoch, err := strconv.ParseFloat(value, 64); err == nil {
+		switch {
+		case epoch > 1e17:
+			return time.Unix(0, int64(epoch)).UTC(), true
+		case epoch > 1e14:
+			return time.UnixMicro(int64(epoch)).UTC(), true
+		case epoch > 1e11:
+			return time.UnixMilli(int64(epoch)).UTC(), true
+		case epoch > 1e8:
+			return time.Unix(0, int64(epoch*1e9)).UTC(), true
+		}
+		return time.Time{}, false
+	}
+
+	// Python and Java write fractional seconds after a comma
+	if idx := strings.LastIndexByte(value, ','); idx > 0 && idx+1 < len(value) && value[idx+1] >= '0' && value[idx+1] <= '9' {
+		value = value[:idx] + "." + value[idx+1:]
+	}
+	for _, layout := range logTimeLayouts {
+		ts, err := time.Parse(layout, value)
+		if err != nil {
+			continue
+		}
+		if ts.Year() == 0 {
+			if ref.IsZero() {
+				ref = time.Now()
+			}
+			ts = ts.AddDate(ref.Year(), 0, 0)
+			if ts.After(ref.Add(24 * time.Hour)) {
+				ts = ts.AddDate(-1, 0, 0)
+			}
+		}
+		return ts, true
+	}
+	return time.Time{}, false
+}
+
+// logTemplate replaces the variable parts of a message with <*>: quoted
+// strings, words containing digits (numbers, IDs, addresses, durations),
+// path segments containing digits and the values of key=value pairs
+func logTemplate(message string) string {
+	message = quotedPattern.ReplaceAllString(message, `"<*>"`)
+
+	var words []string
+	for _, word := range strings.Fields(message) {
+		word = templateWord(word)
+		if word == "<*>" && len(words) > 0 && words[len(words)-1] == "<*>" {
+			continue
+		}
+		words = append(words, word)
+	}
+	return truncateRunes(strings.Join(words, " "), maxTemplateBytes)
+}
+
+// templateWord masks one word of a message
+func templateWord(word string) string {
+	if key, value, ok := strings.Cut(word, "="); ok && key != "" && value != "" && isLogKey(key) {
+		return key + "=<*>"
+	}
+
+	core := strings.TrimRight(word, ",;:.)]}'\"")
+	suffix := word[len(core):]
+	trimmed := strings.TrimLeft(core, "([{'\"")
+	prefix := core[:len(core)-len(trimmed)]
+	core = trimmed
+	if !strings.ContainsFunc(core, unicode.IsDigit) {
+		return word
+	}
+
+	// Keep the route of paths and URLs, e.g. /api/users/<*>/orders
+	if strings.Contains(core, "/") && !strings.HasPrefix(core, "<") {
+		segments := strings.Split(core, "/")
+		for i, segment := range segments {
+			if strings.ContainsFunc(segment, unicode.IsDigit) {
+				segments[i] = "<*>"
+			}
+		}
+		return prefix + strings.Join(segments, "/") + suffix
+	}
+	return prefix + "<*>" + suffix
+}
+
+// isLogKey reports whether s looks like the key of a key=value pair
+func isLogKey(s string) bool {
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.' && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+func startsWithSpace(line string) bool {
+	return line != "" && (line[0] == ' ' || line[0] == '\t')
+}
+
+// truncateRunes shortens s to at most n bytes without splitting a character
+func truncateRunes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	cut := n
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
+
+// sortedTemplates returns the templates at or above WARN (issues) or below
+// it, ordered by severity then count
+func (a *logAnalysis) sortedTemplates(issues bool) []*logTemplateRecord {
+	var list []*logTemplateRecord
+	for _, record := range a.templates {
+		if (logLevelRanks[record.level] <= logLevelRanks["WARN"]) == issues {
+			list = append(list, record)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if issues && list[i].level != list[j].level {
+			return logLevelRanks[list[i].level] < logLevelRanks[list[j].level]
+		}
+		if list[i].count != list[j].count {
+			return list[i].count > list[j].count
+		}
+		return list[i].template < list[j].template
+	})
+	return list
+}
+
+// render writes the summary as Markdown sections
+func (a *logAnalysis) render() string {
+	var builder strings.Builder
+	builder.WriteString("## Summary\n\n")
+	builder.WriteString(fmt.Sprintf("- Format: %s\n", logFormatNames[a.format]))
+	builder.WriteString(fmt.Sprintf("- Lines: %d\n", a.lines))
+	builder.WriteString(fmt.Sprintf("- Entries: %d\n", a.entries))
+	if a.continuation > 0 {
+		builder.WriteString(fmt.Sprintf("- Continuation lines (e.g. stack traces) attached to the entry above: %d\n", a.continuation))
+	}
+	if a.unparsed > 0 {
+		builder.WriteString(fmt.Sprintf("- Lines not in %s format, read as plain text: %d\n", logFormatNames[a.format], a.unparsed))
+	}
+	if a.truncated > 0 {
+		builder.WriteString(fmt.Sprintf("- Lines cut at %s: %d\n", formatFileSize(maxLogLineBytes), a.truncated))
+	}
+	if !a.first.IsZero() {
+		builder.WriteString(fmt.Sprintf("- First entry: %s\n", a.first.UTC().Format(time.RFC3339Nano)))
+		builder.WriteString(fmt.Sprintf("- Last entry: %s\n", a.last.UTC().Format(time.RFC3339Nano)))
+		builder.WriteString(fmt.Sprintf("- Duration: %s\n", formatFlowDuration(a.last.Sub(a.first))))
+	}
+	if a.untimed > 0 {
+		builder.WriteString(fmt.Sprintf("- Entries without a timestamp: %d\n", a.untimed))
+	}
+	if len(a.levels) > 0 {
+		levels := make([]string, 0, len(a.levels))
+		for level := range a.levels {
+			levels = append(levels, level)
+		}
+		sort.Slice(levels, func(i, j int) bool { return logLevelRanks[levels[i]] < logLevelRanks[levels[j]] })
+		var counts []string
+		for _, level := range levels {
+			counts = append(counts, fmt.Sprintf("%s %d", level, a.levels[level]))
+		}
+		builder.WriteString(fmt.Sprintf("- Levels: %s\n", strings.Join(counts, ", ")))
+	}
+	builder.WriteString(fmt.Sprintf("- Message templates: %d (variable parts shown as <*>)\n", len(a.templates)))
+	if a.overflow > 0 {
+		builder.WriteString(fmt.Sprintf("- Entries beyond the limit of %d templates: %d\n", maxLogTemplates, a.overflow))
+	}
+
+	span := a.last.Sub(a.first)
+	issues := a.sortedTemplates(true)
+	if len(issues) > 0 {
+		listed := issues[:min(len(issues), a.opts.MaxTemplates)]
+		builder.WriteString("\n## Errors and warnings\n\n")
+		if len(issues) > len(listed) {
+			builder.WriteString(fmt.Sprintf("Top %d of %d templates, most severe first, then by count.\n", len(listed), len(issues)))
+		} else {
+			builder.WriteString("Templates, most severe first, then by count.\n")
+		}
+		for i, record := range listed {
+			builder.WriteString(fmt.Sprintf("\n### %d. %s ×%d: %s\n", i+1, record.level, record.count, record.template))
+			if !record.first.IsZero() {
+				seen := fmt.Sprintf("First %s, last %s", formatRecordTime(record.first), formatRecordTime(record.last))
+				if rate := formatLogRate(record.count, span); rate != "" {
+					seen += fmt.Sprintf(", about %s over the log", rate)
+				}
+				builder.WriteString("\n" + seen + ".\n")
+			}
+			for _, sample := range record.samples {
+				builder.WriteString("\n```\n" + sample + "\n```\n")
+			}
+		}
+	}
+
+	others := a.sortedTemplates(false)
+	if len(others) > 0 {
+		listed := others[:min(len(others), a.opts.MaxTemplates)]
+		builder.WriteString("\n## Other templates\n\n")
+		if len(others) > len(listed) {
+			builder.WriteString(fmt.Sprintf("Top %d of %d templates below WARN, by count.\n\n", len(listed), len(others)))
+		}
+		builder.WriteString("| Level | Count | First seen | Last seen | Template |\n")
+		builder.WriteString("|---|---|---|---|---|\n")
+		for _, record := range listed {
+			builder.WriteString(fmt.Sprintf("| %s | %d | %s | %s | %s |\n",
+				record.level, record.count, formatRecordTime(record.first), formatRecordTime(record.last), escapeTableCell(record.template)))
+		}
+	}
+
+	a.timeline.render(&builder)
+
+	return strings.TrimSpace(builder.String())
+}
+
+// formatLogRate expresses count over span per minute, hour or day
+func formatLogRate(count int, span time.Duration) string {
+	if count < 2 || span < time.Minute {
+		return ""
+	}
+	perMinute := float64(count) / span.Minutes()
+	switch {
+	case perMinute >= 1:
+		return fmt.Sprintf("%.1f per minute", perMinute)
+	case perMinute*60 >= 1:
+		return fmt.Sprintf("%.1f per hour", perMinute*60)
+	default:
+		return fmt.Sprintf("%.1f per day", perMinute*60*24)
+	}
+}
@@ -0,0 +1,396 @@
+package analyzer
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"local-agent/config"
+	"local-agent/types"
+)
+
+// MinifyStat reports how many tokens minification saved on a file
+type MinifyStat struct {
+	RelPath        string
+	OriginalTokens int
+	MinifiedTokens int
+}
+
+// String describes the saving, e.g. "Minified main.go: 1200 → 800 tokens
+// (saved 400, 33%)"
+func (s MinifyStat) String() string {
+	saved := s.OriginalTokens - s.MinifiedTokens
+	percent := 0
+	if s.OriginalTokens > 0 {
+		percent = saved * 100 / s.OriginalTokens
+	}
+	return fmt.Sprintf("Minified %s: %d → %d tokens (saved %d, %d%%)", s.RelPath, s.OriginalTokens, s.MinifiedTokens, saved, percent)
+}
+
+// commentSyntax describes the comments of a language
+type commentSyntax struct {
+	line       []string // prefixes of line comments
+	blockStart string
+	blockEnd   string
+}
+
+var (
+	cStyleComments = commentSyntax{line: []string{"//"}, blockStart: "/*", blockEnd: "*/"}
+	hashComments   = commentSyntax{line: []string{"#"}}
+	markupComments = commentSyntax{blockStart: "<!--", blockEnd: "-->"}
+)
+
+// languageComments maps code fence identifiers to their comment syntax.
+// Comments are left alone in languages missing here, e.g. plain text.
+var languageComments = map[string]commentSyntax{
+	"go":         cStyleComments,
+	"javascript": cStyleComments,
+	"typescript": cStyleComments,
+	"java":       cStyleComments,
+	"c":          cStyleComments,
+	"cpp":        cStyleComments,
+	"rust":       cStyleComments,
+	"swift":      cStyleComments,
+	"kotlin":     cStyleComments,
+	"scala":      cStyleComments,
+	"groovy":     cStyleComments,
+	"php":        {line: []string{"//", "#"}, blockStart: "/*", blockEnd: "*/"},
+	"hcl":        {line: []string{"#", "//"}, blockStart: "/*", blockEnd: "*/"},
+	"python":     hashComments,
+	"ruby":       hashComments,
+	"bash":       hashComments,
+	"zsh":        hashComments,
+	"fish":       hashComments,
+	"perl":       hashComments,
+	"yaml":       hashComments,
+	"toml":       hashComments,
+	"dockerfile": hashComments,
+	"makefile":   hashComments,
+	"r":          hashComments,
+	"elixir":     hashComments,
+	"starlark":   hashComments,
+	"cmake":      hashComments,
+	"nginx":      hashComments,
+	"just":       hashComments,
+	"awk":        hashComments,
+	"tcl":        hashComments,
+	"julia":      hashComments,
+	"powershell": {line: []string{"#"}, blockStart: "<#", blockEnd: "#>"},
+	"ini":        {line: []string{";", "#"}},
+	"sql":        {line: []string{"--"}, blockStart: "/*", blockEnd: "*/"},
+	"lua":        {line: []string{"--"}, blockStart: "--[[", blockEnd: "]]"},
+	"haskell":    {line: []string{"--"}, blockStart: "{-", blockEnd: "-}"},
+	"lisp":       {line: []string{";"}},
+	"scheme":     {line: []string{";"}},
+	"erlang":     {line: []string{"%"}},
+	"vim":        {line: []string{`"`}},
+	"html":       markupComments,
+	"xml":        markupComments,
+	"markdown":   markupComments,
+}
+
+var (
+	// licensePattern tells a license banner from other leading comments
+	licensePattern = regexp.MustCompile(`(?i)copyright|\blicen[cs]ed?\b|spdx-license-identifier|all rights reserved|permission is hereby granted`)
+	spdxPattern    = regexp.MustCompile(`SPDX-License-Identifier:\s*([\w.+() -]+?)\s*(?:\*/|-->|$)`)
+	// generatedFilePattern marks a whole file as generated: Go's convention
+	// and the @generated and <auto-generated> markers of other tools
+	generatedFilePattern = regexp.MustCompile(`^\s*(?://|#)\s*Code generated .* DO NOT EDIT\.?\s*$|@generated\b|<auto-generated`)
+	// generatedBeginPattern and generatedEndPattern delimit generated sections
+	generatedBeginPattern = regexp.MustCompile(`(?i)\b(?:begin|start)\s+(?:of\s+)?(?:auto-?)?generated\b`)
+	generatedEndPattern   = regexp.MustCompile(`(?i)\bend\s+(?:of\s+)?(?:auto-?)?generated\b`)
+	// stringLiteralPattern matches double-quoted and backquoted strings on one line
+	stringLiteralPattern = regexp.MustCompile("\"(?:[^\"\\\\]|\\\\.)*\"|`[^`]*`")
+)
+
+// minifyLine is a line of minified content. A note stands in for a block of
+// removed lines.
+type minifyLine struct {
+	number int
+	text   string
+	note   bool
+}
+
+// minifyText applies the configured passes to content whose first line is
+// line startLine of its file. When lines are removed, every remaining line is
+// prefixed with its original line number and removed blocks are replaced by
+// a note, so citations still point at the right lines; numbered reports
+// that. changed is false when no pass changed anything.
+func minifyText(content, language string, startLine int, opts config.MinifyConfig) (result string, numbered, changed bool) {
+	raw := strings.Split(content, "\n")
+	lines := make([]minifyLine, len(raw))
+	for i, text := range raw {
+		lines[i] = minifyLine{number: startLine + i, text: text}
+	}
+
+	syntax, hasComments := languageComments[language]
+	if opts.ElideGenerated {
+		lines = elideGenerated(lines)
+	}
+	if opts.StripLicenses && startLine == 1 {
+		if !hasComments {
+			syntax = commentSyntax{line: []string{"//", "#"}, blockStart: "/*", blockEnd: "*/"}
+		}
+		lines = stripLicense(lines, syntax)
+	}
+	if opts.DropComments && hasComments {
+		lines = dropComments(lines, syntax)
+	}
+	if opts.MaxStringLength > 0 && language != "" && language != "markdown" {
+		for i := range lines {
+			if !lines[i].note {
+				lines[i].text = shortenStrings(lines[i].text, opts.MaxStringLength)
+			}
+		}
+	}
+	if opts.CollapseWhitespace {
+		kept := lines[:0]
+		for _, line := range lines {
+			line.text = strings.TrimRight(line.text, " \t\r")
+			if line.text != "" || line.note {
+				kept = append(kept, line)
+			}
+		}
+		lines = kept
+	}
+
+	removed := len(lines) < len(raw)
+	for _, line := range lines {
+		removed = removed || line.note
+	}
+
+	var builder strings.Builder
+	if !removed {
+		for i, line := range lines {
+			if i > 0 {
+				builder.WriteString("\n")
+			}
+			builder.WriteString(line.text)
+		}
+	} else {
+		width := len(strconv.Itoa(startLine + len(raw) - 1))
+		for i, line := range lines {
+			if i > 0 {
+				builder.WriteString("\n")
+			}
+			if line.note {
+				builder.WriteString(fmt.Sprintf("%*s  … %s", width, "", line.text))
+			} else {
+				builder.WriteString(fmt.Sprintf("%*d  %s", width, line.number, line.text))
+			}
+		}
+	}
+
+	result = builder.String()
+	return result, removed, result != content
+}
+
+// removedNote describes the lines first to last that were removed
+func removedNote(first, last int, what string) minifyLine {
+	lines := fmt.Sprintf("line %d", first)
+	if last > first {
+		lines = fmt.Sprintf("lines %d-%d", first, last)
+	}
+	return minifyLine{number: first, text: fmt.Sprintf("%s: %s removed", lines, what), note: true}
+}
+
+// elideGenerated replaces generated code by a note. A file-level marker
+// elides everything after it; BEGIN/END GENERATED markers elide the lines
+// between them.
+func elideGenerated(lines []minifyLine) []minifyLine {
+	for i := 0; i < len(lines) && i < 20; i++ {
+		if generatedFilePattern.MatchString(lines[i].text) {
+			if i+1 >= len(lines) {
+				return lines
+			}
+			kept := append([]minifyLine(nil), lines[:i+1]...)
+			return append(kept, removedNote(lines[i+1].number, lines[len(lines)-1].number, "generated code"))
+		}
+	}
+
+	var kept []minifyLine
+	for i := 0; i < len(lines); i++ {
+		kept = append(kept, lines[i])
+		if !generatedBeginPattern.MatchString(lines[i].text) {
+			continue
+		}
+		end := i + 1
+		for end < len(lines) && !generatedEndPattern.MatchString(lines[end].text) {
+			end++
+		}
+		if end == len(lines) || end == i+1 {
+			continue
+		}
+		kept = append(kept, removedNote(lines[i+1].number, lines[end-1].number, "generated code"))
+		i = end - 1
+	}
+	return kept
+}
+
+// stripLicense replaces the first comment block of a file by a note when it
+// is a license banner. A shebang line and blank lines before it are kept.
+func stripLicense(lines []minifyLine, syntax commentSyntax) []minifyLine {
+	start := 0
+	if len(lines) > 0 && strings.HasPrefix(lines[0].text, "#!") {
+		start = 1
+	}
+	for start < len(lines) && strings.TrimSpace(lines[start].text) == "" {
+		start++
+	}
+	if start == len(lines) {
+		return lines
+	}
+
+	end := start
+	first := strings.TrimSpace(lines[start].text)
+	switch {
+	case syntax.blockStart != "" && strings.HasPrefix(first, syntax.blockStart):
+		for end < len(lines) && !strings.Contains(blockRest(lines, start, end, syntax), syntax.blockEnd) {
+			end++
+		}
+		if end == len(lines) {
+			return lines
+		}
+		end++
+	case isLineComment(first, syntax):
+		for end < len(lines) && isLineComment(strings.TrimSpace(lines[end].text), syntax) {
+			end++
+		}
+	default:
+		return lines
+	}
+
+	var banner strings.Builder
+	for _, line := range lines[start:end] {
+		banner.WriteString(line.text + "\n")
+	}
+	if !licensePattern.MatchString(banner.String()) {
+		return lines
+	}
+
+	what := "license header"
+	if m := spdxPattern.FindStringSubmatch(banner.String()); m != nil {
+		what = fmt.Sprintf("license header (%s)", m[1])
+	}
+
+	kept := append([]minifyLine(nil), lines[:start]...)
+	kept = append(kept, removedNote(lines[start].number, lines[end-1].number, what))
+	return append(kept, lines[end:]...)
+}
+
+// blockRest returns the text of line end that may close a block comment
+// opened on line start
+func blockRest(lines []minifyLine, start, end int, syntax commentSyntax) string {
+	text := lines[end].text
+	if end == start {
+		_, text, _ = strings.Cut(text, syntax.blockStart)
+	}
+	return text
+}
+
+// isLineComment reports whether a trimmed line is a line comment
+func isLineComment(trimmed string, syntax commentSyntax) bool {
+	for _, prefix := range syntax.line {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// dropComments removes comment-only lines and block comments that span
+// whole lines. A shebang line is kept, and so are lines that mix code and
+// comments.
+func dropComments(lines []minifyLine, syntax commentSyntax) []minifyLine {
+	var kept []minifyLine
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line.text)
+		if line.note || (i == 0 && strings.HasPrefix(trimmed, "#!")) {
+			kept = append(kept, line)
+			continue
+		}
+
+		if syntax.blockStart != "" && strings.HasPrefix(trimmed, syntax.blockStart) {
+			end := i
+			for end < len(lines) && !strings.Contains(blockRest(lines, i, end, syntax), syntax.blockEnd) {
+				end++
+			}
+			if end < len(lines) {
+				// Only drop the block when nothing follows its end
+				_, after, _ := strings.Cut(blockRest(lines, i, end, syntax), syntax.blockEnd)
+				if strings.TrimSpace(after) == "" {
+					i = end
+					continue
+				}
+			}
+		}
+
+		if trimmed != "" && isLineComment(trimmed, syntax) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return kept
+}
+
+// shortenStrings cuts string literals longer than max characters, noting
+// how many characters were dropped
+func shortenStrings(line string, limit int) string {
+	return stringLiteralPattern.ReplaceAllStringFunc(line, func(literal string) string {
+		quote := literal[:1]
+		inner := literal[1 : len(literal)-1]
+		length := utf8.RuneCountInString(inner)
+		if length <= limit {
+			return literal
+		}
+		runes := []rune(inner)
+		// Keep escape sequences whole
+		cut := string(runes[:limit])
+		if strings.HasSuffix(cut, `\`) && !strings.HasSuffix(cut, `\\`) {
+			cut = cut[:len(cut)-1]
+		}
+		return fmt.Sprintf("%s%s…[+%d chars]%s", quote, cut, length-utf8.RuneCountInString(cut), quote)
+	})
+}
+
+// minifyLanguage returns the language that decides how a file is minified
+func minifyLanguage(file *types.FileInfo) string {
+	if file.Language != "" {
+		return file.Language
+	}
+	return getLanguageIdentifier(file.Extension)
+}
+
+// minifyContent minifies text whose first line is line startLine of file,
+// reporting whether its lines are numbered. ok is false when minification is
+// off, does not apply to the file or changes nothing.
+func (a *Analyzer) minifyContent(file *types.FileInfo, content string, startLine int) (minified string, numbered, ok bool) {
+	if !a.config.Minify.Enabled() || file.Type != types.TypeText || content == "" {
+		return "", false, false
+	}
+	return minifyText(content, minifyLanguage(file), startLine, a.config.Minify)
+}
+
+// MinifyReport lists the tokens minification saves on each file that it
+// shrinks, in the order of files
+func (a *Analyzer) MinifyReport(files []*types.FileInfo) []MinifyStat {
+	var stats []MinifyStat
+	for _, file := range files {
+		if file == nil || file.IsSensitive {
+			continue
+		}
+		minified, _, ok := a.minifyContent(file, file.Content, 1)
+		if !ok {
+			continue
+		}
+		stats = append(stats, MinifyStat{
+			RelPath:        file.RelPath,
+			OriginalTokens: file.TokenCount,
+			MinifiedTokens: a.tokenizer.EstimateTokensSimple(minified),
+		})
+	}
+	return stats
+}
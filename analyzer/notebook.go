@@ -0,0 +1,288 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Limits on the cell outputs kept in the extracted notebook
+const (
+	maxNotebookOutputLines = 20
+	maxNotebookOutputBytes = 2000
+)
+
+var (
+	// ansiEscapePattern matches the color codes of error tracebacks
+	ansiEscapePattern = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+	// dataURIPattern matches images inlined into markdown cells
+	dataURIPattern = regexp.MustCompile(`data:[\w/+.-]+;base64,[A-Za-z0-9+/=]+`)
+	// markdownHeadingPattern matches ATX headings, which are demoted below
+	// the cell sections
+	markdownHeadingPattern = regexp.MustCompile(`^ {0,3}#{1,6}(\s|$)`)
+)
+
+// kernelLanguages maps kernel names to languages for notebooks whose
+// metadata does not name the language
+var kernelLanguages = map[string]string{
+	"python": "python", "ir": "r", "julia": "julia", "bash": "bash",
+	"javascript": "javascript", "typescript": "typescript", "scala": "scala",
+	"spylon": "scala", "sparkmagic": "python", "ruby": "ruby", "go": "go",
+	"gophernotes": "go", "rust": "rust", "evcxr": "rust", "java": "java",
+	"ijava": "java", "kotlin": "kotlin", "sql": "sql", "xsql": "sql",
+	"xcpp": "cpp", "powershell": "powershell", "matlab": "matlab", "octave": "octave",
+}
+
+// notebookText is a notebook string field, stored either as one string or
+// as a list of lines
+type notebookText string
+
+func (t *notebookText) UnmarshalJSON(data []byte) error {
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*t = notebookText(strings.Join(lines, ""))
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	*t = notebookText(text)
+	return nil
+}
+
+// notebookOutput is one output of a code cell
+type notebookOutput struct {
+	OutputType string                     `json:"output_type"`
+	Name       string                     `json:"name"` // stream: stdout or stderr
+	Text       notebookText               `json:"text"`
+	Data       map[string]json.RawMessage `json:"data"`
+	EName      string                     `json:"ename"`
+	EValue     string                     `json:"evalue"`
+	Traceback  []string                   `json:"traceback"`
+}
+
+// notebookCell is one cell of an nbformat 4 notebook
+type notebookCell struct {
+	CellType       string           `json:"cell_type"`
+	Source         notebookText     `json:"source"`
+	ExecutionCount *int             `json:"execution_count"`
+	Outputs        []notebookOutput `json:"outputs"`
+}
+
+// notebookFile is the part of an nbformat 4 notebook that is extracted
+type notebookFile struct {
+	NBFormat int `json:"nbformat"`
+	Metadata struct {
+		KernelSpec struct {
+			Name        string `json:"name"`
+			DisplayName string `json:"display_name"`
+			Language    string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+	Cells []notebookCell `json:"cells"`
+}
+
+// ReadNotebookContent renders a Jupyter notebook as Markdown, one
+// "## Cell N" section per cell in notebook order. Code is fenced in the
+// kernel language, text outputs are truncated and binary outputs (images,
+// HTML, widgets) are replaced by a note. It also returns the kernel and
+// cell counts as metadata.
+func (d *Detector) ReadNotebookContent(path string) (string, map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read notebook: %w", err)
+	}
+
+	var notebook notebookFile
+	if err := json.Unmarshal(data, &notebook); err != nil {
+		return "", nil, fmt.Errorf("failed to parse notebook: %w", err)
+	}
+	if notebook.NBFormat < 4 {
+		return "", nil, fmt.Errorf("unsupported notebook format %d; convert it with \"jupyter nbconvert --to notebook\"", notebook.NBFormat)
+	}
+
+	language := notebookLanguage(&notebook)
+	metadata := make(map[string]string)
+	if name := notebook.Metadata.KernelSpec.DisplayName; name != "" {
+		metadata["Kernel"] = name
+	} else if name := notebook.Metadata.KernelSpec.Name; name != "" {
+		metadata["Kernel"] = name
+	}
+	if language != "" {
+		metadata["Kernel language"] = strings.TrimSpace(language + " " + notebook.Metadata.LanguageInfo.Version)
+	}
+
+	counts := make(map[string]int)
+	var builder strings.Builder
+	for i, cell := range notebook.Cells {
+		counts[cell.CellType]++
+		source := strings.TrimRight(string(cell.Source), "\n")
+		if strings.TrimSpace(source) == "" && len(cell.Outputs) == 0 {
+			continue
+		}
+
+		heading := fmt.Sprintf("## Cell %d (%s)", i+1, cell.CellType)
+		if cell.CellType == "code" && cell.ExecutionCount != nil {
+			heading = fmt.Sprintf("## Cell %d (code, In [%d])", i+1, *cell.ExecutionCount)
+		}
+		builder.WriteString(heading + "\n\n")
+
+		switch cell.CellType {
+		case "markdown":
+			builder.WriteString(renderMarkdownCell(source) + "\n\n")
+		case "code":
+			if strings.TrimSpace(source) != "" {
+				builder.WriteString(fenceBlock(source, language) + "\n\n")
+			}
+			for _, output := range cell.Outputs {
+				if text := renderNotebookOutput(output); text != "" {
+					builder.WriteString(text + "\n\n")
+				}
+			}
+		default:
+			builder.WriteString(fenceBlock(source, "") + "\n\n")
+		}
+	}
+
+	metadata["Cells"] = formatCellCounts(len(notebook.Cells), counts)
+	return strings.TrimSpace(builder.String()), metadata, nil
+}
+
+// notebookLanguage returns the fence language of the notebook's code cells
+func notebookLanguage(notebook *notebookFile) string {
+	if name := notebook.Metadata.LanguageInfo.Name; name != "" {
+		return strings.ToLower(name)
+	}
+	if language := notebook.Metadata.KernelSpec.Language; language != "" {
+		return strings.ToLower(language)
+	}
+	// Kernel names carry versions, e.g. python3 or julia-1.10
+	name := strings.ToLower(notebook.Metadata.KernelSpec.Name)
+	name = strings.TrimRight(strings.SplitN(name, "-", 2)[0], "0123456789.")
+	return kernelLanguages[name]
+}
+
+// renderMarkdownCell demotes headings below the cell section, so chunks
+// keep their cell number, and drops inlined images
+func renderMarkdownCell(source string) string {
+	lines := strings.Split(source, "\n")
+	fenced := fencedLines(lines)
+	for i, line := range lines {
+		if !fenced[i] && markdownHeadingPattern.MatchString(line) {
+			lines[i] = "##" + strings.TrimLeft(line, " ")
+		}
+	}
+	return dataURIPattern.ReplaceAllString(strings.Join(lines, "\n"), "data:(inline image omitted)")
+}
+
+// renderNotebookOutput renders a code cell output, or returns "" when the
+// output has nothing to show
+func renderNotebookOutput(output notebookOutput) string {
+	switch output.OutputType {
+	case "stream":
+		label := "Output"
+		if output.Name == "stderr" {
+			label = "Output (stderr)"
+		}
+		text := truncateOutput(string(output.Text), false)
+		if strings.TrimSpace(text) == "" {
+			return ""
+		}
+		return label + ":\n\n" + fenceBlock(text, "")
+	case "error":
+		// The end of a traceback names the failing line
+		text := ansiEscapePattern.ReplaceAllString(strings.Join(output.Traceback, "\n"), "")
+		if strings.TrimSpace(text) == "" {
+			text = output.EName + ": " + output.EValue
+		}
+		return fmt.Sprintf("Error: %s: %s\n\n%s", output.EName, output.EValue, fenceBlock(truncateOutput(text, true), ""))
+	case "execute_result", "display_data":
+		for _, mime := range []string{"text/plain", "text/markdown", "application/json"} {
+			raw, ok := output.Data[mime]
+			if !ok {
+				continue
+			}
+			var text notebookText
+			if mime == "application/json" {
+				text = notebookText(raw)
+			} else if err := json.Unmarshal(raw, &text); err != nil {
+				continue
+			}
+			return "Result:\n\n" + fenceBlock(truncateOutput(string(text), false), "")
+		}
+		var mimes []string
+		for mime := range output.Data {
+			mimes = append(mimes, mime)
+		}
+		if len(mimes) == 0 {
+			return ""
+		}
+		sort.Strings(mimes)
+		return fmt.Sprintf("[%s output omitted]", strings.Join(mimes, ", "))
+	}
+	return ""
+}
+
+// truncateOutput keeps the first (or, fromEnd, the last) lines of an output
+// within maxNotebookOutputLines and maxNotebookOutputBytes
+func truncateOutput(text string, fromEnd bool) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	total := len(lines)
+	if fromEnd {
+		for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+			lines[i], lines[j] = lines[j], lines[i]
+		}
+	}
+
+	var kept []string
+	size := 0
+	for _, line := range lines {
+		if len(kept) == maxNotebookOutputLines || size+len(line) > maxNotebookOutputBytes {
+			break
+		}
+		kept = append(kept, line)
+		size += len(line) + 1
+	}
+	if len(kept) == 0 {
+		kept = append(kept, truncateRunes(lines[0], maxNotebookOutputBytes))
+	}
+
+	omitted := total - len(kept)
+	if fromEnd {
+		for i, j := 0, len(kept)-1; i < j; i, j = i+1, j-1 {
+			kept[i], kept[j] = kept[j], kept[i]
+		}
+		if omitted > 0 {
+			kept = append([]string{fmt.Sprintf("... (%d earlier lines omitted)", omitted)}, kept...)
+		}
+	} else if omitted > 0 {
+		kept = append(kept, fmt.Sprintf("... (%d more lines omitted)", omitted))
+	}
+	return strings.Join(kept, "\n")
+}
+
+// formatCellCounts summarizes the cells by type, e.g. "12 (8 code, 4 markdown)"
+func formatCellCounts(total int, counts map[string]int) string {
+	var kinds []string
+	for kind := range counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	var parts []string
+	for _, kind := range kinds {
+		parts = append(parts, strconv.Itoa(counts[kind])+" "+kind)
+	}
+	if len(parts) == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%d (%s)", total, strings.Join(parts, ", "))
+}
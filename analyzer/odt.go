@@ -0,0 +1,180 @@
+package analyzer
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ReadODTContent extracts headings, paragraphs, lists and tables from an
+// OpenDocument text file as Markdown. Heading levels are shifted down by one
+// so that top-level headings start "## " sections the chunker can cite.
+func (d *Detector) ReadODTContent(filePath string) (string, error) {
+	reader, err := zip.OpenReader(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open ODT: %w", err)
+	}
+	defer reader.Close()
+
+	var content *zip.File
+	for _, file := range reader.File {
+		if file.Name == "content.xml" {
+			content = file
+			break
+		}
+	}
+	if content == nil {
+		return "", fmt.Errorf("no content.xml found in ODT")
+	}
+
+	rc, err := content.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open ODT content: %w", err)
+	}
+	defer rc.Close()
+
+	text, err := parseODTContent(rc)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse ODT content: %w", err)
+	}
+	if text == "" {
+		return "", fmt.Errorf("no text content extracted from ODT")
+	}
+	return text, nil
+}
+
+// parseODTContent streams the office:text body of content.xml
+func parseODTContent(r io.Reader) (string, error) {
+	var lines []string
+	var paragraph strings.Builder
+	var cells []string
+	prefix := ""
+	depth := 0     // nesting of text:p/text:h (notes contain paragraphs)
+	listDepth := 0 // nesting of text:list
+	tableRows := 0 // rows emitted for the current table
+	inCell := false
+	inBody := false
+
+	decoder := xml.NewDecoder(r)
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "text":
+				if t.Name.Space == "urn:oasis:names:tc:opendocument:xmlns:office:1.0" {
+					inBody = true
+				}
+			case "h":
+				if depth == 0 {
+					level := 1
+					for _, a := range t.Attr {
+						if a.Name.Local == "outline-level" {
+							if n, err := strconv.Atoi(a.Value); err == nil && n > 0 {
+								level = n
+							}
+						}
+					}
+					prefix = strings.Repeat("#", min(level+1, 6)) + " "
+				}
+				depth++
+			case "p":
+				if depth == 0 {
+					prefix = ""
+					if listDepth > 0 {
+						prefix = strings.Repeat("  ", listDepth-1) + "- "
+					}
+				} else {
+					paragraph.WriteString(" ")
+				}
+				depth++
+			case "list":
+				listDepth++
+			case "table":
+				tableRows = 0
+			case "table-cell", "covered-table-cell":
+				inCell = true
+				paragraph.Reset()
+			case "s":
+				count := 1
+				for _, a := range t.Attr {
+					if a.Name.Local == "c" {
+						if n, err := strconv.Atoi(a.Value); err == nil && n > 0 {
+							count = n
+						}
+					}
+				}
+				paragraph.WriteString(strings.Repeat(" ", count))
+			case "tab", "line-break":
+				paragraph.WriteString(" ")
+			case "note-citation":
+				// Skip the footnote number; the note body follows inline
+				if err := decoder.Skip(); err != nil {
+					return "", err
+				}
+			}
+
+		case xml.CharData:
+			if inBody && depth > 0 {
+				paragraph.Write(t)
+			}
+
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "text":
+				if t.Name.Space == "urn:oasis:names:tc:opendocument:xmlns:office:1.0" {
+					inBody = false
+				}
+			case "h", "p":
+				depth--
+				if depth > 0 {
+					paragraph.WriteString(" ")
+					continue
+				}
+				if inCell {
+					paragraph.WriteString(" ")
+					continue
+				}
+				text := strings.TrimSpace(paragraph.String())
+				paragraph.Reset()
+				if text == "" {
+					continue
+				}
+				// Blank line between blocks, but not between list items
+				isItem := strings.HasSuffix(prefix, "- ")
+				if !isItem || len(lines) == 0 || !strings.HasPrefix(strings.TrimLeft(lines[len(lines)-1], " "), "- ") {
+					lines = append(lines, "")
+				}
+				lines = append(lines, prefix+text)
+			case "list":
+				listDepth--
+			case "table-cell", "covered-table-cell":
+				inCell = false
+				cells = append(cells, escapeTableCell(paragraph.String()))
+				paragraph.Reset()
+			case "table-row":
+				if tableRows == 0 {
+					lines = append(lines, "")
+				}
+				lines = append(lines, "| "+strings.Join(cells, " | ")+" |")
+				if tableRows == 0 {
+					lines = append(lines, "|"+strings.Repeat("---|", len(cells)))
+				}
+				tableRows++
+				cells = nil
+			}
+		}
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n")), nil
+}
@@ -0,0 +1,700 @@
+package analyzer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"local-agent/config"
+	"local-agent/types"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/google/gopacket/reassembly"
+)
+
+const (
+	// maxTrackedFlows bounds the conversations, and the distinct keys of
+	// other per-capture tables, kept in memory
+	maxTrackedFlows = 50000
+	// pcapFlushInterval is how often (in packets) idle TCP streams are flushed
+	pcapFlushInterval = 10000
+	// pcapStreamTimeout closes TCP streams idle for longer than this
+	pcapStreamTimeout = 2 * time.Minute
+	// defaultPCAPMaxFlows is used when the configured table size is not positive
+	defaultPCAPMaxFlows = 50
+)
+
+// pcapngMagic is the block type of a PCAPNG section header
+var pcapngMagic = []byte{0x0A, 0x0D, 0x0D, 0x0A}
+
+// ReadPCAPContent extracts a traffic summary from a PCAP/PCAPNG file:
+// capture statistics, heuristic indicators, protocols, a per-window
+// timeline, top talkers and a conversation table of 5-tuple flows built from
+// reassembled TCP streams. When opts.StreamPreviewBytes is positive the first
+// bytes of each listed TCP stream are included. Each part is a "## " section
+// so large captures can be chunked by section, and the timeline by time
+// range. The indicators (port scans, beaconing, DNS tunneling, cleartext
+// credentials, unusual ports) are also returned as findings.
+//
+// Packets are streamed and aggregated into bounded tables, so memory does not
+// grow with the capture. opts.From and opts.To restrict the analysis to a
+// time range and opts.Filter to the packets matching a filter expression
+// (see PCAPFilter); everything is computed from the remaining packets.
+func (d *Detector) ReadPCAPContent(path string, opts config.PCAPConfig) (string, []types.Finding, error) {
+	analysis, err := analyzePCAP(path, opts)
+	if err != nil {
+		return "", nil, err
+	}
+	findings := analysis.findings()
+	return analysis.render(findings), findings, nil
+}
+
+// analyzePCAP streams the packets of a capture within the configured time
+// range and filter into a pcapAnalysis
+func analyzePCAP(path string, opts config.PCAPConfig) (*pcapAnalysis, error) {
+	from, to, err := opts.TimeRange()
+	if err != nil {
+		return nil, err
+	}
+	filter, err := ParsePCAPFilter(opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PCAP file: %w", err)
+	}
+	defer f.Close()
+
+	// Sniff the format rather than trusting the extension
+	reader := bufio.NewReader(f)
+	magic, _ := reader.Peek(4)
+
+	var packetSource *gopacket.PacketSource
+	if bytes.Equal(magic, pcapngMagic) {
+		ngReader, err := pcapgo.NewNgReader(reader, pcapgo.DefaultNgReaderOptions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create PCAPNG reader: %w", err)
+		}
+		packetSource = gopacket.NewPacketSource(ngReader, ngReader.LinkType())
+	} else {
+		pcapReader, err := pcapgo.NewReader(reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create PCAP reader: %w", err)
+		}
+		packetSource = gopacket.NewPacketSource(pcapReader, pcapReader.LinkType())
+	}
+
+	analysis := newPCAPAnalysis(opts)
+	analysis.from, analysis.to, analysis.filter = from, to, filter
+	for packet := range packetSource.Packets() {
+		ts := packet.Metadata().Timestamp
+		if !from.IsZero() && ts.Before(from) || !to.IsZero() && ts.After(to) {
+			analysis.outside++
+			// Tolerate slightly out-of-order captures before giving up on the rest
+			if !to.IsZero() && ts.After(to.Add(pcapStreamTimeout)) {
+				analysis.stopped = true
+				break
+			}
+			continue
+		}
+		if !filter.matches(packet) {
+			analysis.unmatched++
+			continue
+		}
+		analysis.addPacket(packet)
+	}
+	analysis.finish()
+	return analysis, nil
+}
+
+// pcapFlowKey identifies a conversation regardless of direction
+type pcapFlowKey struct {
+	proto string
+	a, b  string // endpoints in sorted order
+}
+
+// pcapFlow aggregates one conversation. Index 0 of the per-direction
+// counters is client to server, index 1 server to client.
+type pcapFlow struct {
+	id      int
+	proto   string
+	client  string
+	server  string
+	packets [2]int
+	bytes   [2]int64
+	payload [2]int64 // reassembled TCP payload bytes
+	preview [2][]byte
+	first   time.Time
+	last    time.Time
+	flags   uint8 // TCP flags seen, see tcpFlagNames
+}
+
+// pcapAnalysis accumulates statistics over the packets of a capture
+type pcapAnalysis struct {
+	opts config.PCAPConfig
+
+	packets int
+	bytes   int64
+	first   time.Time
+	last    time.Time
+
+	// Time range from the configuration; zero times are open
+	from    time.Time
+	to      time.Time
+	outside int  // packets skipped for falling outside the range
+	stopped bool // reading stopped once packets were well past the range
+
+	filter    *PCAPFilter // nil matches every packet
+	unmatched int         // packets in the range rejected by the filter
+
+	timeline *timeline
+
+	protocols map[string]int
+	srcIPs    map[string]int
+	dstIPs    map[string]int
+	srcPorts  map[string]int
+	dstPorts  map[string]int
+
+	flows     map[pcapFlowKey]*pcapFlow
+	untracked int // packets of conversations beyond maxTrackedFlows
+	assembler *reassembly.Assembler
+
+	// Application-layer records, keyed for deduplication
+	dns  map[string]*dnsRecord
+	http map[string]*httpRecord
+	tls  map[string]*tlsRecord
+
+	// Indicator state, see pcapindicators.go
+	probes    map[string]*scanRecord
+	beacons   map[string]*beaconRecord
+	tunnels   map[string]*tunnelRecord
+	cleartext map[string]*cleartextRecord
+}
+
+func newPCAPAnalysis(opts config.PCAPConfig) *pcapAnalysis {
+	if opts.MaxFlows <= 0 {
+		opts.MaxFlows = defaultPCAPMaxFlows
+	}
+
+	analysis := &pcapAnalysis{
+		opts:      opts,
+		protocols: make(map[string]int),
+		srcIPs:    make(map[string]int),
+		dstIPs:    make(map[string]int),
+		srcPorts:  make(map[string]int),
+		dstPorts:  make(map[string]int),
+		timeline:  newPCAPTimeline(),
+		flows:     make(map[pcapFlowKey]*pcapFlow),
+		dns:       make(map[string]*dnsRecord),
+		http:      make(map[string]*httpRecord),
+		tls:       make(map[string]*tlsRecord),
+		probes:    make(map[string]*scanRecord),
+		beacons:   make(map[string]*beaconRecord),
+		tunnels:   make(map[string]*tunnelRecord),
+		cleartext: make(map[string]*cleartextRecord),
+	}
+	analysis.assembler = reassembly.NewAssembler(reassembly.NewStreamPool(&pcapStreamFactory{analysis: analysis}))
+	return analysis
+}
+
+// addPacket updates the statistics with one packet
+func (p *pcapAnalysis) addPacket(packet gopacket.Packet) {
+	meta := packet.Metadata()
+	p.packets++
+	p.bytes += int64(meta.Length)
+	if p.packets == 1 {
+		p.first = meta.Timestamp
+	}
+	p.last = meta.Timestamp
+
+	var srcIP, dstIP string
+	if ipLayer := packet.Layer(layers.LayerTypeIPv4); ipLayer != nil {
+		ip, _ := ipLayer.(*layers.IPv4)
+		srcIP, dstIP = ip.SrcIP.String(), ip.DstIP.String()
+		p.protocols["IPv4"]++
+	} else if ipLayer := packet.Layer(layers.LayerTypeIPv6); ipLayer != nil {
+		ip, _ := ipLayer.(*layers.IPv6)
+		srcIP, dstIP = ip.SrcIP.String(), ip.DstIP.String()
+		p.protocols["IPv6"]++
+	}
+	if srcIP != "" {
+		countBounded(p.srcIPs, srcIP)
+		countBounded(p.dstIPs, dstIP)
+	}
+
+	var tcp *layers.TCP
+	proto := ""
+	srcPort, dstPort := "", ""
+	switch t := packet.TransportLayer().(type) {
+	case *layers.TCP:
+		tcp = t
+		proto = "TCP"
+		srcPort, dstPort = fmt.Sprintf("%d", t.SrcPort), fmt.Sprintf("%d", t.DstPort)
+	case *layers.UDP:
+		proto = "UDP"
+		srcPort, dstPort = fmt.Sprintf("%d", t.SrcPort), fmt.Sprintf("%d", t.DstPort)
+	}
+	if proto == "" {
+		if packet.Layer(layers.LayerTypeICMPv4) != nil {
+			proto = "ICMP"
+		} else if packet.Layer(layers.LayerTypeICMPv6) != nil {
+			proto = "ICMPv6"
+		}
+	}
+	if proto != "" {
+		p.protocols[proto]++
+	}
+	if srcPort != "" {
+		p.srcPorts[srcPort]++
+		p.dstPorts[dstPort]++
+	}
+
+	// Application protocols
+	appProto := ""
+	if packet.ApplicationLayer() != nil {
+		if dnsLayer := packet.Layer(layers.LayerTypeDNS); dnsLayer != nil {
+			appProto = "DNS"
+			if srcIP != "" {
+				p.addDNS(dnsLayer.(*layers.DNS), srcIP, dstIP, meta.Timestamp)
+			}
+		} else if packet.Layer(layers.LayerTypeTLS) != nil {
+			appProto = "TLS"
+		} else if tcp != nil && (tcp.DstPort == 80 || tcp.SrcPort == 80 || tcp.DstPort == 8080 || tcp.SrcPort == 8080) {
+			appProto = "HTTP"
+		}
+	}
+	if appProto != "" {
+		p.protocols[appProto]++
+	}
+
+	// The timeline counts each packet under its highest decoded protocol
+	switch {
+	case appProto != "":
+		p.timeline.add(meta.Timestamp, meta.Length, appProto)
+	case proto != "":
+		p.timeline.add(meta.Timestamp, meta.Length, proto)
+	default:
+		p.timeline.add(meta.Timestamp, meta.Length, "Other")
+	}
+
+	if srcIP == "" || proto == "" {
+		return
+	}
+
+	src, dst := srcIP, dstIP
+	if srcPort != "" {
+		src, dst = joinHostPort(srcIP, srcPort), joinHostPort(dstIP, dstPort)
+	}
+	flow := p.flow(proto, src, dst, tcp)
+	if flow != nil {
+		dir := 0
+		if src != flow.client {
+			dir = 1
+		}
+		flow.packets[dir]++
+		flow.bytes[dir] += int64(meta.Length)
+		flow.last = meta.Timestamp
+		if tcp != nil {
+			flow.flags |= tcpFlagBits(tcp)
+		}
+	}
+
+	// Connection attempts, for port scan detection
+	if tcp != nil && tcp.SYN && !tcp.ACK || proto == "UDP" && flow != nil && src == flow.client {
+		p.addProbe(srcIP, dstIP, portNumber(dst), meta.Timestamp)
+	}
+
+	if tcp != nil {
+		p.assembler.AssembleWithContext(packet.NetworkLayer().NetworkFlow(), tcp, &pcapCaptureContext{meta.CaptureInfo})
+	}
+	if p.packets%pcapFlushInterval == 0 {
+		p.assembler.FlushCloseOlderThan(p.last.Add(-pcapStreamTimeout))
+	}
+}
+
+// flow returns the conversation for a packet, creating it on first sight.
+// The client is the sender of a bare SYN, or the receiver of a SYN-ACK;
+// otherwise the side with the higher port, or the first sender.
+func (p *pcapAnalysis) flow(proto, src, dst string, tcp *layers.TCP) *pcapFlow {
+	key := pcapFlowKey{proto: proto, a: src, b: dst}
+	if key.b < key.a {
+		key.a, key.b = key.b, key.a
+	}
+	if flow, ok := p.flows[key]; ok {
+		return flow
+	}
+	if len(p.flows) >= maxTrackedFlows {
+		p.untracked++
+		return nil
+	}
+
+	client, server := src, dst
+	switch {
+	case tcp != nil && tcp.SYN && !tcp.ACK:
+	case tcp != nil && tcp.SYN && tcp.ACK:
+		client, server = dst, src
+	case tcp != nil || proto == "UDP":
+		if portNumber(src) < portNumber(dst) && portNumber(src) < 1024 {
+			client, server = dst, src
+		}
+	}
+
+	flow := &pcapFlow{proto: proto, client: client, server: server}
+	flow.first = p.last
+	p.flows[key] = flow
+	p.addConnection(proto, client, server, flow.first)
+	return flow
+}
+
+// finish flushes the remaining TCP streams
+func (p *pcapAnalysis) finish() {
+	p.assembler.FlushAll()
+}
+
+// sortedFlows returns the flows ordered by total bytes, numbered from 1
+func (p *pcapAnalysis) sortedFlows() []*pcapFlow {
+	flows := make([]*pcapFlow, 0, len(p.flows))
+	for _, flow := range p.flows {
+		flows = append(flows, flow)
+	}
+	sort.Slice(flows, func(i, j int) bool {
+		bi, bj := flows[i].bytes[0]+flows[i].bytes[1], flows[j].bytes[0]+flows[j].bytes[1]
+		if bi != bj {
+			return bi > bj
+		}
+		return flows[i].first.Before(flows[j].first)
+	})
+	for i, flow := range flows {
+		flow.id = i + 1
+	}
+	return flows
+}
+
+// render writes the analysis and its findings as Markdown sections
+func (p *pcapAnalysis) render(findings []types.Finding) string {
+	var builder strings.Builder
+	builder.WriteString("## Summary\n\n")
+	if !p.from.IsZero() || !p.to.IsZero() {
+		window := fmt.Sprintf("- Time window: %s to %s", formatWindowBound(p.from, "capture start"), formatWindowBound(p.to, "capture end"))
+		if p.stopped {
+			window += fmt.Sprintf(" (%d packets outside skipped; reading stopped after the window)", p.outside)
+		} else {
+			window += fmt.Sprintf(" (%d packets outside skipped)", p.outside)
+		}
+		builder.WriteString(window + "\n")
+	}
+	if p.filter != nil {
+		builder.WriteString(fmt.Sprintf("- Filter: %s (%d packets did not match)\n", p.filter, p.unmatched))
+	}
+	builder.WriteString(fmt.Sprintf("- Total Packets: %d\n", p.packets))
+	builder.WriteString(fmt.Sprintf("- Total Bytes: %s\n", formatFileSize(p.bytes)))
+	if p.packets > 0 {
+		builder.WriteString(fmt.Sprintf("- First Packet: %s\n", p.first.UTC().Format(time.RFC3339Nano)))
+		builder.WriteString(fmt.Sprintf("- Last Packet: %s\n", p.last.UTC().Format(time.RFC3339Nano)))
+		builder.WriteString(fmt.Sprintf("- Duration: %s\n", formatFlowDuration(p.last.Sub(p.first))))
+	}
+	builder.WriteString(fmt.Sprintf("- Conversations: %d\n", len(p.flows)))
+	if p.untracked > 0 {
+		builder.WriteString(fmt.Sprintf("- Packets outside tracked conversations: %d (limit of %d conversations reached)\n", p.untracked, maxTrackedFlows))
+	}
+
+	renderIndicators(&builder, findings)
+
+	builder.WriteString("\n## Protocols\n\n")
+	for _, entry := range sortedCounts(p.protocols, 0) {
+		percentage := float64(entry.value) / float64(p.packets) * 100
+		builder.WriteString(fmt.Sprintf("- %s: %d packets (%.2f%%)\n", entry.key, entry.value, percentage))
+	}
+
+	p.timeline.render(&builder)
+
+	builder.WriteString("\n## Top talkers\n")
+	topCount := 5
+	for _, list := range []struct {
+		title  string
+		counts map[string]int
+		prefix string
+	}{
+		{"Top Source IPs", p.srcIPs, ""},
+		{"Top Destination IPs", p.dstIPs, ""},
+		{"Top Source Ports", p.srcPorts, "Port "},
+		{"Top Destination Ports", p.dstPorts, "Port "},
+	} {
+		builder.WriteString(fmt.Sprintf("\n%s:\n", list.title))
+		for _, entry := range sortedCounts(list.counts, topCount) {
+			builder.WriteString(fmt.Sprintf("- %s%s: %d packets\n", list.prefix, entry.key, entry.value))
+		}
+	}
+
+	flows := p.sortedFlows()
+	listed := flows[:min(len(flows), p.opts.MaxFlows)]
+	if len(listed) > 0 {
+		builder.WriteString("\n## Conversations\n\n")
+		if len(flows) > len(listed) {
+			builder.WriteString(fmt.Sprintf("Top %d of %d conversations by bytes.\n\n", len(listed), len(flows)))
+		}
+		builder.WriteString("| Flow | Proto | Client | Server | Packets (c→s/s→c) | Bytes (c→s/s→c) | Start | Duration | TCP flags |\n")
+		builder.WriteString("|---|---|---|---|---|---|---|---|---|\n")
+		for _, flow := range listed {
+			builder.WriteString(fmt.Sprintf("| %d | %s | %s | %s | %d/%d | %d/%d | %s | %s | %s |\n",
+				flow.id, flow.proto, flow.client, flow.server,
+				flow.packets[0], flow.packets[1], flow.bytes[0], flow.bytes[1],
+				flow.first.UTC().Format("2006-01-02 15:04:05.000"),
+				formatFlowDuration(flow.last.Sub(flow.first)), flow.flagString()))
+		}
+	}
+
+	p.renderApplicationLayer(&builder)
+
+	if p.opts.StreamPreviewBytes > 0 {
+		for _, flow := range listed {
+			if len(flow.preview[0]) == 0 && len(flow.preview[1]) == 0 {
+				continue
+			}
+			builder.WriteString(fmt.Sprintf("\n## Flow %d: %s %s -> %s\n", flow.id, flow.proto, flow.client, flow.server))
+			for dir, label := range []string{"Client to server", "Server to client"} {
+				if len(flow.preview[dir]) == 0 {
+					continue
+				}
+				builder.WriteString(fmt.Sprintf("\n%s (first %d of %d bytes):\n```\n%s\n```\n",
+					label, len(flow.preview[dir]), flow.payload[dir], printablePayload(flow.preview[dir])))
+			}
+		}
+	}
+
+	return strings.TrimSpace(builder.String())
+}
+
+// tcpFlagNames are the TCP flags reported per flow, in display order
+var tcpFlagNames = []string{"SYN", "ACK", "PSH", "URG", "FIN", "RST"}
+
+// tcpFlagBits returns the flags of a segment as a bit set over tcpFlagNames
+func tcpFlagBits(tcp *layers.TCP) uint8 {
+	var bits uint8
+	for i, set := range []bool{tcp.SYN, tcp.ACK, tcp.PSH, tcp.URG, tcp.FIN, tcp.RST} {
+		if set {
+			bits |= 1 << i
+		}
+	}
+	return bits
+}
+
+// flagString lists the TCP flags seen on a flow
+func (f *pcapFlow) flagString() string {
+	var names []string
+	for i, name := range tcpFlagNames {
+		if f.flags&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "-"
+	}
+	return strings.Join(names, " ")
+}
+
+// formatWindowBound formats one end of the configured time range
+func formatWindowBound(t time.Time, open string) string {
+	if t.IsZero() {
+		return open
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// countBounded increments m[key], ignoring new keys once m holds
+// maxTrackedFlows keys so that memory stays bounded
+func countBounded(m map[string]int, key string) {
+	if _, ok := m[key]; ok || len(m) < maxTrackedFlows {
+		m[key]++
+	}
+}
+
+// countEntry is one key of a counter map
+type countEntry struct {
+	key   string
+	value int
+}
+
+// sortedCounts returns the entries of m by descending count (ties by key),
+// limited to n entries when n is positive
+func sortedCounts(m map[string]int, n int) []countEntry {
+	entries := make([]countEntry, 0, len(m))
+	for k, v := range m {
+		entries = append(entries, countEntry{k, v})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].value != entries[j].value {
+			return entries[i].value > entries[j].value
+		}
+		return entries[i].key < entries[j].key
+	})
+	if n > 0 && len(entries) > n {
+		entries = entries[:n]
+	}
+	return entries
+}
+
+// joinHostPort formats an endpoint, bracketing IPv6 addresses
+func joinHostPort(ip, port string) string {
+	if strings.Contains(ip, ":") {
+		return "[" + ip + "]:" + port
+	}
+	return ip + ":" + port
+}
+
+// portNumber returns the port of an "ip:port" endpoint, or 0
+func portNumber(endpoint string) int {
+	idx := strings.LastIndexByte(endpoint, ':')
+	if idx < 0 {
+		return 0
+	}
+	port, _ := strconv.Atoi(endpoint[idx+1:])
+	return port
+}
+
+// formatFlowDuration rounds a duration for display
+func formatFlowDuration(d time.Duration) string {
+	switch {
+	case d <= 0:
+		return "0s"
+	case d < time.Second:
+		return d.Round(time.Microsecond).String()
+	default:
+		return d.Round(time.Millisecond).String()
+	}
+}
+
+// printablePayload renders payload bytes as text, keeping line breaks and
+// replacing other control and non-ASCII bytes with '.'
+func printablePayload(data []byte) string {
+	out := make([]byte, 0, len(data))
+	for i, b := range data {
+		switch {
+		case b == '\r' && i+1 < len(data) && data[i+1] == '\n':
+			// CRLF becomes a single line break
+		case b == '\n' || b == '\r':
+			out = append(out, '\n')
+		case b == '\t' || (b >= 32 && b < 127):
+			out = append(out, b)
+		default:
+			out = append(out, '.')
+		}
+	}
+	return strings.TrimRight(string(out), "\n")
+}
+
+// pcapCaptureContext passes capture info to the reassembler
+type pcapCaptureContext struct {
+	ci gopacket.CaptureInfo
+}
+
+func (c *pcapCaptureContext) GetCaptureInfo() gopacket.CaptureInfo {
+	return c.ci
+}
+
+// pcapStreamFactory attaches reassembled TCP streams to their flows
+type pcapStreamFactory struct {
+	analysis *pcapAnalysis
+}
+
+func (f *pcapStreamFactory) New(netFlow, tcpFlow gopacket.Flow, tcp *layers.TCP, ac reassembly.AssemblerContext) reassembly.Stream {
+	src := joinHostPort(netFlow.Src().String(), tcpFlow.Src().String())
+	dst := joinHostPort(netFlow.Dst().String(), tcpFlow.Dst().String())
+	key := pcapFlowKey{proto: "TCP", a: src, b: dst}
+	if key.b < key.a {
+		key.a, key.b = key.b, key.a
+	}
+	flow := f.analysis.flows[key]
+
+	stream := &pcapStream{analysis: f.analysis, flow: flow, ends: [2]string{src, dst}}
+	if flow != nil && flow.client != src {
+		stream.reversed = true
+		stream.ends = [2]string{dst, src}
+	}
+	return stream
+}
+
+// pcapStream records the payload size and first bytes of each direction and
+// decodes HTTP and TLS from the start of the stream. Index 0 is the flow's
+// client to server direction.
+type pcapStream struct {
+	analysis *pcapAnalysis
+	flow     *pcapFlow
+	ends     [2]string // client and server endpoints
+	reversed bool      // the reassembler's client is the flow's server
+	buffers  [2]*streamBuffer
+}
+
+func (s *pcapStream) Accept(tcp *layers.TCP, ci gopacket.CaptureInfo, dir reassembly.TCPFlowDirection, nextSeq reassembly.Sequence, start *bool, ac reassembly.AssemblerContext) bool {
+	// Captures often start mid-connection; accept data without a SYN
+	*start = true
+	return true
+}
+
+func (s *pcapStream) ReassembledSG(sg reassembly.ScatterGather, ac reassembly.AssemblerContext) {
+	length, _ := sg.Lengths()
+	if length == 0 {
+		return
+	}
+
+	dir, _, _, _ := sg.Info()
+	idx := 0
+	if (dir == reassembly.TCPDirServerToClient) != s.reversed {
+		idx = 1
+	}
+
+	previewBytes := s.analysis.opts.StreamPreviewBytes
+	var data []byte
+	buffer := s.buffers[idx]
+	if buffer == nil || buffer.kind != "" && !buffer.full || s.flow != nil && len(s.flow.preview[idx]) < previewBytes {
+		data = sg.Fetch(min(length, max(maxAppScanBytes, previewBytes)))
+	}
+
+	if buffer == nil {
+		kind := classifyStream(data)
+		if _, ok := cleartextServices[portNumber(s.ends[1])]; ok && kind == "" && idx == 0 {
+			kind = "cleartext"
+		}
+		buffer = &streamBuffer{kind: kind}
+		s.buffers[idx] = buffer
+	}
+	if buffer.kind != "" {
+		buffer.append(data, sg.CaptureInfo(0).Timestamp)
+	}
+
+	if s.flow == nil {
+		return
+	}
+	s.flow.payload[idx] += int64(length)
+	if need := previewBytes - len(s.flow.preview[idx]); need > 0 {
+		s.flow.preview[idx] = append(s.flow.preview[idx], data[:min(len(data), need)]...)
+	}
+}
+
+func (s *pcapStream) ReassemblyComplete(ac reassembly.AssemblerContext) bool {
+	for idx, buffer := range s.buffers {
+		if buffer == nil {
+			continue
+		}
+		sender, receiver := s.ends[idx], s.ends[1-idx]
+		switch buffer.kind {
+		case "http-request":
+			s.analysis.addHTTP(buffer, s.buffers[1-idx], sender, receiver)
+		case "tls":
+			s.analysis.addTLS(buffer, sender, receiver)
+		case "cleartext":
+			s.analysis.addCleartext(buffer, sender, receiver)
+		}
+	}
+	s.buffers = [2]*streamBuffer{}
+	return true
+}
@@ -0,0 +1,349 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"local-agent/config"
+)
+
+// pcapExportTables are the tables written by ExportPCAP, in order. Their
+// columns are documented in PCAP_EXPORT.md.
+var pcapExportTables = []string{"flows", "dns", "http", "protocols", "timeline"}
+
+// flowExport is one conversation of the flows table
+type flowExport struct {
+	ID                    int      `json:"id"`
+	Protocol              string   `json:"protocol"`
+	ClientIP              string   `json:"client_ip"`
+	ClientPort            int      `json:"client_port,omitempty"`
+	ServerIP              string   `json:"server_ip"`
+	ServerPort            int      `json:"server_port,omitempty"`
+	PacketsClientToServer int      `json:"packets_client_to_server"`
+	PacketsServerToClient int      `json:"packets_server_to_client"`
+	BytesClientToServer   int64    `json:"bytes_client_to_server"`
+	BytesServerToClient   int64    `json:"bytes_server_to_client"`
+	PayloadClientToServer int64    `json:"payload_bytes_client_to_server"`
+	PayloadServerToClient int64    `json:"payload_bytes_server_to_client"`
+	Start                 string   `json:"start"`
+	End                   string   `json:"end"`
+	DurationSeconds       float64  `json:"duration_seconds"`
+	TCPFlags              []string `json:"tcp_flags"`
+}
+
+// dnsExport is one deduplicated question of the dns table
+type dnsExport struct {
+	Query     string   `json:"query"`
+	Type      string   `json:"type"`
+	Count     int      `json:"count"`
+	Clients   []string `json:"clients"`
+	Answers   []string `json:"answers"`
+	FirstSeen string   `json:"first_seen"`
+	LastSeen  string   `json:"last_seen"`
+}
+
+// httpExport is one deduplicated request of the http table
+type httpExport struct {
+	Method    string   `json:"method"`
+	Host      string   `json:"host"`
+	Path      string   `json:"path"`
+	Count     int      `json:"count"`
+	Statuses  []string `json:"statuses"`
+	Clients   []string `json:"clients"`
+	FirstSeen string   `json:"first_seen"`
+	LastSeen  string   `json:"last_seen"`
+}
+
+// protocolExport is one row of the protocols histogram
+type protocolExport struct {
+	Protocol string  `json:"protocol"`
+	Packets  int     `json:"packets"`
+	Percent  float64 `json:"percent"`
+}
+
+// timelineExport is the traffic of one protocol in one timeline window
+type timelineExport struct {
+	WindowStart   string `json:"window_start"`
+	WindowSeconds int    `json:"window_seconds"`
+	Protocol      string `json:"protocol"`
+	Packets       int    `json:"packets"`
+	Bytes         int64  `json:"bytes"`
+}
+
+// pcapExportSummary describes the capture and the export; written as
+// <name>.summary.json
+type pcapExportSummary struct {
+	Capture              string   `json:"capture"`
+	ExportedAt           string   `json:"exported_at"`
+	From                 string   `json:"from,omitempty"`
+	To                   string   `json:"to,omitempty"`
+	Filter               string   `json:"filter,omitempty"`
+	Packets              int      `json:"packets"`
+	Bytes                int64    `json:"bytes"`
+	FirstPacket          string   `json:"first_packet,omitempty"`
+	LastPacket           string   `json:"last_packet,omitempty"`
+	PacketsOutsideWindow int      `json:"packets_outside_window"`
+	PacketsUnmatched     int      `json:"packets_unmatched"`
+	PacketsUntracked     int      `json:"packets_untracked"`
+	Files                []string `json:"files"`
+}
+
+// ExportPCAP analyzes a capture like ReadPCAPContent and writes its flows,
+// DNS queries, HTTP requests, protocol counts and timeline to dir, each as
+// <name>.<table>.csv and <name>.<table>.json, followed by
+// <name>.summary.json. Unlike the Markdown summary, the tables are not cut to
+// their top entries. It returns the paths written.
+func (d *Detector) ExportPCAP(path, dir, name string, opts config.PCAPConfig) ([]string, error) {
+	analysis, err := analyzePCAP(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	tables := map[string]any{
+		"flows":     analysis.exportFlows(),
+		"dns":       analysis.exportDNS(),
+		"http":      analysis.exportHTTP(),
+		"protocols": analysis.exportProtocols(),
+		"timeline":  analysis.exportTimeline(),
+	}
+
+	var written []string
+	for _, table := range pcapExportTables {
+		base := filepath.Join(dir, name+"."+table)
+		if err := writeExportCSV(base+".csv", tables[table]); err != nil {
+			return written, err
+		}
+		if err := writeExportJSON(base+".json", tables[table]); err != nil {
+			return written, err
+		}
+		written = append(written, base+".csv", base+".json")
+	}
+
+	summary := pcapExportSummary{
+		Capture:              path,
+		ExportedAt:           formatExportTime(time.Now()),
+		Filter:               opts.Filter,
+		Packets:              analysis.packets,
+		Bytes:                analysis.bytes,
+		PacketsOutsideWindow: analysis.outside,
+		PacketsUnmatched:     analysis.unmatched,
+		PacketsUntracked:     analysis.untracked,
+	}
+	if !analysis.from.IsZero() {
+		summary.From = formatExportTime(analysis.from)
+	}
+	if !analysis.to.IsZero() {
+		summary.To = formatExportTime(analysis.to)
+	}
+	if analysis.packets > 0 {
+		summary.FirstPacket = formatExportTime(analysis.first)
+		summary.LastPacket = formatExportTime(analysis.last)
+	}
+	for _, file := range written {
+		summary.Files = append(summary.Files, filepath.Base(file))
+	}
+	summaryPath := filepath.Join(dir, name+".summary.json")
+	if err := writeExportJSON(summaryPath, summary); err != nil {
+		return written, err
+	}
+	return append(written, summaryPath), nil
+}
+
+// PCAPExportName derives export file names from a capture's relative path,
+// e.g. "captures/dump.pcap" becomes "captures_dump"
+func PCAPExportName(relPath string) string {
+	name := strings.TrimSuffix(filepath.ToSlash(relPath), filepath.Ext(relPath))
+	name = strings.NewReplacer("/", "_", "!", "", ":", "_").Replace(name)
+	return strings.Trim(name, "._")
+}
+
+func (p *pcapAnalysis) exportFlows() []flowExport {
+	flows := p.sortedFlows()
+	records := make([]flowExport, 0, len(flows))
+	for _, flow := range flows {
+		record := flowExport{
+			ID:                    flow.id,
+			Protocol:              flow.proto,
+			ClientIP:              flow.client,
+			ServerIP:              flow.server,
+			PacketsClientToServer: flow.packets[0],
+			PacketsServerToClient: flow.packets[1],
+			BytesClientToServer:   flow.bytes[0],
+			BytesServerToClient:   flow.bytes[1],
+			PayloadClientToServer: flow.payload[0],
+			PayloadServerToClient: flow.payload[1],
+			Start:                 formatExportTime(flow.first),
+			End:                   formatExportTime(flow.last),
+			DurationSeconds:       flow.last.Sub(flow.first).Seconds(),
+			TCPFlags:              []string{},
+		}
+		// ICMP endpoints are bare addresses
+		if flow.proto == "TCP" || flow.proto == "UDP" {
+			record.ClientIP, record.ClientPort = hostOf(flow.client), portNumber(flow.client)
+			record.ServerIP, record.ServerPort = hostOf(flow.server), portNumber(flow.server)
+		}
+		for i, name := range tcpFlagNames {
+			if flow.flags&(1<<i) != 0 {
+				record.TCPFlags = append(record.TCPFlags, name)
+			}
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func (p *pcapAnalysis) exportDNS() []dnsExport {
+	records := make([]dnsExport, 0, len(p.dns))
+	for _, r := range sortedRecords(p.dns, func(r *dnsRecord) *appRecord { return &r.appRecord }, func(r *dnsRecord) string { return r.name }) {
+		records = append(records, dnsExport{
+			Query:     r.name,
+			Type:      r.qtype,
+			Count:     r.count,
+			Clients:   r.clients.all(),
+			Answers:   r.answers.all(),
+			FirstSeen: formatExportTime(r.first),
+			LastSeen:  formatExportTime(r.last),
+		})
+	}
+	return records
+}
+
+func (p *pcapAnalysis) exportHTTP() []httpExport {
+	records := make([]httpExport, 0, len(p.http))
+	for _, r := range sortedRecords(p.http, func(r *httpRecord) *appRecord { return &r.appRecord }, func(r *httpRecord) string { return r.host + r.path }) {
+		records = append(records, httpExport{
+			Method:    r.method,
+			Host:      r.host,
+			Path:      r.path,
+			Count:     r.count,
+			Statuses:  r.statuses.all(),
+			Clients:   r.clients.all(),
+			FirstSeen: formatExportTime(r.first),
+			LastSeen:  formatExportTime(r.last),
+		})
+	}
+	return records
+}
+
+func (p *pcapAnalysis) exportProtocols() []protocolExport {
+	records := make([]protocolExport, 0, len(p.protocols))
+	for _, entry := range sortedCounts(p.protocols, 0) {
+		records = append(records, protocolExport{
+			Protocol: entry.key,
+			Packets:  entry.value,
+			Percent:  math.Round(float64(entry.value)/float64(p.packets)*10000) / 100,
+		})
+	}
+	return records
+}
+
+func (p *pcapAnalysis) exportTimeline() []timelineExport {
+	width := int(timeBucketWidths[p.timeline.width].Seconds())
+	var records []timelineExport
+	for _, start := range p.timeline.sortedStarts() {
+		bucket := p.timeline.buckets[start]
+		for _, entry := range sortedCounts(bucket.classes, 0) {
+			records = append(records, timelineExport{
+				WindowStart:   formatExportTime(start),
+				WindowSeconds: width,
+				Protocol:      entry.key,
+				Packets:       entry.value,
+				Bytes:         bucket.classBytes[entry.key],
+			})
+		}
+	}
+	if records == nil {
+		records = []timelineExport{}
+	}
+	return records
+}
+
+// formatExportTime formats timestamps as RFC 3339 in UTC, or "" when unset
+func formatExportTime(ts time.Time) string {
+	if ts.IsZero() {
+		return ""
+	}
+	return ts.UTC().Format(time.RFC3339Nano)
+}
+
+// writeExportJSON writes records as indented JSON
+func writeExportJSON(path string, records any) error {
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", filepath.Base(path), err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeExportCSV writes a slice of export structs as CSV. The header is
+// taken from the JSON field names so both formats share one schema; list
+// fields are joined with ";".
+func writeExportCSV(path string, records any) error {
+	rows := reflect.ValueOf(records)
+	fields := reflect.TypeOf(records).Elem()
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	header := make([]string, fields.NumField())
+	omitEmpty := make([]bool, fields.NumField())
+	for i := range header {
+		var options string
+		header[i], options, _ = strings.Cut(fields.Field(i).Tag.Get("json"), ",")
+		omitEmpty[i] = options == "omitempty"
+	}
+	w.Write(header)
+	for i := 0; i < rows.Len(); i++ {
+		row := rows.Index(i)
+		record := make([]string, row.NumField())
+		for j := range record {
+			// Fields omitted from the JSON when zero are left empty
+			if !omitEmpty[j] || !row.Field(j).IsZero() {
+				record[j] = exportCSVValue(row.Field(j))
+			}
+		}
+		w.Write(record)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", filepath.Base(path), err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// exportCSVValue formats one field of an export struct as a CSV cell
+func exportCSVValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	case reflect.Slice:
+		values := make([]string, v.Len())
+		for i := range values {
+			values[i] = v.Index(i).String()
+		}
+		return strings.Join(values, ";")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
@@ -0,0 +1,451 @@
+package analyzer
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// PCAPFilter is a parsed packet filter expression. It covers a BPF-like
+// subset evaluated on decoded packets:
+//
+//	host 10.0.0.5            src host 10.0.0.5        dst host fe80::1
+//	net 10.0.0.0/8           src net 192.168.1.0/24
+//	port 443                 dst port https           portrange 8000-8080
+//	tcp  udp  icmp  icmp6  ip  ip6                    proto udp
+//	tcp port 80              (expr)  not/!  and/&&  or/||
+//
+// As in BPF, "and" and "or" have equal precedence and group left to right,
+// and a bare value repeats the previous qualifier, so "port 80 or 443" means
+// "port 80 or port 443". Host names are not resolved.
+type PCAPFilter struct {
+	expr string
+	root filterNode
+}
+
+// ParsePCAPFilter parses a filter expression. An empty expression yields a
+// nil filter, which matches every packet.
+func ParsePCAPFilter(expr string) (*PCAPFilter, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, nil
+	}
+
+	parser := &filterParser{tokens: tokenizeFilter(expr)}
+	root, err := parser.parseExpr()
+	if err == nil && parser.pos < len(parser.tokens) {
+		err = fmt.Errorf("unexpected %q", parser.tokens[parser.pos])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid packet filter %q: %w", expr, err)
+	}
+	return &PCAPFilter{expr: expr, root: root}, nil
+}
+
+// CombinePCAPFilters joins filter expressions so that packets must match all
+// of them. Empty expressions are ignored.
+func CombinePCAPFilters(exprs ...string) string {
+	var parts []string
+	for _, expr := range exprs {
+		if expr = strings.TrimSpace(expr); expr != "" {
+			parts = append(parts, expr)
+		}
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	for i, part := range parts {
+		parts[i] = "(" + part + ")"
+	}
+	return strings.Join(parts, " and ")
+}
+
+// SplitFocusFilter splits an interactive focus argument such as
+// "capture.pcap where host 10.0.0.5" into the path and filter expression
+func SplitFocusFilter(arg string) (string, string) {
+	if idx := strings.Index(strings.ToLower(arg), " where "); idx >= 0 {
+		return strings.TrimSpace(arg[:idx]), strings.TrimSpace(arg[idx+len(" where "):])
+	}
+	return strings.TrimSpace(arg), ""
+}
+
+// String returns the expression the filter was parsed from
+func (f *PCAPFilter) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.expr
+}
+
+// matches reports whether a packet passes the filter
+func (f *PCAPFilter) matches(packet gopacket.Packet) bool {
+	if f == nil {
+		return true
+	}
+	return f.root.match(newFilterPacket(packet))
+}
+
+// filterPacket holds the packet fields filters look at
+type filterPacket struct {
+	src, dst         netip.Addr // invalid without an IP layer
+	srcPort, dstPort int        // -1 without TCP or UDP
+	transport        string     // "tcp", "udp", "icmp", "icmp6" or ""
+}
+
+func newFilterPacket(packet gopacket.Packet) *filterPacket {
+	fp := &filterPacket{srcPort: -1, dstPort: -1}
+	if ipLayer := packet.Layer(layers.LayerTypeIPv4); ipLayer != nil {
+		ip, _ := ipLayer.(*layers.IPv4)
+		fp.src, _ = netip.AddrFromSlice(ip.SrcIP)
+		fp.dst, _ = netip.AddrFromSlice(ip.DstIP)
+	} else if ipLayer := packet.Layer(layers.LayerTypeIPv6); ipLayer != nil {
+		ip, _ := ipLayer.(*layers.IPv6)
+		fp.src, _ = netip.AddrFromSlice(ip.SrcIP)
+		fp.dst, _ = netip.AddrFromSlice(ip.DstIP)
+	}
+	fp.src, fp.dst = fp.src.Unmap(), fp.dst.Unmap()
+
+	switch t := packet.TransportLayer().(type) {
+	case *layers.TCP:
+		fp.transport = "tcp"
+		fp.srcPort, fp.dstPort = int(t.SrcPort), int(t.DstPort)
+	case *layers.UDP:
+		fp.transport = "udp"
+		fp.srcPort, fp.dstPort = int(t.SrcPort), int(t.DstPort)
+	default:
+		if packet.Layer(layers.LayerTypeICMPv4) != nil {
+			fp.transport = "icmp"
+		} else if packet.Layer(layers.LayerTypeICMPv6) != nil {
+			fp.transport = "icmp6"
+		}
+	}
+	return fp
+}
+
+// filterDir is the direction qualifier of a primitive
+type filterDir int
+
+const (
+	dirAny filterDir = iota
+	dirSrc
+	dirDst
+)
+
+// filterNode is a node of a parsed filter expression
+type filterNode interface {
+	match(p *filterPacket) bool
+}
+
+type andNode struct{ left, right filterNode }
+
+func (n andNode) match(p *filterPacket) bool { return n.left.match(p) && n.right.match(p) }
+
+type orNode struct{ left, right filterNode }
+
+func (n orNode) match(p *filterPacket) bool { return n.left.match(p) || n.right.match(p) }
+
+type notNode struct{ node filterNode }
+
+func (n notNode) match(p *filterPacket) bool { return !n.node.match(p) }
+
+// protoNode matches a network ("ip", "ip6") or transport protocol
+type protoNode struct{ proto string }
+
+func (n protoNode) match(p *filterPacket) bool {
+	switch n.proto {
+	case "ip":
+		return p.src.Is4()
+	case "ip6":
+		return p.src.Is6()
+	default:
+		return p.transport == n.proto
+	}
+}
+
+// addrNode matches "host" (a full-length prefix) and "net" primitives
+type addrNode struct {
+	dir    filterDir
+	prefix netip.Prefix
+}
+
+func (n addrNode) match(p *filterPacket) bool {
+	src := p.src.IsValid() && n.prefix.Contains(p.src)
+	dst := p.dst.IsValid() && n.prefix.Contains(p.dst)
+	switch n.dir {
+	case dirSrc:
+		return src
+	case dirDst:
+		return dst
+	default:
+		return src || dst
+	}
+}
+
+// portNode matches "port" (low == high) and "portrange" primitives
+type portNode struct {
+	dir       filterDir
+	low, high int
+}
+
+func (n portNode) match(p *filterPacket) bool {
+	if p.srcPort < 0 {
+		return false
+	}
+	src := p.srcPort >= n.low && p.srcPort <= n.high
+	dst := p.dstPort >= n.low && p.dstPort <= n.high
+	switch n.dir {
+	case dirSrc:
+		return src
+	case dirDst:
+		return dst
+	default:
+		return src || dst
+	}
+}
+
+// filterProtocols are the protocol keywords
+var filterProtocols = map[string]bool{"tcp": true, "udp": true, "icmp": true, "icmp6": true, "ip": true, "ip6": true}
+
+// filterPortNames are the service names accepted in place of port numbers
+var filterPortNames = map[string]int{
+	"ftp": 21, "ssh": 22, "telnet": 23, "smtp": 25, "domain": 53, "dns": 53,
+	"http": 80, "pop3": 110, "ntp": 123, "imap": 143, "snmp": 161, "ldap": 389,
+	"https": 443, "smb": 445, "imaps": 993, "pop3s": 995, "rdp": 3389,
+}
+
+// tokenizeFilter splits an expression into words, parentheses and the
+// operators !, && and ||
+func tokenizeFilter(expr string) []string {
+	var tokens []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			flush()
+		case c == '(' || c == ')' || c == '!':
+			flush()
+			tokens = append(tokens, string(c))
+		case (c == '&' || c == '|') && i+1 < len(expr) && expr[i+1] == c:
+			flush()
+			tokens = append(tokens, expr[i:i+2])
+			i++
+		default:
+			word.WriteByte(c)
+		}
+	}
+	flush()
+	return tokens
+}
+
+// filterQualifier is the protocol, direction and kind of a primitive,
+// remembered so that bare values can repeat it
+type filterQualifier struct {
+	proto string
+	dir   filterDir
+	kind  string // "host", "net", "port" or "portrange"
+}
+
+// filterParser is a recursive descent parser. As in tcpdump, "not" binds
+// tightest while "and" and "or" have equal precedence and group left to right.
+type filterParser struct {
+	tokens []string
+	pos    int
+	last   *filterQualifier
+}
+
+func (p *filterParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return strings.ToLower(p.tokens[p.pos])
+	}
+	return ""
+}
+
+func (p *filterParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *filterParser) parseExpr() (filterNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != "and" && op != "&&" && op != "or" && op != "||" {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if op == "and" || op == "&&" {
+			left = andNode{left, right}
+		} else {
+			left = orNode{left, right}
+		}
+	}
+}
+
+func (p *filterParser) parseNot() (filterNode, error) {
+	if p.peek() == "not" || p.peek() == "!" {
+		p.next()
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *filterParser) parsePrimary() (filterNode, error) {
+	switch p.peek() {
+	case "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case "(":
+		p.next()
+		node, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		return node, nil
+	case ")", "and", "&&", "or", "||":
+		return nil, fmt.Errorf("unexpected %q", p.peek())
+	}
+	return p.parsePrimitive()
+}
+
+func (p *filterParser) parsePrimitive() (filterNode, error) {
+	start := p.peek()
+	var q filterQualifier
+
+	if start == "proto" {
+		p.next()
+		proto := p.next()
+		if !filterProtocols[proto] {
+			return nil, fmt.Errorf("unknown protocol %q (use tcp, udp, icmp, icmp6, ip or ip6)", proto)
+		}
+		p.last = nil
+		return protoNode{proto}, nil
+	}
+	if filterProtocols[start] {
+		q.proto = p.next()
+	}
+	switch p.peek() {
+	case "src":
+		q.dir = dirSrc
+		p.next()
+	case "dst":
+		q.dir = dirDst
+		p.next()
+	}
+	switch p.peek() {
+	case "host", "net", "port", "portrange":
+		q.kind = p.next()
+	}
+
+	if q.kind == "" {
+		switch {
+		case q.proto != "" && q.dir == dirAny:
+			// A protocol on its own
+			p.last = nil
+			return protoNode{q.proto}, nil
+		case q.proto != "" || q.dir != dirAny:
+			return nil, fmt.Errorf("expected host, net, port or portrange after %q", p.tokens[p.pos-1])
+		case p.last == nil:
+			return nil, fmt.Errorf("unexpected %q (expected host, net, port, portrange or a protocol)", start)
+		}
+		// A bare value repeats the previous qualifier
+		q = *p.last
+	}
+
+	if p.peek() == "" {
+		return nil, fmt.Errorf("missing value after %q", q.kind)
+	}
+	value := p.tokens[p.pos]
+	p.pos++
+	node, err := buildFilterPrimitive(q, value)
+	if err != nil {
+		return nil, err
+	}
+	p.last = &q
+	if q.proto != "" {
+		node = andNode{protoNode{q.proto}, node}
+	}
+	return node, nil
+}
+
+// buildFilterPrimitive creates the node for a qualified value
+func buildFilterPrimitive(q filterQualifier, value string) (filterNode, error) {
+	switch q.kind {
+	case "host":
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid host %q (expected an IP address)", value)
+		}
+		addr = addr.Unmap()
+		return addrNode{q.dir, netip.PrefixFrom(addr, addr.BitLen())}, nil
+	case "net":
+		if prefix, err := netip.ParsePrefix(value); err == nil {
+			return addrNode{q.dir, prefix.Masked()}, nil
+		}
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid net %q (expected CIDR notation, e.g. 10.0.0.0/8)", value)
+		}
+		addr = addr.Unmap()
+		return addrNode{q.dir, netip.PrefixFrom(addr, addr.BitLen())}, nil
+	case "port":
+		port, err := parseFilterPort(value)
+		if err != nil {
+			return nil, err
+		}
+		return portNode{q.dir, port, port}, nil
+	default: // portrange
+		lowText, highText, ok := strings.Cut(value, "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid port range %q (expected e.g. 8000-8080)", value)
+		}
+		low, err := parseFilterPort(lowText)
+		if err != nil {
+			return nil, err
+		}
+		high, err := parseFilterPort(highText)
+		if err != nil {
+			return nil, err
+		}
+		if high < low {
+			low, high = high, low
+		}
+		return portNode{q.dir, low, high}, nil
+	}
+}
+
+// parseFilterPort parses a port number or service name
+func parseFilterPort(value string) (int, error) {
+	if port, ok := filterPortNames[strings.ToLower(value)]; ok {
+		return port, nil
+	}
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 0 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q", value)
+	}
+	return port, nil
+}
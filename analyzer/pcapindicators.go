@@ -0,0 +1,541 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"local-agent/types"
+)
+
+const (
+	// scanPortThreshold is the number of distinct destination ports one
+	// source must probe to be reported as a port scan
+	scanPortThreshold = 100
+	// scanPortHigh raises a port scan to high severity; it also bounds the
+	// ports and targets kept per source
+	scanPortHigh = 1000
+	// beaconMinConnections is the number of connections needed to judge
+	// whether they are periodic
+	beaconMinConnections = 6
+	// beaconMaxJitter is the largest coefficient of variation of the
+	// intervals between connections reported as beaconing
+	beaconMaxJitter = 0.1
+	// beaconMinInterval ignores bursts of back-to-back connections
+	beaconMinInterval = time.Second
+	// maxBeaconSamples bounds the connection times kept per client and service
+	maxBeaconSamples = 1000
+	// tunnelLabelLength is the DNS label length that alone marks a query as a
+	// tunneling candidate (labels are at most 63 characters)
+	tunnelLabelLength = 40
+	// tunnelMinSubdomain and tunnelEntropy mark shorter subdomains whose
+	// characters look encoded rather than chosen by a person
+	tunnelMinSubdomain = 24
+	tunnelEntropy      = 4.0
+	// tunnelHighQueries raises DNS tunneling to high severity
+	tunnelHighQueries = 50
+	// maxIndicatorsPerKind bounds the findings reported per indicator kind
+	maxIndicatorsPerKind = 20
+)
+
+// cleartextService describes a TCP service that sends credentials
+// unencrypted. Sessions are reported when credential commands are seen, or
+// for every session when always is set.
+type cleartextService struct {
+	name   string
+	always bool
+}
+
+// cleartextServices maps server ports to cleartext credential protocols
+var cleartextServices = map[int]cleartextService{
+	21:  {"FTP", true},
+	23:  {"Telnet", true},
+	25:  {"SMTP", false},
+	110: {"POP3", true},
+	143: {"IMAP", true},
+	587: {"SMTP", false},
+}
+
+// unusualPort describes a server port associated with malware or covert use
+type unusualPort struct {
+	reason   string
+	severity types.Severity
+}
+
+// unusualPorts are server ports reported when traffic reaches them
+var unusualPorts = map[int]unusualPort{
+	1337:  {"commonly used by backdoors", types.SeverityMedium},
+	4444:  {"Metasploit's default listener port", types.SeverityMedium},
+	6666:  {"IRC, often used for botnet command and control", types.SeverityLow},
+	6667:  {"IRC, often used for botnet command and control", types.SeverityLow},
+	6668:  {"IRC, often used for botnet command and control", types.SeverityLow},
+	6669:  {"IRC, often used for botnet command and control", types.SeverityLow},
+	9001:  {"Tor relay port", types.SeverityLow},
+	9050:  {"Tor SOCKS proxy port", types.SeverityLow},
+	12345: {"NetBus backdoor port", types.SeverityMedium},
+	27374: {"SubSeven backdoor port", types.SeverityMedium},
+	31337: {"Back Orifice and other backdoors", types.SeverityMedium},
+}
+
+// periodicServices are server ports polled on a fixed schedule by design,
+// excluded from beaconing
+var periodicServices = map[int]bool{
+	123: true, // NTP
+}
+
+// scanRecord collects the destination ports probed by one source
+type scanRecord struct {
+	source   string
+	ports    map[int]struct{}
+	minPort  int
+	maxPort  int
+	targets  valueSet
+	first    time.Time
+	last     time.Time
+	complete bool // scanPortHigh ports reached, later ports are not counted
+}
+
+// beaconRecord collects the connection start times from a client to a service
+type beaconRecord struct {
+	client string
+	server string
+	proto  string
+	times  []time.Time
+}
+
+// tunnelRecord aggregates DNS queries with suspicious subdomains of a domain
+type tunnelRecord struct {
+	appRecord
+	domain  string
+	qtypes  valueSet
+	longest int
+	entropy float64
+}
+
+// cleartextRecord aggregates the sessions of a cleartext protocol to a server
+type cleartextRecord struct {
+	appRecord
+	protocol string
+	server   string
+	evidence valueSet
+}
+
+// addProbe records a connection attempt from src to a destination port
+func (p *pcapAnalysis) addProbe(src, dst string, port int, ts time.Time) {
+	record, ok := p.probes[src]
+	if !ok {
+		if len(p.probes) >= maxTrackedFlows {
+			return
+		}
+		record = &scanRecord{source: src, ports: make(map[int]struct{}), minPort: port, maxPort: port, first: ts}
+		p.probes[src] = record
+	}
+	record.last = ts
+	if len(record.targets.values) < scanPortHigh {
+		record.targets.add(dst)
+	}
+	if _, ok := record.ports[port]; ok || record.complete {
+		return
+	}
+	record.ports[port] = struct{}{}
+	record.minPort = min(record.minPort, port)
+	record.maxPort = max(record.maxPort, port)
+	record.complete = len(record.ports) >= scanPortHigh
+}
+
+// addConnection records the start of a conversation for beaconing detection
+func (p *pcapAnalysis) addConnection(proto, client, server string, ts time.Time) {
+	if periodicServices[portNumber(server)] {
+		return
+	}
+	key := proto + "|" + hostOf(client) + "|" + server
+	record, ok := p.beacons[key]
+	if !ok {
+		if len(p.beacons) >= maxTrackedFlows {
+			return
+		}
+		record = &beaconRecord{client: hostOf(client), server: server, proto: proto}
+		p.beacons[key] = record
+	}
+	if len(record.times) < maxBeaconSamples {
+		record.times = append(record.times, ts)
+	}
+}
+
+// addTunnelCandidate records a DNS query whose subdomain is long or looks
+// encoded. The domain is approximated by the last two labels.
+func (p *pcapAnalysis) addTunnelCandidate(name, qtype, client string, ts time.Time) {
+	labels := strings.Split(strings.ToLower(name), ".")
+	if len(labels) < 3 {
+		return
+	}
+	subdomain := labels[:len(labels)-2]
+	longest := 0
+	for _, label := range subdomain {
+		longest = max(longest, len(label))
+	}
+	joined := strings.Join(subdomain, "")
+	entropy := shannonEntropy(joined)
+	if longest < tunnelLabelLength && (len(joined) < tunnelMinSubdomain || entropy < tunnelEntropy) {
+		return
+	}
+
+	domain := strings.Join(labels[len(labels)-2:], ".")
+	record, ok := p.tunnels[domain]
+	if !ok {
+		if len(p.tunnels) >= maxTrackedFlows {
+			return
+		}
+		record = &tunnelRecord{domain: domain}
+		p.tunnels[domain] = record
+	}
+	record.seen(client, ts)
+	record.qtypes.add(qtype)
+	record.longest = max(record.longest, longest)
+	record.entropy = math.Max(record.entropy, entropy)
+}
+
+// shannonEntropy returns the entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	var counts [256]int
+	for i := 0; i < len(s); i++ {
+		counts[s[i]]++
+	}
+	entropy := 0.0
+	n := float64(len(s))
+	for _, count := range counts {
+		if count > 0 {
+			p := float64(count) / n
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy
+}
+
+// addCleartext records a client to server stream of a cleartext credential
+// protocol, keeping the credential commands it carried (never their values)
+func (p *pcapAnalysis) addCleartext(buffer *streamBuffer, client, server string) {
+	service := cleartextServices[portNumber(server)]
+	commands := credentialCommands(buffer.data, service.name == "IMAP")
+	if len(commands) == 0 && !service.always {
+		return
+	}
+	p.recordCleartext(service.name, client, server, buffer.timeAt(0), commands)
+}
+
+func (p *pcapAnalysis) recordCleartext(protocol, client, server string, ts time.Time, evidence []string) {
+	key := protocol + "|" + server
+	record, ok := p.cleartext[key]
+	if !ok {
+		if len(p.cleartext) >= maxTrackedFlows {
+			return
+		}
+		record = &cleartextRecord{protocol: protocol, server: server}
+		p.cleartext[key] = record
+	}
+	record.seen(hostOf(client), ts)
+	for _, value := range evidence {
+		record.evidence.add(value)
+	}
+}
+
+// credentialCommands lists the login commands in the client side of a
+// line-based protocol session: USER/PASS (FTP, POP3), APOP, IMAP LOGIN and
+// SMTP/IMAP AUTH with its mechanism. tagged skips the IMAP command tag.
+func credentialCommands(data []byte, tagged bool) []string {
+	var commands []string
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if tagged {
+			fields = fields[1:]
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		verb, args := strings.ToUpper(fields[0]), fields[1:]
+		switch verb {
+		case "DATA":
+			// The rest of an SMTP session is message content
+			return commands
+		case "USER", "PASS", "APOP", "LOGIN":
+			commands = append(commands, verb)
+		case "AUTH", "AUTHENTICATE":
+			if len(args) > 0 {
+				commands = append(commands, verb+" "+strings.ToUpper(args[0]))
+			}
+		}
+	}
+	return commands
+}
+
+// severityRanks orders severities, most severe first
+var severityRanks = map[types.Severity]int{
+	types.SeverityCritical: 0,
+	types.SeverityHigh:     1,
+	types.SeverityMedium:   2,
+	types.SeverityLow:      3,
+	types.SeverityInfo:     4,
+}
+
+// findings runs the heuristic checks over the capture. Findings are ordered
+// by severity; File is left for the caller to fill in.
+func (p *pcapAnalysis) findings() []types.Finding {
+	var findings []types.Finding
+	findings = append(findings, p.scanFindings()...)
+	findings = append(findings, p.beaconFindings()...)
+	findings = append(findings, p.tunnelFindings()...)
+	findings = append(findings, p.cleartextFindings()...)
+	findings = append(findings, p.unusualPortFindings()...)
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severityRanks[findings[i].Severity] < severityRanks[findings[j].Severity]
+	})
+	return findings
+}
+
+// limitFindings applies maxIndicatorsPerKind, noting how many were dropped
+func limitFindings(findings []types.Finding, kind string) []types.Finding {
+	if len(findings) <= maxIndicatorsPerKind {
+		return findings
+	}
+	dropped := len(findings) - maxIndicatorsPerKind
+	findings = findings[:maxIndicatorsPerKind]
+	return append(findings, types.Finding{
+		Severity:    types.SeverityInfo,
+		Category:    findings[0].Category,
+		Description: fmt.Sprintf("%d more %s indicators not listed", dropped, kind),
+	})
+}
+
+func (p *pcapAnalysis) scanFindings() []types.Finding {
+	var records []*scanRecord
+	for _, record := range p.probes {
+		if len(record.ports) >= scanPortThreshold {
+			records = append(records, record)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if len(records[i].ports) != len(records[j].ports) {
+			return len(records[i].ports) > len(records[j].ports)
+		}
+		return records[i].source < records[j].source
+	})
+
+	var findings []types.Finding
+	for _, record := range records {
+		severity := types.SeverityMedium
+		ports := fmt.Sprintf("%d", len(record.ports))
+		if record.complete {
+			severity = types.SeverityHigh
+			ports = fmt.Sprintf("at least %d", scanPortHigh)
+		}
+		findings = append(findings, types.Finding{
+			Severity: severity,
+			Category: "port-scan",
+			Description: fmt.Sprintf("Possible port scan: %s probed %s distinct destination ports (%d-%d) on %s between %s and %s",
+				record.source, ports, record.minPort, record.maxPort, pluralHosts(record.targets),
+				formatRecordTime(record.first), formatRecordTime(record.last)),
+			Suggestion: fmt.Sprintf("Check whether %s is an authorised scanner; otherwise treat it as reconnaissance from a compromised or hostile host", record.source),
+		})
+	}
+	return limitFindings(findings, "port scan")
+}
+
+// pluralHosts describes the targets of a scan
+func pluralHosts(targets valueSet) string {
+	if len(targets.values) == 1 {
+		return "1 host (" + targets.values[0] + ")"
+	}
+	return fmt.Sprintf("%d hosts (%s)", len(targets.values), targets.String())
+}
+
+// beaconStats returns the mean interval between sorted times and its
+// coefficient of variation
+func beaconStats(times []time.Time) (time.Duration, float64) {
+	intervals := make([]float64, 0, len(times)-1)
+	sum := 0.0
+	for i := 1; i < len(times); i++ {
+		interval := times[i].Sub(times[i-1]).Seconds()
+		intervals = append(intervals, interval)
+		sum += interval
+	}
+	mean := sum / float64(len(intervals))
+	if mean <= 0 {
+		return 0, math.Inf(1)
+	}
+	variance := 0.0
+	for _, interval := range intervals {
+		variance += (interval - mean) * (interval - mean)
+	}
+	variance /= float64(len(intervals))
+	return time.Duration(mean * float64(time.Second)), math.Sqrt(variance) / mean
+}
+
+func (p *pcapAnalysis) beaconFindings() []types.Finding {
+	type beacon struct {
+		record *beaconRecord
+		period time.Duration
+		jitter float64
+	}
+	var beacons []beacon
+	for _, record := range p.beacons {
+		if len(record.times) < beaconMinConnections {
+			continue
+		}
+		sort.Slice(record.times, func(i, j int) bool { return record.times[i].Before(record.times[j]) })
+		period, jitter := beaconStats(record.times)
+		if period < beaconMinInterval || jitter > beaconMaxJitter {
+			continue
+		}
+		beacons = append(beacons, beacon{record, period, jitter})
+	}
+	sort.Slice(beacons, func(i, j int) bool {
+		if len(beacons[i].record.times) != len(beacons[j].record.times) {
+			return len(beacons[i].record.times) > len(beacons[j].record.times)
+		}
+		a, b := beacons[i].record, beacons[j].record
+		return a.client+a.server < b.client+b.server
+	})
+
+	var findings []types.Finding
+	for _, b := range beacons {
+		times := b.record.times
+		findings = append(findings, types.Finding{
+			Severity: types.SeverityMedium,
+			Category: "beaconing",
+			Description: fmt.Sprintf("Possible beaconing: %s opened %d %s connections to %s every %s (jitter %.1f%%) between %s and %s",
+				b.record.client, len(times), b.record.proto, b.record.server,
+				formatFlowDuration(b.period), b.jitter*100,
+				formatRecordTime(times[0]), formatRecordTime(times[len(times)-1])),
+			Suggestion: fmt.Sprintf("Identify the process on %s making these connections; regular intervals suggest malware command and control or an unexpected automated agent", b.record.client),
+		})
+	}
+	return limitFindings(findings, "beaconing")
+}
+
+func (p *pcapAnalysis) tunnelFindings() []types.Finding {
+	records := topRecords(p.tunnels, func(r *tunnelRecord) *appRecord { return &r.appRecord }, func(r *tunnelRecord) string { return r.domain })
+
+	var findings []types.Finding
+	for _, r := range records {
+		severity := types.SeverityMedium
+		if r.count >= tunnelHighQueries {
+			severity = types.SeverityHigh
+		}
+		findings = append(findings, types.Finding{
+			Severity: severity,
+			Category: "dns-tunneling",
+			Description: fmt.Sprintf("Possible DNS tunneling: %d queries (%s) for long or high-entropy subdomains of %s from %s (longest label %d characters, entropy up to %.1f bits/character) between %s and %s",
+				r.count, r.qtypes.String(), r.domain, r.clients.String(), r.longest, r.entropy,
+				formatRecordTime(r.first), formatRecordTime(r.last)),
+			Suggestion: fmt.Sprintf("Inspect %s and the querying hosts; encoded subdomains can carry data out of the network over DNS", r.domain),
+		})
+	}
+	return limitFindings(findings, "DNS tunneling")
+}
+
+func (p *pcapAnalysis) cleartextFindings() []types.Finding {
+	records := topRecords(p.cleartext, func(r *cleartextRecord) *appRecord { return &r.appRecord }, func(r *cleartextRecord) string { return r.protocol + r.server })
+
+	var findings []types.Finding
+	for _, r := range records {
+		finding := types.Finding{
+			Severity:   types.SeverityMedium,
+			Category:   "cleartext-credentials",
+			Suggestion: fmt.Sprintf("Replace %s with an encrypted alternative (e.g. SFTP, SSH, IMAPS, HTTPS) and rotate any credentials used", r.protocol),
+		}
+		if len(r.evidence.values) > 0 {
+			finding.Severity = types.SeverityHigh
+			finding.Description = fmt.Sprintf("Cleartext credentials: %s to %s from %s sent %s in %d session(s), first seen %s",
+				r.protocol, r.server, r.clients.String(), r.evidence.String(), r.count, formatRecordTime(r.first))
+		} else {
+			finding.Description = fmt.Sprintf("Cleartext protocol: %d %s session(s) to %s from %s, first seen %s; credentials may be exposed",
+				r.count, r.protocol, r.server, r.clients.String(), formatRecordTime(r.first))
+		}
+		findings = append(findings, finding)
+	}
+	return limitFindings(findings, "cleartext protocol")
+}
+
+func (p *pcapAnalysis) unusualPortFindings() []types.Finding {
+	type portUse struct {
+		proto   string
+		port    int
+		flows   int
+		clients valueSet
+		servers valueSet
+		first   time.Time
+	}
+	uses := make(map[string]*portUse)
+	for _, flow := range p.sortedFlows() {
+		if flow.proto != "TCP" && flow.proto != "UDP" {
+			continue
+		}
+		port := portNumber(flow.server)
+		if _, ok := unusualPorts[port]; !ok {
+			continue
+		}
+		// Unanswered probes are covered by the port scan check
+		if flow.proto == "TCP" && flow.payload[0]+flow.payload[1] == 0 {
+			continue
+		}
+		key := fmt.Sprintf("%s/%d", flow.proto, port)
+		use, ok := uses[key]
+		if !ok {
+			use = &portUse{proto: flow.proto, port: port, first: flow.first}
+			uses[key] = use
+		}
+		use.flows++
+		use.clients.add(hostOf(flow.client))
+		use.servers.add(hostOf(flow.server))
+		if flow.first.Before(use.first) {
+			use.first = flow.first
+		}
+	}
+
+	list := make([]*portUse, 0, len(uses))
+	for _, use := range uses {
+		list = append(list, use)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].port != list[j].port {
+			return list[i].port < list[j].port
+		}
+		return list[i].proto < list[j].proto
+	})
+
+	var findings []types.Finding
+	for _, use := range list {
+		info := unusualPorts[use.port]
+		findings = append(findings, types.Finding{
+			Severity: info.severity,
+			Category: "unusual-port",
+			Description: fmt.Sprintf("Unusual port: %d %s conversation(s) to %s/%d (%s) on %s from %s, first seen %s",
+				use.flows, use.proto, use.proto, use.port, info.reason, use.servers.String(), use.clients.String(),
+				formatRecordTime(use.first)),
+			Suggestion: fmt.Sprintf("Confirm which service listens on port %d and whether this traffic is expected", use.port),
+		})
+	}
+	return limitFindings(findings, "unusual port")
+}
+
+// renderIndicators writes the findings as a Markdown section
+func renderIndicators(builder *strings.Builder, findings []types.Finding) {
+	builder.WriteString("\n## Indicators\n\n")
+	if len(findings) == 0 {
+		builder.WriteString("Heuristic checks found no port scans, beaconing, DNS tunneling, cleartext credentials or unusual ports.\n")
+		return
+	}
+	builder.WriteString("Heuristic checks over the whole capture; verify before acting.\n\n")
+	for _, finding := range findings {
+		builder.WriteString(fmt.Sprintf("- [%s] %s\n", finding.Severity, finding.Description))
+	}
+}
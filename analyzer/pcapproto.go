@@ -0,0 +1,629 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/gopacket/layers"
+)
+
+const (
+	// maxAppScanBytes bounds the reassembled bytes kept per stream direction
+	// for HTTP and TLS decoding
+	maxAppScanBytes = 64 * 1024
+	// maxAppRecords bounds the rows of each application-layer table
+	maxAppRecords = 200
+	// maxRecordValues bounds the distinct values listed per table cell
+	maxRecordValues = 5
+)
+
+// httpMethods are the request methods recognised at the start of a stream
+var httpMethods = []string{"GET", "POST", "PUT", "DELETE", "HEAD", "OPTIONS", "PATCH", "CONNECT", "TRACE"}
+
+// valueSet keeps distinct strings in first-seen order
+type valueSet struct {
+	values []string
+	seen   map[string]bool
+}
+
+func (s *valueSet) add(value string) {
+	if value == "" || s.seen[value] {
+		return
+	}
+	if s.seen == nil {
+		s.seen = make(map[string]bool)
+	}
+	s.seen[value] = true
+	s.values = append(s.values, value)
+}
+
+// all returns every value, never nil
+func (s *valueSet) all() []string {
+	return append([]string{}, s.values...)
+}
+
+// String lists up to maxRecordValues values
+func (s *valueSet) String() string {
+	if len(s.values) == 0 {
+		return "-"
+	}
+	if len(s.values) <= maxRecordValues {
+		return strings.Join(s.values, ", ")
+	}
+	return fmt.Sprintf("%s (+%d more)", strings.Join(s.values[:maxRecordValues], ", "), len(s.values)-maxRecordValues)
+}
+
+// appRecord holds what is common to deduplicated application-layer records
+type appRecord struct {
+	count   int
+	clients valueSet
+	first   time.Time
+	last    time.Time
+}
+
+func (r *appRecord) seen(client string, ts time.Time) {
+	r.count++
+	r.clients.add(client)
+	if r.first.IsZero() || ts.Before(r.first) {
+		r.first = ts
+	}
+	if ts.After(r.last) {
+		r.last = ts
+	}
+}
+
+// dnsRecord is a deduplicated DNS question with the answers seen for it
+type dnsRecord struct {
+	appRecord
+	name    string
+	qtype   string
+	answers valueSet
+}
+
+// httpRecord is a deduplicated HTTP request with the status codes returned
+type httpRecord struct {
+	appRecord
+	method   string
+	host     string
+	path     string
+	statuses valueSet
+}
+
+// tlsRecord is a deduplicated TLS ClientHello
+type tlsRecord struct {
+	appRecord
+	serverName string
+	alpn       valueSet
+	servers    valueSet
+}
+
+// addDNS records the questions and answers of a DNS message
+func (p *pcapAnalysis) addDNS(dns *layers.DNS, srcIP, dstIP string, ts time.Time) {
+	client := srcIP
+	if dns.QR {
+		client = dstIP
+	}
+
+	for _, q := range dns.Questions {
+		name := strings.TrimSuffix(string(q.Name), ".")
+		if !dns.QR {
+			p.addTunnelCandidate(name, q.Type.String(), client, ts)
+		}
+		key := strings.ToLower(name) + "|" + q.Type.String()
+		record, ok := p.dns[key]
+		if !ok {
+			if len(p.dns) >= maxTrackedFlows {
+				continue
+			}
+			record = &dnsRecord{name: name, qtype: q.Type.String()}
+			p.dns[key] = record
+		}
+
+		if !dns.QR {
+			record.seen(client, ts)
+			continue
+		}
+		if record.count == 0 {
+			// Response without a captured query
+			record.seen(client, ts)
+		}
+		if dns.ResponseCode != layers.DNSResponseCodeNoErr {
+			record.answers.add(dns.ResponseCode.String())
+		}
+		for _, answer := range dns.Answers {
+			if value := dnsAnswerString(answer); value != "" {
+				record.answers.add(value)
+			}
+		}
+	}
+}
+
+// dnsAnswerString formats the data of a resource record
+func dnsAnswerString(rr layers.DNSResourceRecord) string {
+	switch rr.Type {
+	case layers.DNSTypeA, layers.DNSTypeAAAA:
+		if rr.IP != nil {
+			return rr.IP.String()
+		}
+	case layers.DNSTypeCNAME:
+		return "CNAME " + string(rr.CNAME)
+	case layers.DNSTypeNS:
+		return "NS " + string(rr.NS)
+	case layers.DNSTypePTR:
+		return "PTR " + string(rr.PTR)
+	case layers.DNSTypeMX:
+		return fmt.Sprintf("MX %d %s", rr.MX.Preference, rr.MX.Name)
+	case layers.DNSTypeSRV:
+		return fmt.Sprintf("SRV %s:%d", rr.SRV.Name, rr.SRV.Port)
+	case layers.DNSTypeTXT:
+		var parts []string
+		for _, txt := range rr.TXTs {
+			parts = append(parts, string(txt))
+		}
+		return "TXT " + strconv.Quote(strings.Join(parts, ""))
+	}
+	return ""
+}
+
+// streamMark records when the data at an offset of a stream buffer arrived
+type streamMark struct {
+	offset int
+	ts     time.Time
+}
+
+// streamBuffer collects the start of one direction of a TCP stream for
+// application-layer decoding
+type streamBuffer struct {
+	kind  string // "http-request", "http-response", "tls", "cleartext" or "" (not decoded)
+	data  []byte
+	marks []streamMark
+	full  bool
+}
+
+// classifyStream decides from the first bytes of a direction whether it is
+// worth buffering
+func classifyStream(data []byte) string {
+	for _, method := range httpMethods {
+		if bytes.HasPrefix(data, []byte(method+" ")) {
+			return "http-request"
+		}
+	}
+	if bytes.HasPrefix(data, []byte("HTTP/1.")) {
+		return "http-response"
+	}
+	if len(data) >= 3 && data[0] == 0x16 && data[1] == 0x03 {
+		return "tls"
+	}
+	return ""
+}
+
+func (b *streamBuffer) append(data []byte, ts time.Time) {
+	if b.full {
+		return
+	}
+	room := maxAppScanBytes - len(b.data)
+	if room <= 0 {
+		b.full = true
+		return
+	}
+	b.marks = append(b.marks, streamMark{offset: len(b.data), ts: ts})
+	b.data = append(b.data, data[:min(len(data), room)]...)
+}
+
+// timeAt returns the arrival time of the byte at offset
+func (b *streamBuffer) timeAt(offset int) time.Time {
+	ts := time.Time{}
+	for _, mark := range b.marks {
+		if mark.offset > offset {
+			break
+		}
+		ts = mark.ts
+	}
+	return ts
+}
+
+// httpMessage is a parsed request or response head
+type httpMessage struct {
+	offset int
+	method string
+	target string
+	host   string
+	status string
+	basic  bool // request carries HTTP Basic credentials
+}
+
+// parseHTTPMessages parses consecutive HTTP/1.x messages from the start of a
+// stream direction. methods gives the request methods for responses (HEAD
+// responses carry no body); parsing stops at the first malformed or
+// truncated message.
+func parseHTTPMessages(data []byte, requests bool, methods []string) []httpMessage {
+	var messages []httpMessage
+	pos := 0
+	for pos < len(data) {
+		headEnd := bytes.Index(data[pos:], []byte("\r\n\r\n"))
+		sepLen := 4
+		if headEnd < 0 {
+			headEnd = bytes.Index(data[pos:], []byte("\n\n"))
+			sepLen = 2
+		}
+		if headEnd < 0 {
+			break
+		}
+		lines := strings.Split(strings.ReplaceAll(string(data[pos:pos+headEnd]), "\r\n", "\n"), "\n")
+		fields := strings.Fields(lines[0])
+		if len(fields) < 2 {
+			break
+		}
+
+		msg := httpMessage{offset: pos}
+		if requests {
+			if len(fields) != 3 || !strings.HasPrefix(fields[2], "HTTP/1.") || classifyStream([]byte(lines[0])) != "http-request" {
+				break
+			}
+			msg.method, msg.target = fields[0], fields[1]
+		} else {
+			if !strings.HasPrefix(fields[0], "HTTP/1.") {
+				break
+			}
+			msg.status = fields[1]
+		}
+
+		contentLength := -1
+		chunked := false
+		for _, line := range lines[1:] {
+			name, value, ok := strings.Cut(line, ":")
+			if !ok {
+				continue
+			}
+			value = strings.TrimSpace(value)
+			switch strings.ToLower(strings.TrimSpace(name)) {
+			case "host":
+				msg.host = value
+			case "authorization":
+				msg.basic = strings.HasPrefix(strings.ToLower(value), "basic ")
+			case "content-length":
+				if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+					contentLength = n
+				}
+			case "transfer-encoding":
+				chunked = strings.Contains(strings.ToLower(value), "chunked")
+			}
+		}
+		messages = append(messages, msg)
+		pos += headEnd + sepLen
+
+		// Skip the body to reach the next message
+		noBody := false
+		if !requests {
+			index := len(messages) - 1
+			noBody = index < len(methods) && methods[index] == "HEAD" ||
+				strings.HasPrefix(msg.status, "1") || msg.status == "204" || msg.status == "304"
+		}
+		switch {
+		case noBody:
+		case chunked:
+			end, ok := skipChunkedBody(data, pos)
+			if !ok {
+				return messages
+			}
+			pos = end
+		case contentLength >= 0:
+			pos += contentLength
+		case !requests:
+			return messages // body runs to the end of the connection
+		}
+	}
+	return messages
+}
+
+// skipChunkedBody returns the offset after a chunked body starting at pos
+func skipChunkedBody(data []byte, pos int) (int, bool) {
+	for {
+		lineEnd := bytes.IndexByte(data[pos:], '\n')
+		if lineEnd < 0 {
+			return 0, false
+		}
+		sizeField, _, _ := strings.Cut(strings.TrimSpace(string(data[pos:pos+lineEnd])), ";")
+		size, err := strconv.ParseInt(sizeField, 16, 64)
+		if err != nil || size < 0 {
+			return 0, false
+		}
+		pos += lineEnd + 1
+		if size == 0 {
+			// Trailer section ends with an empty line
+			for {
+				lineEnd := bytes.IndexByte(data[pos:], '\n')
+				if lineEnd < 0 {
+					return len(data), true
+				}
+				line := strings.TrimSpace(string(data[pos : pos+lineEnd]))
+				pos += lineEnd + 1
+				if line == "" {
+					return pos, true
+				}
+			}
+		}
+		pos += int(size)
+		if pos > len(data) {
+			return 0, false
+		}
+		// CRLF after the chunk data
+		if pos < len(data) && data[pos] == '\r' {
+			pos++
+		}
+		if pos < len(data) && data[pos] == '\n' {
+			pos++
+		}
+	}
+}
+
+// addHTTP records the requests of a stream, pairing responses by order
+func (p *pcapAnalysis) addHTTP(requests, responses *streamBuffer, client, server string) {
+	reqs := parseHTTPMessages(requests.data, true, nil)
+	var methods []string
+	for _, req := range reqs {
+		methods = append(methods, req.method)
+	}
+	var resps []httpMessage
+	if responses != nil && responses.kind == "http-response" {
+		resps = parseHTTPMessages(responses.data, false, methods)
+	}
+
+	for i, req := range reqs {
+		host := req.host
+		if host == "" {
+			host = server
+		}
+		path := req.target
+		if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+			// Absolute-form targets (proxies) carry the host themselves
+			if rest, ok := strings.CutPrefix(path[strings.Index(path, "//")+2:], host); ok && strings.HasPrefix(rest, "/") {
+				path = rest
+			}
+		}
+
+		key := req.method + " " + host + path
+		record, ok := p.http[key]
+		if !ok {
+			if len(p.http) >= maxTrackedFlows {
+				continue
+			}
+			record = &httpRecord{method: req.method, host: host, path: path}
+			p.http[key] = record
+		}
+		record.seen(hostOf(client), requests.timeAt(req.offset))
+		if i < len(resps) {
+			record.statuses.add(resps[i].status)
+		}
+		if req.basic {
+			p.recordCleartext("HTTP", client, server, requests.timeAt(req.offset), []string{"Authorization: Basic"})
+		}
+	}
+}
+
+// clientHello holds the fields of a TLS ClientHello used for reporting
+type clientHello struct {
+	serverName string
+	alpn       []string
+}
+
+// parseClientHello reads a ClientHello from TLS handshake records at the
+// start of a stream, joining handshake fragments across records
+func parseClientHello(data []byte) (*clientHello, bool) {
+	var handshake []byte
+	for len(data) >= 5 && data[0] == 0x16 {
+		length := int(binary.BigEndian.Uint16(data[3:5]))
+		if len(data) < 5+length {
+			handshake = append(handshake, data[5:]...)
+			break
+		}
+		handshake = append(handshake, data[5:5+length]...)
+		data = data[5+length:]
+		if len(handshake) >= 4 && len(handshake) >= 4+int(handshake[1])<<16|int(handshake[2])<<8|int(handshake[3]) {
+			break
+		}
+	}
+
+	if len(handshake) < 4 || handshake[0] != 1 {
+		return nil, false
+	}
+	body := handshake[4:]
+	if n := int(handshake[1])<<16 | int(handshake[2])<<8 | int(handshake[3]); n < len(body) {
+		body = body[:n]
+	}
+
+	r := &byteReader{data: body}
+	r.skip(2 + 32) // version, random
+	r.skip(int(r.uint8()))
+	r.skip(int(r.uint16()))
+	r.skip(int(r.uint8()))
+	extensions := &byteReader{data: r.bytes(int(r.uint16()))}
+	if r.err {
+		return nil, false
+	}
+
+	hello := &clientHello{}
+	for !extensions.err && len(extensions.data) >= 4 {
+		extType := extensions.uint16()
+		ext := &byteReader{data: extensions.bytes(int(extensions.uint16()))}
+		switch extType {
+		case 0: // server_name
+			list := &byteReader{data: ext.bytes(int(ext.uint16()))}
+			for !list.err && len(list.data) > 0 {
+				nameType := list.uint8()
+				name := list.bytes(int(list.uint16()))
+				if nameType == 0 && !list.err && hello.serverName == "" {
+					hello.serverName = string(name)
+				}
+			}
+		case 16: // application_layer_protocol_negotiation
+			list := &byteReader{data: ext.bytes(int(ext.uint16()))}
+			for !list.err && len(list.data) > 0 {
+				proto := list.bytes(int(list.uint8()))
+				if !list.err {
+					hello.alpn = append(hello.alpn, string(proto))
+				}
+			}
+		}
+	}
+	return hello, true
+}
+
+// byteReader reads big-endian fields, setting err instead of panicking on
+// short input
+type byteReader struct {
+	data []byte
+	err  bool
+}
+
+func (r *byteReader) bytes(n int) []byte {
+	if r.err || n > len(r.data) {
+		r.err = true
+		r.data = nil
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *byteReader) skip(n int) { r.bytes(n) }
+
+func (r *byteReader) uint8() uint8 {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *byteReader) uint16() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+// addTLS records the ClientHello at the start of a stream
+func (p *pcapAnalysis) addTLS(buffer *streamBuffer, client, server string) {
+	hello, ok := parseClientHello(buffer.data)
+	if !ok {
+		return
+	}
+	serverName := hello.serverName
+	if serverName == "" {
+		serverName = "(no SNI)"
+	}
+
+	record, ok := p.tls[serverName]
+	if !ok {
+		if len(p.tls) >= maxTrackedFlows {
+			return
+		}
+		record = &tlsRecord{serverName: serverName}
+		p.tls[serverName] = record
+	}
+	record.seen(hostOf(client), buffer.timeAt(0))
+	record.servers.add(server)
+	for _, proto := range hello.alpn {
+		record.alpn.add(proto)
+	}
+}
+
+// hostOf strips the port from an "ip:port" endpoint
+func hostOf(endpoint string) string {
+	idx := strings.LastIndexByte(endpoint, ':')
+	if idx < 0 {
+		return endpoint
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(endpoint[:idx], "["), "]")
+}
+
+// topRecords orders records by count (ties by first sight) and applies maxAppRecords
+func topRecords[T any](records map[string]*T, base func(*T) *appRecord, key func(*T) string) []*T {
+	list := sortedRecords(records, base, key)
+	if len(list) > maxAppRecords {
+		list = list[:maxAppRecords]
+	}
+	return list
+}
+
+// sortedRecords orders records by count, ties by first sight
+func sortedRecords[T any](records map[string]*T, base func(*T) *appRecord, key func(*T) string) []*T {
+	list := make([]*T, 0, len(records))
+	for _, record := range records {
+		list = append(list, record)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := base(list[i]), base(list[j])
+		if a.count != b.count {
+			return a.count > b.count
+		}
+		if !a.first.Equal(b.first) {
+			return a.first.Before(b.first)
+		}
+		return key(list[i]) < key(list[j])
+	})
+	return list
+}
+
+// formatRecordTime formats a first/last seen timestamp
+func formatRecordTime(ts time.Time) string {
+	if ts.IsZero() {
+		return "-"
+	}
+	return ts.UTC().Format("2006-01-02 15:04:05")
+}
+
+// renderApplicationLayer writes the DNS, HTTP and TLS tables
+func (p *pcapAnalysis) renderApplicationLayer(builder *strings.Builder) {
+	if len(p.dns) > 0 {
+		records := topRecords(p.dns, func(r *dnsRecord) *appRecord { return &r.appRecord }, func(r *dnsRecord) string { return r.name })
+		builder.WriteString("\n## DNS queries\n\n")
+		if len(p.dns) > len(records) {
+			builder.WriteString(fmt.Sprintf("Top %d of %d distinct queries.\n\n", len(records), len(p.dns)))
+		}
+		builder.WriteString("| Query | Type | Count | Clients | Answers | First seen | Last seen |\n")
+		builder.WriteString("|---|---|---|---|---|---|---|\n")
+		for _, r := range records {
+			builder.WriteString(fmt.Sprintf("| %s | %s | %d | %s | %s | %s | %s |\n",
+				escapeTableCell(r.name), r.qtype, r.count, escapeTableCell(r.clients.String()),
+				escapeTableCell(r.answers.String()), formatRecordTime(r.first), formatRecordTime(r.last)))
+		}
+	}
+
+	if len(p.http) > 0 {
+		records := topRecords(p.http, func(r *httpRecord) *appRecord { return &r.appRecord }, func(r *httpRecord) string { return r.host + r.path })
+		builder.WriteString("\n## HTTP requests\n\n")
+		if len(p.http) > len(records) {
+			builder.WriteString(fmt.Sprintf("Top %d of %d distinct requests.\n\n", len(records), len(p.http)))
+		}
+		builder.WriteString("| Method | Host | Path | Count | Status | Clients | First seen | Last seen |\n")
+		builder.WriteString("|---|---|---|---|---|---|---|---|\n")
+		for _, r := range records {
+			builder.WriteString(fmt.Sprintf("| %s | %s | %s | %d | %s | %s | %s | %s |\n",
+				r.method, escapeTableCell(r.host), escapeTableCell(r.path), r.count, r.statuses.String(),
+				escapeTableCell(r.clients.String()), formatRecordTime(r.first), formatRecordTime(r.last)))
+		}
+	}
+
+	if len(p.tls) > 0 {
+		records := topRecords(p.tls, func(r *tlsRecord) *appRecord { return &r.appRecord }, func(r *tlsRecord) string { return r.serverName })
+		builder.WriteString("\n## TLS client hellos\n\n")
+		if len(p.tls) > len(records) {
+			builder.WriteString(fmt.Sprintf("Top %d of %d distinct server names.\n\n", len(records), len(p.tls)))
+		}
+		builder.WriteString("| Server name (SNI) | ALPN | Count | Clients | Servers | First seen | Last seen |\n")
+		builder.WriteString("|---|---|---|---|---|---|---|\n")
+		for _, r := range records {
+			builder.WriteString(fmt.Sprintf("| %s | %s | %d | %s | %s | %s | %s |\n",
+				escapeTableCell(r.serverName), escapeTableCell(r.alpn.String()), r.count,
+				escapeTableCell(r.clients.String()), escapeTableCell(r.servers.String()),
+				formatRecordTime(r.first), formatRecordTime(r.last)))
+		}
+	}
+}
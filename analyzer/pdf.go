@@ -0,0 +1,764 @@
+package analyzer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/michalswi/pdf-reader/pdf"
+)
+
+var (
+	// ErrPDFEncrypted is returned for password-protected PDFs
+	ErrPDFEncrypted = errors.New("PDF is encrypted; decrypt it before analysis")
+	// ErrPDFImageOnly is returned for scanned PDFs without a text layer
+	ErrPDFImageOnly = errors.New("PDF has no text layer (scanned or image-only); run OCR before analysis")
+)
+
+// maxPDFFormDepth bounds nested form XObjects
+const maxPDFFormDepth = 8
+
+// pdfPage is the extracted text of one page
+type pdfPage struct {
+	text   string
+	images int
+}
+
+// ReadPDFContent extracts text from a PDF file, one "## Page N" section per
+// page, and returns the document metadata (title, author, page count, ...).
+// Files the native parser cannot read fall back to plain text extraction
+// without page boundaries.
+func (d *Detector) ReadPDFContent(path string) (string, map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	doc, err := parsePDF(data)
+	if err != nil {
+		text, fallbackErr := readPDFPlainText(path)
+		if fallbackErr != nil {
+			return "", nil, fmt.Errorf("failed to parse PDF: %w", err)
+		}
+		return text, nil, nil
+	}
+
+	if _, ok := doc.trailer["Encrypt"]; ok {
+		return "", nil, ErrPDFEncrypted
+	}
+
+	metadata := doc.metadata()
+	pages := doc.pages()
+	metadata["Pages"] = strconv.Itoa(len(pages))
+
+	var builder strings.Builder
+	images := 0
+	hasText := false
+	for i, page := range pages {
+		images += page.images
+		if page.text == "" && page.images == 0 {
+			continue
+		}
+
+		if builder.Len() > 0 {
+			builder.WriteString("\n\n")
+		}
+		builder.WriteString(fmt.Sprintf("## Page %d\n\n", i+1))
+		if page.text == "" {
+			builder.WriteString("[no text layer; page contains only images]")
+			continue
+		}
+		builder.WriteString(page.text)
+		hasText = true
+	}
+
+	if !hasText {
+		if images > 0 {
+			return "", metadata, ErrPDFImageOnly
+		}
+		return "", metadata, fmt.Errorf("no text content extracted from PDF")
+	}
+	return builder.String(), metadata, nil
+}
+
+// readPDFPlainText extracts the whole document as one blob using the simple
+// stream scraper, for files whose structure the native parser rejects
+func readPDFPlainText(path string) (string, error) {
+	f, reader, err := pdf.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open PDF: %w", err)
+	}
+	defer f.Close()
+
+	textReader, err := reader.GetPlainText()
+	if err != nil {
+		return "", fmt.Errorf("failed to extract text from PDF: %w", err)
+	}
+
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(textReader)
+	if err != nil {
+		return "", fmt.Errorf("failed to read extracted text: %w", err)
+	}
+
+	text := strings.TrimSpace(buf.String())
+	if text == "" {
+		return "", fmt.Errorf("no text content extracted from PDF")
+	}
+	return text, nil
+}
+
+// pdfInfoKeys maps document information entries to metadata keys
+var pdfInfoKeys = []struct {
+	entry pdfName
+	key   string
+}{
+	{"Title", "Title"},
+	{"Author", "Author"},
+	{"Subject", "Subject"},
+	{"Keywords", "Keywords"},
+	{"Creator", "Creator"},
+	{"Producer", "Producer"},
+	{"CreationDate", "Created"},
+	{"ModDate", "Modified"},
+}
+
+// metadata reads the document information dictionary
+func (doc *pdfDocument) metadata() map[string]string {
+	metadata := make(map[string]string)
+	info := doc.dict(doc.trailer["Info"])
+	for _, k := range pdfInfoKeys {
+		s, ok := doc.resolve(info[k.entry]).(pdfString)
+		if !ok {
+			continue
+		}
+		value := strings.TrimSpace(decodePDFText(s))
+		if strings.HasSuffix(string(k.entry), "Date") {
+			value = formatPDFDate(value)
+		}
+		if value != "" {
+			metadata[k.key] = value
+		}
+	}
+	return metadata
+}
+
+// decodePDFText decodes a PDF text string: UTF-16BE with a byte order mark,
+// UTF-8 with a byte order mark, or PDFDocEncoding (treated as Latin-1)
+func decodePDFText(s pdfString) string {
+	switch {
+	case len(s) >= 2 && s[0] == 0xFE && s[1] == 0xFF:
+		return decodeUTF16BE(s[2:])
+	case len(s) >= 3 && s[0] == 0xEF && s[1] == 0xBB && s[2] == 0xBF:
+		return string(s[3:])
+	}
+	runes := make([]rune, len(s))
+	for i, b := range s {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+func decodeUTF16BE(b []byte) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+	return string(utf16.Decode(units))
+}
+
+// formatPDFDate turns "D:20240131120000+01'00'" into "2024-01-31"
+func formatPDFDate(value string) string {
+	v := strings.TrimPrefix(value, "D:")
+	if len(v) < 8 {
+		return value
+	}
+	for _, c := range v[:8] {
+		if c < '0' || c > '9' {
+			return value
+		}
+	}
+	return v[0:4] + "-" + v[4:6] + "-" + v[6:8]
+}
+
+// pages walks the page tree and extracts the text of every page in order
+func (doc *pdfDocument) pages() []pdfPage {
+	root := doc.dict(doc.trailer["Root"])
+	extractor := &pdfTextExtractor{doc: doc, fonts: make(map[pdfRef]*pdfFont)}
+
+	var pages []pdfPage
+	visited := make(map[pdfRef]bool)
+	var walk func(node interface{}, resources interface{}, depth int)
+	walk = func(node interface{}, resources interface{}, depth int) {
+		if ref, ok := node.(pdfRef); ok {
+			if visited[ref] {
+				return
+			}
+			visited[ref] = true
+		}
+		dict := doc.dict(node)
+		if dict == nil || depth > 64 {
+			return
+		}
+		if r, ok := dict["Resources"]; ok {
+			resources = r
+		}
+
+		if kids, ok := doc.resolve(dict["Kids"]).(pdfArray); ok && dict["Type"] != pdfName("Page") {
+			for _, kid := range kids {
+				walk(kid, resources, depth+1)
+			}
+			return
+		}
+		pages = append(pages, extractor.page(dict, doc.dict(resources)))
+	}
+	walk(root["Pages"], nil, 0)
+
+	return pages
+}
+
+// pdfTextExtractor interprets content streams, keeping only text
+type pdfTextExtractor struct {
+	doc   *pdfDocument
+	fonts map[pdfRef]*pdfFont
+
+	out      strings.Builder
+	images   int
+	font     *pdfFont
+	y        float64 // baseline of the current text line
+	lastY    float64 // baseline of the last text written
+	leading  float64
+	moved    bool // text position changed since the last text written
+	newline  bool // a line break was requested explicitly
+	wroteAny bool
+}
+
+// page extracts the text of one page
+func (e *pdfTextExtractor) page(dict pdfDict, resources pdfDict) pdfPage {
+	e.out.Reset()
+	e.images = 0
+	e.font = nil
+	e.wroteAny = false
+
+	var content []byte
+	switch c := e.doc.resolve(dict["Contents"]).(type) {
+	case *pdfStream:
+		content, _ = e.doc.decodeStream(c)
+	case pdfArray:
+		for _, part := range c {
+			if stream, ok := e.doc.resolve(part).(*pdfStream); ok {
+				if data, err := e.doc.decodeStream(stream); err == nil {
+					content = append(append(content, data...), '\n')
+				}
+			}
+		}
+	}
+	e.run(content, resources, 0)
+
+	return pdfPage{text: cleanPDFText(e.out.String()), images: e.images}
+}
+
+// run interprets a content stream
+func (e *pdfTextExtractor) run(content []byte, resources pdfDict, depth int) {
+	lex := &pdfLexer{data: content}
+	var operands []interface{}
+
+	number := func(i int) float64 {
+		if i >= len(operands) {
+			return 0
+		}
+		switch v := operands[i].(type) {
+		case int:
+			return float64(v)
+		case float64:
+			return v
+		}
+		return 0
+	}
+
+	for {
+		tok, err := lex.token()
+		if err != nil {
+			return
+		}
+		obj, err := lex.objectFrom(tok, 0)
+		if err != nil {
+			return
+		}
+		op, isOp := obj.(pdfKeyword)
+		if !isOp {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch op {
+		case "BT":
+			e.y = 0
+			e.moved = true
+		case "Tf":
+			if len(operands) > 0 {
+				if name, ok := operands[0].(pdfName); ok {
+					e.font = e.loadFont(e.doc.dict(resources["Font"])[name])
+				}
+			}
+		case "TL":
+			e.leading = number(0)
+		case "Td":
+			e.y += number(1)
+			e.moved = true
+		case "TD":
+			e.leading = -number(1)
+			e.y += number(1)
+			e.moved = true
+		case "Tm":
+			e.y = number(5)
+			e.moved = true
+		case "T*":
+			e.y -= e.leading
+			e.newline = true
+		case "Tj":
+			if len(operands) > 0 {
+				e.show(operands[0])
+			}
+		case "'":
+			e.y -= e.leading
+			e.newline = true
+			if len(operands) > 0 {
+				e.show(operands[0])
+			}
+		case "\"":
+			e.y -= e.leading
+			e.newline = true
+			if len(operands) > 2 {
+				e.show(operands[2])
+			}
+		case "TJ":
+			if len(operands) > 0 {
+				if arr, ok := operands[0].(pdfArray); ok {
+					for _, item := range arr {
+						switch v := item.(type) {
+						case pdfString:
+							e.show(v)
+						case int:
+							e.kern(float64(v))
+						case float64:
+							e.kern(v)
+						}
+					}
+				}
+			}
+		case "Do":
+			if len(operands) > 0 {
+				if name, ok := operands[0].(pdfName); ok {
+					e.xobject(e.doc.dict(resources["XObject"])[name], resources, depth)
+				}
+			}
+		case "BI":
+			e.images++
+			skipInlineImage(lex)
+		}
+		operands = operands[:0]
+	}
+}
+
+// kern handles a TJ adjustment; large negative values separate words
+func (e *pdfTextExtractor) kern(adjust float64) {
+	if adjust < -180 {
+		e.moved = true
+	}
+}
+
+// show writes a text string at the current position
+func (e *pdfTextExtractor) show(obj interface{}) {
+	s, ok := obj.(pdfString)
+	if !ok {
+		return
+	}
+
+	var text string
+	if e.font != nil {
+		text = e.font.decode(s)
+	} else {
+		text = decodePDFText(s)
+	}
+	if text == "" {
+		return
+	}
+
+	if e.wroteAny {
+		switch {
+		case e.newline || math.Abs(e.y-e.lastY) > 1:
+			e.out.WriteString("\n")
+		case e.moved && !strings.HasSuffix(e.out.String(), " ") && !strings.HasPrefix(text, " "):
+			e.out.WriteString(" ")
+		}
+	}
+	e.out.WriteString(text)
+	e.lastY = e.y
+	e.moved = false
+	e.newline = false
+	e.wroteAny = true
+}
+
+// xobject counts images and extracts text from form XObjects
+func (e *pdfTextExtractor) xobject(ref interface{}, parent pdfDict, depth int) {
+	stream, ok := e.doc.resolve(ref).(*pdfStream)
+	if !ok {
+		return
+	}
+	switch stream.dict["Subtype"] {
+	case pdfName("Image"):
+		e.images++
+	case pdfName("Form"):
+		if depth >= maxPDFFormDepth {
+			return
+		}
+		data, err := e.doc.decodeStream(stream)
+		if err != nil {
+			return
+		}
+		resources := parent
+		if r := e.doc.dict(stream.dict["Resources"]); r != nil {
+			resources = r
+		}
+		font := e.font
+		e.run(data, resources, depth+1)
+		e.font = font
+	}
+}
+
+// skipInlineImage moves past the binary data of an inline image (BI ... ID data EI)
+func skipInlineImage(lex *pdfLexer) {
+	idx := bytes.Index(lex.data[lex.pos:], []byte("ID"))
+	if idx < 0 {
+		lex.pos = len(lex.data)
+		return
+	}
+	pos := lex.pos + idx + 2
+	for pos+2 <= len(lex.data) {
+		next := bytes.Index(lex.data[pos:], []byte("EI"))
+		if next < 0 {
+			break
+		}
+		at := pos + next
+		before := at == 0 || isPDFWhitespace(lex.data[at-1])
+		after := at+2 == len(lex.data) || isPDFWhitespace(lex.data[at+2])
+		if before && after {
+			lex.pos = at + 2
+			return
+		}
+		pos = at + 2
+	}
+	lex.pos = len(lex.data)
+}
+
+// cleanPDFText trims trailing spaces and collapses runs of blank lines
+func cleanPDFText(text string) string {
+	lines := strings.Split(text, "\n")
+	var out []string
+	blank := false
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			if !blank && len(out) > 0 {
+				out = append(out, "")
+			}
+			blank = true
+			continue
+		}
+		blank = false
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// pdfFont maps character codes of one font to text
+type pdfFont struct {
+	codeLen   int // bytes per character code
+	toUnicode map[uint32]string
+	encoding  [256]rune
+}
+
+// loadFont builds the decoder for a font resource
+func (e *pdfTextExtractor) loadFont(obj interface{}) *pdfFont {
+	ref, isRef := obj.(pdfRef)
+	if isRef {
+		if font, ok := e.fonts[ref]; ok {
+			return font
+		}
+	}
+
+	dict := e.doc.dict(obj)
+	font := &pdfFont{codeLen: 1}
+	if dict["Subtype"] == pdfName("Type0") {
+		font.codeLen = 2
+	}
+	font.encoding = e.simpleEncoding(dict["Encoding"])
+
+	if stream, ok := e.doc.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+		if data, err := e.doc.decodeStream(stream); err == nil {
+			mapping, codeLen := parseToUnicodeCMap(data)
+			if len(mapping) > 0 {
+				font.toUnicode = mapping
+				if codeLen > 0 {
+					font.codeLen = codeLen
+				}
+			}
+		}
+	}
+
+	if isRef {
+		e.fonts[ref] = font
+	}
+	return font
+}
+
+// simpleEncoding resolves a simple font's /Encoding name or dictionary
+func (e *pdfTextExtractor) simpleEncoding(obj interface{}) [256]rune {
+	var table [256]rune
+	base := pdfName("StandardEncoding")
+	var differences pdfArray
+
+	switch v := e.doc.resolve(obj).(type) {
+	case pdfName:
+		base = v
+	case pdfDict:
+		if name, ok := e.doc.resolve(v["BaseEncoding"]).(pdfName); ok {
+			base = name
+		}
+		differences, _ = e.doc.resolve(v["Differences"]).(pdfArray)
+	}
+
+	for i := range table {
+		switch base {
+		case "MacRomanEncoding":
+			table[i] = decodeMacRoman(byte(i))
+		default:
+			table[i] = decodeCP1252(byte(i))
+		}
+	}
+	if base == "StandardEncoding" {
+		table['\''] = '’'
+		table['`'] = '‘'
+	}
+
+	code := 0
+	for _, item := range differences {
+		switch v := item.(type) {
+		case int:
+			code = v
+		case pdfName:
+			if code >= 0 && code < 256 {
+				if r := glyphToRune(string(v)); r != 0 {
+					table[code] = r
+				}
+			}
+			code++
+		}
+	}
+	return table
+}
+
+// decode maps a shown string to text
+func (f *pdfFont) decode(s pdfString) string {
+	var builder strings.Builder
+	for i := 0; i+f.codeLen <= len(s); i += f.codeLen {
+		var code uint32
+		for j := 0; j < f.codeLen; j++ {
+			code = code<<8 | uint32(s[i+j])
+		}
+		if text, ok := f.toUnicode[code]; ok {
+			builder.WriteString(text)
+			continue
+		}
+		if f.codeLen == 1 {
+			if r := f.encoding[code]; r >= ' ' || r == '\t' {
+				builder.WriteRune(r)
+			}
+		}
+		// Multi-byte codes without a ToUnicode entry cannot be mapped
+	}
+	return strings.ReplaceAll(builder.String(), "\x00", "")
+}
+
+// parseToUnicodeCMap reads bfchar and bfrange mappings from a ToUnicode
+// CMap and returns the code length of its first codespace range
+func parseToUnicodeCMap(data []byte) (map[uint32]string, int) {
+	mapping := make(map[uint32]string)
+	codeLen := 0
+	lex := &pdfLexer{data: data}
+
+	next := func() interface{} {
+		tok, err := lex.token()
+		if err != nil {
+			return nil
+		}
+		obj, err := lex.objectFrom(tok, 0)
+		if err != nil {
+			return nil
+		}
+		return obj
+	}
+	code := func(s pdfString) uint32 {
+		var v uint32
+		for _, b := range s {
+			v = v<<8 | uint32(b)
+		}
+		return v
+	}
+
+	for {
+		tok, err := lex.token()
+		if err != nil {
+			break
+		}
+		kw, ok := tok.(pdfKeyword)
+		if !ok {
+			continue
+		}
+
+		switch kw {
+		case "begincodespacerange":
+			for {
+				lo, ok := next().(pdfString)
+				if !ok {
+					break
+				}
+				next()
+				if codeLen == 0 {
+					codeLen = len(lo)
+				}
+			}
+		case "beginbfchar":
+			for {
+				src, ok := next().(pdfString)
+				if !ok {
+					break
+				}
+				if dst, ok := next().(pdfString); ok {
+					mapping[code(src)] = decodeUTF16BE(dst)
+				}
+			}
+		case "beginbfrange":
+			for {
+				lo, ok := next().(pdfString)
+				if !ok {
+					break
+				}
+				hi, _ := next().(pdfString)
+				first, last := code(lo), code(hi)
+				if last < first || last-first > 0xFFFF {
+					next()
+					continue
+				}
+
+				switch dst := next().(type) {
+				case pdfString:
+					units := utf16.Decode(bytesToUTF16(dst))
+					if len(units) == 0 {
+						continue
+					}
+					for c := first; c <= last; c++ {
+						runes := append([]rune(nil), units...)
+						runes[len(runes)-1] += rune(c - first)
+						mapping[c] = string(runes)
+					}
+				case pdfArray:
+					for i, item := range dst {
+						if s, ok := item.(pdfString); ok && first+uint32(i) <= last {
+							mapping[first+uint32(i)] = decodeUTF16BE(s)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return mapping, codeLen
+}
+
+func bytesToUTF16(b []byte) []uint16 {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+	return units
+}
+
+// macRoman holds MacRomanEncoding for bytes 0x80-0xFF
+var macRoman = []rune("ÄÅÇÉÑÖÜáàâäãåçéèêëíìîïñóòôöõúùûü†°¢£§•¶ß®©™´¨≠ÆØ∞±≤≥¥µ∂∑∏π∫ªºΩæø¿¡¬√ƒ≈∆«»… ÀÃÕŒœ–—“”‘’÷◊ÿŸ⁄€‹›ﬁﬂ‡·‚„‰ÂÊÁËÈÍÎÏÌÓÔÒÚÛÙıˆ˜¯˘˙˚¸˝˛ˇ")
+
+func decodeMacRoman(b byte) rune {
+	if b >= 0x80 && int(b-0x80) < len(macRoman) {
+		return macRoman[b-0x80]
+	}
+	return rune(b)
+}
+
+// glyphNames maps common Adobe glyph names used in /Differences arrays
+var glyphNames = map[string]rune{
+	"space": ' ', "exclam": '!', "quotedbl": '"', "numbersign": '#', "dollar": '$',
+	"percent": '%', "ampersand": '&', "quotesingle": '\'', "parenleft": '(',
+	"parenright": ')', "asterisk": '*', "plus": '+', "comma": ',', "hyphen": '-',
+	"period": '.', "slash": '/', "zero": '0', "one": '1', "two": '2', "three": '3',
+	"four": '4', "five": '5', "six": '6', "seven": '7', "eight": '8', "nine": '9',
+	"colon": ':', "semicolon": ';', "less": '<', "equal": '=', "greater": '>',
+	"question": '?', "at": '@', "bracketleft": '[', "backslash": '\\',
+	"bracketright": ']', "asciicircum": '^', "underscore": '_', "grave": '`',
+	"braceleft": '{', "bar": '|', "braceright": '}', "asciitilde": '~',
+	"quoteleft": '‘', "quoteright": '’', "quotedblleft": '“', "quotedblright": '”',
+	"quotesinglbase": '‚', "quotedblbase": '„', "bullet": '•', "endash": '–',
+	"emdash": '—', "ellipsis": '…', "dagger": '†', "daggerdbl": '‡',
+	"trademark": '™', "copyright": '©', "registered": '®', "degree": '°',
+	"section": '§', "paragraph": '¶', "periodcentered": '·', "minus": '−',
+	"multiply": '×', "divide": '÷', "Euro": '€', "sterling": '£', "yen": '¥',
+	"cent": '¢', "nbspace": ' ', "fi": 'ﬁ', "fl": 'ﬂ', "ff": 'ﬀ', "ffi": 'ﬃ',
+	"ffl": 'ﬄ', "germandbls": 'ß', "dotlessi": 'ı', "guillemotleft": '«',
+	"guillemotright": '»', "plusminus": '±', "mu": 'µ', "OE": 'Œ', "oe": 'œ',
+}
+
+// latin1LetterNames are the glyph names of U+00C0..U+00FF in order
+var latin1LetterNames = strings.Fields(`Agrave Aacute Acircumflex Atilde Adieresis
+	Aring AE Ccedilla Egrave Eacute Ecircumflex Edieresis Igrave Iacute Icircumflex
+	Idieresis Eth Ntilde Ograve Oacute Ocircumflex Otilde Odieresis multiply Oslash
+	Ugrave Uacute Ucircumflex Udieresis Yacute Thorn germandbls agrave aacute
+	acircumflex atilde adieresis aring ae ccedilla egrave eacute ecircumflex edieresis
+	igrave iacute icircumflex idieresis eth ntilde ograve oacute ocircumflex otilde
+	odieresis divide oslash ugrave uacute ucircumflex udieresis yacute thorn ydieresis`)
+
+// glyphToRune maps a glyph name to a rune, or 0 when unknown
+func glyphToRune(name string) rune {
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		name = name[:i] // variants such as "a.sc"
+	}
+	if utf8.RuneCountInString(name) == 1 {
+		r, _ := utf8.DecodeRuneInString(name)
+		return r
+	}
+	if r, ok := glyphNames[name]; ok {
+		return r
+	}
+	for i, n := range latin1LetterNames {
+		if n == name {
+			return rune(0xC0 + i)
+		}
+	}
+	for _, prefix := range []string{"uni", "u"} {
+		if strings.HasPrefix(name, prefix) && len(name) >= len(prefix)+4 {
+			hexDigits := name[len(prefix):]
+			if prefix == "uni" {
+				hexDigits = hexDigits[:4]
+			}
+			if v, err := strconv.ParseUint(hexDigits, 16, 32); err == nil && utf8.ValidRune(rune(v)) {
+				return rune(v)
+			}
+		}
+	}
+	return 0
+}
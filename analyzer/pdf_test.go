@@ -0,0 +1,288 @@
+package analyzer
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// testPDF builds small PDF files in memory
+type testPDF struct {
+	buf     bytes.Buffer
+	offsets map[int]int
+}
+
+func newTestPDF() *testPDF {
+	p := &testPDF{offsets: make(map[int]int)}
+	p.buf.WriteString("%PDF-1.7\n%\xE2\xE3\xCF\xD3\n")
+	return p
+}
+
+// obj writes an indirect object
+func (p *testPDF) obj(num int, body string) {
+	p.offsets[num] = p.buf.Len()
+	fmt.Fprintf(&p.buf, "%d 0 obj\n%s\nendobj\n", num, body)
+}
+
+// stream writes a stream object. dict holds the entries other than /Length.
+func (p *testPDF) stream(num int, dict string, data []byte) {
+	p.offsets[num] = p.buf.Len()
+	fmt.Fprintf(&p.buf, "%d 0 obj\n<< %s /Length %d >>\nstream\n", num, dict, len(data))
+	p.buf.Write(data)
+	p.buf.WriteString("\nendstream\nendobj\n")
+}
+
+// objectStream writes objs into a compressed object stream and returns the
+// index of each object in it
+func (p *testPDF) objectStream(num int, objs map[int]string) map[int]int {
+	nums := make([]int, 0, len(objs))
+	for n := range objs {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+
+	var header, body bytes.Buffer
+	index := make(map[int]int)
+	for i, n := range nums {
+		fmt.Fprintf(&header, "%d %d ", n, body.Len())
+		body.WriteString(objs[n] + "\n")
+		index[n] = i
+	}
+	dict := fmt.Sprintf("/Type /ObjStm /N %d /First %d /Filter /FlateDecode", len(nums), header.Len())
+	p.stream(num, dict, deflate(append(header.Bytes(), body.Bytes()...)))
+	return index
+}
+
+// classicXref writes an xref table and trailer covering objects 0..size-1
+func (p *testPDF) classicXref(size int, trailer string) []byte {
+	start := p.buf.Len()
+	fmt.Fprintf(&p.buf, "xref\n0 %d\n0000000000 65535 f \n", size)
+	for n := 1; n < size; n++ {
+		if off, ok := p.offsets[n]; ok {
+			fmt.Fprintf(&p.buf, "%010d 00000 n \n", off)
+		} else {
+			p.buf.WriteString("0000000000 00000 f \n")
+		}
+	}
+	fmt.Fprintf(&p.buf, "trailer\n<< /Size %d %s >>\nstartxref\n%d\n%%%%EOF\n", size, trailer, start)
+	return p.buf.Bytes()
+}
+
+// xrefStream writes a cross-reference stream as object num, compressed with
+// the PNG Up predictor. inStream maps objects to their object stream and index.
+func (p *testPDF) xrefStream(num, objStm int, inStream map[int]int, trailer string) []byte {
+	p.offsets[num] = p.buf.Len()
+	size := num + 1
+
+	const columns = 7 // W [1 4 2]
+	var rows []byte
+	prev := make([]byte, columns)
+	for n := 0; n < size; n++ {
+		row := make([]byte, columns)
+		switch off, ok := p.offsets[n]; {
+		case n == num:
+			row[0] = 1
+			binary.BigEndian.PutUint32(row[1:], uint32(p.buf.Len()))
+		case ok:
+			row[0] = 1
+			binary.BigEndian.PutUint32(row[1:], uint32(off))
+		default:
+			if index, ok := inStream[n]; ok {
+				row[0] = 2
+				binary.BigEndian.PutUint32(row[1:], uint32(objStm))
+				binary.BigEndian.PutUint16(row[5:], uint16(index))
+			}
+		}
+		rows = append(rows, 2) // Up
+		for i := range row {
+			rows = append(rows, row[i]-prev[i])
+		}
+		prev = row
+	}
+
+	start := p.buf.Len()
+	dict := fmt.Sprintf("/Type /XRef /Size %d /W [1 4 2] /Filter /FlateDecode /DecodeParms << /Predictor 12 /Columns %d >> %s",
+		size, columns, trailer)
+	p.stream(num, dict, deflate(rows))
+	fmt.Fprintf(&p.buf, "startxref\n%d\n%%%%EOF\n", start)
+	return p.buf.Bytes()
+}
+
+func deflate(data []byte) []byte {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+}
+
+// Objects shared by the test documents: catalog (1), page tree (2), pages
+// (3, 4), their contents (5, 6), font (7) and info dictionary (8)
+var testPDFObjects = map[int]string{
+	1: "<< /Type /Catalog /Pages 2 0 R >>",
+	2: "<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /Resources << /Font << /F1 7 0 R >> >> >>",
+	3: "<< /Type /Page /Parent 2 0 R /Contents 5 0 R >>",
+	4: "<< /Type /Page /Parent 2 0 R /Contents 6 0 R >>",
+	7: "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+	8: "<< /Title (Quarterly report) /Author <FEFF004A006F> /CreationDate (D:20240301120000Z) >>",
+}
+
+const testPDFText = "## Page 1\n\nFirst page\nsecond line\n\n## Page 2\n\nLast page"
+
+func testPDFContents(p *testPDF) {
+	p.stream(5, "/Filter /FlateDecode", deflate([]byte("BT /F1 12 Tf 72 720 Td (First page) Tj 0 -14 Td (second line) Tj ET")))
+	p.stream(6, "", []byte("BT /F1 12 Tf 72 720 Td [(Last)-250(page)] TJ ET"))
+}
+
+func readTestPDF(t *testing.T, data []byte) (string, map[string]string, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.pdf")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return NewDetector().ReadPDFContent(path)
+}
+
+func TestReadPDFContentClassicXref(t *testing.T) {
+	p := newTestPDF()
+	for _, n := range []int{1, 2, 3, 4, 7, 8} {
+		p.obj(n, testPDFObjects[n])
+	}
+	testPDFContents(p)
+	data := p.classicXref(9, "/Root 1 0 R /Info 8 0 R")
+
+	text, metadata, err := readTestPDF(t, data)
+	if err != nil {
+		t.Fatalf("ReadPDFContent: %v", err)
+	}
+	if text != testPDFText {
+		t.Errorf("text = %q, want %q", text, testPDFText)
+	}
+	want := map[string]string{"Title": "Quarterly report", "Author": "Jo", "Created": "2024-03-01", "Pages": "2"}
+	for key, value := range want {
+		if metadata[key] != value {
+			t.Errorf("metadata[%s] = %q, want %q", key, metadata[key], value)
+		}
+	}
+}
+
+func TestReadPDFContentXrefAndObjectStreams(t *testing.T) {
+	p := newTestPDF()
+	testPDFContents(p)
+	index := p.objectStream(9, testPDFObjects)
+	data := p.xrefStream(10, 9, index, "/Root 1 0 R /Info 8 0 R")
+
+	doc, err := parsePDF(data)
+	if err != nil {
+		t.Fatalf("parsePDF: %v", err)
+	}
+	if entry := doc.xref[3]; !entry.inStream || entry.streamNum != 9 || entry.index != index[3] {
+		t.Errorf("xref[3] = %+v, want object %d of stream 9", entry, index[3])
+	}
+
+	text, metadata, err := readTestPDF(t, data)
+	if err != nil {
+		t.Fatalf("ReadPDFContent: %v", err)
+	}
+	if text != testPDFText {
+		t.Errorf("text = %q, want %q", text, testPDFText)
+	}
+	if metadata["Title"] != "Quarterly report" {
+		t.Errorf("Title = %q", metadata["Title"])
+	}
+
+	// Every object comes from one decoded copy of the object stream
+	if len(doc.objStms) != 1 || doc.objectStream(9) != doc.objStms[9] {
+		t.Errorf("object stream decoded %d times", len(doc.objStms))
+	}
+}
+
+func TestReadPDFContentDamagedXref(t *testing.T) {
+	p := newTestPDF()
+	for _, n := range []int{1, 2, 3, 4, 7, 8} {
+		p.obj(n, testPDFObjects[n])
+	}
+	testPDFContents(p)
+	data := p.classicXref(9, "/Root 1 0 R /Info 8 0 R")
+	// Point startxref into the middle of an object
+	data = bytes.Replace(data, []byte(fmt.Sprintf("startxref\n%d", bytes.LastIndex(data, []byte("xref\n0 ")))), []byte("startxref\n20"), 1)
+	if !bytes.Contains(data, []byte("startxref\n20\n")) {
+		t.Fatal("startxref not replaced")
+	}
+
+	text, _, err := readTestPDF(t, data)
+	if err != nil {
+		t.Fatalf("ReadPDFContent: %v", err)
+	}
+	if text != testPDFText {
+		t.Errorf("text = %q, want %q", text, testPDFText)
+	}
+}
+
+func TestReadPDFContentEncrypted(t *testing.T) {
+	p := newTestPDF()
+	for _, n := range []int{1, 2, 3, 4, 7} {
+		p.obj(n, testPDFObjects[n])
+	}
+	testPDFContents(p)
+	p.obj(8, "<< /Filter /Standard /V 2 /R 3 /O <00> /U <00> /P -4 >>")
+	data := p.classicXref(9, "/Root 1 0 R /Encrypt 8 0 R")
+
+	if _, _, err := readTestPDF(t, data); !errors.Is(err, ErrPDFEncrypted) {
+		t.Errorf("err = %v, want ErrPDFEncrypted", err)
+	}
+}
+
+func TestReadPDFContentImageOnly(t *testing.T) {
+	p := newTestPDF()
+	p.obj(1, testPDFObjects[1])
+	p.obj(2, "<< /Type /Pages /Kids [3 0 R] /Count 1 >>")
+	p.obj(3, "<< /Type /Page /Parent 2 0 R /Contents 4 0 R /Resources << /XObject << /Im0 5 0 R >> >> >>")
+	p.stream(4, "", []byte("q 612 0 0 792 0 0 cm /Im0 Do Q"))
+	p.stream(5, "/Type /XObject /Subtype /Image /Width 1 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 8", []byte{0})
+	data := p.classicXref(6, "/Root 1 0 R")
+
+	_, metadata, err := readTestPDF(t, data)
+	if !errors.Is(err, ErrPDFImageOnly) {
+		t.Errorf("err = %v, want ErrPDFImageOnly", err)
+	}
+	if metadata["Pages"] != "1" {
+		t.Errorf("Pages = %q, want 1", metadata["Pages"])
+	}
+}
+
+func TestObjectFromStreamOutOfRange(t *testing.T) {
+	tests := []struct {
+		name  string
+		first string
+		body  string
+	}{
+		{"negative First", "-5", "3 0 << /A 1 >>"},
+		{"First beyond stream", "500", "3 0 << /A 1 >>"},
+		{"offset beyond stream", "6", "3 400 << /A 1 >>"},
+		{"negative offset", "6", "3 -9 << /A 1 >>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPDF()
+			p.obj(1, "<< /Type /Catalog >>")
+			p.stream(2, "/Type /ObjStm /N 1 /First "+tt.first, []byte(tt.body))
+			doc, err := parsePDF(p.classicXref(3, "/Root 1 0 R"))
+			if err != nil {
+				t.Fatalf("parsePDF: %v", err)
+			}
+			doc.xref[3] = pdfXrefEntry{inStream: true, streamNum: 2, index: 0}
+
+			if obj := doc.object(3); obj != nil {
+				t.Errorf("object(3) = %v, want nil", obj)
+			}
+		})
+	}
+}
@@ -0,0 +1,945 @@
+package analyzer
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// Minimal PDF object model: enough of the file structure (xref tables and
+// streams, object streams, filters) to walk the page tree and read content
+// streams. Rendering and writing are out of scope.
+
+type pdfName string
+
+type pdfString []byte
+
+type pdfArray []interface{}
+
+type pdfDict map[pdfName]interface{}
+
+type pdfRef struct {
+	num int
+	gen int
+}
+
+type pdfStream struct {
+	dict pdfDict
+	raw  []byte
+}
+
+// pdfKeyword is a bare word: operators in content streams, or obj/R/stream
+// while parsing the file structure
+type pdfKeyword string
+
+// maxPDFStreamSize bounds decompressed streams
+const maxPDFStreamSize = 64 * 1024 * 1024
+
+// pdfLexer tokenizes PDF syntax
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func isPDFWhitespace(b byte) bool {
+	return b == 0 || b == '\t' || b == '\n' || b == '\f' || b == '\r' || b == ' '
+}
+
+func isPDFDelimiter(b byte) bool {
+	switch b {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		b := l.data[l.pos]
+		if isPDFWhitespace(b) {
+			l.pos++
+			continue
+		}
+		if b == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		return
+	}
+}
+
+// token returns the next token: a number (int or float64), pdfName,
+// pdfString, pdfKeyword, or one of the delimiters "[", "]", "<<", ">>".
+// It returns io.EOF at the end of data.
+func (l *pdfLexer) token() (interface{}, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.EOF
+	}
+
+	b := l.data[l.pos]
+	switch {
+	case b == '[' || b == ']' || b == '{' || b == '}':
+		l.pos++
+		return pdfKeyword(string(b)), nil
+	case b == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		l.pos += 2
+		return pdfKeyword("<<"), nil
+	case b == '>' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '>':
+		l.pos += 2
+		return pdfKeyword(">>"), nil
+	case b == '<':
+		return l.hexString()
+	case b == '(':
+		return l.literalString()
+	case b == '/':
+		l.pos++
+		start := l.pos
+		for l.pos < len(l.data) && !isPDFWhitespace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+			l.pos++
+		}
+		return pdfName(decodeNameEscapes(l.data[start:l.pos])), nil
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFWhitespace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	if l.pos == start {
+		l.pos++ // stray delimiter such as ')' or '>'
+		return pdfKeyword(string(b)), nil
+	}
+
+	word := string(l.data[start:l.pos])
+	if n, err := strconv.Atoi(word); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(word, 64); err == nil {
+		return f, nil
+	}
+	return pdfKeyword(word), nil
+}
+
+func decodeNameEscapes(raw []byte) string {
+	if bytes.IndexByte(raw, '#') < 0 {
+		return string(raw)
+	}
+	var out []byte
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == '#' && i+2 < len(raw) {
+			if v, err := strconv.ParseUint(string(raw[i+1:i+3]), 16, 8); err == nil {
+				out = append(out, byte(v))
+				i += 2
+				continue
+			}
+		}
+		out = append(out, raw[i])
+	}
+	return string(out)
+}
+
+func (l *pdfLexer) hexString() (interface{}, error) {
+	l.pos++ // <
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if b := l.data[l.pos]; !isPDFWhitespace(b) {
+			digits = append(digits, b)
+		}
+		l.pos++
+	}
+	l.pos++ // >
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	if _, err := hex.Decode(out, digits); err != nil {
+		return pdfString(nil), nil
+	}
+	return pdfString(out), nil
+}
+
+func (l *pdfLexer) literalString() (interface{}, error) {
+	l.pos++ // (
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		b := l.data[l.pos]
+		l.pos++
+		switch b {
+		case '(':
+			depth++
+			out = append(out, b)
+		case ')':
+			depth--
+			if depth == 0 {
+				return pdfString(out), nil
+			}
+			out = append(out, b)
+		case '\\':
+			if l.pos >= len(l.data) {
+				break
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+			case '\n':
+			case '0', '1', '2', '3', '4', '5', '6', '7':
+				v := int(e - '0')
+				for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+					v = v*8 + int(l.data[l.pos]-'0')
+					l.pos++
+				}
+				out = append(out, byte(v))
+			default:
+				out = append(out, e)
+			}
+		default:
+			out = append(out, b)
+		}
+	}
+	return pdfString(out), nil
+}
+
+// object parses a complete object, combining "n g R" into a pdfRef. Bare
+// keywords are returned as pdfKeyword so content streams can use the same
+// parser for operators.
+func (l *pdfLexer) object() (interface{}, error) {
+	tok, err := l.token()
+	if err != nil {
+		return nil, err
+	}
+	return l.objectFrom(tok, 0)
+}
+
+func (l *pdfLexer) objectFrom(tok interface{}, depth int) (interface{}, error) {
+	if depth > 64 {
+		return nil, fmt.Errorf("objects nested too deeply")
+	}
+
+	switch t := tok.(type) {
+	case int:
+		// Look ahead for "gen R"
+		save := l.pos
+		gen, err := l.token()
+		if g, ok := gen.(int); ok && err == nil {
+			r, err := l.token()
+			if kw, ok := r.(pdfKeyword); ok && err == nil && kw == "R" {
+				return pdfRef{num: t, gen: g}, nil
+			}
+		}
+		l.pos = save
+		return t, nil
+
+	case pdfKeyword:
+		switch t {
+		case "[":
+			var arr pdfArray
+			for {
+				next, err := l.token()
+				if err != nil {
+					return arr, nil
+				}
+				if kw, ok := next.(pdfKeyword); ok && kw == "]" {
+					return arr, nil
+				}
+				value, err := l.objectFrom(next, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				arr = append(arr, value)
+			}
+		case "<<":
+			dict := make(pdfDict)
+			for {
+				next, err := l.token()
+				if err != nil {
+					return dict, nil
+				}
+				if kw, ok := next.(pdfKeyword); ok && kw == ">>" {
+					return dict, nil
+				}
+				key, ok := next.(pdfName)
+				if !ok {
+					continue // malformed key; skip it
+				}
+				valueTok, err := l.token()
+				if err != nil {
+					return dict, nil
+				}
+				if kw, ok := valueTok.(pdfKeyword); ok && kw == ">>" {
+					return dict, nil
+				}
+				value, err := l.objectFrom(valueTok, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				dict[key] = value
+			}
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return t, nil
+	}
+
+	return tok, nil
+}
+
+// pdfXrefEntry locates an object either at a file offset or inside an object stream
+type pdfXrefEntry struct {
+	offset    int
+	inStream  bool
+	streamNum int
+	index     int
+}
+
+// pdfDocument is a parsed PDF file held in memory
+type pdfDocument struct {
+	data    []byte
+	xref    map[int]pdfXrefEntry
+	trailer pdfDict
+	cache   map[int]interface{}
+	loading map[int]bool
+	objStms map[int]*pdfObjectStream // decoded object streams, nil if unreadable
+}
+
+var startxrefPattern = regexp.MustCompile(`startxref\s+(\d+)`)
+
+// parsePDF reads the cross-reference data of a PDF. Files with a damaged
+// xref are recovered by scanning for "n g obj" headers.
+func parsePDF(data []byte) (*pdfDocument, error) {
+	if !bytes.Contains(data[:min(len(data), 1024)], []byte("%PDF-")) {
+		return nil, fmt.Errorf("not a valid PDF file")
+	}
+
+	doc := &pdfDocument{
+		data:    data,
+		xref:    make(map[int]pdfXrefEntry),
+		trailer: make(pdfDict),
+		cache:   make(map[int]interface{}),
+		loading: make(map[int]bool),
+		objStms: make(map[int]*pdfObjectStream),
+	}
+
+	tail := data[max(0, len(data)-2048):]
+	matches := startxrefPattern.FindAllSubmatch(tail, -1)
+	if len(matches) > 0 {
+		offset, _ := strconv.Atoi(string(matches[len(matches)-1][1]))
+		if err := doc.readXrefChain(offset); err != nil {
+			doc.xref = make(map[int]pdfXrefEntry)
+			doc.trailer = make(pdfDict)
+		}
+	}
+
+	// Offsets that do not point at objects also mean a damaged xref
+	if doc.dict(doc.trailer["Root"]) == nil {
+		doc.reconstructXref()
+	}
+	if _, ok := doc.trailer["Root"]; !ok {
+		return nil, fmt.Errorf("PDF has no document catalog")
+	}
+	return doc, nil
+}
+
+// readXrefChain follows /Prev links from the newest cross-reference section.
+// Entries already seen take precedence, as they come from newer sections.
+func (doc *pdfDocument) readXrefChain(offset int) error {
+	visited := make(map[int]bool)
+	for {
+		if visited[offset] || offset < 0 || offset >= len(doc.data) {
+			break
+		}
+		visited[offset] = true
+
+		trailer, err := doc.readXrefSection(offset)
+		if err != nil {
+			return err
+		}
+		for key, value := range trailer {
+			if _, ok := doc.trailer[key]; !ok {
+				doc.trailer[key] = value
+			}
+		}
+
+		// Hybrid files keep compressed objects in an extra xref stream
+		if stm, ok := trailer["XRefStm"].(int); ok && !visited[stm] {
+			visited[stm] = true
+			if _, err := doc.readXrefSection(stm); err != nil {
+				return err
+			}
+		}
+
+		prev, ok := trailer["Prev"].(int)
+		if !ok {
+			break
+		}
+		offset = prev
+	}
+	return nil
+}
+
+// readXrefSection reads a classic xref table or an xref stream at offset
+func (doc *pdfDocument) readXrefSection(offset int) (pdfDict, error) {
+	lex := &pdfLexer{data: doc.data, pos: offset}
+	tok, err := lex.token()
+	if err != nil {
+		return nil, err
+	}
+
+	if kw, ok := tok.(pdfKeyword); ok && kw == "xref" {
+		for {
+			save := lex.pos
+			first, err := lex.token()
+			if err != nil {
+				return nil, err
+			}
+			if kw, ok := first.(pdfKeyword); ok && kw == "trailer" {
+				trailer, err := lex.object()
+				if err != nil {
+					return nil, err
+				}
+				dict, ok := trailer.(pdfDict)
+				if !ok {
+					return nil, fmt.Errorf("invalid trailer")
+				}
+				return dict, nil
+			}
+			start, ok := first.(int)
+			countTok, err := lex.token()
+			count, ok2 := countTok.(int)
+			if !ok || !ok2 || err != nil {
+				lex.pos = save
+				return nil, fmt.Errorf("invalid xref subsection")
+			}
+			for i := 0; i < count; i++ {
+				offTok, _ := lex.token()
+				_, _ = lex.token() // generation
+				kind, _ := lex.token()
+				off, ok := offTok.(int)
+				if kw, isKw := kind.(pdfKeyword); ok && isKw && kw == "n" {
+					if _, exists := doc.xref[start+i]; !exists {
+						doc.xref[start+i] = pdfXrefEntry{offset: off}
+					}
+				} else if _, exists := doc.xref[start+i]; !exists {
+					doc.xref[start+i] = pdfXrefEntry{offset: -1} // free
+				}
+			}
+		}
+	}
+
+	// Cross-reference stream
+	obj, _, err := doc.parseIndirectAt(offset)
+	if err != nil {
+		return nil, err
+	}
+	stream, ok := obj.(*pdfStream)
+	if !ok {
+		return nil, fmt.Errorf("invalid xref stream")
+	}
+	data, err := doc.decodeStream(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	widths, _ := stream.dict["W"].(pdfArray)
+	if len(widths) != 3 {
+		return nil, fmt.Errorf("invalid xref stream widths")
+	}
+	w := make([]int, 3)
+	for i := range w {
+		w[i], _ = widths[i].(int)
+		if w[i] < 0 || w[i] > 8 {
+			return nil, fmt.Errorf("invalid xref stream widths")
+		}
+	}
+	size, _ := stream.dict["Size"].(int)
+	index := pdfArray{0, size}
+	if idx, ok := stream.dict["Index"].(pdfArray); ok {
+		index = idx
+	}
+
+	rowLen := w[0] + w[1] + w[2]
+	pos := 0
+	readField := func(width, def int) int {
+		if width == 0 {
+			return def
+		}
+		v := 0
+		for i := 0; i < width; i++ {
+			v = v<<8 | int(data[pos+i])
+		}
+		pos += width
+		return v
+	}
+
+	for i := 0; i+1 < len(index); i += 2 {
+		start, _ := index[i].(int)
+		count, _ := index[i+1].(int)
+		for j := 0; j < count; j++ {
+			if rowLen == 0 || pos+rowLen > len(data) {
+				return stream.dict, nil
+			}
+			kind := readField(w[0], 1)
+			f2 := readField(w[1], 0)
+			f3 := readField(w[2], 0)
+			num := start + j
+			if _, exists := doc.xref[num]; exists {
+				continue
+			}
+			switch kind {
+			case 1:
+				doc.xref[num] = pdfXrefEntry{offset: f2}
+			case 2:
+				doc.xref[num] = pdfXrefEntry{inStream: true, streamNum: f2, index: f3}
+			default:
+				doc.xref[num] = pdfXrefEntry{offset: -1}
+			}
+		}
+	}
+
+	return stream.dict, nil
+}
+
+var objHeaderPattern = regexp.MustCompile(`(?m)(\d+)\s+(\d+)\s+obj\b`)
+
+// reconstructXref scans the whole file for object headers and trailers
+func (doc *pdfDocument) reconstructXref() {
+	doc.xref = make(map[int]pdfXrefEntry)
+	doc.cache = make(map[int]interface{})
+	doc.objStms = make(map[int]*pdfObjectStream)
+	for _, loc := range objHeaderPattern.FindAllSubmatchIndex(doc.data, -1) {
+		num, _ := strconv.Atoi(string(doc.data[loc[2]:loc[3]]))
+		doc.xref[num] = pdfXrefEntry{offset: loc[0]} // later definitions win
+	}
+
+	// Prefer the last trailer dictionary; otherwise look for the catalog
+	if idx := bytes.LastIndex(doc.data, []byte("trailer")); idx >= 0 {
+		lex := &pdfLexer{data: doc.data, pos: idx + len("trailer")}
+		if obj, err := lex.object(); err == nil {
+			if dict, ok := obj.(pdfDict); ok {
+				for key, value := range dict {
+					doc.trailer[key] = value
+				}
+			}
+		}
+	}
+
+	if doc.dict(doc.trailer["Root"]) != nil {
+		return
+	}
+	delete(doc.trailer, "Root")
+	for num := range doc.xref {
+		obj := doc.resolve(pdfRef{num: num})
+		if stream, ok := obj.(*pdfStream); ok {
+			// Xref streams carry the trailer keys in their dictionary
+			if stream.dict["Type"] == pdfName("XRef") {
+				for key, value := range stream.dict {
+					if _, exists := doc.trailer[key]; !exists {
+						doc.trailer[key] = value
+					}
+				}
+			}
+			// Object streams may hold the catalog
+			if stream.dict["Type"] == pdfName("ObjStm") {
+				doc.indexObjectStream(num, stream)
+			}
+		}
+		if dict, ok := obj.(pdfDict); ok && dict["Type"] == pdfName("Catalog") {
+			doc.trailer["Root"] = pdfRef{num: num}
+		}
+	}
+	if _, ok := doc.trailer["Root"]; ok {
+		return
+	}
+	for num, entry := range doc.xref {
+		if !entry.inStream {
+			continue
+		}
+		if dict, ok := doc.resolve(pdfRef{num: num}).(pdfDict); ok && dict["Type"] == pdfName("Catalog") {
+			doc.trailer["Root"] = pdfRef{num: num}
+			return
+		}
+	}
+}
+
+// indexObjectStream adds the objects of an object stream to a reconstructed xref
+func (doc *pdfDocument) indexObjectStream(streamNum int, stream *pdfStream) {
+	objStm, nums := doc.decodeObjectStream(stream)
+	doc.objStms[streamNum] = objStm
+	for i, num := range nums {
+		if _, exists := doc.xref[num]; !exists {
+			doc.xref[num] = pdfXrefEntry{inStream: true, streamNum: streamNum, index: i}
+		}
+	}
+}
+
+// parseIndirectAt parses "n g obj ... endobj" at offset
+func (doc *pdfDocument) parseIndirectAt(offset int) (interface{}, int, error) {
+	if offset < 0 || offset >= len(doc.data) {
+		return nil, 0, fmt.Errorf("object offset out of range")
+	}
+	lex := &pdfLexer{data: doc.data, pos: offset}
+	numTok, _ := lex.token()
+	_, _ = lex.token()
+	objTok, _ := lex.token()
+	num, ok := numTok.(int)
+	if kw, isKw := objTok.(pdfKeyword); !ok || !isKw || kw != "obj" {
+		return nil, 0, fmt.Errorf("no object at offset %d", offset)
+	}
+
+	obj, err := lex.object()
+	if err != nil {
+		return nil, num, err
+	}
+
+	dict, isDict := obj.(pdfDict)
+	if !isDict {
+		return obj, num, nil
+	}
+
+	save := lex.pos
+	next, err := lex.token()
+	if kw, ok := next.(pdfKeyword); err != nil || !ok || kw != "stream" {
+		lex.pos = save
+		return obj, num, nil
+	}
+
+	// Stream data starts after the EOL that follows the keyword
+	start := lex.pos
+	if start < len(doc.data) && doc.data[start] == '\r' {
+		start++
+	}
+	if start < len(doc.data) && doc.data[start] == '\n' {
+		start++
+	}
+
+	length := -1
+	switch l := dict["Length"].(type) {
+	case int:
+		length = l
+	case pdfRef:
+		if l.num != num {
+			if resolved, ok := doc.resolve(l).(int); ok {
+				length = resolved
+			}
+		}
+	}
+
+	end := start + length
+	if length < 0 || end > len(doc.data) || !bytes.HasPrefix(bytes.TrimLeft(doc.data[end:min(end+32, len(doc.data))], "\r\n \t"), []byte("endstream")) {
+		// Missing or wrong /Length: fall back to the endstream keyword
+		idx := bytes.Index(doc.data[start:], []byte("endstream"))
+		if idx < 0 {
+			return nil, num, fmt.Errorf("unterminated stream")
+		}
+		end = start + idx
+		for end > start && (doc.data[end-1] == '\n' || doc.data[end-1] == '\r') {
+			end--
+		}
+	}
+
+	return &pdfStream{dict: dict, raw: doc.data[start:end]}, num, nil
+}
+
+// resolve follows indirect references
+func (doc *pdfDocument) resolve(obj interface{}) interface{} {
+	for i := 0; i < 32; i++ {
+		ref, ok := obj.(pdfRef)
+		if !ok {
+			return obj
+		}
+		obj = doc.object(ref.num)
+	}
+	return nil
+}
+
+// object loads an object by number
+func (doc *pdfDocument) object(num int) interface{} {
+	if obj, ok := doc.cache[num]; ok {
+		return obj
+	}
+	entry, ok := doc.xref[num]
+	if !ok || doc.loading[num] {
+		return nil
+	}
+	doc.loading[num] = true
+	defer delete(doc.loading, num)
+
+	var obj interface{}
+	if entry.inStream {
+		obj = doc.objectFromStream(entry.streamNum, entry.index)
+	} else if entry.offset >= 0 {
+		obj, _, _ = doc.parseIndirectAt(entry.offset)
+	}
+
+	doc.cache[num] = obj
+	return obj
+}
+
+// pdfObjectStream is a decoded object stream with the offsets of its objects
+type pdfObjectStream struct {
+	data    []byte
+	offsets []int // relative to the start of the first object
+}
+
+// objectStream decodes an object stream once and caches it by object number
+func (doc *pdfDocument) objectStream(streamNum int) *pdfObjectStream {
+	if objStm, ok := doc.objStms[streamNum]; ok {
+		return objStm
+	}
+	var objStm *pdfObjectStream
+	if stream, ok := doc.resolve(pdfRef{num: streamNum}).(*pdfStream); ok {
+		objStm, _ = doc.decodeObjectStream(stream)
+	}
+	doc.objStms[streamNum] = objStm
+	return objStm
+}
+
+// decodeObjectStream decodes an object stream and reads the header of
+// object number and offset pairs before /First. It also returns the object
+// numbers of the objects in the stream.
+func (doc *pdfDocument) decodeObjectStream(stream *pdfStream) (*pdfObjectStream, []int) {
+	data, err := doc.decodeStream(stream)
+	if err != nil {
+		return nil, nil
+	}
+
+	n, _ := stream.dict["N"].(int)
+	first, ok := stream.dict["First"].(int)
+	if !ok || first < 0 || first > len(data) {
+		return nil, nil
+	}
+
+	objStm := &pdfObjectStream{data: data[first:]}
+	var nums []int
+	lex := &pdfLexer{data: data[:first]}
+	for i := 0; i < n; i++ {
+		numTok, err1 := lex.token()
+		offTok, err2 := lex.token()
+		num, ok1 := numTok.(int)
+		offset, ok2 := offTok.(int)
+		if err1 != nil || err2 != nil || !ok1 || !ok2 {
+			break
+		}
+		if offset < 0 || offset >= len(objStm.data) {
+			offset = -1
+		}
+		objStm.offsets = append(objStm.offsets, offset)
+		nums = append(nums, num)
+	}
+	return objStm, nums
+}
+
+// objectFromStream reads the index-th object of an object stream
+func (doc *pdfDocument) objectFromStream(streamNum, index int) interface{} {
+	objStm := doc.objectStream(streamNum)
+	if objStm == nil || index < 0 || index >= len(objStm.offsets) || objStm.offsets[index] < 0 {
+		return nil
+	}
+
+	lex := &pdfLexer{data: objStm.data, pos: objStm.offsets[index]}
+	obj, err := lex.object()
+	if err != nil {
+		return nil
+	}
+	return obj
+}
+
+// dict resolves obj and returns it as a dictionary (a stream's dictionary
+// for streams)
+func (doc *pdfDocument) dict(obj interface{}) pdfDict {
+	switch v := doc.resolve(obj).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+// decodeStream applies the stream's filters
+func (doc *pdfDocument) decodeStream(stream *pdfStream) ([]byte, error) {
+	var filters pdfArray
+	switch f := doc.resolve(stream.dict["Filter"]).(type) {
+	case pdfName:
+		filters = pdfArray{f}
+	case pdfArray:
+		filters = f
+	}
+	var params pdfArray
+	switch p := doc.resolve(stream.dict["DecodeParms"]).(type) {
+	case pdfDict:
+		params = pdfArray{p}
+	case pdfArray:
+		params = p
+	}
+
+	data := stream.raw
+	for i, f := range filters {
+		name, _ := doc.resolve(f).(pdfName)
+		var param pdfDict
+		if i < len(params) {
+			param = doc.dict(params[i])
+		}
+
+		var err error
+		switch name {
+		case "FlateDecode", "Fl":
+			data, err = inflatePDF(data)
+			if err == nil {
+				data, err = applyPredictor(data, param)
+			}
+		case "ASCIIHexDecode", "AHx":
+			data, err = decodeASCIIHex(data)
+		case "ASCII85Decode", "A85":
+			data, err = decodeASCII85(data)
+		default:
+			return nil, fmt.Errorf("unsupported filter %s", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return data, nil
+}
+
+func inflatePDF(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	out, err := io.ReadAll(io.LimitReader(r, maxPDFStreamSize))
+	// Truncated streams are common; keep what was inflated
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	return out, nil
+}
+
+// applyPredictor undoes PNG predictors (used by xref and object streams)
+func applyPredictor(data []byte, param pdfDict) ([]byte, error) {
+	predictor, _ := param["Predictor"].(int)
+	if predictor < 10 {
+		if predictor == 2 {
+			return nil, fmt.Errorf("TIFF predictor not supported")
+		}
+		return data, nil
+	}
+
+	columns, ok := param["Columns"].(int)
+	if !ok || columns < 1 {
+		columns = 1
+	}
+	colors, ok := param["Colors"].(int)
+	if !ok || colors < 1 {
+		colors = 1
+	}
+	bpc, ok := param["BitsPerComponent"].(int)
+	if !ok || bpc < 1 {
+		bpc = 8
+	}
+
+	bpp := max(1, colors*bpc/8)
+	rowLen := (columns*colors*bpc + 7) / 8
+	var out []byte
+	prev := make([]byte, rowLen)
+	for pos := 0; pos+1+rowLen <= len(data); pos += 1 + rowLen {
+		filter := data[pos]
+		row := append([]byte(nil), data[pos+1:pos+1+rowLen]...)
+		for i := range row {
+			var left, up, upLeft byte
+			if i >= bpp {
+				left = row[i-bpp]
+				upLeft = prev[i-bpp]
+			}
+			up = prev[i]
+			switch filter {
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			}
+		}
+		out = append(out, row...)
+		prev = row
+	}
+	return out, nil
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	if pa <= pb && pa <= pc {
+		return a
+	}
+	if pb <= pc {
+		return b
+	}
+	return c
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func decodeASCIIHex(data []byte) ([]byte, error) {
+	var digits []byte
+	for _, b := range data {
+		if b == '>' {
+			break
+		}
+		if !isPDFWhitespace(b) {
+			digits = append(digits, b)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	_, err := hex.Decode(out, digits)
+	return out, err
+}
+
+func decodeASCII85(data []byte) ([]byte, error) {
+	data = bytes.TrimSpace(data)
+	data = bytes.TrimPrefix(data, []byte("<~"))
+	if idx := bytes.Index(data, []byte("~>")); idx >= 0 {
+		data = data[:idx]
+	}
+	out := make([]byte, 4*len(data)/5+4)
+	n, _, err := ascii85.Decode(out, data, true)
+	return out[:n], err
+}
@@ -0,0 +1,233 @@
+package analyzer
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// slideShape holds the text of one shape on a slide or notes page
+type slideShape struct {
+	placeholder string // ph type: title, ctrTitle, body, sldNum, ...
+	paragraphs  []string
+}
+
+// slideContent is the text extracted from one slide
+type slideContent struct {
+	title  string
+	body   []string
+	notes  []string
+	hidden bool
+}
+
+// ignoredPlaceholders carry slide furniture rather than content
+var ignoredPlaceholders = map[string]bool{
+	"sldNum": true, "dt": true, "ftr": true, "hdr": true, "sldImg": true,
+}
+
+// ReadPPTXContent extracts slide titles, body text and speaker notes from a
+// PPTX file, one "## Slide N" section per slide
+func (d *Detector) ReadPPTXContent(filePath string) (string, error) {
+	reader, err := zip.OpenReader(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open PPTX: %w", err)
+	}
+	defer reader.Close()
+
+	files := make(map[string]*zip.File, len(reader.File))
+	for _, file := range reader.File {
+		files[file.Name] = file
+	}
+
+	var presentation struct {
+		Slides []struct {
+			ID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sldIdLst>sldId"`
+	}
+	if err := decodeZipXML(files["ppt/presentation.xml"], &presentation); err != nil {
+		return "", fmt.Errorf("failed to read PPTX presentation: %w", err)
+	}
+	rels, err := readZipRelationships(files["ppt/_rels/presentation.xml.rels"], "ppt")
+	if err != nil {
+		return "", fmt.Errorf("failed to read PPTX relationships: %w", err)
+	}
+
+	var builder strings.Builder
+	for i, ref := range presentation.Slides {
+		part := rels[ref.ID].target
+		file := files[part]
+		if file == nil {
+			continue
+		}
+
+		slide, err := readPPTXSlide(files, file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read slide %d: %w", i+1, err)
+		}
+
+		if builder.Len() > 0 {
+			builder.WriteString("\n")
+		}
+		builder.WriteString(fmt.Sprintf("## Slide %d", i+1))
+		if slide.title != "" {
+			builder.WriteString(": " + slide.title)
+		}
+		if slide.hidden {
+			builder.WriteString(" (hidden)")
+		}
+		builder.WriteString("\n")
+
+		if len(slide.body) > 0 {
+			builder.WriteString("\n" + strings.Join(slide.body, "\n") + "\n")
+		}
+		if len(slide.notes) > 0 {
+			builder.WriteString("\nSpeaker notes:\n" + strings.Join(slide.notes, "\n") + "\n")
+		}
+	}
+
+	text := strings.TrimSpace(builder.String())
+	if text == "" {
+		return "", fmt.Errorf("no slides found in PPTX")
+	}
+	return text, nil
+}
+
+// readPPTXSlide reads a slide part and, through its relationships, its notes page
+func readPPTXSlide(files map[string]*zip.File, file *zip.File) (*slideContent, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	shapes, hidden, err := parseSlideXML(rc)
+	rc.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	slide := &slideContent{hidden: hidden}
+	for _, shape := range shapes {
+		switch {
+		case ignoredPlaceholders[shape.placeholder]:
+		case (shape.placeholder == "title" || shape.placeholder == "ctrTitle") && slide.title == "":
+			slide.title = strings.Join(shape.paragraphs, " ")
+		default:
+			slide.body = append(slide.body, shape.paragraphs...)
+		}
+	}
+
+	dir, base := path.Split(file.Name)
+	rels, err := readZipRelationships(files[path.Join(dir, "_rels", base+".rels")], dir)
+	if err != nil {
+		return slide, nil // slides without relationships have no notes
+	}
+	for _, rel := range rels {
+		if !strings.HasSuffix(rel.kind, "/notesSlide") || files[rel.target] == nil {
+			continue
+		}
+
+		rc, err := files[rel.target].Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open notes: %w", err)
+		}
+		noteShapes, _, err := parseSlideXML(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse notes: %w", err)
+		}
+
+		for _, shape := range noteShapes {
+			if shape.placeholder == "body" {
+				slide.notes = append(slide.notes, shape.paragraphs...)
+			}
+		}
+	}
+
+	return slide, nil
+}
+
+// parseSlideXML collects the paragraphs of every shape on a slide or notes
+// page. Table rows become single "cell | cell" paragraphs.
+func parseSlideXML(r io.Reader) ([]slideShape, bool, error) {
+	var shapes []slideShape
+	var current *slideShape
+	var paragraph strings.Builder
+	var cells []string
+	inCell := false
+	hidden := false
+
+	decoder := xml.NewDecoder(r)
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "sld":
+				for _, a := range t.Attr {
+					if a.Name.Local == "show" && (a.Value == "0" || a.Value == "false") {
+						hidden = true
+					}
+				}
+			case "sp", "graphicFrame":
+				shapes = append(shapes, slideShape{})
+				current = &shapes[len(shapes)-1]
+			case "ph":
+				if current != nil {
+					current.placeholder = "body"
+					for _, a := range t.Attr {
+						if a.Name.Local == "type" {
+							current.placeholder = a.Value
+						}
+					}
+				}
+			case "tc":
+				inCell = true
+				paragraph.Reset()
+			case "t":
+				var text string
+				if err := decoder.DecodeElement(&text, &t); err != nil {
+					return nil, false, err
+				}
+				paragraph.WriteString(text)
+			case "br":
+				paragraph.WriteString(" ")
+			}
+
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p":
+				if inCell {
+					paragraph.WriteString(" ")
+					continue
+				}
+				text := strings.TrimSpace(paragraph.String())
+				paragraph.Reset()
+				if text != "" && current != nil {
+					current.paragraphs = append(current.paragraphs, text)
+				}
+			case "tc":
+				inCell = false
+				cells = append(cells, strings.TrimSpace(paragraph.String()))
+				paragraph.Reset()
+			case "tr":
+				if current != nil && strings.TrimSpace(strings.Join(cells, "")) != "" {
+					current.paragraphs = append(current.paragraphs, strings.Join(cells, " | "))
+				}
+				cells = nil
+			case "sp", "graphicFrame":
+				current = nil
+			}
+		}
+	}
+
+	return shapes, hidden, nil
+}
@@ -0,0 +1,701 @@
+package analyzer
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits applied when rendering a sheet as a Markdown table
+const (
+	maxSheetRows    = 5000
+	maxSheetColumns = 50
+	maxCellLength   = 200
+)
+
+// sheet is a worksheet reduced to its non-empty cell values
+type sheet struct {
+	name      string
+	rows      []sheetRow
+	totalRows int // non-empty rows before the row limit was applied
+	maxCols   int // widest row before the column limit was applied
+}
+
+type sheetRow struct {
+	num   int // 1-based row number in the spreadsheet
+	cells []string
+}
+
+// addRow records a row, applying the row and column limits
+func (s *sheet) addRow(num int, cells []string) {
+	for len(cells) > 0 && strings.TrimSpace(cells[len(cells)-1]) == "" {
+		cells = cells[:len(cells)-1]
+	}
+	if len(cells) == 0 {
+		return
+	}
+
+	s.totalRows++
+	if len(cells) > s.maxCols {
+		s.maxCols = len(cells)
+	}
+	if len(s.rows) >= maxSheetRows {
+		return
+	}
+	if len(cells) > maxSheetColumns {
+		cells = cells[:maxSheetColumns]
+	}
+	s.rows = append(s.rows, sheetRow{num: num, cells: cells})
+}
+
+// ReadXLSXContent extracts all worksheets of an XLSX workbook as Markdown tables
+func (d *Detector) ReadXLSXContent(filePath string) (string, error) {
+	reader, err := zip.OpenReader(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open XLSX: %w", err)
+	}
+	defer reader.Close()
+
+	files := make(map[string]*zip.File, len(reader.File))
+	for _, file := range reader.File {
+		files[file.Name] = file
+	}
+
+	sharedStrings, err := readXLSXSharedStrings(files["xl/sharedStrings.xml"])
+	if err != nil {
+		return "", fmt.Errorf("failed to read XLSX shared strings: %w", err)
+	}
+	dateStyles, err := readXLSXDateStyles(files["xl/styles.xml"])
+	if err != nil {
+		return "", fmt.Errorf("failed to read XLSX styles: %w", err)
+	}
+	refs, err := readXLSXWorkbook(files)
+	if err != nil {
+		return "", err
+	}
+
+	var sheets []*sheet
+	for _, ref := range refs {
+		file := files[ref.part]
+		if file == nil {
+			continue // chart sheets and missing parts
+		}
+		s, err := readXLSXSheet(file, ref.name, sharedStrings, dateStyles)
+		if err != nil {
+			return "", fmt.Errorf("failed to read sheet %s: %w", ref.name, err)
+		}
+		sheets = append(sheets, s)
+	}
+
+	return renderSheets(sheets, "XLSX")
+}
+
+// ReadODSContent extracts all tables of an OpenDocument spreadsheet as Markdown tables
+func (d *Detector) ReadODSContent(filePath string) (string, error) {
+	reader, err := zip.OpenReader(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open ODS: %w", err)
+	}
+	defer reader.Close()
+
+	var content *zip.File
+	for _, file := range reader.File {
+		if file.Name == "content.xml" {
+			content = file
+			break
+		}
+	}
+	if content == nil {
+		return "", fmt.Errorf("no content.xml found in ODS")
+	}
+
+	rc, err := content.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open ODS content: %w", err)
+	}
+	defer rc.Close()
+
+	sheets, err := parseODSContent(rc)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse ODS content: %w", err)
+	}
+
+	return renderSheets(sheets, "ODS")
+}
+
+// renderSheets writes each sheet as a "## Sheet:" section holding a Markdown
+// table. The first row becomes the table header and a Row column keeps the
+// spreadsheet's own row numbers so chunks and answers can cite them.
+func renderSheets(sheets []*sheet, format string) (string, error) {
+	var builder strings.Builder
+
+	for _, s := range sheets {
+		if len(s.rows) == 0 {
+			continue
+		}
+		if builder.Len() > 0 {
+			builder.WriteString("\n")
+		}
+
+		builder.WriteString(fmt.Sprintf("## Sheet: %s\n\n", s.name))
+		builder.WriteString(fmt.Sprintf("%d rows x %d columns", s.totalRows, s.maxCols))
+		if s.totalRows > len(s.rows) {
+			builder.WriteString(fmt.Sprintf(", first %d rows shown", len(s.rows)))
+		}
+		if s.maxCols > maxSheetColumns {
+			builder.WriteString(fmt.Sprintf(", first %d columns shown", maxSheetColumns))
+		}
+		builder.WriteString("\n\n")
+
+		width := 0
+		for _, row := range s.rows {
+			if len(row.cells) > width {
+				width = len(row.cells)
+			}
+		}
+
+		header := s.rows[0].cells
+		builder.WriteString("| Row |")
+		for col := 0; col < width; col++ {
+			name := ""
+			if col < len(header) {
+				name = escapeTableCell(header[col])
+			}
+			if name == "" {
+				name = columnName(col)
+			}
+			builder.WriteString(" " + name + " |")
+		}
+		builder.WriteString("\n|---|" + strings.Repeat("---|", width) + "\n")
+
+		for _, row := range s.rows[1:] {
+			builder.WriteString(fmt.Sprintf("| %d |", row.num))
+			for col := 0; col < width; col++ {
+				value := ""
+				if col < len(row.cells) {
+					value = escapeTableCell(row.cells[col])
+				}
+				builder.WriteString(" " + value + " |")
+			}
+			builder.WriteString("\n")
+		}
+	}
+
+	text := strings.TrimSpace(builder.String())
+	if text == "" {
+		return "", fmt.Errorf("no cell content extracted from %s", format)
+	}
+	return text, nil
+}
+
+// escapeTableCell keeps a cell value on one table line
+func escapeTableCell(value string) string {
+	value = strings.TrimSpace(value)
+	value = strings.ReplaceAll(value, "\r\n", " ")
+	value = strings.ReplaceAll(value, "\n", " ")
+	value = strings.ReplaceAll(value, "|", `\|`)
+	if len(value) > maxCellLength {
+		cut := maxCellLength
+		for cut > 0 && !isRuneStart(value[cut]) {
+			cut--
+		}
+		value = value[:cut] + "…"
+	}
+	return value
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// columnName converts a 0-based column index to a spreadsheet letter (A, B, ..., AA)
+func columnName(col int) string {
+	name := ""
+	for col >= 0 {
+		name = string(rune('A'+col%26)) + name
+		col = col/26 - 1
+	}
+	return name
+}
+
+// columnIndex converts a cell reference like "BC12" to a 0-based column index
+func columnIndex(ref string) (int, bool) {
+	col := 0
+	n := 0
+	for _, r := range ref {
+		if r >= 'a' && r <= 'z' {
+			r -= 'a' - 'A'
+		}
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+		n++
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return col - 1, true
+}
+
+// XLSX
+
+type xlsxSheetRef struct {
+	name string
+	part string
+}
+
+// readXLSXWorkbook lists the worksheets in workbook order with their part names
+func readXLSXWorkbook(files map[string]*zip.File) ([]xlsxSheetRef, error) {
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodeZipXML(files["xl/workbook.xml"], &workbook); err != nil {
+		return nil, fmt.Errorf("failed to read XLSX workbook: %w", err)
+	}
+
+	targets, err := readZipRelationships(files["xl/_rels/workbook.xml.rels"], "xl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read XLSX relationships: %w", err)
+	}
+
+	var refs []xlsxSheetRef
+	for _, s := range workbook.Sheets {
+		refs = append(refs, xlsxSheetRef{name: s.Name, part: targets[s.ID].target})
+	}
+	if len(refs) == 0 {
+		return nil, fmt.Errorf("no worksheets found in XLSX")
+	}
+	return refs, nil
+}
+
+// zipRelationship is an entry of an OOXML .rels part
+type zipRelationship struct {
+	target string // zip member name the relationship points to
+	kind   string // relationship type URI
+}
+
+// readZipRelationships reads a .rels part, resolving targets relative to baseDir
+func readZipRelationships(file *zip.File, baseDir string) (map[string]zipRelationship, error) {
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Type   string `xml:"Type,attr"`
+			Target string `xml:"Target,attr"`
+			Mode   string `xml:"TargetMode,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeZipXML(file, &rels); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]zipRelationship, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		if rel.Mode == "External" {
+			continue
+		}
+		target := rel.Target
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(target, "/")
+		} else {
+			target = path.Join(baseDir, target)
+		}
+		result[rel.ID] = zipRelationship{target: target, kind: rel.Type}
+	}
+	return result, nil
+}
+
+// decodeZipXML unmarshals a zip member into v
+func decodeZipXML(file *zip.File, v interface{}) error {
+	if file == nil {
+		return fmt.Errorf("missing part")
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+// readXLSXSharedStrings returns the shared string table; rich-text runs are
+// concatenated and phonetic hints skipped
+func readXLSXSharedStrings(file *zip.File) ([]string, error) {
+	if file == nil {
+		return nil, nil
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var stringsTable []string
+	var current strings.Builder
+	inPhonetic := false
+
+	decoder := xml.NewDecoder(rc)
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				current.Reset()
+			case "rPh":
+				inPhonetic = true
+			case "t":
+				var text string
+				if err := decoder.DecodeElement(&text, &t); err != nil {
+					return nil, err
+				}
+				if !inPhonetic {
+					current.WriteString(text)
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				stringsTable = append(stringsTable, current.String())
+			case "rPh":
+				inPhonetic = false
+			}
+		}
+	}
+
+	return stringsTable, nil
+}
+
+// builtinDateFormats are the built-in number formats that display dates or times
+var builtinDateFormats = map[int]bool{
+	14: true, 15: true, 16: true, 17: true, 18: true, 19: true, 20: true, 21: true, 22: true,
+	45: true, 46: true, 47: true,
+}
+
+// readXLSXDateStyles returns which cell style indexes (the s attribute)
+// format their value as a date
+func readXLSXDateStyles(file *zip.File) (map[int]bool, error) {
+	dateStyles := make(map[int]bool)
+	if file == nil {
+		return dateStyles, nil
+	}
+
+	var styles struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellXfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if err := decodeZipXML(file, &styles); err != nil {
+		return nil, err
+	}
+
+	dateFormats := make(map[int]bool)
+	for id := range builtinDateFormats {
+		dateFormats[id] = true
+	}
+	for _, numFmt := range styles.NumFmts {
+		dateFormats[numFmt.ID] = isDateFormatCode(numFmt.Code)
+	}
+
+	for i, xf := range styles.CellXfs {
+		if dateFormats[xf.NumFmtID] {
+			dateStyles[i] = true
+		}
+	}
+	return dateStyles, nil
+}
+
+// isDateFormatCode reports whether a custom number format displays a date,
+// ignoring quoted literals, escapes and [colour]/[locale] sections
+func isDateFormatCode(code string) bool {
+	inQuote, inBracket, escaped := false, false, false
+	for _, r := range strings.ToLower(code) {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case r == '[':
+			inBracket = true
+		case r == ']':
+			inBracket = false
+		case inBracket:
+		case r == 'd', r == 'm', r == 'y', r == 'h', r == 's':
+			return true
+		}
+	}
+	return false
+}
+
+// excelDate converts a 1900-system date serial to its display form
+func excelDate(serial float64) string {
+	// Serial 60 is the fictitious 1900-02-29; the epoch absorbs it for later dates
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	days := math.Floor(serial)
+	seconds := math.Round((serial - days) * 86400)
+	t := epoch.AddDate(0, 0, int(days)).Add(time.Duration(seconds) * time.Second)
+
+	if seconds == 0 {
+		return t.Format("2006-01-02")
+	}
+	if days == 0 {
+		return t.Format("15:04:05")
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// readXLSXSheet streams a worksheet part, resolving shared strings and dates
+func readXLSXSheet(file *zip.File, name string, sharedStrings []string, dateStyles map[int]bool) (*sheet, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	type xlsxCell struct {
+		Ref    string `xml:"r,attr"`
+		Type   string `xml:"t,attr"`
+		Style  int    `xml:"s,attr"`
+		Value  string `xml:"v"`
+		Inline struct {
+			Text string   `xml:"t"`
+			Runs []string `xml:"r>t"`
+		} `xml:"is"`
+	}
+	type xlsxRow struct {
+		Num   int        `xml:"r,attr"`
+		Cells []xlsxCell `xml:"c"`
+	}
+
+	s := &sheet{name: name}
+	lastRow := 0
+
+	decoder := xml.NewDecoder(rc)
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+
+		var row xlsxRow
+		if err := decoder.DecodeElement(&row, &start); err != nil {
+			return nil, err
+		}
+		if row.Num == 0 {
+			row.Num = lastRow + 1
+		}
+		lastRow = row.Num
+
+		var cells []string
+		for i, c := range row.Cells {
+			col := i
+			if c.Ref != "" {
+				if idx, ok := columnIndex(c.Ref); ok {
+					col = idx
+				}
+			}
+			if col >= maxSheetColumns*4 {
+				continue // far-off cells only widen the reported column count
+			}
+
+			var value string
+			switch c.Type {
+			case "s":
+				if idx, err := strconv.Atoi(c.Value); err == nil && idx >= 0 && idx < len(sharedStrings) {
+					value = sharedStrings[idx]
+				}
+			case "inlineStr":
+				value = c.Inline.Text + strings.Join(c.Inline.Runs, "")
+			case "b":
+				value = "FALSE"
+				if c.Value == "1" {
+					value = "TRUE"
+				}
+			case "str", "e", "d":
+				value = c.Value
+			default:
+				value = c.Value
+				if dateStyles[c.Style] {
+					if serial, err := strconv.ParseFloat(c.Value, 64); err == nil {
+						value = excelDate(serial)
+					}
+				}
+			}
+
+			for len(cells) <= col {
+				cells = append(cells, "")
+			}
+			cells[col] = value
+		}
+
+		s.addRow(row.Num, cells)
+	}
+
+	return s, nil
+}
+
+// ODS
+
+// maxODSRepeat caps number-*-repeated attributes; files often pad tables
+// with empty rows or columns repeated up to the sheet size
+const maxODSRepeat = 1000
+
+// parseODSContent streams content.xml, handling repeated rows and columns
+func parseODSContent(r io.Reader) ([]*sheet, error) {
+	var sheets []*sheet
+	var current *sheet
+	var cells []string
+	var cellText strings.Builder
+	var cellValue string
+	cellRepeat, rowRepeat := 1, 1
+	rowNum := 0
+	paragraphs := 0
+	inCell := false
+
+	attr := func(el xml.StartElement, local string) string {
+		for _, a := range el.Attr {
+			if a.Name.Local == local {
+				return a.Value
+			}
+		}
+		return ""
+	}
+	repeat := func(el xml.StartElement, local string) int {
+		n, err := strconv.Atoi(attr(el, local))
+		if err != nil || n < 1 {
+			return 1
+		}
+		return n
+	}
+
+	decoder := xml.NewDecoder(r)
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "table":
+				current = &sheet{name: attr(t, "name")}
+				rowNum = 0
+			case "table-row":
+				cells = cells[:0]
+				rowRepeat = repeat(t, "number-rows-repeated")
+			case "table-cell", "covered-table-cell":
+				inCell = true
+				cellText.Reset()
+				paragraphs = 0
+				cellRepeat = repeat(t, "number-columns-repeated")
+				// Typed values are more precise than the formatted paragraph text
+				cellValue = ""
+				switch attr(t, "value-type") {
+				case "date":
+					cellValue = attr(t, "date-value")
+				case "boolean":
+					cellValue = strings.ToUpper(attr(t, "boolean-value"))
+				}
+			case "p":
+				if inCell {
+					if paragraphs > 0 {
+						cellText.WriteString(" ")
+					}
+					paragraphs++
+				}
+			case "s":
+				if inCell {
+					cellText.WriteString(strings.Repeat(" ", repeat(t, "c")))
+				}
+			case "tab":
+				if inCell {
+					cellText.WriteString(" ")
+				}
+			}
+
+		case xml.CharData:
+			if inCell {
+				cellText.Write(t)
+			}
+
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "table-cell", "covered-table-cell":
+				inCell = false
+				value := cellText.String()
+				if cellValue != "" {
+					value = cellValue
+				}
+				if value == "" && cellRepeat > maxODSRepeat {
+					cellRepeat = 1 // trailing padding; the row trims it anyway
+				}
+				for i := 0; i < cellRepeat && len(cells) < maxSheetColumns*4; i++ {
+					cells = append(cells, value)
+				}
+			case "table-row":
+				if current == nil {
+					continue
+				}
+				empty := true
+				for _, c := range cells {
+					if strings.TrimSpace(c) != "" {
+						empty = false
+						break
+					}
+				}
+				if empty {
+					rowNum += rowRepeat
+					continue
+				}
+				for i := 0; i < rowRepeat; i++ {
+					rowNum++
+					if i < maxODSRepeat {
+						current.addRow(rowNum, append([]string(nil), cells...))
+					}
+				}
+			case "table":
+				if current != nil {
+					sheets = append(sheets, current)
+				}
+				current = nil
+			}
+		}
+	}
+
+	return sheets, nil
+}
@@ -0,0 +1,745 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"local-agent/config"
+)
+
+// SQLite database file format, read directly from the b-tree pages. Only
+// what a schema review needs is supported: the schema table, row counts
+// and the first rows of each table.
+
+var sqliteMagic = []byte("SQLite format 3\x00")
+
+const (
+	sqliteHeaderSize = 100
+	maxSQLitePayload = 64 * 1024 // payload bytes read per sampled row
+	maxSQLiteDepth   = 64        // b-tree depth, guarding against corrupt files
+	maxSQLiteSchema  = 10000     // schema entries read
+	maxSQLiteColumns = 50        // columns shown in sample rows
+	sqliteLeafTable  = 0x0D
+	sqliteLeafIndex  = 0x0A
+	sqliteInnerTable = 0x05
+	sqliteInnerIndex = 0x02
+	sqliteEncodingLE = 2
+	sqliteEncodingBE = 3
+)
+
+// sqliteFile is an open database file
+type sqliteFile struct {
+	r        io.ReaderAt
+	pageSize int
+	usable   int // page size minus the reserved bytes at the end of each page
+	pages    uint32
+	encoding uint32
+	version  uint32 // SQLITE_VERSION_NUMBER of the library that last wrote the file
+	wal      bool
+}
+
+// sqliteSchemaEntry is a row of the sqlite_schema table
+type sqliteSchemaEntry struct {
+	kind     string // table, view, index or trigger
+	name     string
+	table    string
+	rootPage int64
+	sql      string
+}
+
+// sqliteTable is a table with the statistics shown for it
+type sqliteTable struct {
+	entry      sqliteSchemaEntry
+	columns    []string
+	rowidAlias int // column holding the rowid (INTEGER PRIMARY KEY), or -1
+	noRowid    bool
+	rows       int64
+	sample     [][]string
+	err        error
+}
+
+// isSQLite reports whether data starts with the SQLite database header
+func isSQLite(data []byte) bool {
+	return bytes.HasPrefix(data, sqliteMagic)
+}
+
+// ReadSQLiteContent lists the tables, views, indexes and triggers of a
+// SQLite database with their CREATE statements, the row count of every
+// table and the first rows of each. The database is read without SQLite
+// itself, so the file is never modified and no driver is needed.
+func (d *Detector) ReadSQLiteContent(path string, opts config.SQLiteConfig) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open database: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat database: %w", err)
+	}
+
+	db, err := openSQLite(file, stat.Size())
+	if err != nil {
+		return "", err
+	}
+
+	schema, err := db.readSchema()
+	if err != nil {
+		return "", fmt.Errorf("failed to read database schema: %w", err)
+	}
+
+	var tables []*sqliteTable
+	byKind := make(map[string][]sqliteSchemaEntry)
+	for _, entry := range schema {
+		byKind[entry.kind] = append(byKind[entry.kind], entry)
+		if entry.kind != "table" {
+			continue
+		}
+		table := &sqliteTable{entry: entry, rowidAlias: -1}
+		table.columns, table.rowidAlias, table.noRowid = parseSQLiteColumns(entry.sql)
+		if entry.rootPage <= 0 {
+			// Virtual tables keep their rows elsewhere
+			table.rows = -1
+			tables = append(tables, table)
+			continue
+		}
+		table.rows, table.err = db.countRows(uint32(entry.rootPage))
+		// WITHOUT ROWID tables store rows in primary key order, not as records
+		// of the declared columns, so they are counted but not sampled
+		if table.err == nil && !table.noRowid && len(tables) < opts.MaxTables && opts.SampleRows > 0 {
+			table.sample, table.err = db.sampleRows(uint32(entry.rootPage), opts.SampleRows, table.rowidAlias)
+		}
+		tables = append(tables, table)
+	}
+
+	_, walErr := os.Stat(path + "-wal")
+	return db.render(tables, byKind, opts, walErr == nil), nil
+}
+
+// openSQLite reads the database header
+func openSQLite(r io.ReaderAt, size int64) (*sqliteFile, error) {
+	header := make([]byte, sqliteHeaderSize)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("failed to read database header: %w", err)
+	}
+	if !isSQLite(header) {
+		return nil, fmt.Errorf("not a SQLite 3 database")
+	}
+
+	be := binary.BigEndian
+	pageSize := int(be.Uint16(header[16:]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return nil, fmt.Errorf("invalid database page size %d", pageSize)
+	}
+
+	db := &sqliteFile{
+		r:        r,
+		pageSize: pageSize,
+		usable:   pageSize - int(header[20]),
+		encoding: be.Uint32(header[56:]),
+		version:  be.Uint32(header[96:]),
+		wal:      header[18] == 2,
+	}
+	// The page count in the header is only valid if written by 3.7.0 or later
+	db.pages = be.Uint32(header[28:])
+	if db.pages == 0 || be.Uint32(header[24:]) != be.Uint32(header[92:]) {
+		db.pages = uint32(size / int64(pageSize))
+	}
+	if db.usable < 480 {
+		return nil, fmt.Errorf("invalid database reserved space %d", header[20])
+	}
+	return db, nil
+}
+
+// page reads page number n (1-based)
+func (db *sqliteFile) page(n uint32) ([]byte, error) {
+	if n == 0 || n > db.pages {
+		return nil, fmt.Errorf("page %d out of range", n)
+	}
+	data := make([]byte, db.pageSize)
+	if _, err := db.r.ReadAt(data, int64(n-1)*int64(db.pageSize)); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read page %d: %w", n, err)
+	}
+	return data, nil
+}
+
+// btreePage is a parsed b-tree page header and cell pointer array
+type btreePage struct {
+	data  []byte
+	kind  byte
+	cells []int // cell offsets within data
+	right uint32
+}
+
+func (db *sqliteFile) btreePage(n uint32) (*btreePage, error) {
+	data, err := db.page(n)
+	if err != nil {
+		return nil, err
+	}
+	offset := 0
+	if n == 1 {
+		offset = sqliteHeaderSize
+	}
+	if len(data) < offset+8 {
+		return nil, fmt.Errorf("page %d is truncated", n)
+	}
+
+	page := &btreePage{data: data, kind: data[offset]}
+	headerSize := 8
+	switch page.kind {
+	case sqliteLeafTable, sqliteLeafIndex:
+	case sqliteInnerTable, sqliteInnerIndex:
+		headerSize = 12
+		page.right = binary.BigEndian.Uint32(data[offset+8:])
+	default:
+		return nil, fmt.Errorf("page %d is not a b-tree page", n)
+	}
+
+	count := int(binary.BigEndian.Uint16(data[offset+3:]))
+	pointers := offset + headerSize
+	if pointers+2*count > len(data) {
+		return nil, fmt.Errorf("page %d has an invalid cell count", n)
+	}
+	for i := 0; i < count; i++ {
+		cell := int(binary.BigEndian.Uint16(data[pointers+2*i:]))
+		if cell >= len(data) {
+			return nil, fmt.Errorf("page %d has an invalid cell pointer", n)
+		}
+		page.cells = append(page.cells, cell)
+	}
+	return page, nil
+}
+
+// walk visits the pages of the b-tree rooted at root in key order, calling
+// visit for each page. visit returns false to stop the walk.
+func (db *sqliteFile) walk(root uint32, visit func(page *btreePage) (bool, error)) error {
+	seen := make(map[uint32]bool)
+	var descend func(n uint32, depth int) (bool, error)
+	descend = func(n uint32, depth int) (bool, error) {
+		if depth > maxSQLiteDepth || seen[n] {
+			return false, fmt.Errorf("b-tree rooted at page %d is corrupt", root)
+		}
+		seen[n] = true
+
+		page, err := db.btreePage(n)
+		if err != nil {
+			return false, err
+		}
+		if more, err := visit(page); !more || err != nil {
+			return false, err
+		}
+		if page.kind == sqliteLeafTable || page.kind == sqliteLeafIndex {
+			return true, nil
+		}
+		for _, cell := range page.cells {
+			if cell+4 > len(page.data) {
+				return false, fmt.Errorf("page %d has a truncated cell", n)
+			}
+			if more, err := descend(binary.BigEndian.Uint32(page.data[cell:]), depth+1); !more || err != nil {
+				return false, err
+			}
+		}
+		return descend(page.right, depth+1)
+	}
+	_, err := descend(root, 0)
+	return err
+}
+
+// countRows counts the entries of a table b-tree, or of an index b-tree
+// for WITHOUT ROWID tables, whose interior cells hold entries too
+func (db *sqliteFile) countRows(root uint32) (int64, error) {
+	var rows int64
+	err := db.walk(root, func(page *btreePage) (bool, error) {
+		if page.kind != sqliteInnerTable {
+			rows += int64(len(page.cells))
+		}
+		return true, nil
+	})
+	return rows, err
+}
+
+// sampleRows decodes the first limit rows of a rowid table. The rowid is
+// filled into the INTEGER PRIMARY KEY column, which stores NULL.
+func (db *sqliteFile) sampleRows(root uint32, limit int, rowidAlias int) ([][]string, error) {
+	var rows [][]string
+	err := db.walk(root, func(page *btreePage) (bool, error) {
+		if page.kind != sqliteLeafTable {
+			return true, nil
+		}
+		for _, cell := range page.cells {
+			payload, rowid, err := db.tableLeafCell(page.data, cell)
+			if err != nil {
+				return false, err
+			}
+			values := db.decodeRecord(payload)
+			if rowidAlias >= 0 && rowidAlias < len(values) && values[rowidAlias] == "NULL" {
+				values[rowidAlias] = strconv.FormatInt(rowid, 10)
+			}
+			rows = append(rows, values)
+			if len(rows) == limit {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	return rows, err
+}
+
+// tableLeafCell returns the payload (up to maxSQLitePayload bytes,
+// following overflow pages) and rowid of a table leaf cell
+func (db *sqliteFile) tableLeafCell(data []byte, offset int) ([]byte, int64, error) {
+	size, n := readSQLiteVarint(data[offset:])
+	offset += n
+	rowid, n := readSQLiteVarint(data[offset:])
+	offset += n
+
+	// Payload stored on the page, per the file format's overflow rules
+	usable := int64(db.usable)
+	maxLocal := usable - 35
+	local := size
+	if size > maxLocal {
+		minLocal := (usable-12)*32/255 - 23
+		local = minLocal + (size-minLocal)%(usable-4)
+		if local > maxLocal {
+			local = minLocal
+		}
+	}
+	if size < 0 || offset+int(local) > len(data) {
+		return nil, 0, fmt.Errorf("cell exceeds its page")
+	}
+
+	want := min(size, maxSQLitePayload)
+	payload := append([]byte(nil), data[offset:offset+int(min(local, want))]...)
+	if local < size && int64(len(payload)) < want && offset+int(local)+4 <= len(data) {
+		next := binary.BigEndian.Uint32(data[offset+int(local):])
+		seen := make(map[uint32]bool)
+		for next != 0 && int64(len(payload)) < want && !seen[next] {
+			seen[next] = true
+			page, err := db.page(next)
+			if err != nil {
+				return nil, 0, err
+			}
+			chunk := page[4:db.usable]
+			if rest := want - int64(len(payload)); int64(len(chunk)) > rest {
+				chunk = chunk[:rest]
+			}
+			payload = append(payload, chunk...)
+			next = binary.BigEndian.Uint32(page)
+		}
+	}
+	return payload, rowid, nil
+}
+
+// decodeRecord renders the values of a record as text. Values cut off by
+// maxSQLitePayload are shown as far as they were read.
+func (db *sqliteFile) decodeRecord(payload []byte) []string {
+	headerSize, n := readSQLiteVarint(payload)
+	if headerSize > int64(len(payload)) || headerSize < int64(n) {
+		return nil
+	}
+
+	var serialTypes []int64
+	for offset := n; offset < int(headerSize); {
+		serialType, n := readSQLiteVarint(payload[offset:])
+		if n == 0 {
+			break
+		}
+		serialTypes = append(serialTypes, serialType)
+		offset += n
+	}
+
+	var values []string
+	body := payload[headerSize:]
+	for _, serialType := range serialTypes {
+		size := sqliteSerialSize(serialType)
+		value := body[:min(int64(len(body)), size)]
+		body = body[len(value):]
+
+		switch {
+		case serialType == 0:
+			values = append(values, "NULL")
+		case serialType >= 1 && serialType <= 6:
+			if int64(len(value)) < size {
+				values = append(values, "")
+				continue
+			}
+			var v int64
+			for _, b := range value {
+				v = v<<8 | int64(b)
+			}
+			// Sign-extend integers narrower than 64 bits
+			if shift := 64 - 8*len(value); shift > 0 {
+				v = v << shift >> shift
+			}
+			values = append(values, strconv.FormatInt(v, 10))
+		case serialType == 7:
+			if len(value) < 8 {
+				values = append(values, "")
+				continue
+			}
+			values = append(values, formatDecimal(math.Float64frombits(binary.BigEndian.Uint64(value))))
+		case serialType == 8:
+			values = append(values, "0")
+		case serialType == 9:
+			values = append(values, "1")
+		case serialType >= 12 && serialType%2 == 0:
+			values = append(values, fmt.Sprintf("<blob, %d bytes>", size))
+		case serialType >= 13:
+			values = append(values, db.decodeText(value))
+		default:
+			values = append(values, "")
+		}
+	}
+	return values
+}
+
+// decodeText decodes a text value in the database encoding
+func (db *sqliteFile) decodeText(value []byte) string {
+	if db.encoding != sqliteEncodingLE && db.encoding != sqliteEncodingBE {
+		return string(value)
+	}
+	units := make([]uint16, len(value)/2)
+	for i := range units {
+		if db.encoding == sqliteEncodingLE {
+			units[i] = binary.LittleEndian.Uint16(value[2*i:])
+		} else {
+			units[i] = binary.BigEndian.Uint16(value[2*i:])
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+// sqliteSerialSize returns the body size of a value of the serial type
+func sqliteSerialSize(serialType int64) int64 {
+	switch {
+	case serialType >= 12:
+		return (serialType - 12) / 2
+	case serialType >= 1 && serialType <= 4:
+		return serialType
+	case serialType == 5:
+		return 6
+	case serialType == 6 || serialType == 7:
+		return 8
+	}
+	return 0
+}
+
+// readSQLiteVarint decodes a big-endian varint of up to 9 bytes, returning
+// the value and the bytes used (0 on truncated input)
+func readSQLiteVarint(data []byte) (int64, int) {
+	var v uint64
+	for i := 0; i < 9 && i < len(data); i++ {
+		if i == 8 {
+			return int64(v<<8 | uint64(data[i])), 9
+		}
+		v = v<<7 | uint64(data[i]&0x7F)
+		if data[i]&0x80 == 0 {
+			return int64(v), i + 1
+		}
+	}
+	return int64(v), len(data)
+}
+
+// readSchema reads the sqlite_schema table, rooted at page 1
+func (db *sqliteFile) readSchema() ([]sqliteSchemaEntry, error) {
+	var entries []sqliteSchemaEntry
+	err := db.walk(1, func(page *btreePage) (bool, error) {
+		if page.kind != sqliteLeafTable {
+			return true, nil
+		}
+		for _, cell := range page.cells {
+			payload, _, err := db.tableLeafCell(page.data, cell)
+			if err != nil {
+				return false, err
+			}
+			values := db.decodeRecord(payload)
+			if len(values) < 5 {
+				continue
+			}
+			rootPage, _ := strconv.ParseInt(values[3], 10, 64)
+			sql := values[4]
+			if sql == "NULL" {
+				sql = ""
+			}
+			entries = append(entries, sqliteSchemaEntry{kind: values[0], name: values[1], table: values[2], rootPage: rootPage, sql: sql})
+			if len(entries) == maxSQLiteSchema {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	return entries, err
+}
+
+// parseSQLiteColumns extracts the column names of a CREATE TABLE statement,
+// the index of the column aliasing the rowid (-1 if none) and whether the
+// table is WITHOUT ROWID
+func parseSQLiteColumns(sql string) ([]string, int, bool) {
+	open := strings.IndexByte(sql, '(')
+	if open < 0 {
+		return nil, -1, false
+	}
+
+	// Split the definitions at top-level commas, skipping quoted text
+	var defs []string
+	depth, start, end := 0, open+1, -1
+	var quote byte
+	for i := open; i < len(sql) && end < 0; i++ {
+		c := sql[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '[':
+			quote = ']'
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				defs = append(defs, sql[start:i])
+				end = i
+			}
+		case c == ',' && depth == 1:
+			defs = append(defs, sql[start:i])
+			start = i + 1
+		}
+	}
+	if end < 0 {
+		return nil, -1, false
+	}
+	withoutRowid := strings.Contains(strings.ToUpper(strings.Join(strings.Fields(sql[end:]), " ")), "WITHOUT ROWID")
+
+	var names []string
+	columnTypes := make(map[string]string)
+	alias := -1
+	for _, def := range defs {
+		def = strings.TrimSpace(def)
+		fields := strings.Fields(strings.ToUpper(def))
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "CONSTRAINT", "PRIMARY", "UNIQUE", "CHECK", "FOREIGN":
+			// A table-level PRIMARY KEY on one INTEGER column aliases the rowid
+			if fields[0] == "PRIMARY" {
+				if inner := strings.TrimSpace(between(def, "(", ")")); inner != "" && !strings.Contains(inner, ",") {
+					name := unquoteSQLiteName(strings.Fields(inner)[0])
+					for i, column := range names {
+						if strings.EqualFold(column, name) && columnTypes[strings.ToLower(column)] == "INTEGER" {
+							alias = i
+						}
+					}
+				}
+			}
+			continue
+		}
+
+		name, rest := splitSQLiteName(def)
+		restFields := strings.Fields(strings.ToUpper(rest))
+		columnType := ""
+		if len(restFields) > 0 {
+			columnType = restFields[0]
+		}
+		columnTypes[strings.ToLower(name)] = columnType
+		if columnType == "INTEGER" && strings.Contains(strings.Join(restFields, " "), "PRIMARY KEY") && !strings.Contains(strings.Join(restFields, " "), "PRIMARY KEY DESC") {
+			alias = len(names)
+		}
+		names = append(names, name)
+	}
+	if withoutRowid {
+		alias = -1
+	}
+	return names, alias, withoutRowid
+}
+
+// splitSQLiteName splits a column definition into its (unquoted) name and
+// the rest
+func splitSQLiteName(def string) (string, string) {
+	if def == "" {
+		return "", ""
+	}
+	closing := map[byte]byte{'"': '"', '`': '`', '[': ']', '\'': '\''}[def[0]]
+	if closing != 0 {
+		if end := strings.IndexByte(def[1:], closing); end >= 0 {
+			return def[1 : end+1], def[end+2:]
+		}
+	}
+	if i := strings.IndexAny(def, " \t\r\n"); i >= 0 {
+		return def[:i], def[i:]
+	}
+	return def, ""
+}
+
+func unquoteSQLiteName(name string) string {
+	unquoted, _ := splitSQLiteName(name)
+	return unquoted
+}
+
+// between returns the text between the first open and the last close
+func between(s, open, close string) string {
+	start := strings.Index(s, open)
+	end := strings.LastIndex(s, close)
+	if start < 0 || end <= start {
+		return ""
+	}
+	return s[start+len(open) : end]
+}
+
+// render writes the database overview followed by one section per table
+// and view, up to opts.MaxTables of each
+func (db *sqliteFile) render(tables []*sqliteTable, byKind map[string][]sqliteSchemaEntry, opts config.SQLiteConfig, walFile bool) string {
+	var builder strings.Builder
+
+	var user, internal []*sqliteTable
+	for _, table := range tables {
+		if strings.HasPrefix(table.entry.name, "sqlite_") {
+			internal = append(internal, table)
+		} else {
+			user = append(user, table)
+		}
+	}
+	var indexes []sqliteSchemaEntry
+	for _, index := range byKind["index"] {
+		// Automatic indexes for UNIQUE and PRIMARY KEY constraints have no SQL
+		if index.sql != "" {
+			indexes = append(indexes, index)
+		}
+	}
+
+	builder.WriteString("## Database\n\n")
+	if db.version > 0 {
+		builder.WriteString(fmt.Sprintf("- Last written by SQLite %d.%d.%d\n", db.version/1000000, db.version/1000%1000, db.version%1000))
+	}
+	encoding := "UTF-8"
+	if db.encoding == sqliteEncodingLE {
+		encoding = "UTF-16le"
+	} else if db.encoding == sqliteEncodingBE {
+		encoding = "UTF-16be"
+	}
+	builder.WriteString(fmt.Sprintf("- Pages: %d of %d bytes, text encoding %s\n", db.pages, db.pageSize, encoding))
+	builder.WriteString(fmt.Sprintf("- Tables: %d, views: %d, indexes: %d, triggers: %d\n",
+		len(user), len(byKind["view"]), len(indexes), len(byKind["trigger"])))
+	if len(internal) > 0 {
+		var names []string
+		for _, table := range internal {
+			names = append(names, table.entry.name)
+		}
+		builder.WriteString(fmt.Sprintf("- Internal tables: %s\n", strings.Join(names, ", ")))
+	}
+	if db.wal && walFile {
+		builder.WriteString("- Journal mode WAL with a -wal file present: changes not yet checkpointed into the database file are not shown\n")
+	}
+
+	if len(user) > 0 {
+		builder.WriteString("\n| Table | Rows | Columns |\n|---|---|---|\n")
+		for _, table := range user {
+			builder.WriteString(fmt.Sprintf("| %s | %s | %d |\n", escapeTableCell(table.entry.name), formatSQLiteRows(table), len(table.columns)))
+		}
+	}
+
+	indexesByTable := make(map[string][]sqliteSchemaEntry)
+	for _, index := range indexes {
+		indexesByTable[index.table] = append(indexesByTable[index.table], index)
+	}
+	triggersByTable := make(map[string][]sqliteSchemaEntry)
+	for _, trigger := range byKind["trigger"] {
+		triggersByTable[trigger.table] = append(triggersByTable[trigger.table], trigger)
+	}
+
+	for i, table := range user {
+		if i == opts.MaxTables {
+			builder.WriteString(fmt.Sprintf("\n%d more tables are listed above without their schema (limit %d).\n", len(user)-i, opts.MaxTables))
+			break
+		}
+		builder.WriteString(fmt.Sprintf("\n## Table: %s\n\n", table.entry.name))
+		builder.WriteString(fmt.Sprintf("Rows: %s\n\n", formatSQLiteRows(table)))
+		builder.WriteString(fenceBlock(table.entry.sql+";", "sql") + "\n")
+
+		var related []string
+		for _, entry := range append(indexesByTable[table.entry.name], triggersByTable[table.entry.name]...) {
+			related = append(related, entry.sql+";")
+		}
+		if len(related) > 0 {
+			builder.WriteString("\nIndexes and triggers:\n\n" + fenceBlock(strings.Join(related, "\n"), "sql") + "\n")
+		}
+
+		if table.err != nil {
+			builder.WriteString(fmt.Sprintf("\nRows could not be read: %v\n", table.err))
+		} else if len(table.sample) == 1 {
+			builder.WriteString("\nFirst row:\n\n")
+			writeSQLiteRows(&builder, table)
+		} else if len(table.sample) > 0 {
+			builder.WriteString(fmt.Sprintf("\nFirst %d rows:\n\n", len(table.sample)))
+			writeSQLiteRows(&builder, table)
+		} else if table.noRowid && table.rows > 0 {
+			builder.WriteString("\nRows of WITHOUT ROWID tables are not sampled.\n")
+		}
+	}
+
+	views := byKind["view"]
+	for i, view := range views {
+		if i == opts.MaxTables {
+			builder.WriteString(fmt.Sprintf("\n%d more views not shown (limit %d).\n", len(views)-i, opts.MaxTables))
+			break
+		}
+		builder.WriteString(fmt.Sprintf("\n## View: %s\n\n%s\n", view.name, fenceBlock(view.sql+";", "sql")))
+	}
+
+	return strings.TrimSpace(builder.String())
+}
+
+// writeSQLiteRows writes a table's sample rows as a Markdown table
+func writeSQLiteRows(builder *strings.Builder, table *sqliteTable) {
+	width := len(table.columns)
+	for _, row := range table.sample {
+		width = max(width, len(row))
+	}
+	width = min(width, maxSQLiteColumns)
+
+	builder.WriteString("|")
+	for col := 0; col < width; col++ {
+		name := fmt.Sprintf("column %d", col+1)
+		if col < len(table.columns) {
+			name = table.columns[col]
+		}
+		builder.WriteString(" " + escapeTableCell(name) + " |")
+	}
+	builder.WriteString("\n|" + strings.Repeat("---|", width) + "\n")
+	for _, row := range table.sample {
+		builder.WriteString("|")
+		for col := 0; col < width; col++ {
+			// Columns added by ALTER TABLE are missing from older rows
+			value := "(default)"
+			if col < len(row) {
+				value = row[col]
+			}
+			builder.WriteString(" " + escapeTableCell(value) + " |")
+		}
+		builder.WriteString("\n")
+	}
+}
+
+func formatSQLiteRows(table *sqliteTable) string {
+	switch {
+	case table.rows < 0:
+		return "virtual"
+	case table.err != nil && table.rows == 0:
+		return "unreadable"
+	}
+	return strconv.FormatInt(table.rows, 10)
+}
@@ -0,0 +1,459 @@
+package analyzer
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"local-agent/types"
+)
+
+var (
+	// hclAttributePattern matches "name = value" at the start of a line
+	hclAttributePattern = regexp.MustCompile(`^([\w-]+)\s*=\s*(.*)$`)
+	// hclBlockPattern matches a block header such as
+	// `resource "aws_instance" "web" {`, or a block opened and closed on
+	// one line
+	hclBlockPattern = regexp.MustCompile(`^([\w-]+)((?:\s+(?:"[^"]*"|[\w-]+))*)\s*\{\s*(\})?$`)
+	// hclLabelPattern matches one block label
+	hclLabelPattern = regexp.MustCompile(`"[^"]*"|[\w-]+`)
+	// hclHeredocPattern matches the start of a heredoc value
+	hclHeredocPattern = regexp.MustCompile(`^<<-?\s*([A-Za-z_]\w*)$`)
+	// hclStringPattern matches the string literals of an expression
+	hclStringPattern = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"`)
+)
+
+// hclBlock is a block of a Terraform file, read without evaluating any
+// expression
+type hclBlock struct {
+	kind       string // resource, variable, ingress, ...
+	labels     []string
+	attributes []hclAttribute
+	blocks     []*hclBlock
+	start, end int // lines
+}
+
+// hclAttribute is an argument of a block, with its expression as written
+type hclAttribute struct {
+	name  string
+	value string
+	line  int
+}
+
+// attribute returns the named attribute of b, or nil
+func (b *hclBlock) attribute(name string) *hclAttribute {
+	for i := range b.attributes {
+		if b.attributes[i].name == name {
+			return &b.attributes[i]
+		}
+	}
+	return nil
+}
+
+// literal returns the value of a string, number or bool attribute, or ""
+// when it is missing or an expression
+func (b *hclBlock) literal(name string) string {
+	attribute := b.attribute(name)
+	if attribute == nil {
+		return ""
+	}
+	if match := hclStringPattern.FindStringSubmatch(attribute.value); match != nil && match[0] == attribute.value {
+		return match[1]
+	}
+	if strings.ContainsAny(attribute.value, " .([{\"") {
+		return ""
+	}
+	return attribute.value
+}
+
+// stringValues returns the string literals of the named attributes
+func (b *hclBlock) stringValues(names ...string) []string {
+	var values []string
+	for _, name := range names {
+		if attribute := b.attribute(name); attribute != nil {
+			for _, match := range hclStringPattern.FindAllStringSubmatch(attribute.value, -1) {
+				values = append(values, match[1])
+			}
+		}
+	}
+	return values
+}
+
+// address names a block the way Terraform does, e.g. aws_instance.web,
+// data.aws_ami.ubuntu or module.vpc
+func (b *hclBlock) address() string {
+	switch {
+	case b.kind == "resource" && len(b.labels) == 2:
+		return b.labels[0] + "." + b.labels[1]
+	case len(b.labels) > 0:
+		return b.kind + "." + strings.Join(b.labels, ".")
+	}
+	return b.kind
+}
+
+// parseHCL reads the block structure of a Terraform file. It relies on the
+// layout terraform fmt produces, one attribute or block header per line,
+// which is enough to summarize and check a configuration.
+func parseHCL(source string) []*hclBlock {
+	lines := strings.Split(source, "\n")
+	root := &hclBlock{}
+	stack := []*hclBlock{root}
+	inComment := false
+
+	for i := 0; i < len(lines); i++ {
+		line, _ := scanHCL(lines[i], &inComment)
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		current := stack[len(stack)-1]
+
+		if match := hclAttributePattern.FindStringSubmatch(line); match != nil {
+			start := i
+			value := strings.TrimSpace(match[2])
+			if heredoc := hclHeredocPattern.FindStringSubmatch(value); heredoc != nil {
+				var body []string
+				for i+1 < len(lines) {
+					i++
+					if strings.TrimSpace(lines[i]) == heredoc[1] {
+						break
+					}
+					body = append(body, lines[i])
+				}
+				value = strings.Join(body, "\n")
+			} else {
+				_, depth := scanHCL(value, new(bool))
+				for depth > 0 && i+1 < len(lines) {
+					i++
+					next, delta := scanHCL(lines[i], &inComment)
+					value += "\n" + strings.TrimSpace(next)
+					depth += delta
+				}
+			}
+			current.attributes = append(current.attributes, hclAttribute{name: match[1], value: value, line: start + 1})
+			continue
+		}
+
+		if match := hclBlockPattern.FindStringSubmatch(line); match != nil {
+			block := &hclBlock{kind: match[1], start: i + 1, end: i + 1}
+			for _, label := range hclLabelPattern.FindAllString(match[2], -1) {
+				block.labels = append(block.labels, strings.Trim(label, `"`))
+			}
+			current.blocks = append(current.blocks, block)
+			if match[3] == "" {
+				stack = append(stack, block)
+			}
+			continue
+		}
+
+		if strings.HasPrefix(line, "}") && len(stack) > 1 {
+			current.end = i + 1
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return root.blocks
+}
+
+// scanHCL removes the comments from a line and returns it with the change
+// in bracket depth outside string literals. inComment carries an open
+// /* comment over to the next line.
+func scanHCL(line string, inComment *bool) (string, int) {
+	var kept strings.Builder
+	depth := 0
+	inString := false
+	interpolation := 0
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if *inComment {
+			if c == '*' && i+1 < len(line) && line[i+1] == '/' {
+				*inComment = false
+				i++
+			}
+			continue
+		}
+		if inString {
+			kept.WriteByte(c)
+			switch {
+			case c == '\\' && i+1 < len(line):
+				i++
+				kept.WriteByte(line[i])
+			case c == '$' && i+1 < len(line) && line[i+1] == '{':
+				interpolation++
+				i++
+				kept.WriteByte('{')
+			case c == '}' && interpolation > 0:
+				interpolation--
+			case c == '"' && interpolation == 0:
+				inString = false
+			}
+			continue
+		}
+		switch {
+		case c == '#' || (c == '/' && i+1 < len(line) && line[i+1] == '/'):
+			return kept.String(), depth
+		case c == '/' && i+1 < len(line) && line[i+1] == '*':
+			*inComment = true
+			i++
+			continue
+		case c == '"':
+			inString = true
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		}
+		kept.WriteByte(c)
+	}
+	return kept.String(), depth
+}
+
+// terraformResources summarizes the top-level blocks of a Terraform file
+// and checks the resources
+func terraformResources(source string) ([]iacResource, []string) {
+	lines := strings.Split(source, "\n")
+	blocks := parseHCL(source)
+
+	kinds := make(map[string]int)
+	resourceTypes := make(map[string]int)
+	var resources []iacResource
+	for _, block := range blocks {
+		kinds[block.kind]++
+		if block.kind == "resource" && len(block.labels) > 0 {
+			resourceTypes[block.labels[0]]++
+		}
+
+		resource := iacResource{heading: strings.TrimSpace(block.kind + " " + strings.Join(block.labels, ".")), language: "hcl"}
+		resource.facts = append(resource.facts, [2]string{"Lines", fmt.Sprintf("%d-%d", block.start, block.end)})
+		if source := block.literal("source"); source != "" && block.kind == "module" {
+			resource.facts = append(resource.facts, [2]string{"Source", strings.TrimSpace(source + " " + block.literal("version"))})
+		}
+		for _, meta := range []string{"count", "for_each", "provider"} {
+			if attribute := block.attribute(meta); attribute != nil && block.kind != "provider" {
+				resource.facts = append(resource.facts, [2]string{meta, truncateRunes(attribute.value, 100)})
+			}
+		}
+		if nested := formatNestedBlocks(block); nested != "" {
+			resource.facts = append(resource.facts, [2]string{"Nested blocks", nested})
+		}
+		if block.kind == "resource" {
+			resource.findings = checkTerraformResource(block)
+		}
+		if block.end >= block.start && block.end <= len(lines) {
+			resource.source = strings.Join(lines[block.start-1:block.end], "\n")
+		}
+		resources = append(resources, resource)
+	}
+
+	format := []string{"Format: Terraform"}
+	if summary := formatKindCounts(kinds); summary != "" {
+		format = append(format, "Blocks: "+summary)
+	}
+	if summary := formatKindCounts(resourceTypes); summary != "" {
+		format = append(format, "Resource types: "+summary)
+	}
+	return resources, format
+}
+
+// formatNestedBlocks lists the nested blocks of b with their counts, e.g.
+// "ingress (2), egress"
+func formatNestedBlocks(b *hclBlock) string {
+	counts := make(map[string]int)
+	var order []string
+	for _, nested := range b.blocks {
+		if counts[nested.kind] == 0 {
+			order = append(order, nested.kind)
+		}
+		counts[nested.kind]++
+	}
+	var parts []string
+	for _, kind := range order {
+		if counts[kind] > 1 {
+			parts = append(parts, fmt.Sprintf("%s (%d)", kind, counts[kind]))
+		} else {
+			parts = append(parts, kind)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// checkTerraformResource runs the misconfiguration checks on a resource
+// block and its nested blocks
+func checkTerraformResource(resource *hclBlock) []types.Finding {
+	if len(resource.labels) == 0 {
+		return nil
+	}
+	address := resource.address()
+	var findings []types.Finding
+	add := func(line int, severity types.Severity, description, suggestion string) {
+		findings = append(findings, types.Finding{
+			Line:        line,
+			Severity:    severity,
+			Category:    iacCategory,
+			Description: address + " " + description,
+			Suggestion:  suggestion,
+		})
+	}
+
+	// openIngress reports a rule of block that admits any address
+	openIngress := func(block *hclBlock, cidrAttributes []string, ports string, allPorts bool, adminPort bool) {
+		for _, name := range cidrAttributes {
+			attribute := block.attribute(name)
+			if attribute == nil {
+				continue
+			}
+			for _, cidr := range block.stringValues(name) {
+				if !isAnyAddress(cidr) {
+					continue
+				}
+				severity := types.SeverityMedium
+				if allPorts || adminPort {
+					severity = types.SeverityHigh
+				}
+				add(attribute.line, severity, fmt.Sprintf("allows ingress from %s on %s", cidr, ports),
+					"Restrict the source to known networks, or put the service behind a load balancer or VPN")
+				break
+			}
+		}
+	}
+
+	switch resource.labels[0] {
+	case "aws_security_group":
+		for _, rule := range resource.blocks {
+			if rule.kind == "ingress" {
+				ports, all, admin := awsPorts(rule.literal("from_port"), rule.literal("to_port"), rule.literal("protocol"))
+				openIngress(rule, []string{"cidr_blocks", "ipv6_cidr_blocks"}, ports, all, admin)
+			}
+		}
+	case "aws_security_group_rule":
+		if resource.literal("type") == "ingress" {
+			ports, all, admin := awsPorts(resource.literal("from_port"), resource.literal("to_port"), resource.literal("protocol"))
+			openIngress(resource, []string{"cidr_blocks", "ipv6_cidr_blocks"}, ports, all, admin)
+		}
+	case "aws_vpc_security_group_ingress_rule":
+		ports, all, admin := awsPorts(resource.literal("from_port"), resource.literal("to_port"), resource.literal("ip_protocol"))
+		openIngress(resource, []string{"cidr_ipv4", "cidr_ipv6"}, ports, all, admin)
+	case "google_compute_firewall":
+		if !strings.EqualFold(resource.literal("direction"), "EGRESS") {
+			var portList []string
+			all := false
+			for _, allow := range resource.blocks {
+				if allow.kind != "allow" {
+					continue
+				}
+				ports := allow.stringValues("ports")
+				if len(ports) == 0 {
+					all = true
+				}
+				portList = append(portList, ports...)
+			}
+			ports := "ports " + strings.Join(portList, ", ")
+			if all {
+				ports = "all ports"
+			}
+			openIngress(resource, []string{"source_ranges"}, ports, all, includesAdminPort(portList))
+		}
+	case "azurerm_network_security_rule":
+		checkAzureRule(resource, openIngress)
+	case "azurerm_network_security_group":
+		for _, rule := range resource.blocks {
+			if rule.kind == "security_rule" {
+				checkAzureRule(rule, openIngress)
+			}
+		}
+	}
+
+	// Settings that are wrong whatever the resource type
+	var walk func(block *hclBlock)
+	walk = func(block *hclBlock) {
+		for _, attribute := range block.attributes {
+			value := strings.Trim(attribute.value, `"`)
+			switch {
+			case attribute.name == "privileged" && value == "true":
+				add(attribute.line, types.SeverityHigh, "runs a privileged container",
+					"Remove privileged = true and grant only the capabilities the container needs")
+			case attribute.name == "publicly_accessible" && value == "true":
+				add(attribute.line, types.SeverityHigh, "is publicly accessible from the internet",
+					"Set publicly_accessible = false and reach the database through the private network")
+			case attribute.name == "acl" && (value == "public-read" || value == "public-read-write"):
+				add(attribute.line, types.SeverityHigh, fmt.Sprintf("grants public access with the %q ACL", value),
+					"Make the bucket private and serve public content through a CDN with origin access control")
+			case (attribute.name == "encrypted" || attribute.name == "storage_encrypted") && value == "false":
+				add(attribute.line, types.SeverityMedium, "stores data unencrypted ("+attribute.name+" = false)",
+					"Enable encryption at rest")
+			case attribute.name == "image":
+				if tag, ok := imageTag(value); ok && tag == "latest" && value == block.literal("image") {
+					add(attribute.line, types.SeverityMedium, fmt.Sprintf("uses the mutable \"latest\" image tag (%s)", value),
+						"Pin the image to a version tag or digest so deployments are reproducible")
+				}
+			}
+		}
+		for _, nested := range block.blocks {
+			walk(nested)
+		}
+	}
+	walk(resource)
+
+	return findings
+}
+
+// checkAzureRule checks an inbound allow rule of an Azure network security
+// group
+func checkAzureRule(rule *hclBlock, openIngress func(*hclBlock, []string, string, bool, bool)) {
+	if !strings.EqualFold(rule.literal("direction"), "Inbound") || !strings.EqualFold(rule.literal("access"), "Allow") {
+		return
+	}
+	ports := rule.stringValues("destination_port_range", "destination_port_ranges")
+	all := len(ports) == 0
+	for _, port := range ports {
+		if port == "*" || port == "0-65535" {
+			all = true
+		}
+	}
+	description := "ports " + strings.Join(ports, ", ")
+	if all {
+		description = "all ports"
+	}
+	openIngress(rule, []string{"source_address_prefix", "source_address_prefixes"}, description, all, includesAdminPort(ports))
+}
+
+// awsPorts describes an AWS port range, reporting whether it covers all
+// ports or a remote administration port (SSH, RDP)
+func awsPorts(from, to, protocol string) (string, bool, bool) {
+	if protocol == "-1" || strings.EqualFold(protocol, "all") {
+		return "all ports", true, true
+	}
+	low, errLow := strconv.Atoi(from)
+	high, errHigh := strconv.Atoi(to)
+	if errLow != nil || errHigh != nil {
+		return "ports " + from + "-" + to, false, false
+	}
+	if low <= 0 && high >= 65535 {
+		return "all ports", true, true
+	}
+	admin := (low <= 22 && high >= 22) || (low <= 3389 && high >= 3389)
+	if low == high {
+		return fmt.Sprintf("port %d", low), false, admin
+	}
+	return fmt.Sprintf("ports %d-%d", low, high), false, admin
+}
+
+// includesAdminPort reports whether a list of ports and port ranges
+// includes SSH or RDP
+func includesAdminPort(ports []string) bool {
+	for _, port := range ports {
+		low, high, isRange := strings.Cut(port, "-")
+		if !isRange {
+			high = low
+		}
+		from, errFrom := strconv.Atoi(low)
+		to, errTo := strconv.Atoi(high)
+		if errFrom != nil || errTo != nil {
+			continue
+		}
+		if (from <= 22 && to >= 22) || (from <= 3389 && to >= 3389) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxPCAPTimeBuckets and maxLogTimeBuckets bound the timeline rows; the
+// window widens through timeBucketWidths whenever more are needed. Logs get
+// fewer rows since their timeline is a side note to the templates.
+const (
+	maxPCAPTimeBuckets = 1440
+	maxLogTimeBuckets  = 48
+)
+
+// timeBucketWidths are the timeline window widths, finest first
+var timeBucketWidths = []time.Duration{
+	time.Minute,
+	5 * time.Minute,
+	15 * time.Minute,
+	time.Hour,
+	6 * time.Hour,
+	24 * time.Hour,
+}
+
+// timeBucket holds the traffic of one timeline window
+type timeBucket struct {
+	count      int
+	bytes      int64
+	classes    map[string]int   // events by class
+	classBytes map[string]int64 // bytes by class
+}
+
+// timeline aggregates events, such as packets or log entries, into fixed
+// windows aligned to UTC. Each event is counted under one class (a protocol
+// or a log level) that gets its own column.
+type timeline struct {
+	width   int                       // index into timeBucketWidths
+	buckets map[time.Time]*timeBucket // keyed by window start
+	totals  map[string]int            // events per class over all windows
+
+	maxBuckets int            // rows kept before widening
+	order      map[string]int // class column order; nil orders by count
+	unit       string         // name of the count column, e.g. "Packets"
+	bytes      bool           // whether the Bytes column is shown
+	note       string         // explains the class columns
+}
+
+func newPCAPTimeline() *timeline {
+	return &timeline{
+		buckets:    make(map[time.Time]*timeBucket),
+		totals:     make(map[string]int),
+		maxBuckets: maxPCAPTimeBuckets,
+		unit:       "Packets",
+		bytes:      true,
+		note:       "Protocol columns count each packet once, by its highest decoded protocol.",
+	}
+}
+
+func newLogTimeline() *timeline {
+	return &timeline{
+		buckets:    make(map[time.Time]*timeBucket),
+		totals:     make(map[string]int),
+		maxBuckets: maxLogTimeBuckets,
+		order:      logLevelRanks,
+		unit:       "Entries",
+		note:       "Level columns count entries by level; \"-\" is entries without a level.",
+	}
+}
+
+// add counts one event of length bytes in the window containing ts
+func (t *timeline) add(ts time.Time, length int, class string) {
+	start := ts.UTC().Truncate(timeBucketWidths[t.width])
+	bucket, ok := t.buckets[start]
+	if !ok {
+		bucket = &timeBucket{classes: make(map[string]int), classBytes: make(map[string]int64)}
+		t.buckets[start] = bucket
+	}
+	bucket.count++
+	bucket.bytes += int64(length)
+	bucket.classes[class]++
+	bucket.classBytes[class] += int64(length)
+	t.totals[class]++
+
+	for len(t.buckets) > t.maxBuckets && t.width < len(timeBucketWidths)-1 {
+		t.widen()
+	}
+}
+
+// widen merges the windows into the next coarser width
+func (t *timeline) widen() {
+	t.width++
+	width := timeBucketWidths[t.width]
+	merged := make(map[time.Time]*timeBucket)
+	for start, bucket := range t.buckets {
+		key := start.Truncate(width)
+		target, ok := merged[key]
+		if !ok {
+			merged[key] = bucket
+			continue
+		}
+		target.count += bucket.count
+		target.bytes += bucket.bytes
+		for class, count := range bucket.classes {
+			target.classes[class] += count
+		}
+		for class, length := range bucket.classBytes {
+			target.classBytes[class] += length
+		}
+	}
+	t.buckets = merged
+}
+
+// render writes the timeline as a Markdown table, one row per window with
+// events. The first column lets large captures and logs be chunked by time
+// range.
+func (t *timeline) render(builder *strings.Builder) {
+	if len(t.buckets) == 0 {
+		return
+	}
+
+	width := timeBucketWidths[t.width]
+	layout := "2006-01-02 15:04"
+	if width >= 24*time.Hour {
+		layout = "2006-01-02"
+	}
+
+	starts := t.sortedStarts()
+	var classes []string
+	for _, entry := range sortedCounts(t.totals, 0) {
+		classes = append(classes, entry.key)
+	}
+	if t.order != nil {
+		sort.SliceStable(classes, func(i, j int) bool { return t.order[classes[i]] < t.order[classes[j]] })
+	}
+
+	header := []string{"Window (UTC)", t.unit}
+	if t.bytes {
+		header = append(header, "Bytes")
+	}
+	header = append(header, classes...)
+
+	builder.WriteString("\n## Timeline\n\n")
+	builder.WriteString(fmt.Sprintf("%s per %s window (UTC); windows without %s are omitted. %s\n\n",
+		t.unit, formatWindowWidth(width), strings.ToLower(t.unit), t.note))
+	builder.WriteString("| " + strings.Join(header, " | ") + " |\n")
+	builder.WriteString("|" + strings.Repeat("---|", len(header)) + "\n")
+	for _, start := range starts {
+		bucket := t.buckets[start]
+		row := []string{start.Format(layout), fmt.Sprintf("%d", bucket.count)}
+		if t.bytes {
+			row = append(row, fmt.Sprintf("%d", bucket.bytes))
+		}
+		for _, class := range classes {
+			row = append(row, fmt.Sprintf("%d", bucket.classes[class]))
+		}
+		builder.WriteString("| " + strings.Join(row, " | ") + " |\n")
+	}
+}
+
+// sortedStarts returns the window starts in time order
+func (t *timeline) sortedStarts() []time.Time {
+	starts := make([]time.Time, 0, len(t.buckets))
+	for start := range t.buckets {
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	return starts
+}
+
+// formatWindowWidth names a window width, e.g. "5-minute"
+func formatWindowWidth(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%d-day", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%d-hour", d/time.Hour)
+	default:
+		return fmt.Sprintf("%d-minute", d/time.Minute)
+	}
+}
@@ -6,17 +6,28 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Config represents the complete agent configuration
 type Config struct {
-	Agent    AgentConfig    `yaml:"agent" json:"agent"`
-	LLM      LLMConfig      `yaml:"llm" json:"llm"`
-	Filters  FilterConfig   `yaml:"filters" json:"filters"`
-	Security SecurityConfig `yaml:"security" json:"security"`
-	Chunking ChunkingConfig `yaml:"chunking" json:"chunking"`
+	Agent       AgentConfig      `yaml:"agent" json:"agent"`
+	LLM         LLMConfig        `yaml:"llm" json:"llm"`
+	Filters     FilterConfig     `yaml:"filters" json:"filters"`
+	Security    SecurityConfig   `yaml:"security" json:"security"`
+	Chunking    ChunkingConfig   `yaml:"chunking" json:"chunking"`
+	Review      ReviewConfig     `yaml:"review" json:"review"`
+	Archives    ArchiveConfig    `yaml:"archives" json:"archives"`
+	PCAP        PCAPConfig       `yaml:"pcap" json:"pcap"`
+	Logs        LogConfig        `yaml:"logs" json:"logs"`
+	CSV         CSVConfig        `yaml:"csv" json:"csv"`
+	SQLite      SQLiteConfig     `yaml:"sqlite" json:"sqlite"`
+	Executables ExecutableConfig `yaml:"executables" json:"executables"`
+	Minify      MinifyConfig     `yaml:"minify" json:"minify"`
+	Dedupe      DedupeConfig     `yaml:"dedupe" json:"dedupe"`
 }
 
 // AgentConfig contains general agent settings
@@ -59,6 +70,147 @@ type ChunkingConfig struct {
 	Overlap   int    `yaml:"overlap" json:"overlap"`       // overlap between chunks
 }
 
+// ReviewConfig contains diff review settings
+type ReviewConfig struct {
+	ContextLines int `yaml:"context_lines" json:"context_lines"` // unchanged lines shown around each hunk
+}
+
+// ArchiveConfig contains limits for reading archive members (zip, tar, tar.gz, gz, bz2)
+type ArchiveConfig struct {
+	Enabled       bool `yaml:"enabled" json:"enabled"`
+	MaxMembers    int  `yaml:"max_members" json:"max_members"`         // members listed per top-level archive
+	MaxTotalBytes int  `yaml:"max_total_bytes" json:"max_total_bytes"` // uncompressed bytes read per top-level archive
+	MaxDepth      int  `yaml:"max_depth" json:"max_depth"`             // nesting levels, 1 = no archives inside archives
+}
+
+// PCAPConfig contains settings for packet capture analysis
+type PCAPConfig struct {
+	MaxFlows           int    `yaml:"max_flows" json:"max_flows"`                       // conversations listed, largest first
+	StreamPreviewBytes int    `yaml:"stream_preview_bytes" json:"stream_preview_bytes"` // reassembled TCP bytes shown per direction, 0 = off
+	From               string `yaml:"from" json:"from"`                                 // analyze packets at or after this time, "" = capture start
+	To                 string `yaml:"to" json:"to"`                                     // analyze packets at or before this time, "" = capture end
+	Filter             string `yaml:"filter" json:"filter"`                             // BPF-like packet filter, e.g. "host 10.0.0.5 and port 443"
+	MaxFileSizeBytes   int64  `yaml:"max_file_size_bytes" json:"max_file_size_bytes"`   // captures are streamed up to this size, above agent.max_file_size_bytes
+}
+
+// LogConfig contains settings for log file summaries
+type LogConfig struct {
+	MaxTemplates     int `yaml:"max_templates" json:"max_templates"`             // message templates listed per section, most frequent first
+	Samples          int `yaml:"samples" json:"samples"`                         // example messages shown per error/warning template
+	MaxFileSizeBytes int `yaml:"max_file_size_bytes" json:"max_file_size_bytes"` // logs are summarized up to this size, above agent.max_file_size_bytes
+}
+
+// CSVConfig contains settings for CSV/TSV profiles
+type CSVConfig struct {
+	PreviewRows      int `yaml:"preview_rows" json:"preview_rows"`               // first data rows shown below the column profile
+	MaxFileSizeBytes int `yaml:"max_file_size_bytes" json:"max_file_size_bytes"` // tables are profiled up to this size, above agent.max_file_size_bytes
+}
+
+// SQLiteConfig contains settings for SQLite database summaries
+type SQLiteConfig struct {
+	MaxTables        int `yaml:"max_tables" json:"max_tables"`                   // tables (and views) shown with their schema and sample rows
+	SampleRows       int `yaml:"sample_rows" json:"sample_rows"`                 // first rows shown per table
+	MaxFileSizeBytes int `yaml:"max_file_size_bytes" json:"max_file_size_bytes"` // databases are summarized up to this size, above agent.max_file_size_bytes
+}
+
+// ExecutableConfig contains settings for ELF, PE and Mach-O summaries
+type ExecutableConfig struct {
+	MaxSymbols       int `yaml:"max_symbols" json:"max_symbols"`                 // imported and exported symbols listed, each
+	MaxStrings       int `yaml:"max_strings" json:"max_strings"`                 // printable strings listed, URLs, paths and the like first
+	MinStringLength  int `yaml:"min_string_length" json:"min_string_length"`     // shorter runs of printable bytes are ignored
+	MaxFileSizeBytes int `yaml:"max_file_size_bytes" json:"max_file_size_bytes"` // executables are summarized up to this size, above agent.max_file_size_bytes
+}
+
+// MinifyConfig contains the passes that shrink text files before they are
+// sent to the LLM. All are off by default.
+type MinifyConfig struct {
+	StripLicenses      bool `yaml:"strip_licenses" json:"strip_licenses"`           // license banners at the top of files
+	CollapseWhitespace bool `yaml:"collapse_whitespace" json:"collapse_whitespace"` // blank lines and trailing whitespace
+	DropComments       bool `yaml:"drop_comments" json:"drop_comments"`             // comment-only lines and comment blocks
+	ElideGenerated     bool `yaml:"elide_generated" json:"elide_generated"`         // code marked as generated, e.g. "// Code generated ... DO NOT EDIT."
+	MaxStringLength    int  `yaml:"max_string_length" json:"max_string_length"`     // longer string literals are shortened, 0 = off
+}
+
+// DedupeConfig contains settings for grouping duplicate files, of which only
+// one is analyzed
+type DedupeConfig struct {
+	Enabled    bool    `yaml:"enabled" json:"enabled"`
+	Similarity float64 `yaml:"similarity" json:"similarity"` // estimated Jaccard similarity of word shingles from which files are near-duplicates, 1 = identical only
+}
+
+// defaultMinifyStringLength is the string literal length kept when the
+// strings pass is turned on without a length
+const defaultMinifyStringLength = 80
+
+// Enabled reports whether any minification pass is on
+func (c MinifyConfig) Enabled() bool {
+	return c.StripLicenses || c.CollapseWhitespace || c.DropComments || c.ElideGenerated || c.MaxStringLength > 0
+}
+
+// SetPasses turns on the passes named in a comma-separated list of
+// licenses, whitespace, comments, generated and strings, or all of them with
+// "all". "none" turns every pass off.
+func (c *MinifyConfig) SetPasses(spec string) error {
+	for _, name := range strings.Split(spec, ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "licenses":
+			c.StripLicenses = true
+		case "whitespace":
+			c.CollapseWhitespace = true
+		case "comments":
+			c.DropComments = true
+		case "generated":
+			c.ElideGenerated = true
+		case "strings":
+			if c.MaxStringLength <= 0 {
+				c.MaxStringLength = defaultMinifyStringLength
+			}
+		case "all":
+			if err := c.SetPasses("licenses,whitespace,comments,generated,strings"); err != nil {
+				return err
+			}
+		case "none":
+			*c = MinifyConfig{}
+		case "":
+		default:
+			return fmt.Errorf("unknown minify pass %q (use licenses, whitespace, comments, generated, strings, all or none)", strings.TrimSpace(name))
+		}
+	}
+	return nil
+}
+
+// pcapTimeLayouts are the accepted formats of pcap from/to. Values without a
+// zone are UTC, matching the timestamps in capture summaries.
+var pcapTimeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+// TimeRange parses From and To. A zero time leaves that side of the range open.
+func (c PCAPConfig) TimeRange() (time.Time, time.Time, error) {
+	parse := func(name, value string) (time.Time, error) {
+		if value == "" {
+			return time.Time{}, nil
+		}
+		for _, layout := range pcapTimeLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("pcap %s: invalid time %q (use e.g. 2024-03-01T12:00:00Z or \"2024-03-01 12:00:00\")", name, value)
+	}
+
+	from, err := parse("from", c.From)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	to, err := parse("to", c.To)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("pcap to must not be before from")
+	}
+	return from, to, nil
+}
+
 // DefaultConfig returns a configuration with sensible defaults
 func DefaultConfig() *Config {
 	// Read from environment variables with defaults
@@ -109,6 +261,7 @@ func DefaultConfig() *Config {
 				"*.js",
 				"*.ts",
 				"*.py",
+				"*.ipynb",
 				"*.java",
 				"*.c",
 				"*.cpp",
@@ -117,8 +270,20 @@ func DefaultConfig() *Config {
 				"*.yaml",
 				"*.yml",
 				"*.json",
+				"*.csv",
+				"*.tsv",
+				"*.db",
+				"*.sqlite",
+				"*.sqlite3",
+				"*.exe",
+				"*.dll",
+				"*.so",
+				"*.dylib",
 				"*.txt",
 				"*.tf",
+				"Dockerfile",
+				"Makefile",
+				"Jenkinsfile",
 				"*.rs",
 				"*.rb",
 				"*.php",
@@ -130,8 +295,17 @@ func DefaultConfig() *Config {
 				"*.pdf",
 				"*.doc",
 				"*.docx",
+				"*.xlsx",
+				"*.ods",
+				"*.pptx",
+				"*.odt",
 				"*.pcap",
 				"*.pcapng",
+				"*.zip",
+				"*.tar",
+				"*.tgz",
+				"*.gz",
+				"*.bz2",
 			},
 		},
 		Security: SecurityConfig{
@@ -145,6 +319,44 @@ func DefaultConfig() *Config {
 			ChunkSize: 1000,
 			Overlap:   100,
 		},
+		Review: ReviewConfig{
+			ContextLines: 5,
+		},
+		Archives: ArchiveConfig{
+			Enabled:       true,
+			MaxMembers:    1000,
+			MaxTotalBytes: 200 * 1024 * 1024, // 200MB
+			MaxDepth:      2,
+		},
+		PCAP: PCAPConfig{
+			MaxFlows:           50,
+			StreamPreviewBytes: 0,
+			MaxFileSizeBytes:   8 * 1024 * 1024 * 1024, // 8GB
+		},
+		Logs: LogConfig{
+			MaxTemplates:     30,
+			Samples:          2,
+			MaxFileSizeBytes: 512 * 1024 * 1024, // 512MB
+		},
+		CSV: CSVConfig{
+			PreviewRows:      20,
+			MaxFileSizeBytes: 512 * 1024 * 1024, // 512MB
+		},
+		SQLite: SQLiteConfig{
+			MaxTables:        50,
+			SampleRows:       3,
+			MaxFileSizeBytes: 512 * 1024 * 1024, // 512MB
+		},
+		Executables: ExecutableConfig{
+			MaxSymbols:       200,
+			MaxStrings:       200,
+			MinStringLength:  6,
+			MaxFileSizeBytes: 512 * 1024 * 1024, // 512MB
+		},
+		Dedupe: DedupeConfig{
+			Enabled:    true,
+			Similarity: 0.9,
+		},
 	}
 }
 
@@ -239,9 +451,106 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("chunk_size must be positive")
 	}
 
+	if c.Archives.Enabled {
+		if c.Archives.MaxMembers <= 0 {
+			return fmt.Errorf("archives max_members must be positive")
+		}
+		if c.Archives.MaxTotalBytes <= 0 {
+			return fmt.Errorf("archives max_total_bytes must be positive")
+		}
+		if c.Archives.MaxDepth <= 0 {
+			return fmt.Errorf("archives max_depth must be positive")
+		}
+	}
+
+	if c.PCAP.MaxFlows <= 0 {
+		return fmt.Errorf("pcap max_flows must be positive")
+	}
+	if c.PCAP.StreamPreviewBytes < 0 {
+		return fmt.Errorf("pcap stream_preview_bytes must not be negative")
+	}
+	if _, _, err := c.PCAP.TimeRange(); err != nil {
+		return err
+	}
+	if c.PCAP.MaxFileSizeBytes <= 0 {
+		return fmt.Errorf("pcap max_file_size_bytes must be positive")
+	}
+
+	if c.Logs.MaxTemplates <= 0 {
+		return fmt.Errorf("logs max_templates must be positive")
+	}
+	if c.Logs.Samples < 0 {
+		return fmt.Errorf("logs samples must not be negative")
+	}
+	if c.Logs.MaxFileSizeBytes <= 0 {
+		return fmt.Errorf("logs max_file_size_bytes must be positive")
+	}
+
+	if c.CSV.PreviewRows < 0 {
+		return fmt.Errorf("csv preview_rows must not be negative")
+	}
+	if c.CSV.MaxFileSizeBytes <= 0 {
+		return fmt.Errorf("csv max_file_size_bytes must be positive")
+	}
+
+	if c.SQLite.MaxTables <= 0 {
+		return fmt.Errorf("sqlite max_tables must be positive")
+	}
+	if c.SQLite.SampleRows < 0 {
+		return fmt.Errorf("sqlite sample_rows must not be negative")
+	}
+	if c.SQLite.MaxFileSizeBytes <= 0 {
+		return fmt.Errorf("sqlite max_file_size_bytes must be positive")
+	}
+
+	if c.Executables.MaxSymbols <= 0 {
+		return fmt.Errorf("executables max_symbols must be positive")
+	}
+	if c.Executables.MaxStrings < 0 {
+		return fmt.Errorf("executables max_strings must not be negative")
+	}
+	if c.Executables.MinStringLength < 4 {
+		return fmt.Errorf("executables min_string_length must be at least 4")
+	}
+	if c.Executables.MaxFileSizeBytes <= 0 {
+		return fmt.Errorf("executables max_file_size_bytes must be positive")
+	}
+
+	if c.Minify.MaxStringLength < 0 {
+		return fmt.Errorf("minify max_string_length must not be negative")
+	}
+
+	if c.Dedupe.Similarity <= 0 || c.Dedupe.Similarity > 1 {
+		return fmt.Errorf("dedupe similarity must be between 0 and 1")
+	}
+
+	if c.Review.ContextLines < 0 {
+		return fmt.Errorf("review context_lines must not be negative")
+	}
+
 	return nil
 }
 
+// MaxFileSize returns the size above which the content of the file at path
+// is not read, and the setting it comes from. Packet captures, logs, CSV/TSV
+// tables, SQLite databases and executables are summarized without holding
+// them in memory, so they have limits of their own.
+func (c *Config) MaxFileSize(path string) (int64, string) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pcap", ".pcapng", ".cap":
+		return c.PCAP.MaxFileSizeBytes, "pcap max_file_size_bytes"
+	case ".log":
+		return int64(c.Logs.MaxFileSizeBytes), "logs max_file_size_bytes"
+	case ".csv", ".tsv":
+		return int64(c.CSV.MaxFileSizeBytes), "csv max_file_size_bytes"
+	case ".db", ".db3", ".sqlite", ".sqlite3":
+		return int64(c.SQLite.MaxFileSizeBytes), "sqlite max_file_size_bytes"
+	case ".exe", ".dll", ".so", ".dylib":
+		return int64(c.Executables.MaxFileSizeBytes), "executables max_file_size_bytes"
+	}
+	return int64(c.Agent.MaxFileSizeBytes), "agent max_file_size_bytes"
+}
+
 // ToJSON converts config to JSON string
 func (c *Config) ToJSON() (string, error) {
 	data, err := json.MarshalIndent(c, "", "  ")
@@ -1,10 +1,19 @@
 package filter
 
 import (
+	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 
 	"local-agent/config"
+	"local-agent/types"
+)
+
+// Sources recorded for patterns that come from the configuration
+const (
+	sourceDenyPatterns  = "config deny_patterns"
+	sourceAllowPatterns = "config allow_patterns"
 )
 
 // Filter manages file filtering based on patterns and rules
@@ -38,23 +47,26 @@ func NewFilter(cfg *config.Config, rootDir string) (*Filter, error) {
 		customParser, err := LoadCustomIgnoreFile(rootDir, cfg.Filters.CustomIgnoreFile)
 		if err == nil {
 			// Merge custom patterns into deny parser
-			for _, pattern := range customParser.GetPatterns() {
-				f.denyParser.AddPattern(pattern)
-			}
+			f.denyParser.Merge(customParser)
 		}
 	}
 
 	// Load deny patterns
-	f.denyParser.AddPatterns(cfg.Filters.DenyPatterns)
+	f.denyParser.AddPatternsFrom(sourceDenyPatterns, cfg.Filters.DenyPatterns)
 
 	// Load allow patterns
-	f.allowParser.AddPatterns(cfg.Filters.AllowPatterns)
+	f.allowParser.AddPatternsFrom(sourceAllowPatterns, cfg.Filters.AllowPatterns)
 
 	return f, nil
 }
 
 // ShouldInclude determines if a file should be included based on filters
 func (f *Filter) ShouldInclude(path string, info interface{}) bool {
+	return f.Evaluate(path).Included
+}
+
+// Evaluate runs the filter rules against a file and reports which rule decided the outcome
+func (f *Filter) Evaluate(path string) types.FilterDecision {
 	// Prefer matching on workspace-relative paths for predictable glob behavior
 	relPath, err := filepath.Rel(f.rootDir, path)
 	if err != nil {
@@ -62,56 +74,222 @@ func (f *Filter) ShouldInclude(path string, info interface{}) bool {
 	}
 	relPath = filepath.ToSlash(relPath)
 
+	decision := types.FilterDecision{Path: relPath}
+
 	// Determine if it's a directory (assumed false for files)
 	isDir := false
 
 	// 1. Check gitignore first
-	if f.gitignoreParser != nil && f.gitignoreParser.Match(relPath, isDir) {
-		return false
+	if f.gitignoreParser != nil {
+		if rule, matched := f.gitignoreParser.MatchRule(relPath, isDir); matched {
+			return f.excludedByRule(decision, "gitignore", rule, "matches a .gitignore pattern")
+		}
 	}
 
 	// 2. Check deny patterns
-	if f.denyParser.Match(relPath, isDir) {
-		return false
+	if rule, matched := f.denyParser.MatchRule(relPath, isDir); matched {
+		if rule.Source == sourceDenyPatterns {
+			return f.excludedByRule(decision, "deny", rule, "matches a configured deny pattern")
+		}
+		return f.excludedByRule(decision, "agentignore", rule, fmt.Sprintf("matches a %s pattern", f.config.Filters.CustomIgnoreFile))
 	}
 
 	// 3. Check allow patterns (if specified)
 	if len(f.config.Filters.AllowPatterns) > 0 {
 		// If allow patterns are specified, file must match at least one
-		if !f.allowParser.Match(relPath, isDir) {
+		rule, allowed := f.allowParser.MatchRule(relPath, isDir)
+		if !allowed {
 			// Check if file extension is in allow patterns
 			ext := filepath.Ext(relPath)
 			if ext == "" {
-				return false
+				decision.Stage = "allow"
+				decision.Source = sourceAllowPatterns
+				decision.Reason = "no allow pattern matches and the file has no extension"
+				return decision
 			}
 
 			extPattern := "*" + ext
 			// Check if extension matches any allow pattern
 			matched := false
-			for _, pattern := range f.config.Filters.AllowPatterns {
+			for i, pattern := range f.config.Filters.AllowPatterns {
 				if pattern == extPattern {
 					matched = true
+					decision.Stage = "extension"
+					decision.Rule = pattern
+					decision.Source = sourceAllowPatterns
+					decision.Line = i + 1
 					break
 				}
 			}
 			if !matched {
-				return false
+				decision.Stage = "extension"
+				decision.Source = sourceAllowPatterns
+				decision.Reason = fmt.Sprintf("no allow pattern matches and %q is not an allowed extension", ext)
+				return decision
 			}
+		} else {
+			decision.Stage = "allow"
+			decision.Rule = rule.Pattern
+			decision.Source = rule.Source
+			decision.Line = rule.Line
 		}
 	}
 
 	// 4. Check for sensitive patterns
-	if f.isSensitiveFile(relPath) {
-		return false
+	if pattern, sensitive := f.matchSensitivePattern(relPath); sensitive {
+		return types.FilterDecision{
+			Path:   relPath,
+			Stage:  "sensitive",
+			Rule:   pattern,
+			Source: "security detect_secrets",
+			Reason: "path looks like it holds secrets",
+		}
+	}
+
+	decision.Included = true
+	switch decision.Stage {
+	case "allow":
+		decision.Reason = "matches an allow pattern"
+	case "extension":
+		decision.Reason = "extension is listed in allow patterns"
+	default:
+		decision.Reason = "no filter rule excludes it"
+	}
+	return decision
+}
+
+// excludedByRule fills an exclusion decision from a matched ignore rule
+func (f *Filter) excludedByRule(decision types.FilterDecision, stage string, rule Rule, reason string) types.FilterDecision {
+	decision.Included = false
+	decision.Stage = stage
+	decision.Rule = rule.Pattern
+	decision.Source = f.displaySource(rule.Source)
+	decision.Line = rule.Line
+	decision.Reason = reason
+	return decision
+}
+
+// displaySource shortens ignore file paths to be relative to the scan root
+func (f *Filter) displaySource(source string) string {
+	if filepath.IsAbs(source) {
+		if rel, err := filepath.Rel(f.rootDir, source); err == nil {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return source
+}
+
+// ExplainPath explains the filter decision for a single file on disk.
+// Unlike Evaluate it also covers symlinks, depth and size limits that the
+// scanner applies outside of the pattern rules.
+func (f *Filter) ExplainPath(path string) (types.FilterDecision, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(f.rootDir, path)
+	}
+	path = filepath.Clean(path)
+
+	// Archive members are explained by the filter alone; the checks below apply to their archive
+	statPath := path
+	if idx := strings.Index(path, types.ArchiveSeparator); idx >= 0 {
+		statPath = path[:idx]
+	}
+
+	info, err := os.Lstat(statPath)
+	if err != nil {
+		return types.FilterDecision{}, err
+	}
+
+	relPath, err := filepath.Rel(f.rootDir, path)
+	if err != nil || relPath == ".." || strings.HasPrefix(filepath.ToSlash(relPath), "../") {
+		return types.FilterDecision{}, fmt.Errorf("%s is outside of %s", path, f.rootDir)
+	}
+	relPath = filepath.ToSlash(relPath)
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		if !f.ShouldFollowSymlink(statPath) {
+			return types.FilterDecision{
+				Path:   relPath,
+				Stage:  "symlink",
+				Source: "security follow_symlinks",
+				Reason: "symlinks are not followed",
+			}, nil
+		}
+		if info, err = os.Stat(statPath); err != nil {
+			return types.FilterDecision{}, err
+		}
+	}
+
+	if info.IsDir() {
+		return types.FilterDecision{}, fmt.Errorf("%s is a directory; provide a file path", relPath)
+	}
+
+	// Files at depth N are listed when their parent directory (depth N-1) is walked
+	statRel, _ := filepath.Rel(f.rootDir, statPath)
+	if !f.IsWithinDepthLimit(strings.Count(filepath.ToSlash(statRel), "/")) {
+		return types.FilterDecision{
+			Path:   relPath,
+			Stage:  "depth",
+			Source: "security max_depth",
+			Reason: fmt.Sprintf("file is nested deeper than max_depth (%d)", f.config.Security.MaxDepth),
+		}, nil
+	}
+
+	// A member is only seen when its archive passes the filter
+	if statPath != path {
+		if archiveDecision := f.Evaluate(statPath); !archiveDecision.Included {
+			archiveDecision.Reason = fmt.Sprintf("its archive is excluded: %s", archiveDecision.Reason)
+			archiveDecision.Path = relPath
+			return archiveDecision, nil
+		}
+		return f.Evaluate(path), nil
+	}
+
+	decision := f.Evaluate(path)
+	if maxSize, source := f.config.MaxFileSize(path); decision.Included && info.Size() > maxSize {
+		return types.FilterDecision{
+			Path:   relPath,
+			Stage:  "size",
+			Source: source,
+			Reason: fmt.Sprintf("passes all filters, but its size (%d bytes) exceeds %s (%d), so its content is not read",
+				info.Size(), source, maxSize),
+		}, nil
+	}
+
+	return decision, nil
+}
+
+// FormatDecision renders a filter decision for terminal and chat output
+func FormatDecision(d types.FilterDecision) string {
+	var b strings.Builder
+	if d.Included {
+		b.WriteString(fmt.Sprintf("✅ %s: included\n", d.Path))
+	} else {
+		b.WriteString(fmt.Sprintf("🚫 %s: excluded (%s)\n", d.Path, d.Stage))
+	}
+
+	if d.Rule != "" {
+		b.WriteString(fmt.Sprintf("   Rule: %s", d.Rule))
+		if d.Source != "" {
+			if d.Line > 0 {
+				b.WriteString(fmt.Sprintf(" (%s:%d)", d.Source, d.Line))
+			} else {
+				b.WriteString(fmt.Sprintf(" (%s)", d.Source))
+			}
+		}
+		b.WriteString("\n")
+	} else if d.Source != "" {
+		b.WriteString(fmt.Sprintf("   Source: %s\n", d.Source))
 	}
+	b.WriteString(fmt.Sprintf("   Reason: %s", d.Reason))
 
-	return true
+	return b.String()
 }
 
-// isSensitiveFile checks if a file appears to contain sensitive data
-func (f *Filter) isSensitiveFile(path string) bool {
+// matchSensitivePattern checks if a file appears to contain sensitive data
+// and returns the pattern it matched
+func (f *Filter) matchSensitivePattern(path string) (string, bool) {
 	if !f.config.Security.DetectSecrets {
-		return false
+		return "", false
 	}
 
 	lowerPath := strings.ToLower(path)
@@ -137,11 +315,11 @@ func (f *Filter) isSensitiveFile(path string) bool {
 
 	for _, pattern := range sensitivePatterns {
 		if strings.Contains(lowerPath, pattern) || strings.Contains(baseName, pattern) {
-			return true
+			return pattern, true
 		}
 	}
 
-	return false
+	return "", false
 }
 
 // ShouldFollowSymlink determines if a symlink should be followed
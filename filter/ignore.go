@@ -11,11 +11,22 @@ import (
 // ignorePattern represents a single gitignore-style pattern
 type ignorePattern struct {
 	pattern  string
+	raw      string
+	source   string
+	line     int
 	negate   bool
 	dirOnly  bool
 	absolute bool
 }
 
+// Rule describes a pattern together with where it was defined
+type Rule struct {
+	Pattern string
+	Source  string
+	Line    int
+	Negate  bool
+}
+
 // IgnoreParser parses and matches .gitignore-style patterns
 type IgnoreParser struct {
 	patterns []ignorePattern
@@ -40,7 +51,9 @@ func (p *IgnoreParser) LoadFile(path string) error {
 	defer file.Close()
 
 	scanner := bufio.NewScanner(file)
+	lineNum := 0
 	for scanner.Scan() {
+		lineNum++
 		line := strings.TrimSpace(scanner.Text())
 
 		// Skip empty lines and comments
@@ -49,6 +62,8 @@ func (p *IgnoreParser) LoadFile(path string) error {
 		}
 
 		pattern := p.parsePattern(line)
+		pattern.source = path
+		pattern.line = lineNum
 		p.patterns = append(p.patterns, pattern)
 	}
 
@@ -67,10 +82,30 @@ func (p *IgnoreParser) AddPatterns(patterns []string) {
 	}
 }
 
+// AddPatternsFrom adds multiple patterns and records their origin.
+// The line of each pattern is its 1-based position in the list.
+func (p *IgnoreParser) AddPatternsFrom(source string, patterns []string) {
+	for i, pattern := range patterns {
+		ip := p.parsePattern(pattern)
+		ip.source = source
+		ip.line = i + 1
+		p.patterns = append(p.patterns, ip)
+	}
+}
+
+// Merge appends all patterns of other, keeping their origin
+func (p *IgnoreParser) Merge(other *IgnoreParser) {
+	if other == nil {
+		return
+	}
+	p.patterns = append(p.patterns, other.patterns...)
+}
+
 // parsePattern parses a gitignore pattern string
 func (p *IgnoreParser) parsePattern(pattern string) ignorePattern {
 	ip := ignorePattern{
 		pattern: pattern,
+		raw:     pattern,
 	}
 
 	// Check for negation
@@ -97,11 +132,20 @@ func (p *IgnoreParser) parsePattern(pattern string) ignorePattern {
 
 // Match checks if a path matches any of the patterns
 func (p *IgnoreParser) Match(path string, isDir bool) bool {
+	_, matched := p.MatchRule(path, isDir)
+	return matched
+}
+
+// MatchRule reports whether path matches and returns the rule that decided it.
+// As with gitignore, the last matching pattern wins; a negated rule re-includes
+// the path and is returned with matched set to false.
+func (p *IgnoreParser) MatchRule(path string, isDir bool) (Rule, bool) {
 	// Normalize path
 	path = filepath.Clean(path)
 	path = filepath.ToSlash(path)
 
 	matched := false
+	var rule Rule
 
 	for _, pattern := range p.patterns {
 		// Skip directory-only patterns for files
@@ -111,10 +155,16 @@ func (p *IgnoreParser) Match(path string, isDir bool) bool {
 
 		if p.matchPattern(path, pattern) {
 			matched = !pattern.negate
+			rule = Rule{
+				Pattern: pattern.raw,
+				Source:  pattern.source,
+				Line:    pattern.line,
+				Negate:  pattern.negate,
+			}
 		}
 	}
 
-	return matched
+	return rule, matched
 }
 
 // matchPattern checks if a path matches a specific pattern
@@ -368,8 +368,12 @@ func (c *OllamaClient) AnalyzeChunk(task string, file *types.FileInfo, chunkInde
 	}
 
 	chunk := file.Chunks[chunkIndex]
-	content := fmt.Sprintf("File: %s (Lines %d-%d)\n\n```\n%s\n```",
-		file.RelPath, chunk.StartLine, chunk.EndLine, chunk.Content)
+	location := fmt.Sprintf("Lines %d-%d", chunk.StartLine, chunk.EndLine)
+	if chunk.Section != "" {
+		location = chunk.Section + ", " + location
+	}
+	content := fmt.Sprintf("File: %s (%s)\n\n```\n%s\n```",
+		file.RelPath, location, chunk.Content)
 
 	return c.Analyze(task, content, temperature)
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"os"
@@ -13,10 +14,12 @@ import (
 	"local-agent/config"
 	"local-agent/filter"
 	"local-agent/llm"
+	"local-agent/review"
 	"local-agent/security"
 	"local-agent/sessionlog"
 	"local-agent/tui"
 	"local-agent/types"
+	"local-agent/vcs"
 	"local-agent/webui"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -32,7 +35,20 @@ func main() {
 		model           = flag.String("model", "", "LLM model to use (overrides config)")
 		host            = flag.String("host", "localhost:11434", "Ollama instance host (e.g., localhost:11434, 192.168.1.100:8080, or ollama.example.com:11434)")
 		dryRun          = flag.Bool("dry-run", false, "List files without analyzing")
+		showExcluded    = flag.Bool("show-excluded", false, "With --dry-run, also list excluded files and the rule that excluded them")
+		explainPath     = flag.String("explain", "", "Explain why a file is included or excluded by the filters (relative to --dir)")
 		noDetectSecrets = flag.Bool("no-detect-secrets", false, "Disable secret/sensitive content detection")
+		since           = flag.String("since", "", "Analyze only files changed since this git ref (e.g. origin/main)")
+		staged          = flag.Bool("staged", false, "Analyze only files staged in git")
+		uncommitted     = flag.Bool("uncommitted", false, "Analyze only files with uncommitted changes, including untracked files")
+		reviewMode      = flag.Bool("review", false, "Review the git diff hunk by hunk instead of whole files (uncommitted changes unless --since or --staged is set)")
+		diffFile        = flag.String("diff", "", "Review the hunks of a .patch/.diff file instead of the git diff")
+		diffContext     = flag.Int("diff-context", -1, "Unchanged lines to send around each hunk in review mode (overrides config)")
+		pcapFrom        = flag.String("pcap-from", "", "Analyze only capture packets at or after this time, e.g. \"2024-03-01 12:00\" in UTC (overrides config)")
+		pcapTo          = flag.String("pcap-to", "", "Analyze only capture packets at or before this time (overrides config)")
+		pcapFilter      = flag.String("pcap-filter", "", "Analyze only capture packets matching this filter, e.g. \"host 10.0.0.5 and port 443\" (overrides config)")
+		pcapExport      = flag.String("pcap-export", "", "Write the flows, DNS, HTTP and protocol tables of the analyzed captures to this directory as CSV and JSON")
+		minify          = flag.String("minify", "", "Shrink text files before sending them: comma-separated passes licenses, whitespace, comments, generated, strings, or all/none (overrides config)")
 
 		showVersion = flag.Bool("version", false, "Show version")
 		checkHealth = flag.Bool("health", false, "Check LLM connectivity")
@@ -77,6 +93,40 @@ func main() {
 		cfg.Security.DetectSecrets = false
 	}
 
+	// Override review context if specified via flag
+	if *diffContext >= 0 {
+		cfg.Review.ContextLines = *diffContext
+	}
+
+	// Override capture time window if specified via flags
+	if *pcapFrom != "" {
+		cfg.PCAP.From = *pcapFrom
+	}
+	if *pcapTo != "" {
+		cfg.PCAP.To = *pcapTo
+	}
+	if _, _, err := cfg.PCAP.TimeRange(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid capture time window: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Override capture filter if specified via flag
+	if *pcapFilter != "" {
+		cfg.PCAP.Filter = *pcapFilter
+	}
+	if _, err := analyzer.ParsePCAPFilter(cfg.PCAP.Filter); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid capture filter: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Override minification passes if specified via flag
+	if *minify != "" {
+		if err := cfg.Minify.SetPasses(*minify); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --minify: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// Initialize LLM client
 	llmClient := llm.NewOllamaClient(cfg.LLM.Endpoint, cfg.LLM.Model, cfg.LLM.Timeout)
 
@@ -104,6 +154,16 @@ func main() {
 		os.Exit(1)
 	}
 
+	changeScope, err := changeScopeFromFlags(*since, *staged, *uncommitted)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid change selection: %v\n", err)
+		os.Exit(1)
+	}
+	if changeScope != nil && *focusFile != "" {
+		fmt.Fprintf(os.Stderr, "--focus cannot be combined with --since, --staged or --uncommitted\n")
+		os.Exit(1)
+	}
+
 	focusRel := ""
 	if *focusFile != "" {
 		originalDir := absDir
@@ -121,8 +181,34 @@ func main() {
 		}
 	}
 
+	// Explain a single filter decision without contacting the LLM
+	if *explainPath != "" {
+		explainFilterDecision(absDir, *explainPath, cfg)
+		return
+	}
+
+	// Review diff hunks instead of whole files
+	if *reviewMode || *diffFile != "" {
+		if *interactive || *focusFile != "" {
+			fmt.Fprintf(os.Stderr, "--review and --diff cannot be combined with --interactive or --focus\n")
+			os.Exit(1)
+		}
+		if *diffFile != "" && changeScope != nil {
+			fmt.Fprintf(os.Stderr, "--diff cannot be combined with --since, --staged or --uncommitted\n")
+			os.Exit(1)
+		}
+		runReview(absDir, *diffFile, changeScope, *task, *dryRun, cfg, llmClient)
+		return
+	}
+
 	// If interactive mode requested, start the interactive session
 	if *interactive {
+		if changeScope != nil {
+			fmt.Printf("ℹ️  Change selection flags apply to standalone runs; use 'focus changed' in interactive mode.\n")
+		}
+		if *pcapExport != "" {
+			fmt.Printf("ℹ️  --pcap-export applies to standalone runs; use 'export pcap <dir>' in interactive mode.\n")
+		}
 		ensureLLMAvailable(llmClient)
 		startInteractiveMode(absDir, cfg, llmClient, focusRel)
 		return
@@ -157,8 +243,34 @@ func main() {
 		fmt.Printf("\n🎯 Focus enabled: %s\nOnly this file will be analyzed.\n", focusRel)
 	}
 
+	// Limit analysis to changed files; the other scanned files are listed to the LLM
+	var changedFiles []string
+	if changeScope != nil {
+		changedFiles, err = vcs.ChangedFiles(absDir, *changeScope)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to list changed files: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\n🔀 Change selection: %s (%d changed, %d in scan)\n",
+			changeScope, len(changedFiles), countScannedFiles(result, changedFiles))
+		for _, path := range changedFiles {
+			fmt.Printf("   %s\n", path)
+		}
+	}
+
+	// Export capture tables before any LLM work so they are written even on --dry-run
+	if *pcapExport != "" {
+		if err := exportCaptures(result, focusRel, changedFiles, *pcapExport, cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to export captures: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// If dry-run, stop here
 	if *dryRun {
+		if *showExcluded {
+			displayExcludedFiles(result, cfg)
+		}
 		return
 	}
 
@@ -172,7 +284,7 @@ func main() {
 	// Perform analysis
 	fmt.Printf("\n🔬 Analyzing files with task: %s\n\n", *task)
 
-	analysisResult, err := analyzeFiles(result, focusRel, *task, cfg, llmClient)
+	analysisResult, err := analyzeFiles(result, focusRel, changedFiles, *task, cfg, llmClient)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Analysis failed: %v\n", err)
 		os.Exit(1)
@@ -271,8 +383,9 @@ func scanDirectory(rootPath string, cfg *config.Config) (*types.ScanResult, erro
 		}
 
 		// Apply filters to files
-		if !fileFilter.ShouldInclude(current, info) {
+		if decision := fileFilter.Evaluate(current); !decision.Included {
 			result.FilteredFiles++
+			result.Excluded = append(result.Excluded, decision)
 			return
 		}
 
@@ -305,21 +418,40 @@ func scanDirectory(rootPath string, cfg *config.Config) (*types.ScanResult, erro
 		}
 	}
 
+	// Archive members become virtual files that go through the same filter
+	analyzer.ExpandArchives(result, fileFilter.Evaluate)
+
+	// Duplicates are analyzed once, through their group's representative
+	analyzer.GroupDuplicates(result)
+
 	result.Duration = time.Since(startTime)
 	return result, nil
 }
 
-func analyzeFiles(scanResult *types.ScanResult, focusRel string, task string, cfg *config.Config, llmClient *llm.OllamaClient) (*types.AnalysisResponse, error) {
+func analyzeFiles(scanResult *types.ScanResult, focusRel string, changedFiles []string, task string, cfg *config.Config, llmClient *llm.OllamaClient) (*types.AnalysisResponse, error) {
+	fileInfoPtrs, err := selectFilesForAnalysis(scanResult, focusRel, changedFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	// Findings computed while reading files (e.g. PCAP indicators) come first
+	fileFindings := analyzer.CollectFindings(fileInfoPtrs)
+
+	// The unchanged part of the scan is named so the model knows it exists
+	if focusRel == "" && changedFiles != nil {
+		task += analyzer.UnchangedFilesNote(scanResult.Files, fileInfoPtrs)
+	}
+
 	// Prepare files for LLM
 	analyzer := analyzer.NewAnalyzer(cfg)
 
-	fileInfoPtrs, err := selectFilesForAnalysis(scanResult, focusRel)
+	// Always process files individually (one request per file)
+	response, err := analyzeBatches(fileInfoPtrs, task, cfg, llmClient, analyzer)
 	if err != nil {
 		return nil, err
 	}
-
-	// Always process files individually (one request per file)
-	return analyzeBatches(fileInfoPtrs, task, cfg, llmClient, analyzer)
+	response.Findings = append(fileFindings, response.Findings...)
+	return response, nil
 }
 
 func analyzeBatches(files []*types.FileInfo, task string, cfg *config.Config, llmClient *llm.OllamaClient, analyzer *analyzer.Analyzer) (*types.AnalysisResponse, error) {
@@ -360,6 +492,12 @@ func analyzeBatches(files []*types.FileInfo, task string, cfg *config.Config, ll
 func prepareBatches(files []*types.FileInfo, tokenLimit int) [][]*types.FileInfo {
 	var batches [][]*types.FileInfo
 
+	// The analysis of a group's representative applies to its duplicates
+	files, duplicates := analyzer.SkipDuplicates(files, tokenLimit)
+	for _, file := range duplicates {
+		fmt.Printf("   ♻️  Skipping %s (duplicate of %s)\n", file.RelPath, file.DuplicateOf)
+	}
+
 	for _, file := range files {
 		if file == nil || !file.IsReadable {
 			continue
@@ -541,6 +679,9 @@ func processBatch(batch []*types.FileInfo, task string, cfg *config.Config, llmC
 				file.RelPath, file.TokenCount, len(file.Content), file.IsReadable)
 		}
 	}
+	for _, stat := range analyzer.MinifyReport(batch) {
+		fmt.Printf("   [INFO] %s\n", stat)
+	}
 
 	content := analyzer.PrepareForLLM(batch, cfg.Agent.TokenLimit)
 
@@ -555,7 +696,12 @@ func processBatch(batch []*types.FileInfo, task string, cfg *config.Config, llmC
 		actualTask = fmt.Sprintf("Analyze the file '%s'. %s", batch[0].RelPath, task)
 	}
 
-	return llmClient.Analyze(actualTask, content, cfg.LLM.Temperature)
+	response, err := llmClient.Analyze(actualTask, content, cfg.LLM.Temperature)
+	if err != nil {
+		return nil, err
+	}
+	response.Response += analyzer.DuplicateNote(batch)
+	return response, nil
 }
 
 func formatFileSection(fileName, body string) string {
@@ -574,7 +720,24 @@ func formatFileHeaderLine(fileName string) string {
 	return fmt.Sprintf("=== %s%s%s ===", ansiGreen, fileName, ansiReset)
 }
 
-func selectFilesForAnalysis(scanResult *types.ScanResult, focusRel string) ([]*types.FileInfo, error) {
+// selectFilesForAnalysis picks the focused file, the changed files (when a
+// change selection is active, changedFiles is non-nil) or all scanned files
+func selectFilesForAnalysis(scanResult *types.ScanResult, focusRel string, changedFiles []string) ([]*types.FileInfo, error) {
+	if focusRel == "" && changedFiles != nil {
+		changed := make(map[string]struct{}, len(changedFiles))
+		for _, path := range changedFiles {
+			changed[normalizeRelPath(path)] = struct{}{}
+		}
+
+		var fileInfoPtrs []*types.FileInfo
+		for i := range scanResult.Files {
+			if _, ok := changed[normalizeRelPath(scanResult.Files[i].RelPath)]; ok {
+				fileInfoPtrs = append(fileInfoPtrs, &scanResult.Files[i])
+			}
+		}
+		return fileInfoPtrs, nil
+	}
+
 	if focusRel == "" {
 		fileInfoPtrs := make([]*types.FileInfo, len(scanResult.Files))
 		for i := range scanResult.Files {
@@ -593,6 +756,197 @@ func selectFilesForAnalysis(scanResult *types.ScanResult, focusRel string) ([]*t
 	return nil, fmt.Errorf("focused file %s not found in scan results (possibly filtered out)", focusRel)
 }
 
+// exportCaptures writes the tables of the packet captures selected for
+// analysis to dir
+func exportCaptures(scanResult *types.ScanResult, focusRel string, changedFiles []string, dir string, cfg *config.Config) error {
+	files, err := selectFilesForAnalysis(scanResult, focusRel, changedFiles)
+	if err != nil {
+		return err
+	}
+
+	analyzerEngine := analyzer.NewAnalyzer(cfg)
+	exported := 0
+	for _, file := range files {
+		if file.Type != types.TypePCAP {
+			continue
+		}
+		written, err := analyzerEngine.ExportPCAP(file, dir, "")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Could not export %s: %v\n", file.RelPath, err)
+			continue
+		}
+		fmt.Printf("\n📤 Exported %s: %d files in %s\n", file.RelPath, len(written), dir)
+		exported++
+	}
+	if exported == 0 {
+		fmt.Printf("\nℹ️  No packet captures exported.\n")
+	}
+	return nil
+}
+
+func countScannedFiles(result *types.ScanResult, relPaths []string) int {
+	count := 0
+	for _, path := range relPaths {
+		if scanResultHasFile(result, path) {
+			count++
+		}
+	}
+	return count
+}
+
+func changeScopeFromFlags(since string, staged, uncommitted bool) (*vcs.Scope, error) {
+	var scopes []vcs.Scope
+	if since != "" {
+		scopes = append(scopes, vcs.Scope{Mode: vcs.ModeSince, Ref: since})
+	}
+	if staged {
+		scopes = append(scopes, vcs.Scope{Mode: vcs.ModeStaged})
+	}
+	if uncommitted {
+		scopes = append(scopes, vcs.Scope{Mode: vcs.ModeUncommitted})
+	}
+
+	switch len(scopes) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &scopes[0], nil
+	default:
+		return nil, fmt.Errorf("use only one of --since, --staged and --uncommitted")
+	}
+}
+
+// runReview reviews a unified diff hunk by hunk, either from a patch file or
+// from git for the given change scope
+func runReview(rootDir, diffPath string, scope *vcs.Scope, task string, dryRun bool, cfg *config.Config, llmClient *llm.OllamaClient) {
+	var files []review.FileDiff
+	var err error
+	source := review.DiskSource(rootDir)
+
+	if diffPath != "" {
+		fmt.Printf("📄 Reviewing patch: %s\n", diffPath)
+		files, err = review.ParseFile(diffPath)
+	} else {
+		if scope == nil {
+			scope = &vcs.Scope{Mode: vcs.ModeUncommitted}
+		}
+		fmt.Printf("🔀 Reviewing %s in %s\n", scope, rootDir)
+
+		var diff []byte
+		diff, err = vcs.Diff(rootDir, *scope, cfg.Review.ContextLines)
+		if err == nil {
+			files, err = review.Parse(bytes.NewReader(diff))
+		}
+
+		// The working tree may differ from what is staged
+		if scope.Mode == vcs.ModeStaged {
+			source = func(path string) ([]string, error) {
+				data, err := vcs.StagedFile(rootDir, path)
+				if err != nil {
+					return nil, err
+				}
+				return review.SplitLines(string(data)), nil
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read diff: %v\n", err)
+		os.Exit(1)
+	}
+
+	files, err = filterReviewFiles(rootDir, files, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize filter: %v\n", err)
+		os.Exit(1)
+	}
+
+	hunks := 0
+	for _, file := range files {
+		hunks += len(file.Hunks)
+	}
+	fmt.Printf("   %d files, %d hunks (context: %d lines)\n", len(files), hunks, cfg.Review.ContextLines)
+
+	if dryRun {
+		for _, file := range files {
+			fmt.Printf("\n%s\n", formatFileHeaderLine(file.Path()))
+			for i := range file.Hunks {
+				fmt.Printf("   %s\n", file.Hunks[i].Header())
+			}
+		}
+		return
+	}
+
+	if hunks == 0 {
+		fmt.Printf("\nNothing to review.\n")
+		return
+	}
+
+	ensureLLMAvailable(llmClient)
+
+	fmt.Printf("\n🔬 Reviewing hunks with task: %s\n\n", reviewTask(task))
+	reviewer := review.NewReviewer(cfg, llmClient, source)
+	reviewer.SetProgress(func(message string) {
+		fmt.Printf("   %s\n", message)
+	})
+
+	analysisResult := reviewer.Review(files, task).AnalysisResponse()
+
+	fmt.Printf("\n🎯 Review Complete\n")
+	fmt.Printf("   Total duration: %v\n", analysisResult.Duration)
+	fmt.Printf("   Tokens: %d\n", analysisResult.TokensUsed)
+	fmt.Printf("\n📝 Review comments:\n%s\n", analysisResult.Response)
+	displayFindingCounts(analysisResult.Findings)
+
+	saveSessionRecord("review", rootDir, "", reviewTask(task), cfg.LLM.Model, nil, analysisResult)
+}
+
+func reviewTask(task string) string {
+	if strings.TrimSpace(task) == "" {
+		return review.DefaultTask
+	}
+	return task
+}
+
+// filterReviewFiles drops files the scan filters would exclude, so review
+// mode never sends content a normal scan would keep away from the LLM
+func filterReviewFiles(rootDir string, files []review.FileDiff, cfg *config.Config) ([]review.FileDiff, error) {
+	fileFilter, err := filter.NewFilter(cfg, rootDir)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := files[:0]
+	for _, file := range files {
+		decision := fileFilter.Evaluate(review.ResolvePath(rootDir, file.Path()))
+		if !decision.Included {
+			fmt.Printf("   🚫 %s\n", formatExcludedLine(decision))
+			continue
+		}
+		kept = append(kept, file)
+	}
+	return kept, nil
+}
+
+// displayFindingCounts prints how many findings were reported per severity
+func displayFindingCounts(findings []types.Finding) {
+	if len(findings) == 0 {
+		return
+	}
+
+	counts := make(map[types.Severity]int)
+	for _, finding := range findings {
+		counts[finding.Severity]++
+	}
+
+	var parts []string
+	for _, severity := range []types.Severity{types.SeverityCritical, types.SeverityHigh, types.SeverityMedium, types.SeverityLow, types.SeverityInfo} {
+		if counts[severity] > 0 {
+			parts = append(parts, fmt.Sprintf("%s: %d", severity, counts[severity]))
+		}
+	}
+	fmt.Printf("\n🔍 Findings: %d (%s)\n", len(findings), strings.Join(parts, ", "))
+}
+
 func scanResultHasFile(result *types.ScanResult, relPath string) bool {
 	normalized := normalizeRelPath(relPath)
 	for _, file := range result.Files {
@@ -622,13 +976,22 @@ func resolveFocusPath(rootDir, focusInput string, dirLocked bool) (string, strin
 		return "", rootDir, err
 	}
 
-	info, err := os.Stat(absFocus)
+	// Archive members (bundle.tar.gz!/var/log/app.log) are checked through their archive
+	statPath := absFocus
+	if idx := strings.Index(absFocus, types.ArchiveSeparator); idx >= 0 {
+		statPath = absFocus[:idx]
+	}
+
+	info, err := os.Stat(statPath)
 	if err != nil {
 		return "", rootDir, err
 	}
 	if info.IsDir() {
 		return "", rootDir, fmt.Errorf("%s is a directory; provide a file path for --focus", focusInput)
 	}
+	if statPath != absFocus && !analyzer.IsExpandableArchive(statPath) {
+		return "", rootDir, fmt.Errorf("%s is not a supported archive (zip, tar, tar.gz, tgz, tar.bz2, gz, bz2)", statPath)
+	}
 
 	rel, err := filepath.Rel(rootDir, absFocus)
 	if err == nil {
@@ -642,7 +1005,7 @@ func resolveFocusPath(rootDir, focusInput string, dirLocked bool) (string, strin
 		return "", rootDir, fmt.Errorf("focus file must be inside the target directory (%s)", rootDir)
 	}
 
-	newRoot := filepath.Dir(absFocus)
+	newRoot := filepath.Dir(statPath)
 	relToNewRoot, err := filepath.Rel(newRoot, absFocus)
 	if err != nil {
 		return "", rootDir, err
@@ -665,6 +1028,13 @@ func displayScanResult(result *types.ScanResult) {
 		}
 	}
 
+	if lines := analyzer.FormatDuplicateGroups(result.Duplicates, 20); len(lines) > 0 {
+		fmt.Printf("\n   %s\n", lines[0])
+		for _, line := range lines[1:] {
+			fmt.Printf("      %s\n", line)
+		}
+	}
+
 	if len(result.Errors) > 0 {
 		fmt.Printf("\n   ⚠️  Errors: %d\n", len(result.Errors))
 		for _, e := range result.Errors {
@@ -673,6 +1043,50 @@ func displayScanResult(result *types.ScanResult) {
 	}
 }
 
+func displayExcludedFiles(result *types.ScanResult, cfg *config.Config) {
+	fmt.Printf("\n🚫 Excluded files: %d\n", len(result.Excluded))
+	for _, decision := range result.Excluded {
+		fmt.Printf("   %s\n", formatExcludedLine(decision))
+	}
+
+	// Oversized files pass the filters but their content is never read
+	for _, file := range result.Files {
+		if maxSize, source := cfg.MaxFileSize(file.Path); file.Size > maxSize {
+			fmt.Printf("   %s [size] exceeds %s (%s > %s), content not read\n",
+				file.RelPath, source, formatBytes(file.Size), formatBytes(maxSize))
+		}
+	}
+}
+
+func formatExcludedLine(decision types.FilterDecision) string {
+	line := fmt.Sprintf("%s [%s] %s", decision.Path, decision.Stage, decision.Reason)
+	if decision.Rule != "" {
+		line += fmt.Sprintf(" — %s", decision.Rule)
+		if decision.Line > 0 {
+			line += fmt.Sprintf(" (%s:%d)", decision.Source, decision.Line)
+		} else if decision.Source != "" {
+			line += fmt.Sprintf(" (%s)", decision.Source)
+		}
+	}
+	return line
+}
+
+func explainFilterDecision(rootDir, path string, cfg *config.Config) {
+	fileFilter, err := filter.NewFilter(cfg, rootDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize filter: %v\n", err)
+		os.Exit(1)
+	}
+
+	decision, err := fileFilter.ExplainPath(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot explain %s: %v\n", path, err)
+		os.Exit(1)
+	}
+
+	fmt.Println(filter.FormatDecision(decision))
+}
+
 func displayAnalysisResult(result *types.AnalysisResponse) {
 	fmt.Printf("🎯 Analysis Complete\n")
 	fmt.Printf("   Total duration: %v\n", result.Duration)
@@ -0,0 +1,135 @@
+package review
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Function identifies the declaration a hunk belongs to
+type Function struct {
+	Line      int    // new-file line number, 0 when only git's section header is known
+	Signature string // declaration line as written in the source
+}
+
+// declarationPatterns recognise function and type declarations in the
+// languages the scanner allows by default
+var declarationPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^func\s`),                                                       // Go
+	regexp.MustCompile(`^\s*(?:async\s+)?def\s+\w+`),                                    // Python, Ruby, Scala
+	regexp.MustCompile(`^\s*class\s+\w+`),                                               // Python, Ruby, JS, PHP
+	regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:async\s+)?function\b`),      // JS, TS, PHP, shell
+	regexp.MustCompile(`^\s*(?:pub(?:\([\w:]+\))?\s+)?(?:async\s+)?(?:unsafe\s+)?fn\s`), // Rust
+	regexp.MustCompile(`^\s*(?:[\w@]+\s+)*fun\s+[\w.<>]+\s*\(`),                         // Kotlin
+	regexp.MustCompile(`^\s*(?:[\w@]+\s+)*func\s+\w+`),                                  // Swift
+	regexp.MustCompile(`^\s*(?:public|private|protected|static|\s)*function\s+\w+`),     // PHP methods
+	regexp.MustCompile(`^\s*(?:resource|data|module)\s+"[^"]+"`),                        // Terraform blocks
+	regexp.MustCompile(`^\s*\w+\s*\(\)\s*\{`),                                           // shell functions
+	// C, C++, Java and friends: a return type, a name and an opening parameter list
+	regexp.MustCompile(`^[\w][\w\s\*&:<>,\[\]]*?[\s\*&]([A-Za-z_][\w:~]*)\s*\([^;]*$`),
+}
+
+// controlKeywords rule out statements the C-like pattern would otherwise accept
+var controlKeywords = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "catch": true,
+	"return": true, "else": true, "do": true, "sizeof": true, "new": true,
+}
+
+// EnclosingFunction scans backwards from line (1-based) in the new version of
+// a file for the nearest declaration. The scan stops at a closing brace in
+// column 0, which marks the end of a previous top-level block.
+func EnclosingFunction(source []string, line int) (Function, bool) {
+	if line > len(source) {
+		line = len(source)
+	}
+
+	for i := line - 1; i >= 0; i-- {
+		text := source[i]
+		if i < line-1 && strings.HasPrefix(text, "}") {
+			return Function{}, false
+		}
+		if isDeclaration(text) {
+			signature := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "{"))
+			return Function{Line: i + 1, Signature: signature}, true
+		}
+	}
+
+	return Function{}, false
+}
+
+func isDeclaration(text string) bool {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" || strings.HasPrefix(trimmed, "//") || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "*") {
+		return false
+	}
+
+	for i, pattern := range declarationPatterns {
+		match := pattern.FindStringSubmatch(text)
+		if match == nil {
+			continue
+		}
+		// The generic C-like pattern needs its name checked against keywords
+		if i == len(declarationPatterns)-1 {
+			first := strings.Fields(trimmed)[0]
+			if controlKeywords[first] || controlKeywords[match[1]] {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+// matchesSource reports whether the context and added lines of every hunk
+// appear at their new-file line numbers in source. Only then can the file on
+// disk be used for extra context and function lookup.
+func matchesSource(file *FileDiff, source []string) bool {
+	for _, hunk := range file.Hunks {
+		for _, line := range hunk.Lines {
+			if line.Kind == LineRemoved {
+				continue
+			}
+			if line.NewLine < 1 || line.NewLine > len(source) {
+				return false
+			}
+			if strings.TrimRight(source[line.NewLine-1], "\r") != line.Content {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// contextLines returns up to n source lines before and after the hunk that
+// the diff itself does not already show
+func contextLines(hunk *Hunk, source []string, n int) (before, after []Line) {
+	if source == nil || n <= 0 {
+		return nil, nil
+	}
+
+	leading, trailing := 0, 0
+	for _, line := range hunk.Lines {
+		if line.Kind != LineContext {
+			break
+		}
+		leading++
+	}
+	for i := len(hunk.Lines) - 1; i >= 0 && hunk.Lines[i].Kind == LineContext; i-- {
+		trailing++
+	}
+
+	for num := hunk.NewStart - (n - leading); num < hunk.NewStart; num++ {
+		if num >= 1 && num <= len(source) {
+			before = append(before, Line{Kind: LineContext, Content: source[num-1], NewLine: num})
+		}
+	}
+
+	end := hunk.NewStart + hunk.NewLines
+	for num := end; num < end+(n-trailing); num++ {
+		if num >= 1 && num <= len(source) {
+			after = append(after, Line{Kind: LineContext, Content: source[num-1], NewLine: num})
+		}
+	}
+
+	return before, after
+}
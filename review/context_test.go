@@ -0,0 +1,116 @@
+package review
+
+import "testing"
+
+func TestEnclosingFunction(t *testing.T) {
+	source := SplitLines(`package main
+
+import "fmt"
+
+func first() {
+	fmt.Println("a")
+}
+
+var x = 1
+
+func (s *Server) second(
+	a int,
+) error {
+	if a > 0 {
+		return nil
+	}
+	return nil
+}
+`)
+
+	tests := []struct {
+		name string
+		line int
+		want Function
+		ok   bool
+	}{
+		{"inside function", 6, Function{Line: 5, Signature: "func first()"}, true},
+		{"on the declaration", 5, Function{Line: 5, Signature: "func first()"}, true},
+		{"after a closing brace", 9, Function{}, false},
+		{"before any declaration", 3, Function{}, false},
+		{"skips control statements", 15, Function{Line: 11, Signature: "func (s *Server) second("}, true},
+		{"past the end of the file", 100, Function{Line: 11, Signature: "func (s *Server) second("}, true},
+	}
+	for _, tt := range tests {
+		got, ok := EnclosingFunction(source, tt.line)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%s: EnclosingFunction(%d) = %+v, %v; want %+v, %v", tt.name, tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestIsDeclaration(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"func main() {", true},
+		{"def handler(event, context):", true},
+		{"    async def run(self):", true},
+		{"class User(Base):", true},
+		{"export async function load() {", true},
+		{"pub(crate) fn parse(input: &str) -> Result<()> {", true},
+		{`resource "aws_s3_bucket" "logs" {`, true},
+		{"static int parse_header(const char *buf, size_t len)", true},
+		{"deploy() {", true},
+		{"    if (x > 0) {", false},
+		{"    return foo(bar);", false},
+		{"// func commented()", false},
+		{"# def commented():", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isDeclaration(tt.text); got != tt.want {
+			t.Errorf("isDeclaration(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestContextLines(t *testing.T) {
+	source := SplitLines("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n")
+	hunk := &Hunk{
+		NewStart: 4, NewLines: 3,
+		Lines: []Line{
+			{Kind: LineContext, Content: "4", NewLine: 4},
+			{Kind: LineAdded, Content: "5", NewLine: 5},
+			{Kind: LineContext, Content: "6", NewLine: 6},
+		},
+	}
+
+	before, after := contextLines(hunk, source, 3)
+	if got := lineNumbers(before); !equalInts(got, []int{2, 3}) {
+		t.Errorf("before = %v, want [2 3]", got)
+	}
+	if got := lineNumbers(after); !equalInts(got, []int{7, 8}) {
+		t.Errorf("after = %v, want [7 8]", got)
+	}
+
+	if before, after := contextLines(hunk, nil, 3); before != nil || after != nil {
+		t.Errorf("contextLines without source = %v, %v; want nothing", before, after)
+	}
+}
+
+func lineNumbers(lines []Line) []int {
+	var nums []int
+	for _, line := range lines {
+		nums = append(nums, line.NewLine)
+	}
+	return nums
+}
+
+func equalInts(x, y []int) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,301 @@
+package review
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LineKind marks a diff line as context, addition or removal
+type LineKind byte
+
+const (
+	LineContext LineKind = ' '
+	LineAdded   LineKind = '+'
+	LineRemoved LineKind = '-'
+)
+
+// Line is a single line of a hunk. OldLine/NewLine are 0 when the line does
+// not exist on that side of the diff.
+type Line struct {
+	Kind    LineKind
+	Content string
+	OldLine int
+	NewLine int
+}
+
+// Hunk is one @@ section of a unified diff
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Section  string // text after the closing @@, usually the enclosing function
+	Lines    []Line
+}
+
+// Header returns the hunk's @@ line
+func (h *Hunk) Header() string {
+	header := fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+	if h.Section != "" {
+		header += " " + h.Section
+	}
+	return header
+}
+
+// FirstChange returns the new-file line number of the first added line, or
+// the line following the first removal for hunks that only delete lines
+func (h *Hunk) FirstChange() int {
+	next := h.NewStart
+	for _, line := range h.Lines {
+		switch line.Kind {
+		case LineAdded:
+			return line.NewLine
+		case LineRemoved:
+			return next
+		default:
+			next = line.NewLine + 1
+		}
+	}
+	return h.NewStart
+}
+
+// HasAdditions reports whether the hunk adds any lines
+func (h *Hunk) HasAdditions() bool {
+	for _, line := range h.Lines {
+		if line.Kind == LineAdded {
+			return true
+		}
+	}
+	return false
+}
+
+// FileDiff holds the hunks of one file in a unified diff
+type FileDiff struct {
+	OldPath   string
+	NewPath   string
+	IsNew     bool
+	IsDeleted bool
+	IsBinary  bool
+	Hunks     []Hunk
+}
+
+// Path returns the path the file has after the change
+func (f *FileDiff) Path() string {
+	if f.IsDeleted {
+		return f.OldPath
+	}
+	return f.NewPath
+}
+
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@ ?(.*)$`)
+
+// ParseFile parses a .patch/.diff file
+func ParseFile(path string) ([]FileDiff, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open diff: %w", err)
+	}
+	defer file.Close()
+
+	return Parse(file)
+}
+
+// Parse reads a unified diff as produced by `git diff`, `git format-patch`
+// or `diff -u`. Text outside file sections (commit messages, signatures) is
+// ignored.
+func Parse(r io.Reader) ([]FileDiff, error) {
+	var files []FileDiff
+	var current *FileDiff
+	var hunk *Hunk
+	oldRemaining, newRemaining := 0, 0
+	oldLine, newLine := 0, 0
+
+	startFile := func() {
+		files = append(files, FileDiff{})
+		current = &files[len(files)-1]
+		hunk = nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	lineNum := 0
+
+	for scanner.Scan() {
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		lineNum++
+
+		// Inside a hunk, the header counts tell us which lines belong to it
+		if hunk != nil && (oldRemaining > 0 || newRemaining > 0) {
+			if strings.HasPrefix(text, `\`) {
+				continue // "\ No newline at end of file"
+			}
+
+			kind := LineContext
+			content := text
+			if text != "" {
+				kind = LineKind(text[0])
+				content = text[1:]
+			}
+
+			switch kind {
+			case LineContext:
+				hunk.Lines = append(hunk.Lines, Line{Kind: kind, Content: content, OldLine: oldLine, NewLine: newLine})
+				oldLine++
+				newLine++
+				oldRemaining--
+				newRemaining--
+			case LineAdded:
+				hunk.Lines = append(hunk.Lines, Line{Kind: kind, Content: content, NewLine: newLine})
+				newLine++
+				newRemaining--
+			case LineRemoved:
+				hunk.Lines = append(hunk.Lines, Line{Kind: kind, Content: content, OldLine: oldLine})
+				oldLine++
+				oldRemaining--
+			default:
+				return nil, fmt.Errorf("line %d: unexpected line in hunk %q", lineNum, hunk.Header())
+			}
+			continue
+		}
+		if strings.HasPrefix(text, `\`) {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(text, "diff --git "):
+			startFile()
+			if oldPath, newPath, ok := splitGitHeader(strings.TrimPrefix(text, "diff --git ")); ok {
+				current.OldPath, current.NewPath = oldPath, newPath
+			}
+
+		case strings.HasPrefix(text, "--- "):
+			// Plain `diff -u` output has no "diff --git" line
+			if current == nil || len(current.Hunks) > 0 {
+				startFile()
+			}
+			path := parsePathLine(strings.TrimPrefix(text, "--- "))
+			if path == "" {
+				current.IsNew = true
+			} else {
+				current.OldPath = stripPrefix(path, "a/")
+			}
+
+		case strings.HasPrefix(text, "+++ ") && current != nil:
+			path := parsePathLine(strings.TrimPrefix(text, "+++ "))
+			if path == "" {
+				current.IsDeleted = true
+			} else {
+				current.NewPath = stripPrefix(path, "b/")
+			}
+
+		case strings.HasPrefix(text, "new file mode") && current != nil:
+			current.IsNew = true
+
+		case strings.HasPrefix(text, "deleted file mode") && current != nil:
+			current.IsDeleted = true
+
+		case strings.HasPrefix(text, "rename from ") && current != nil:
+			current.OldPath = strings.TrimPrefix(text, "rename from ")
+
+		case strings.HasPrefix(text, "rename to ") && current != nil:
+			current.NewPath = strings.TrimPrefix(text, "rename to ")
+
+		case strings.HasPrefix(text, "Binary files ") && current != nil:
+			current.IsBinary = true
+
+		case strings.HasPrefix(text, "@@ "):
+			if current == nil {
+				return nil, fmt.Errorf("line %d: hunk header before any file header", lineNum)
+			}
+			match := hunkHeaderPattern.FindStringSubmatch(text)
+			if match == nil {
+				return nil, fmt.Errorf("line %d: malformed hunk header %q", lineNum, text)
+			}
+
+			current.Hunks = append(current.Hunks, Hunk{
+				OldStart: atoi(match[1]),
+				OldLines: countOrOne(match[2]),
+				NewStart: atoi(match[3]),
+				NewLines: countOrOne(match[4]),
+				Section:  strings.TrimSpace(match[5]),
+			})
+			hunk = &current.Hunks[len(current.Hunks)-1]
+			oldRemaining, newRemaining = hunk.OldLines, hunk.NewLines
+			oldLine, newLine = hunk.OldStart, hunk.NewStart
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read diff: %w", err)
+	}
+	if hunk != nil && (oldRemaining > 0 || newRemaining > 0) {
+		return nil, fmt.Errorf("diff ends in the middle of hunk %q", hunk.Header())
+	}
+
+	// Drop sections that carried no file information (e.g. mode-only changes)
+	result := files[:0]
+	for _, file := range files {
+		if file.OldPath == "" && file.NewPath == "" {
+			continue
+		}
+		if file.NewPath == "" {
+			file.NewPath = file.OldPath
+		}
+		if file.OldPath == "" {
+			file.OldPath = file.NewPath
+		}
+		result = append(result, file)
+	}
+
+	return result, nil
+}
+
+// splitGitHeader splits "a/old b/new" from a diff --git line. Paths with
+// spaces are ambiguous here; the ---/+++ lines that follow take precedence.
+func splitGitHeader(paths string) (string, string, bool) {
+	if strings.HasPrefix(paths, `"`) {
+		return "", "", false
+	}
+	idx := strings.Index(paths, " b/")
+	if idx < 0 {
+		return "", "", false
+	}
+	return stripPrefix(paths[:idx], "a/"), paths[idx+3:], true
+}
+
+// parsePathLine extracts the path from a ---/+++ line, returning "" for /dev/null
+func parsePathLine(value string) string {
+	// diff -u appends a tab and a timestamp
+	if tab := strings.IndexByte(value, '\t'); tab >= 0 {
+		value = value[:tab]
+	}
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+	if value == "/dev/null" {
+		return ""
+	}
+	return value
+}
+
+func stripPrefix(path, prefix string) string {
+	return strings.TrimPrefix(path, prefix)
+}
+
+func atoi(value string) int {
+	n, _ := strconv.Atoi(value)
+	return n
+}
+
+// countOrOne parses an optional hunk line count, which defaults to 1
+func countOrOne(value string) int {
+	if value == "" {
+		return 1
+	}
+	return atoi(value)
+}
@@ -0,0 +1,269 @@
+package review
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+		want []FileDiff
+	}{
+		{
+			name: "modification",
+			diff: `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -10,4 +10,5 @@ func main() {
+ 	a := 1
+-	b := 2
++	b := 3
++	c := 4
+ 	fmt.Println(a, b)
+ }
+`,
+			want: []FileDiff{{
+				OldPath: "main.go",
+				NewPath: "main.go",
+				Hunks: []Hunk{{
+					OldStart: 10, OldLines: 4, NewStart: 10, NewLines: 5,
+					Section: "func main() {",
+					Lines: []Line{
+						{Kind: LineContext, Content: "\ta := 1", OldLine: 10, NewLine: 10},
+						{Kind: LineRemoved, Content: "\tb := 2", OldLine: 11},
+						{Kind: LineAdded, Content: "\tb := 3", NewLine: 11},
+						{Kind: LineAdded, Content: "\tc := 4", NewLine: 12},
+						{Kind: LineContext, Content: "\tfmt.Println(a, b)", OldLine: 12, NewLine: 13},
+						{Kind: LineContext, Content: "}", OldLine: 13, NewLine: 14},
+					},
+				}},
+			}},
+		},
+		{
+			name: "rename with changes",
+			diff: `diff --git a/old/name.py b/new/name.py
+similarity index 90%
+rename from old/name.py
+rename to new/name.py
+index 1111111..2222222 100644
+--- a/old/name.py
++++ b/new/name.py
+@@ -1 +1 @@
+-x = 1
++x = 2
+`,
+			want: []FileDiff{{
+				OldPath: "old/name.py",
+				NewPath: "new/name.py",
+				Hunks: []Hunk{{
+					OldStart: 1, OldLines: 1, NewStart: 1, NewLines: 1,
+					Lines: []Line{
+						{Kind: LineRemoved, Content: "x = 1", OldLine: 1},
+						{Kind: LineAdded, Content: "x = 2", NewLine: 1},
+					},
+				}},
+			}},
+		},
+		{
+			name: "pure rename",
+			diff: `diff --git a/a.txt b/b.txt
+similarity index 100%
+rename from a.txt
+rename to b.txt
+`,
+			want: []FileDiff{{OldPath: "a.txt", NewPath: "b.txt"}},
+		},
+		{
+			name: "no newline at end of file",
+			diff: `diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -1,2 +1,2 @@
+ first
+-second
+\ No newline at end of file
++second
+`,
+			want: []FileDiff{{
+				OldPath: "a.txt",
+				NewPath: "a.txt",
+				Hunks: []Hunk{{
+					OldStart: 1, OldLines: 2, NewStart: 1, NewLines: 2,
+					Lines: []Line{
+						{Kind: LineContext, Content: "first", OldLine: 1, NewLine: 1},
+						{Kind: LineRemoved, Content: "second", OldLine: 2},
+						{Kind: LineAdded, Content: "second", NewLine: 2},
+					},
+				}},
+			}},
+		},
+		{
+			name: "new file",
+			diff: `diff --git a/new.txt b/new.txt
+new file mode 100644
+index 0000000..1111111
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1,2 @@
++one
++two
+\ No newline at end of file
+`,
+			want: []FileDiff{{
+				OldPath: "new.txt",
+				NewPath: "new.txt",
+				IsNew:   true,
+				Hunks: []Hunk{{
+					OldStart: 0, OldLines: 0, NewStart: 1, NewLines: 2,
+					Lines: []Line{
+						{Kind: LineAdded, Content: "one", NewLine: 1},
+						{Kind: LineAdded, Content: "two", NewLine: 2},
+					},
+				}},
+			}},
+		},
+		{
+			name: "deleted file",
+			diff: `diff --git a/gone.txt b/gone.txt
+deleted file mode 100644
+index 1111111..0000000
+--- a/gone.txt
++++ /dev/null
+@@ -1,2 +0,0 @@
+-one
+-two
+`,
+			want: []FileDiff{{
+				OldPath:   "gone.txt",
+				NewPath:   "gone.txt",
+				IsDeleted: true,
+				Hunks: []Hunk{{
+					OldStart: 1, OldLines: 2, NewStart: 0, NewLines: 0,
+					Lines: []Line{
+						{Kind: LineRemoved, Content: "one", OldLine: 1},
+						{Kind: LineRemoved, Content: "two", OldLine: 2},
+					},
+				}},
+			}},
+		},
+		{
+			name: "pure deletion hunk",
+			diff: `--- a.txt	2024-03-01 12:00:00
++++ a.txt	2024-03-01 12:05:00
+@@ -4,3 +3,0 @@ section
+-x
+-y
+-z
+@@ -10,2 +7,3 @@
+ keep
++added
+ keep
+`,
+			want: []FileDiff{{
+				OldPath: "a.txt",
+				NewPath: "a.txt",
+				Hunks: []Hunk{
+					{
+						OldStart: 4, OldLines: 3, NewStart: 3, NewLines: 0,
+						Section: "section",
+						Lines: []Line{
+							{Kind: LineRemoved, Content: "x", OldLine: 4},
+							{Kind: LineRemoved, Content: "y", OldLine: 5},
+							{Kind: LineRemoved, Content: "z", OldLine: 6},
+						},
+					},
+					{
+						OldStart: 10, OldLines: 2, NewStart: 7, NewLines: 3,
+						Lines: []Line{
+							{Kind: LineContext, Content: "keep", OldLine: 10, NewLine: 7},
+							{Kind: LineAdded, Content: "added", NewLine: 8},
+							{Kind: LineContext, Content: "keep", OldLine: 11, NewLine: 9},
+						},
+					},
+				},
+			}},
+		},
+		{
+			name: "lines that look like headers inside a hunk",
+			diff: `From 1234 Mon Sep 17 00:00:00 2001
+Subject: [PATCH] tweak
+
+diff --git a/a.md b/a.md
+--- a/a.md
++++ b/a.md
+@@ -1,2 +1,2 @@
+--- a/b
++++ b/c
+ context
+-- 
+2.39.5
+`,
+			want: []FileDiff{{
+				OldPath: "a.md",
+				NewPath: "a.md",
+				Hunks: []Hunk{{
+					OldStart: 1, OldLines: 2, NewStart: 1, NewLines: 2,
+					Lines: []Line{
+						{Kind: LineRemoved, Content: "-- a/b", OldLine: 1},
+						{Kind: LineAdded, Content: "++ b/c", NewLine: 1},
+						{Kind: LineContext, Content: "context", OldLine: 2, NewLine: 2},
+					},
+				}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.diff))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse:\ngot  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+	}{
+		{"hunk before file header", "@@ -1 +1 @@\n-a\n+b\n"},
+		{"malformed hunk header", "--- a\n+++ b\n@@ -x +1 @@\n"},
+		{"truncated hunk", "--- a\n+++ b\n@@ -1,3 +1,3 @@\n a\n"},
+		{"unexpected line in hunk", "--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n?b\n"},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(strings.NewReader(tt.diff)); err == nil {
+			t.Errorf("%s: Parse succeeded, want an error", tt.name)
+		}
+	}
+}
+
+func TestHunkFirstChange(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+		want int
+	}{
+		{"addition", "--- a\n+++ b\n@@ -5,2 +5,3 @@\n x\n+y\n z\n", 6},
+		{"deletion after context", "--- a\n+++ b\n@@ -5,3 +5,2 @@\n x\n-y\n z\n", 6},
+		{"deletion only", "--- a\n+++ b\n@@ -4,3 +3,0 @@\n-x\n-y\n-z\n", 3},
+	}
+	for _, tt := range tests {
+		files, err := Parse(strings.NewReader(tt.diff))
+		if err != nil {
+			t.Fatalf("%s: Parse: %v", tt.name, err)
+		}
+		if got := files[0].Hunks[0].FirstChange(); got != tt.want {
+			t.Errorf("%s: FirstChange() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,461 @@
+package review
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"local-agent/config"
+	"local-agent/llm"
+	"local-agent/security"
+	"local-agent/types"
+)
+
+// DefaultTask is used when a review is started without --task
+const DefaultTask = "Review this change for bugs, security problems and maintainability issues."
+
+// SourceFunc returns the new version of a changed file, split into lines.
+// It returns an error when the file is not available.
+type SourceFunc func(path string) ([]string, error)
+
+// DiskSource reads new file versions from the working tree under root
+func DiskSource(root string) SourceFunc {
+	return func(path string) ([]string, error) {
+		data, err := os.ReadFile(ResolvePath(root, path))
+		if err != nil {
+			return nil, err
+		}
+		return SplitLines(string(data)), nil
+	}
+}
+
+// ResolvePath turns a diff path into a filesystem path. `diff -u` output
+// may carry absolute paths; git paths are relative to root.
+func ResolvePath(root, path string) string {
+	path = filepath.FromSlash(path)
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(root, path)
+}
+
+// SplitLines splits file content into lines without line terminators
+func SplitLines(content string) []string {
+	content = strings.TrimSuffix(content, "\n")
+	if content == "" {
+		return nil
+	}
+	return strings.Split(content, "\n")
+}
+
+// Comment is a review comment on one line of the new file
+type Comment struct {
+	Line     int
+	Severity types.Severity
+	Text     string
+}
+
+// HunkReview is the outcome of reviewing one hunk
+type HunkReview struct {
+	Hunk       *Hunk
+	Function   Function
+	Comments   []Comment
+	Response   string // raw model reply
+	TokensUsed int
+	Duration   time.Duration
+	Err        error
+}
+
+// FileReview groups the hunk reviews of one file
+type FileReview struct {
+	Path  string
+	Hunks []HunkReview
+}
+
+// Result is the outcome of a diff review
+type Result struct {
+	Files      []FileReview
+	Skipped    []string // files left out of the review, with the reason
+	Model      string
+	TokensUsed int
+	Duration   time.Duration
+}
+
+// Reviewer sends each diff hunk to the LLM as its own request
+type Reviewer struct {
+	config       *config.Config
+	client       *llm.OllamaClient
+	tokenizer    *llm.Tokenizer
+	validator    *security.Validator
+	contextLines int
+	source       SourceFunc
+	progress     func(string)
+}
+
+// NewReviewer creates a reviewer. source may be nil when new file versions
+// are not available; hunks are then sent with the context the diff carries.
+func NewReviewer(cfg *config.Config, client *llm.OllamaClient, source SourceFunc) *Reviewer {
+	return &Reviewer{
+		config:       cfg,
+		client:       client,
+		tokenizer:    llm.NewTokenizer(),
+		validator:    security.NewValidator(),
+		contextLines: cfg.Review.ContextLines,
+		source:       source,
+	}
+}
+
+// SetProgress registers a callback for progress messages
+func (r *Reviewer) SetProgress(progress func(string)) {
+	r.progress = progress
+}
+
+// hunkJob is a single hunk prepared for review
+type hunkJob struct {
+	file  int
+	hunk  int
+	path  string
+	isNew bool
+	count int
+}
+
+// Review reviews every hunk that adds lines. Hunks are processed with the
+// configured number of concurrent requests; results keep diff order.
+func (r *Reviewer) Review(files []FileDiff, task string) *Result {
+	if strings.TrimSpace(task) == "" {
+		task = DefaultTask
+	}
+
+	result := &Result{}
+	var jobs []hunkJob
+	sources := make(map[int][]string)
+	position := make(map[int]int) // diff file index -> index in result.Files
+
+	for i := range files {
+		file := &files[i]
+		switch {
+		case file.IsBinary:
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: binary file", file.Path()))
+			continue
+		case file.IsDeleted:
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: deleted", file.Path()))
+			continue
+		}
+
+		if r.source != nil {
+			if source, err := r.source(file.NewPath); err == nil && matchesSource(file, source) {
+				sources[i] = source
+			}
+		}
+
+		fileReview := FileReview{Path: file.NewPath}
+		for j := range file.Hunks {
+			if !file.Hunks[j].HasAdditions() {
+				continue
+			}
+			fileReview.Hunks = append(fileReview.Hunks, HunkReview{Hunk: &file.Hunks[j]})
+		}
+		if len(fileReview.Hunks) == 0 {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: no added lines", file.Path()))
+			continue
+		}
+
+		for j := range fileReview.Hunks {
+			jobs = append(jobs, hunkJob{file: i, hunk: j, path: file.NewPath, isNew: file.IsNew, count: len(fileReview.Hunks)})
+		}
+		position[i] = len(result.Files)
+		result.Files = append(result.Files, fileReview)
+	}
+
+	maxWorkers := r.config.Agent.ConcurrentFiles
+	if maxWorkers < 1 {
+		maxWorkers = 1
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sem := make(chan struct{}, maxWorkers)
+
+	for n, job := range jobs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(n int, job hunkJob) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			review := &result.Files[position[job.file]].Hunks[job.hunk]
+			r.notify(fmt.Sprintf("Reviewing hunk %d/%d: %s %s", n+1, len(jobs), job.path, review.Hunk.Header()))
+			r.reviewHunk(review, job, sources[job.file], task)
+
+			mu.Lock()
+			result.TokensUsed += review.TokensUsed
+			result.Duration += review.Duration
+			mu.Unlock()
+		}(n, job)
+	}
+	wg.Wait()
+
+	result.Model = r.client.GetModel()
+	return result
+}
+
+func (r *Reviewer) notify(message string) {
+	if r.progress != nil {
+		r.progress(message)
+	}
+}
+
+func (r *Reviewer) reviewHunk(review *HunkReview, job hunkJob, source []string, task string) {
+	hunk := review.Hunk
+
+	review.Function = hunkFunction(hunk, source)
+
+	before, after := contextLines(hunk, source, r.contextLines)
+	content := FormatHunk(job.path, job.isNew, hunk, review.Function, before, after, job.hunk+1, job.count)
+
+	// Redact secrets before the hunk leaves the machine, as PrepareForLLM does for files
+	content = r.validator.SanitizeContent(content)
+
+	if tokens := r.tokenizer.EstimateTokens(content); tokens > r.config.Agent.TokenLimit {
+		review.Err = fmt.Errorf("hunk has %d tokens, exceeds limit of %d", tokens, r.config.Agent.TokenLimit)
+		return
+	}
+
+	response, err := r.client.Analyze(buildTask(task, job.path), content, r.config.LLM.Temperature)
+	if err != nil {
+		review.Err = err
+		return
+	}
+
+	review.Response = strings.TrimSpace(response.Response)
+	review.TokensUsed = response.TokensUsed
+	review.Duration = response.Duration
+	review.Comments = ParseComments(review.Response, hunk, before, after)
+}
+
+// buildTask adds the reply format the comment parser expects to the user's task
+// hunkFunction returns the declaration enclosing a hunk. git's section header
+// is a guess from the old file, so the new source is preferred when it has a
+// declaration above the change; otherwise the header is kept.
+func hunkFunction(hunk *Hunk, source []string) Function {
+	if source != nil {
+		if fn, ok := EnclosingFunction(source, hunk.FirstChange()); ok {
+			return fn
+		}
+	}
+	return Function{Signature: hunk.Section}
+}
+
+func buildTask(task, path string) string {
+	return fmt.Sprintf("Review the change to '%s'. %s\n\n"+
+		"Comment only on added lines (marked +); the other lines are context. "+
+		"Every line in the hunk starts with its line number in the new file. "+
+		"Write each comment on its own line as `L<line> [critical|high|medium|low|info] <comment>`. "+
+		"If there is nothing worth commenting on, reply with `LGTM`.", path, task)
+}
+
+// FormatHunk renders a hunk for the prompt, prefixing every line with its
+// new-file line number. Removed lines have no number and are marked with -.
+func FormatHunk(path string, isNew bool, hunk *Hunk, fn Function, before, after []Line, index, total int) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("File: %s", path))
+	if isNew {
+		b.WriteString(" (new file)")
+	}
+	b.WriteString(fmt.Sprintf("\nHunk %d/%d: %s\n", index, total, hunk.Header()))
+	if fn.Signature != "" {
+		if fn.Line > 0 {
+			b.WriteString(fmt.Sprintf("Enclosing function (line %d): %s\n", fn.Line, fn.Signature))
+		} else {
+			b.WriteString(fmt.Sprintf("Enclosing function: %s\n", fn.Signature))
+		}
+	}
+
+	width := len(strconv.Itoa(hunk.NewStart + hunk.NewLines + len(after)))
+
+	b.WriteString("\n```diff\n")
+	writeLines := func(lines []Line) {
+		for _, line := range lines {
+			number := strings.Repeat(" ", width)
+			if line.Kind != LineRemoved {
+				number = fmt.Sprintf("%*d", width, line.NewLine)
+			}
+			b.WriteString(fmt.Sprintf("%s %c %s\n", number, line.Kind, line.Content))
+		}
+	}
+	writeLines(before)
+	writeLines(hunk.Lines)
+	writeLines(after)
+	b.WriteString("```\n")
+
+	return b.String()
+}
+
+// commentPattern matches "L42 [high] comment" and common variations such as
+// "- Line 42: [HIGH] comment" or "**L42** (medium) comment"
+var commentPattern = regexp.MustCompile(`(?i)^\s*(?:[-*•]\s*)?\**\s*L(?:ine)?\s*(\d+)\s*\**\s*[:.)\-–]?\s*(?:[\[(]\s*(critical|high|medium|low|info)\s*[\])])?\s*[:\-–]?\s*(.+)$`)
+
+// ParseComments extracts line comments from a model reply and maps them
+// onto new-file line numbers shown in the hunk
+func ParseComments(response string, hunk *Hunk, before, after []Line) []Comment {
+	var shown []int
+	for _, lines := range [][]Line{before, hunk.Lines, after} {
+		for _, line := range lines {
+			if line.Kind != LineRemoved {
+				shown = append(shown, line.NewLine)
+			}
+		}
+	}
+
+	var comments []Comment
+	for _, raw := range strings.Split(response, "\n") {
+		match := commentPattern.FindStringSubmatch(raw)
+		if match == nil {
+			continue
+		}
+
+		line, _ := strconv.Atoi(match[1])
+		text := strings.TrimSpace(strings.Trim(strings.TrimSpace(match[3]), "*"))
+		if text == "" {
+			continue
+		}
+
+		comments = append(comments, Comment{
+			Line:     mapLine(line, hunk, shown),
+			Severity: parseSeverity(match[2]),
+			Text:     text,
+		})
+	}
+
+	return comments
+}
+
+// mapLine resolves the line number a model used to a new-file line number.
+// Numbers shown in the hunk are taken as-is; small numbers below the hunk are
+// read as positions within the hunk; anything else snaps to the first change.
+func mapLine(line int, hunk *Hunk, shown []int) int {
+	for _, num := range shown {
+		if num == line {
+			return line
+		}
+	}
+
+	if len(shown) > 0 && line >= 1 && line < shown[0] && line <= len(shown) {
+		return shown[line-1]
+	}
+
+	return hunk.FirstChange()
+}
+
+func parseSeverity(value string) types.Severity {
+	switch types.Severity(strings.ToLower(value)) {
+	case types.SeverityCritical:
+		return types.SeverityCritical
+	case types.SeverityHigh:
+		return types.SeverityHigh
+	case types.SeverityMedium:
+		return types.SeverityMedium
+	case types.SeverityLow:
+		return types.SeverityLow
+	default:
+		return types.SeverityInfo
+	}
+}
+
+// Findings converts all comments into findings, ordered by file and line
+func (r *Result) Findings() []types.Finding {
+	var findings []types.Finding
+	for _, file := range r.Files {
+		for _, hunk := range file.Hunks {
+			for _, comment := range hunk.Comments {
+				findings = append(findings, types.Finding{
+					File:        file.Path,
+					Line:        comment.Line,
+					Severity:    comment.Severity,
+					Category:    "review",
+					Description: comment.Text,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
+
+	return findings
+}
+
+// FileTokens returns the tokens used per file
+func (r *Result) FileTokens() map[string]int {
+	tokens := make(map[string]int)
+	for _, file := range r.Files {
+		for _, hunk := range file.Hunks {
+			tokens[file.Path] += hunk.TokensUsed
+		}
+	}
+	return tokens
+}
+
+// Format renders the review grouped by file and hunk
+func (r *Result) Format() string {
+	var b strings.Builder
+
+	for _, file := range r.Files {
+		b.WriteString(fmt.Sprintf("\n=== %s ===\n", file.Path))
+		for _, hunk := range file.Hunks {
+			b.WriteString(hunk.Hunk.Header())
+			if hunk.Function.Line > 0 {
+				b.WriteString(fmt.Sprintf("  (in %s, line %d)", hunk.Function.Signature, hunk.Function.Line))
+			}
+			b.WriteString("\n")
+
+			switch {
+			case hunk.Err != nil:
+				b.WriteString(fmt.Sprintf("   ⚠️  FAILED: %v\n", hunk.Err))
+			case len(hunk.Comments) > 0:
+				for _, comment := range hunk.Comments {
+					b.WriteString(fmt.Sprintf("   L%d [%s] %s\n", comment.Line, comment.Severity, comment.Text))
+				}
+			case hunk.Response == "" || strings.EqualFold(strings.Trim(hunk.Response, "`. "), "LGTM"):
+				b.WriteString("   ✅ LGTM\n")
+			default:
+				// Reply did not follow the comment format; show it unchanged
+				for _, line := range strings.Split(hunk.Response, "\n") {
+					b.WriteString("   " + line + "\n")
+				}
+			}
+		}
+	}
+
+	if len(r.Skipped) > 0 {
+		b.WriteString("\nSkipped:\n")
+		for _, skipped := range r.Skipped {
+			b.WriteString(fmt.Sprintf("   %s\n", skipped))
+		}
+	}
+
+	return b.String()
+}
+
+// AnalysisResponse converts the review into the common analysis result
+func (r *Result) AnalysisResponse() *types.AnalysisResponse {
+	return &types.AnalysisResponse{
+		Response:   strings.TrimSpace(r.Format()),
+		Model:      r.Model,
+		TokensUsed: r.TokensUsed,
+		FileTokens: r.FileTokens(),
+		Duration:   r.Duration,
+		Findings:   r.Findings(),
+	}
+}
@@ -0,0 +1,115 @@
+package review
+
+import (
+	"strings"
+	"testing"
+
+	"local-agent/types"
+)
+
+func TestHunkFunction(t *testing.T) {
+	hunk := &Hunk{
+		NewStart: 9, NewLines: 1,
+		Section: "func handler() {",
+		Lines:   []Line{{Kind: LineAdded, Content: "x := 1", NewLine: 9}},
+	}
+
+	tests := []struct {
+		name   string
+		source []string
+		want   Function
+	}{
+		{"no source", nil, Function{Signature: "func handler() {"}},
+		{
+			"declaration in source",
+			SplitLines("package a\n\nfunc renamed() {\n\ta()\n\tb()\n\tc()\n\td()\n\te()\n\tx := 1\n}\n"),
+			Function{Line: 3, Signature: "func renamed()"},
+		},
+		{
+			// The scan stops at the closing brace; git's header is kept
+			"no declaration in source",
+			SplitLines("package a\n\nfunc other() {\n}\n\nvar (\n\ta = 1\n\tb = 2\n\tx := 1\n)\n"),
+			Function{Signature: "func handler() {"},
+		},
+	}
+	for _, tt := range tests {
+		if got := hunkFunction(hunk, tt.source); got != tt.want {
+			t.Errorf("%s: hunkFunction = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseComments(t *testing.T) {
+	hunk := &Hunk{
+		NewStart: 40, NewLines: 3,
+		Lines: []Line{
+			{Kind: LineContext, Content: "a", OldLine: 40, NewLine: 40},
+			{Kind: LineRemoved, Content: "b", OldLine: 41},
+			{Kind: LineAdded, Content: "c", NewLine: 41},
+			{Kind: LineContext, Content: "d", OldLine: 42, NewLine: 42},
+		},
+	}
+	before := []Line{{Kind: LineContext, Content: "z", NewLine: 39}}
+
+	response := strings.Join([]string{
+		"L41 [high] SQL built from user input",
+		"- Line 42: [LOW] naming",
+		"**L40** (medium) missing check",
+		"L2 [info] hunk-relative line",
+		"L500 [critical] out of range",
+		"Overall this looks fine.",
+	}, "\n")
+
+	want := []Comment{
+		{Line: 41, Severity: types.SeverityHigh, Text: "SQL built from user input"},
+		{Line: 42, Severity: types.SeverityLow, Text: "naming"},
+		{Line: 40, Severity: types.SeverityMedium, Text: "missing check"},
+		{Line: 40, Severity: types.SeverityInfo, Text: "hunk-relative line"},
+		{Line: 41, Severity: types.SeverityCritical, Text: "out of range"},
+	}
+
+	got := ParseComments(response, hunk, before, nil)
+	if len(got) != len(want) {
+		t.Fatalf("got %d comments, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("comment %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestMapLine(t *testing.T) {
+	hunk := &Hunk{
+		NewStart: 10, NewLines: 3,
+		Lines: []Line{
+			{Kind: LineContext, NewLine: 10},
+			{Kind: LineAdded, NewLine: 11},
+			{Kind: LineContext, NewLine: 12},
+		},
+	}
+	shown := []int{10, 11, 12}
+
+	tests := []struct {
+		name string
+		line int
+		want int
+	}{
+		{"shown line", 12, 12},
+		{"hunk-relative", 2, 11},
+		{"hunk-relative beyond the hunk", 5, 11},
+		{"zero", 0, 11},
+		{"after the hunk", 13, 11},
+	}
+	for _, tt := range tests {
+		if got := mapLine(tt.line, hunk, shown); got != tt.want {
+			t.Errorf("%s: mapLine(%d) = %d, want %d", tt.name, tt.line, got, tt.want)
+		}
+	}
+
+	// A deletion-only hunk shows no new-file lines
+	deletion := &Hunk{NewStart: 3, Lines: []Line{{Kind: LineRemoved, OldLine: 4}}}
+	if got := mapLine(1, deletion, nil); got != 3 {
+		t.Errorf("mapLine on a deletion-only hunk = %d, want 3", got)
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"local-agent/security"
 	"local-agent/sessionlog"
 	"local-agent/types"
+	"local-agent/vcs"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
@@ -37,6 +38,13 @@ type InteractiveModel struct {
 	cfg         *config.Config
 	llmClient   *llm.OllamaClient
 
+	// Packet filter set by 'focus <capture> where <expr>'
+	focusFilter string
+
+	// Change selection set by 'focus changed'
+	changeScope  *vcs.Scope
+	changedFiles map[string]struct{}
+
 	// UI state
 	width     int
 	height    int
@@ -230,6 +238,16 @@ func (m InteractiveModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.focusedPath != "" && !m.focusedFileAvailable() {
 				builder.WriteString(fmt.Sprintf("\n\n🎯 The previously focused file (%s) is no longer available. Reverting to all files.", m.focusedPath))
 				m.focusedPath = ""
+				m.focusFilter = ""
+			}
+			if m.changeScope != nil {
+				if err := m.refreshChangedFiles(); err != nil {
+					builder.WriteString(fmt.Sprintf("\n\n🔀 Could not refresh %s: %v. Reverting to all files.", m.changeScope, err))
+					m.changeScope = nil
+					m.changedFiles = nil
+				} else {
+					builder.WriteString(fmt.Sprintf("\n\n🔀 Focus on %s refreshed: %d files", m.changeScope, len(m.getActiveFiles())))
+				}
 			}
 			m.messages = append(m.messages, Message{
 				Role:      "assistant",
@@ -259,6 +277,8 @@ func (m InteractiveModel) View() string {
 	headerText := fmt.Sprintf("🤖 Interactive Mode | %s | Files: %d", m.model, m.scanResult.TotalFiles)
 	if m.focusedPath != "" {
 		headerText += fmt.Sprintf(" | Focus: %s", m.focusedPath)
+	} else if m.changeScope != nil {
+		headerText += fmt.Sprintf(" | Focus: %s", m.changeScope)
 	}
 	if llm.IsThinkingModel(m.model) {
 		headerText += " | 🧠 Thinking"
@@ -418,7 +438,11 @@ func (m *InteractiveModel) handleCommand(input string) bool {
 • stats - Show scan statistics
 • files - List scanned files
 • focus <path> - Analyze only the specified file
+• focus <capture> where <filter> - Analyze only matching packets, e.g. focus capture.pcap where host 10.0.0.5 and port 443
 • focus clear - Reset focus to analyze all files
+• focus changed [staged|since <ref>] - Analyze only files changed in git
+• explain <path> - Show why a file is included or excluded
+• export pcap <dir> - Write the flow, DNS, HTTP and protocol tables of the active captures to CSV/JSON
 • clear - Clear conversation history
 • quit, exit, q - Exit interactive mode
 
@@ -452,6 +476,13 @@ File breakdown:`,
 			stats += fmt.Sprintf("\n  • %s: %d", key, count)
 		}
 
+		if lines := analyzer.FormatDuplicateGroups(m.scanResult.Duplicates, 20); len(lines) > 0 {
+			stats += "\n\n" + lines[0]
+			for _, line := range lines[1:] {
+				stats += "\n  • " + line
+			}
+		}
+
 		m.messages = append(m.messages, Message{
 			Role:      "assistant",
 			Content:   stats,
@@ -505,6 +536,24 @@ File breakdown:`,
 			return m.handleFocusCommand(input)
 		}
 
+		if lower == "export pcap" || strings.HasPrefix(lower, "export pcap ") {
+			m.messages = append(m.messages, Message{
+				Role:      "assistant",
+				Content:   m.exportCaptures(strings.TrimSpace(input[len("export pcap"):])),
+				Timestamp: time.Now(),
+			})
+			return true
+		}
+
+		if strings.HasPrefix(lower, "explain ") {
+			m.messages = append(m.messages, Message{
+				Role:      "assistant",
+				Content:   m.explainFile(strings.TrimSpace(input[len("explain "):])),
+				Timestamp: time.Now(),
+			})
+			return true
+		}
+
 		// Check for model command
 		if strings.HasPrefix(lower, "model ") {
 			newModel := strings.TrimSpace(strings.TrimPrefix(lower, "model "))
@@ -544,14 +593,36 @@ func waitForProgress(ch chan string) tea.Cmd {
 
 func (m InteractiveModel) processQuestion(question string, files []*types.FileInfo) tea.Cmd {
 	currentFocusedPath := m.focusedPath
+	focusFilter := m.focusFilter
 	progressCh := m.progressCh
 
+	// With 'focus changed', the unchanged part of the scan is named so the model knows it exists
+	request := question
+	if m.focusedPath == "" && m.changeScope != nil && m.scanResult != nil {
+		request += analyzer.UnchangedFilesNote(m.scanResult.Files, files)
+	}
+
 	return func() tea.Msg {
 		// Prepare file context for LLM
 		analyzerEngine := analyzer.NewAnalyzer(m.cfg)
 
+		// A 'focus ... where' filter re-reads the capture with only the matching packets
+		if focusFilter != "" {
+			filtered, err := analyzerEngine.FilterCaptures(files, focusFilter)
+			if err != nil {
+				if progressCh != nil {
+					close(progressCh)
+				}
+				return processCompleteMsg{
+					response: "",
+					err:      err,
+				}
+			}
+			files = filtered
+		}
+
 		// Process files concurrently
-		result, processingInfo, err := m.analyzeBatchesForInteractive(files, question, analyzerEngine, progressCh)
+		result, processingInfo, err := m.analyzeBatchesForInteractive(files, request, analyzerEngine, progressCh)
 		if progressCh != nil {
 			close(progressCh)
 		}
@@ -562,6 +633,9 @@ func (m InteractiveModel) processQuestion(question string, files []*types.FileIn
 			}
 		}
 
+		// Findings computed while reading files (e.g. PCAP indicators) come first
+		result.Findings = append(analyzer.CollectFindings(files), result.Findings...)
+
 		// Format response with processing info
 		var response strings.Builder
 
@@ -573,6 +647,13 @@ func (m InteractiveModel) processQuestion(question string, files []*types.FileIn
 
 		response.WriteString(result.Response)
 
+		if len(result.Findings) > 0 {
+			response.WriteString("\n\n🔍 Findings:\n")
+			for i, finding := range result.Findings {
+				response.WriteString(fmt.Sprintf("   %d. [%s] %s\n", i+1, finding.Severity, finding.Description))
+			}
+		}
+
 		// Add metadata with proper spacing
 		response.WriteString("\n\n")
 		response.WriteString("---\n")
@@ -669,6 +750,16 @@ func (m *InteractiveModel) getActiveFiles() []*types.FileInfo {
 		return nil
 	}
 
+	if m.focusedPath == "" && m.changeScope != nil {
+		var files []*types.FileInfo
+		for i := range m.scanResult.Files {
+			if _, ok := m.changedFiles[normalizePath(m.scanResult.Files[i].RelPath)]; ok {
+				files = append(files, &m.scanResult.Files[i])
+			}
+		}
+		return files
+	}
+
 	if m.focusedPath == "" {
 		files := make([]*types.FileInfo, 0, len(m.scanResult.Files))
 		for i := range m.scanResult.Files {
@@ -725,8 +816,12 @@ func (m *InteractiveModel) handleFocusCommand(input string) bool {
 
 	if arg == "" {
 		var msg string
-		if m.focusedPath == "" {
+		if m.focusedPath == "" && m.changeScope != nil {
+			msg = fmt.Sprintf("🔀 Currently focusing on %s (%d files). Use 'focus clear' to analyze all files.", m.changeScope, len(m.getActiveFiles()))
+		} else if m.focusedPath == "" {
 			msg = "🎯 No focused file. All files will be analyzed."
+		} else if m.focusFilter != "" {
+			msg = fmt.Sprintf("🎯 Currently focusing on %s where %s. Use 'focus clear' to analyze all files.", m.focusedPath, m.focusFilter)
 		} else {
 			msg = fmt.Sprintf("🎯 Currently focusing on %s. Use 'focus clear' to analyze all files.", m.focusedPath)
 		}
@@ -738,7 +833,24 @@ func (m *InteractiveModel) handleFocusCommand(input string) bool {
 		return true
 	}
 
+	if strings.EqualFold(arg, "changed") || strings.HasPrefix(strings.ToLower(arg), "changed ") {
+		return m.handleFocusChanged(strings.TrimSpace(arg[len("changed"):]))
+	}
+
 	if strings.EqualFold(arg, "clear") || strings.EqualFold(arg, "all") || strings.EqualFold(arg, "reset") {
+		if m.changeScope != nil {
+			cleared := m.changeScope
+			m.changeScope = nil
+			m.changedFiles = nil
+			if m.focusedPath == "" {
+				m.messages = append(m.messages, Message{
+					Role:      "assistant",
+					Content:   fmt.Sprintf("🎯 Focus on %s cleared. Future questions will analyze all files.", cleared),
+					Timestamp: time.Now(),
+				})
+				return true
+			}
+		}
 		if m.focusedPath == "" {
 			m.messages = append(m.messages, Message{
 				Role:      "assistant",
@@ -748,6 +860,7 @@ func (m *InteractiveModel) handleFocusCommand(input string) bool {
 		} else {
 			cleared := m.focusedPath
 			m.focusedPath = ""
+			m.focusFilter = ""
 			m.messages = append(m.messages, Message{
 				Role:      "assistant",
 				Content:   fmt.Sprintf("🎯 Focus on %s cleared. Future questions will analyze all files.", cleared),
@@ -757,7 +870,11 @@ func (m *InteractiveModel) handleFocusCommand(input string) bool {
 		return true
 	}
 
-	matchedPath, errMsg := m.resolveFocusTarget(arg)
+	target, filterExpr := analyzer.SplitFocusFilter(arg)
+	matchedPath, errMsg := m.resolveFocusTarget(target)
+	if errMsg == "" && filterExpr != "" {
+		errMsg = m.checkFocusFilter(matchedPath, filterExpr)
+	}
 	if errMsg != "" {
 		m.messages = append(m.messages, Message{
 			Role:      "assistant",
@@ -768,14 +885,141 @@ func (m *InteractiveModel) handleFocusCommand(input string) bool {
 	}
 
 	m.focusedPath = matchedPath
+	m.focusFilter = filterExpr
+	m.changeScope = nil
+	m.changedFiles = nil
+	content := fmt.Sprintf("🎯 Focus set to %s. Only this file will be analyzed until you run 'focus clear'.", matchedPath)
+	if filterExpr != "" {
+		content = fmt.Sprintf("🎯 Focus set to %s where %s. Only matching packets will be analyzed until you run 'focus clear'.", matchedPath, filterExpr)
+	}
+	m.messages = append(m.messages, Message{
+		Role:      "assistant",
+		Content:   content,
+		Timestamp: time.Now(),
+	})
+	return true
+}
+
+// checkFocusFilter validates a 'focus ... where' filter for the focused file
+func (m *InteractiveModel) checkFocusFilter(path, filterExpr string) string {
+	for _, file := range m.scanResult.Files {
+		if file.RelPath != path {
+			continue
+		}
+		if file.Type != types.TypePCAP {
+			return fmt.Sprintf("⚠️  'where' filters apply to packet captures only; %s is not one.", path)
+		}
+		if _, err := analyzer.ParsePCAPFilter(filterExpr); err != nil {
+			return fmt.Sprintf("⚠️  %v", err)
+		}
+		return ""
+	}
+	return fmt.Sprintf("⚠️  Could not find %s. Use 'files' to list available files.", path)
+}
+
+func (m *InteractiveModel) handleFocusChanged(args string) bool {
+	scope, err := vcs.ParseScope(args)
+	if err != nil {
+		m.messages = append(m.messages, Message{
+			Role:      "assistant",
+			Content:   fmt.Sprintf("⚠️  %v", err),
+			Timestamp: time.Now(),
+		})
+		return true
+	}
+
+	previous, previousFiles := m.changeScope, m.changedFiles
+	m.changeScope = &scope
+	if err := m.refreshChangedFiles(); err != nil {
+		m.changeScope, m.changedFiles = previous, previousFiles
+		m.messages = append(m.messages, Message{
+			Role:      "assistant",
+			Content:   fmt.Sprintf("⚠️  Could not list %s: %v", scope, err),
+			Timestamp: time.Now(),
+		})
+		return true
+	}
+
+	m.focusedPath = ""
+	m.focusFilter = ""
+	active := m.getActiveFiles()
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("🔀 Focus set to %s: %d changed, %d in scan. Only these files will be analyzed until you run 'focus clear'.", scope, len(m.changedFiles), len(active)))
+	for i, file := range active {
+		if i >= 50 {
+			builder.WriteString(fmt.Sprintf("\n... and %d more files", len(active)-50))
+			break
+		}
+		builder.WriteString(fmt.Sprintf("\n• %s", file.RelPath))
+	}
+
 	m.messages = append(m.messages, Message{
 		Role:      "assistant",
-		Content:   fmt.Sprintf("🎯 Focus set to %s. Only this file will be analyzed until you run 'focus clear'.", matchedPath),
+		Content:   builder.String(),
 		Timestamp: time.Now(),
 	})
 	return true
 }
 
+// refreshChangedFiles asks git for the files matching the current change scope
+func (m *InteractiveModel) refreshChangedFiles() error {
+	changed, err := vcs.ChangedFiles(m.directory, *m.changeScope)
+	if err != nil {
+		return err
+	}
+
+	m.changedFiles = make(map[string]struct{}, len(changed))
+	for _, path := range changed {
+		m.changedFiles[normalizePath(path)] = struct{}{}
+	}
+	return nil
+}
+
+// exportCaptures writes the tables of the active packet captures to dir,
+// applying the focus filter
+func (m *InteractiveModel) exportCaptures(dir string) string {
+	if dir == "" {
+		return "⚠️  Usage: export pcap <dir>"
+	}
+
+	analyzerEngine := analyzer.NewAnalyzer(m.cfg)
+	var builder strings.Builder
+	for _, file := range m.getActiveFiles() {
+		if file.Type != types.TypePCAP {
+			continue
+		}
+		written, err := analyzerEngine.ExportPCAP(file, dir, m.focusFilter)
+		if err != nil {
+			builder.WriteString(fmt.Sprintf("⚠️  Could not export %s: %v\n", file.RelPath, err))
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("📤 Exported %s: %d files in %s\n", file.RelPath, len(written), dir))
+	}
+	if builder.Len() == 0 {
+		return "ℹ️  No packet captures among the active files."
+	}
+	return strings.TrimSpace(builder.String())
+}
+
+func (m *InteractiveModel) explainFile(path string) string {
+	if path == "" {
+		return "⚠️  Usage: explain <path>"
+	}
+
+	fileFilter, err := filter.NewFilter(m.cfg, m.directory)
+	if err != nil {
+		return fmt.Sprintf("❌ Failed to initialize filter: %v", err)
+	}
+
+	decision, err := fileFilter.ExplainPath(path)
+	if err != nil {
+		return fmt.Sprintf("⚠️  Cannot explain %s: %v", path, err)
+	}
+
+	return filter.FormatDecision(decision)
+}
+
 func (m *InteractiveModel) focusedFileAvailable() bool {
 	if m.focusedPath == "" || m.scanResult == nil {
 		return false
@@ -850,6 +1094,12 @@ func (m InteractiveModel) prepareBatchesForInteractive(files []*types.FileInfo,
 	var batches [][]*types.FileInfo
 	tokenLimit := m.cfg.Agent.TokenLimit
 
+	// The analysis of a group's representative applies to its duplicates
+	files, duplicates := analyzer.SkipDuplicates(files, tokenLimit)
+	for _, file := range duplicates {
+		info.WriteString(fmt.Sprintf("   ♻️  Skipping %s (duplicate of %s)\n", file.RelPath, file.DuplicateOf))
+	}
+
 	for _, file := range files {
 		if file == nil || !file.IsReadable {
 			continue
@@ -1032,10 +1282,18 @@ func (m InteractiveModel) processBatchForInteractive(batch []*types.FileInfo, qu
 		actualQuestion = fmt.Sprintf("Analyze the file '%s'. %s", batch[0].RelPath, question)
 	}
 
+	var response *types.AnalysisResponse
+	var err error
 	if llm.IsThinkingModel(m.cfg.LLM.Model) {
-		return m.llmClient.AnalyzeThinking(actualQuestion, content, m.cfg.LLM.Temperature)
+		response, err = m.llmClient.AnalyzeThinking(actualQuestion, content, m.cfg.LLM.Temperature)
+	} else {
+		response, err = m.llmClient.Analyze(actualQuestion, content, m.cfg.LLM.Temperature)
+	}
+	if err != nil {
+		return nil, err
 	}
-	return m.llmClient.Analyze(actualQuestion, content, m.cfg.LLM.Temperature)
+	response.Response += analyzerEngine.DuplicateNote(batch)
+	return response, nil
 }
 
 func isFileHeaderLine(line string) bool {
@@ -1382,6 +1640,9 @@ func (m InteractiveModel) performRescan() tea.Cmd {
 			}
 		}
 
+		analyzer.ExpandArchives(result, filter.Evaluate)
+		analyzer.GroupDuplicates(result)
+
 		return rescanCompleteMsg{scanResult: result}
 	}
 }
@@ -218,6 +218,12 @@ func (r *Runner) runScan() (*types.ScanResult, error) {
 		}
 	}
 
+	// Archive members become virtual files that go through the same filter
+	analyzerEngine.ExpandArchives(result, fileFilter.Evaluate)
+
+	// Duplicates are analyzed once, through their group's representative
+	analyzerEngine.GroupDuplicates(result)
+
 	result.Duration = time.Since(startTime)
 	return result, nil
 }
@@ -232,7 +238,14 @@ func (r *Runner) runAnalysis(scanResult *types.ScanResult) (*types.AnalysisRespo
 	}
 
 	// Always process files individually (one request per file) with concurrent workers
-	return r.analyzeBatches(fileInfoPtrs, analyzerEngine)
+	response, err := r.analyzeBatches(fileInfoPtrs, analyzerEngine)
+	if err != nil {
+		return nil, err
+	}
+
+	// Findings computed while reading files (e.g. PCAP indicators) come first
+	response.Findings = append(analyzer.CollectFindings(fileInfoPtrs), response.Findings...)
+	return response, nil
 }
 
 // batchJob represents a batch processing job
@@ -285,6 +298,12 @@ func (r *Runner) prepareBatches(files []*types.FileInfo) [][]*types.FileInfo {
 	var batches [][]*types.FileInfo
 	tokenLimit := r.cfg.Agent.TokenLimit
 
+	// The analysis of a group's representative applies to its duplicates
+	files, duplicates := analyzer.SkipDuplicates(files, tokenLimit)
+	for _, file := range duplicates {
+		r.program.Send(SendAnalysisProgress(fmt.Sprintf("♻️  Skipping %s (duplicate of %s)", file.RelPath, file.DuplicateOf)))
+	}
+
 	for _, file := range files {
 		if file == nil || !file.IsReadable {
 			continue
@@ -447,6 +466,9 @@ func (r *Runner) processBatch(batch []*types.FileInfo, analyzerEngine *analyzer.
 				file.RelPath, file.TokenCount, len(file.Content), file.IsReadable)))
 		}
 	}
+	for _, stat := range analyzerEngine.MinifyReport(batch) {
+		r.program.Send(SendAnalysisProgress("[INFO] " + stat.String()))
+	}
 
 	content := analyzerEngine.PrepareForLLM(batch, r.cfg.Agent.TokenLimit)
 
@@ -461,7 +483,12 @@ func (r *Runner) processBatch(batch []*types.FileInfo, analyzerEngine *analyzer.
 		actualTask = fmt.Sprintf("Analyze the file '%s'. %s", batch[0].RelPath, r.model.Task)
 	}
 
-	return r.client.Analyze(actualTask, content, r.cfg.LLM.Temperature)
+	response, err := r.client.Analyze(actualTask, content, r.cfg.LLM.Temperature)
+	if err != nil {
+		return nil, err
+	}
+	response.Response += analyzerEngine.DuplicateNote(batch)
+	return response, nil
 }
 
 func formatFileSection(fileName, body string) string {
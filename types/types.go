@@ -21,6 +21,10 @@ const (
 	DefaultConcurrentOps = 10
 )
 
+// ArchiveSeparator joins an archive path and a member path into the virtual
+// path of an archive member, e.g. bundle.tar.gz!/var/log/app.log
+const ArchiveSeparator = "!/"
+
 // FileCategory represents the size category of a file
 type FileCategory string
 
@@ -34,16 +38,26 @@ const (
 type FileType string
 
 const (
-	TypeText      FileType = "text"
-	TypeBinary    FileType = "binary"
-	TypeArchive   FileType = "archive"
-	TypeImage     FileType = "image"
-	TypePDF       FileType = "pdf"
-	TypeDOC       FileType = "doc"
-	TypeDOCX      FileType = "docx"
-	TypePCAP      FileType = "pcap"
-	TypeUnknown   FileType = "unknown"
-	TypeSensitive FileType = "sensitive"
+	TypeText       FileType = "text"
+	TypeBinary     FileType = "binary"
+	TypeArchive    FileType = "archive"
+	TypeImage      FileType = "image"
+	TypePDF        FileType = "pdf"
+	TypeDOC        FileType = "doc"
+	TypeDOCX       FileType = "docx"
+	TypeXLSX       FileType = "xlsx"
+	TypeODS        FileType = "ods"
+	TypePPTX       FileType = "pptx"
+	TypeODT        FileType = "odt"
+	TypePCAP       FileType = "pcap"
+	TypeLog        FileType = "log"
+	TypeNotebook   FileType = "notebook"
+	TypeCSV        FileType = "csv"
+	TypeSQLite     FileType = "sqlite"
+	TypeExecutable FileType = "executable"
+	TypeIaC        FileType = "iac"
+	TypeUnknown    FileType = "unknown"
+	TypeSensitive  FileType = "sensitive"
 )
 
 // FileInfo represents metadata and content information about a file
@@ -54,6 +68,8 @@ type FileInfo struct {
 	Category    FileCategory        `json:"category"`
 	Type        FileType            `json:"type"`
 	Extension   string              `json:"extension"`
+	Language    string              `json:"language,omitempty"` // code fence identifier of a text file's language, e.g. "dockerfile"
+	Encoding    string              `json:"encoding,omitempty"` // text encoding the content is transcoded from, e.g. "utf-16le"
 	ModTime     time.Time           `json:"mod_time"`
 	IsReadable  bool                `json:"is_readable"`
 	IsSensitive bool                `json:"is_sensitive"`
@@ -61,7 +77,12 @@ type FileInfo struct {
 	TokenCount  int                 `json:"token_count,omitempty"`
 	Content     string              `json:"content,omitempty"`
 	Summary     string              `json:"summary,omitempty"`
+	Metadata    map[string]string   `json:"metadata,omitempty"` // document properties, e.g. PDF title and page count
+	Findings    []Finding           `json:"findings,omitempty"` // deterministic findings from extraction, e.g. PCAP indicators
 	Chunks      []FileChunk         `json:"chunks,omitempty"`
+	ContentHash string              `json:"content_hash,omitempty"` // SHA-256 of Content
+	DuplicateOf string              `json:"duplicate_of,omitempty"` // relative path of the file analyzed in place of this one
+	Duplicates  []string            `json:"duplicates,omitempty"`   // relative paths of the files this one is analyzed for
 }
 
 // FileChunk represents a portion of a large file
@@ -73,18 +94,34 @@ type FileChunk struct {
 	EndOffset   int64  `json:"end_offset"`
 	Content     string `json:"content"`
 	TokenCount  int    `json:"token_count"`
+	Section     string `json:"section,omitempty"` // e.g. "Sheet: Budget, rows 2-501"
+	Slide       int    `json:"slide,omitempty"`   // slide number for presentations
+	Page        int    `json:"page,omitempty"`    // page number for PDFs
+	Cell        int    `json:"cell,omitempty"`    // cell number for notebooks
 }
 
 // ScanResult represents the result of scanning a directory
 type ScanResult struct {
-	RootPath      string         `json:"root_path"`
-	TotalFiles    int            `json:"total_files"`
-	FilteredFiles int            `json:"filtered_files"`
-	TotalSize     int64          `json:"total_size"`
-	Files         []FileInfo     `json:"files"`
-	Errors        []ScanError    `json:"errors,omitempty"`
-	Duration      time.Duration  `json:"duration"`
-	Summary       map[string]int `json:"summary"` // category/type counts
+	RootPath      string           `json:"root_path"`
+	TotalFiles    int              `json:"total_files"`
+	FilteredFiles int              `json:"filtered_files"`
+	TotalSize     int64            `json:"total_size"`
+	Files         []FileInfo       `json:"files"`
+	Excluded      []FilterDecision `json:"excluded,omitempty"`
+	Errors        []ScanError      `json:"errors,omitempty"`
+	Duration      time.Duration    `json:"duration"`
+	Summary       map[string]int   `json:"summary"` // category/type counts
+	Duplicates    []DuplicateGroup `json:"duplicates,omitempty"`
+}
+
+// DuplicateGroup is a set of files with identical or near-identical content.
+// Only the representative is analyzed; its result applies to the members.
+type DuplicateGroup struct {
+	Representative string   `json:"representative"`
+	Members        []string `json:"members"`
+	Identical      bool     `json:"identical"`    // every member has the representative's content hash
+	Similarity     float64  `json:"similarity"`   // lowest estimated similarity of a member to the representative
+	TokensSaved    int      `json:"tokens_saved"` // tokens of the members, which are not sent
 }
 
 // ScanError represents an error encountered during scanning
@@ -143,6 +180,17 @@ type FilterRule struct {
 	Type    string `json:"type"` // "allow" or "deny"
 }
 
+// FilterDecision explains why a file was included in or excluded from a scan
+type FilterDecision struct {
+	Path     string `json:"path"`
+	Included bool   `json:"included"`
+	Stage    string `json:"stage,omitempty"`  // "gitignore", "agentignore", "deny", "allow", "extension", "sensitive", "symlink", "depth", "size"
+	Rule     string `json:"rule,omitempty"`   // pattern that decided the outcome
+	Source   string `json:"source,omitempty"` // file or config key the rule came from
+	Line     int    `json:"line,omitempty"`   // line (or list position) of the rule in its source
+	Reason   string `json:"reason"`
+}
+
 // AgentState represents the local state maintained by the agent
 type AgentState struct {
 	CurrentTask    string               `json:"current_task"`
@@ -0,0 +1,257 @@
+package vcs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Mode selects which set of changes is requested from git
+type Mode string
+
+const (
+	ModeSince       Mode = "since"
+	ModeStaged      Mode = "staged"
+	ModeUncommitted Mode = "uncommitted"
+)
+
+// Scope describes a selection of changed files in a git working tree
+type Scope struct {
+	Mode Mode
+	Ref  string // only used with ModeSince
+}
+
+// String returns a human readable description of the scope
+func (s Scope) String() string {
+	switch s.Mode {
+	case ModeSince:
+		return fmt.Sprintf("changes since %s", s.Ref)
+	case ModeStaged:
+		return "staged changes"
+	case ModeUncommitted:
+		return "uncommitted changes"
+	default:
+		return string(s.Mode)
+	}
+}
+
+// ParseScope parses the arguments of the interactive `focus changed` command:
+// "" or "uncommitted", "staged", or "since <ref>"
+func ParseScope(args string) (Scope, error) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return Scope{Mode: ModeUncommitted}, nil
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case "uncommitted":
+		return Scope{Mode: ModeUncommitted}, nil
+	case "staged":
+		return Scope{Mode: ModeStaged}, nil
+	case "since":
+		if len(fields) < 2 {
+			return Scope{}, fmt.Errorf("missing git ref after 'since'")
+		}
+		if err := checkRef(fields[1]); err != nil {
+			return Scope{}, err
+		}
+		return Scope{Mode: ModeSince, Ref: fields[1]}, nil
+	}
+
+	return Scope{}, fmt.Errorf("unknown change scope %q (use uncommitted, staged or since <ref>)", fields[0])
+}
+
+// ChangedFiles asks git which files changed in the repository containing dir.
+// Paths are slash-separated and relative to dir; changes outside dir and
+// deleted files are omitted. Untracked files count as changes unless the
+// scope is limited to the staging area.
+func ChangedFiles(dir string, scope Scope) ([]string, error) {
+	if _, err := runGit(dir, "rev-parse", "--show-toplevel"); err != nil {
+		return nil, fmt.Errorf("%s is not inside a git repository: %w", dir, err)
+	}
+
+	args := []string{"diff", "--name-only", "-z", "--relative", "--diff-filter=d"}
+	includeUntracked := true
+
+	switch scope.Mode {
+	case ModeSince:
+		commit, err := resolveRef(dir, scope.Ref)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "--end-of-options", commit)
+	case ModeStaged:
+		args = append(args, "--cached")
+		includeUntracked = false
+	case ModeUncommitted:
+		// A repository without commits has no HEAD to compare against
+		if _, err := runGit(dir, "rev-parse", "--verify", "--quiet", "HEAD"); err == nil {
+			args = append(args, "--end-of-options", "HEAD")
+		} else {
+			args = append(args, "--cached")
+		}
+	default:
+		return nil, fmt.Errorf("unknown change scope %q", scope.Mode)
+	}
+
+	out, err := runGit(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]struct{})
+	addPaths(seen, out)
+
+	if includeUntracked {
+		out, err := runGit(dir, "ls-files", "--others", "--exclude-standard", "-z")
+		if err != nil {
+			return nil, err
+		}
+		addPaths(seen, out)
+	}
+
+	files := make([]string, 0, len(seen))
+	for path := range seen {
+		files = append(files, path)
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// Diff returns the unified diff of the scope with contextLines lines of
+// context around each hunk. Paths are relative to dir. Untracked files are
+// appended as new-file diffs unless the scope is limited to the staging area.
+func Diff(dir string, scope Scope, contextLines int) ([]byte, error) {
+	if _, err := runGit(dir, "rev-parse", "--show-toplevel"); err != nil {
+		return nil, fmt.Errorf("%s is not inside a git repository: %w", dir, err)
+	}
+
+	args := []string{"diff", "--no-color", "--no-ext-diff", "--relative", fmt.Sprintf("-U%d", contextLines)}
+	includeUntracked := true
+
+	switch scope.Mode {
+	case ModeSince:
+		commit, err := resolveRef(dir, scope.Ref)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "--end-of-options", commit)
+	case ModeStaged:
+		args = append(args, "--cached")
+		includeUntracked = false
+	case ModeUncommitted:
+		if _, err := runGit(dir, "rev-parse", "--verify", "--quiet", "HEAD"); err == nil {
+			args = append(args, "--end-of-options", "HEAD")
+		} else {
+			args = append(args, "--cached")
+		}
+	default:
+		return nil, fmt.Errorf("unknown change scope %q", scope.Mode)
+	}
+
+	out, err := runGit(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var diff bytes.Buffer
+	diff.Write(out)
+
+	if includeUntracked {
+		out, err := runGit(dir, "ls-files", "--others", "--exclude-standard", "-z")
+		if err != nil {
+			return nil, err
+		}
+
+		untracked := make(map[string]struct{})
+		addPaths(untracked, out)
+		paths := make([]string, 0, len(untracked))
+		for path := range untracked {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			// --no-index exits with status 1 when the files differ, which they always do here
+			out, err := runGitExit(dir, 1, "diff", "--no-index", "--no-color", "--no-ext-diff",
+				fmt.Sprintf("-U%d", contextLines), "--", "/dev/null", path)
+			if err != nil {
+				return nil, err
+			}
+			diff.Write(out)
+		}
+	}
+
+	return diff.Bytes(), nil
+}
+
+// checkRef rejects refs git would parse as options, such as --output=<file>
+func checkRef(ref string) error {
+	if ref == "" {
+		return fmt.Errorf("a git ref is required for --since")
+	}
+	if strings.HasPrefix(ref, "-") {
+		return fmt.Errorf("invalid git ref %q", ref)
+	}
+	return nil
+}
+
+// resolveRef resolves ref to a commit hash in the repository containing dir
+func resolveRef(dir, ref string) (string, error) {
+	if err := checkRef(ref); err != nil {
+		return "", err
+	}
+	out, err := runGit(dir, "rev-parse", "--verify", "--quiet", "--end-of-options", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("unknown git ref %q", ref)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// StagedFile returns the staged (index) version of a file relative to dir
+func StagedFile(dir, path string) ([]byte, error) {
+	return runGit(dir, "show", ":./"+filepath.ToSlash(path))
+}
+
+// addPaths adds NUL-separated paths from git output to the set
+func addPaths(set map[string]struct{}, output []byte) {
+	for _, path := range strings.Split(string(output), "\x00") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		set[filepath.ToSlash(filepath.Clean(path))] = struct{}{}
+	}
+}
+
+// runGit runs a git subcommand in dir and returns its standard output
+func runGit(dir string, args ...string) ([]byte, error) {
+	return runGitExit(dir, 0, args...)
+}
+
+// runGitExit is like runGit but also accepts okExit as a successful exit status
+func runGitExit(dir string, okExit int, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if okExit != 0 && errors.As(err, &exitErr) && exitErr.ExitCode() == okExit {
+			return stdout.Bytes(), nil
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if msg != "" {
+			return nil, fmt.Errorf("git %s failed: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+
+	return stdout.Bytes(), nil
+}
@@ -21,26 +21,30 @@ import (
 	"local-agent/llm"
 	"local-agent/sessionlog"
 	"local-agent/types"
+	"local-agent/vcs"
 )
 
 // Server represents the web UI server
 type Server struct {
-	directory   string
-	model       string
-	endpoint    string
-	scanResult  *types.ScanResult
-	focusedPath string
+	directory     string
+	model         string
+	endpoint      string
+	scanResult    *types.ScanResult
+	focusedPath   string
+	focusFilter   string // packet filter set by 'focus <capture> where <expr>'
+	changeScope   *vcs.Scope
+	changedFiles  map[string]struct{}
 	sessionPrompt string
-	cfg         *config.Config
-	llmClient   *llm.OllamaClient
-	messages    []Message
-	mu          sync.RWMutex
-	progressCh  chan string
-	progressMu  sync.Mutex
-	runMu       sync.Mutex
-	runCancel   context.CancelFunc
-	runActiveID uint64
-	nextRunID   uint64
+	cfg           *config.Config
+	llmClient     *llm.OllamaClient
+	messages      []Message
+	mu            sync.RWMutex
+	progressCh    chan string
+	progressMu    sync.Mutex
+	runMu         sync.Mutex
+	runCancel     context.CancelFunc
+	runActiveID   uint64
+	nextRunID     uint64
 }
 
 // Message represents a chat message
@@ -70,14 +74,15 @@ type ChatResponse struct {
 
 // StatusResponse represents the current status
 type StatusResponse struct {
-	Directory    string `json:"directory"`
-	Model        string `json:"model"`
-	TotalFiles   int    `json:"totalFiles"`
-	FocusedPath  string `json:"focusedPath,omitempty"`
-	SessionPrompt string `json:"sessionPrompt,omitempty"`
-	HasSessionPrompt bool `json:"hasSessionPrompt"`
-	IsThinking   bool   `json:"isThinking"`
-	IsProcessing bool   `json:"isProcessing"`
+	Directory        string `json:"directory"`
+	Model            string `json:"model"`
+	TotalFiles       int    `json:"totalFiles"`
+	FocusedPath      string `json:"focusedPath,omitempty"`
+	FocusFilter      string `json:"focusFilter,omitempty"`
+	SessionPrompt    string `json:"sessionPrompt,omitempty"`
+	HasSessionPrompt bool   `json:"hasSessionPrompt"`
+	IsThinking       bool   `json:"isThinking"`
+	IsProcessing     bool   `json:"isProcessing"`
 }
 
 // NewServer creates a new web UI server
@@ -140,14 +145,15 @@ func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 	defer s.mu.RUnlock()
 
 	status := StatusResponse{
-		Directory:    s.directory,
-		Model:        s.model,
-		TotalFiles:   s.scanResult.TotalFiles,
-		FocusedPath:  s.focusedPath,
-		SessionPrompt: sessionPrompt,
+		Directory:        s.directory,
+		Model:            s.model,
+		TotalFiles:       s.scanResult.TotalFiles,
+		FocusedPath:      s.focusedPath,
+		FocusFilter:      s.focusFilter,
+		SessionPrompt:    sessionPrompt,
 		HasSessionPrompt: sessionPrompt != "",
-		IsThinking:   llm.IsThinkingModel(s.model),
-		IsProcessing: s.isProcessing(),
+		IsThinking:       llm.IsThinkingModel(s.model),
+		IsProcessing:     s.isProcessing(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -370,9 +376,10 @@ func (s *Server) handleRescan(w http.ResponseWriter, r *http.Request) {
 
 	s.mu.Lock()
 	s.scanResult = scanResult
+	note := s.refreshChangeScopeLocked()
 	msg := Message{
 		Role:      "assistant",
-		Content:   fmt.Sprintf("✅ Rescan complete!\n\nFiles found: %d\nFiltered: %d", scanResult.TotalFiles, scanResult.FilteredFiles),
+		Content:   fmt.Sprintf("✅ Rescan complete!\n\nFiles found: %d\nFiltered: %d%s", scanResult.TotalFiles, scanResult.FilteredFiles, note),
 		Timestamp: time.Now(),
 	}
 	s.messages = append(s.messages, msg)
@@ -392,7 +399,8 @@ func (s *Server) handleFocus(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		Path string `json:"path"`
+		Path   string `json:"path"`
+		Filter string `json:"filter"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		sendError(w, "Invalid request")
@@ -400,8 +408,18 @@ func (s *Server) handleFocus(w http.ResponseWriter, r *http.Request) {
 	}
 
 	s.mu.Lock()
+	if req.Path != "" && req.Filter != "" {
+		if problem := s.checkFocusFilterLocked(req.Path, req.Filter); problem != "" {
+			s.mu.Unlock()
+			sendError(w, problem)
+			return
+		}
+	}
+	s.changeScope = nil
+	s.changedFiles = nil
 	if req.Path == "" {
 		s.focusedPath = ""
+		s.focusFilter = ""
 		msg := Message{
 			Role:      "assistant",
 			Content:   "🎯 Focus cleared. All files are now active.",
@@ -417,9 +435,14 @@ func (s *Server) handleFocus(w http.ResponseWriter, r *http.Request) {
 		})
 	} else {
 		s.focusedPath = req.Path
+		s.focusFilter = req.Filter
+		content := fmt.Sprintf("🎯 Focus set to: %s", req.Path)
+		if req.Filter != "" {
+			content += fmt.Sprintf(" where %s", req.Filter)
+		}
 		msg := Message{
 			Role:      "assistant",
-			Content:   fmt.Sprintf("🎯 Focus set to: %s", req.Path),
+			Content:   content,
 			Timestamp: time.Now(),
 		}
 		s.messages = append(s.messages, msg)
@@ -474,7 +497,11 @@ func (s *Server) handleCommand(input string) string {
 • model <name> - Switch to a different LLM model
 • rescan - Rescan the directory for changes
 • focus <path> - Focus on a specific file
+• focus <capture> where <filter> - Focus on the packets of a capture matching a filter
 • focus clear - Clear file focus
+• focus changed [staged|since <ref>] - Focus on files changed in git
+• explain <path> - Show why a file is included or excluded
+• export pcap <dir> - Write the flow, DNS, HTTP and protocol tables of the active captures to CSV/JSON in <dir> below the scanned directory
 • stats - Show current statistics
 • files - List all files in scope`
 
@@ -486,7 +513,7 @@ func (s *Server) handleCommand(input string) string {
 		if strings.TrimSpace(s.sessionPrompt) != "" {
 			sessionPromptState = "set"
 		}
-		return fmt.Sprintf(`📊 Statistics:
+		stats := fmt.Sprintf(`📊 Statistics:
 • Directory: %s
 • Total files scanned: %d
 • Active files: %d
@@ -494,11 +521,24 @@ func (s *Server) handleCommand(input string) string {
 • Session prompt: %s
 • Model: %s`, s.directory, s.scanResult.TotalFiles, len(activeFiles),
 			func() string {
+				if s.focusedPath != "" && s.focusFilter != "" {
+					return s.focusedPath + " where " + s.focusFilter
+				}
 				if s.focusedPath != "" {
 					return s.focusedPath
 				}
+				if s.changeScope != nil {
+					return s.changeScope.String()
+				}
 				return "none"
 			}(), sessionPromptState, s.model)
+		if lines := analyzer.FormatDuplicateGroups(s.scanResult.Duplicates, 20); len(lines) > 0 {
+			stats += "\n\n" + lines[0]
+			for _, line := range lines[1:] {
+				stats += "\n• " + line
+			}
+		}
+		return stats
 
 	case lower == "files":
 		s.mu.RLock()
@@ -540,25 +580,62 @@ func (s *Server) handleCommand(input string) string {
 		}
 		s.mu.Lock()
 		s.scanResult = scanResult
+		note := s.refreshChangeScopeLocked()
 		s.mu.Unlock()
-		return fmt.Sprintf("✅ Rescan complete!\n\nFiles found: %d\nFiltered: %d\nTotal size: %s",
-			scanResult.TotalFiles, scanResult.FilteredFiles, formatBytes(scanResult.TotalSize))
+		return fmt.Sprintf("✅ Rescan complete!\n\nFiles found: %d\nFiltered: %d\nTotal size: %s%s",
+			scanResult.TotalFiles, scanResult.FilteredFiles, formatBytes(scanResult.TotalSize), note)
+
+	case strings.HasPrefix(lower, "explain "):
+		path := strings.TrimSpace(input[len("explain "):])
+		if path == "" {
+			return "❌ Usage: explain <path>"
+		}
+		f, err := filter.NewFilter(s.cfg, s.directory)
+		if err != nil {
+			return fmt.Sprintf("❌ Failed to initialize filter: %v", err)
+		}
+		decision, err := f.ExplainPath(path)
+		if err != nil {
+			return fmt.Sprintf("⚠️  Cannot explain %s: %v", path, err)
+		}
+		return filter.FormatDecision(decision)
+
+	case lower == "export pcap" || strings.HasPrefix(lower, "export pcap "):
+		return s.exportCaptures(strings.TrimSpace(input[len("export pcap"):]))
 
 	case strings.HasPrefix(lower, "focus "):
 		parts := strings.SplitN(input, " ", 2)
 		if len(parts) != 2 {
-			return "❌ Usage: focus <path> or focus clear"
+			return "❌ Usage: focus <path> [where <filter>] or focus clear"
 		}
 		path := strings.TrimSpace(parts[1])
 		if path == "clear" {
 			s.mu.Lock()
 			s.focusedPath = ""
+			s.focusFilter = ""
+			s.changeScope = nil
+			s.changedFiles = nil
 			s.mu.Unlock()
 			return "🎯 Focus cleared. All files are now active."
 		}
+		if lowerPath := strings.ToLower(path); lowerPath == "changed" || strings.HasPrefix(lowerPath, "changed ") {
+			return s.focusChanged(strings.TrimSpace(path[len("changed"):]))
+		}
+		path, filterExpr := analyzer.SplitFocusFilter(path)
 		s.mu.Lock()
+		defer s.mu.Unlock()
+		if filterExpr != "" {
+			if problem := s.checkFocusFilterLocked(path, filterExpr); problem != "" {
+				return problem
+			}
+		}
 		s.focusedPath = path
-		s.mu.Unlock()
+		s.focusFilter = filterExpr
+		s.changeScope = nil
+		s.changedFiles = nil
+		if filterExpr != "" {
+			return fmt.Sprintf("🎯 Focus set to: %s where %s", path, filterExpr)
+		}
 		return fmt.Sprintf("🎯 Focus set to: %s", path)
 	}
 
@@ -578,11 +655,158 @@ func formatBytes(bytes int64) string {
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
 
+// exportCaptures writes the tables of the active packet captures to dir,
+// applying the focus filter
+func (s *Server) exportCaptures(dir string) string {
+	if dir == "" {
+		return "❌ Usage: export pcap <dir>"
+	}
+	target, err := s.exportDir(dir)
+	if err != nil {
+		return fmt.Sprintf("❌ %v", err)
+	}
+
+	s.mu.RLock()
+	activeFiles := s.getActiveFiles()
+	focusFilter := s.focusFilter
+	s.mu.RUnlock()
+
+	analyzerEngine := analyzer.NewAnalyzer(s.cfg)
+	var builder strings.Builder
+	for _, file := range activeFiles {
+		if file.Type != types.TypePCAP {
+			continue
+		}
+		written, err := analyzerEngine.ExportPCAP(file, target, focusFilter)
+		if err != nil {
+			builder.WriteString(fmt.Sprintf("⚠️  Could not export %s: %v\n", file.RelPath, err))
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("📤 Exported %s: %d files in %s\n", file.RelPath, len(written), dir))
+	}
+	if builder.Len() == 0 {
+		return "ℹ️  No packet captures among the active files."
+	}
+	return strings.TrimSpace(builder.String())
+}
+
+// exportDir resolves the directory of 'export pcap'. Web clients may only
+// write below the scanned directory, so dir must be a relative path without
+// ".." that does not lead outside it through a symlink.
+func (s *Server) exportDir(dir string) (string, error) {
+	if filepath.IsAbs(dir) || filepath.VolumeName(dir) != "" || strings.HasPrefix(dir, "/") || strings.HasPrefix(dir, "\\") {
+		return "", fmt.Errorf("export directory must be relative to %s", s.directory)
+	}
+	for _, part := range strings.FieldsFunc(dir, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if part == ".." {
+			return "", fmt.Errorf("export directory must not contain '..'")
+		}
+	}
+
+	root, err := filepath.EvalSymlinks(s.directory)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", s.directory, err)
+	}
+	target := filepath.Join(root, filepath.Clean(dir))
+
+	// The part of the path that already exists must stay inside the root
+	existing := target
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		existing = filepath.Dir(existing)
+	}
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+	if rel, err := filepath.Rel(root, resolved); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("export directory %s leads outside %s", dir, s.directory)
+	}
+	return target, nil
+}
+
+// checkFocusFilterLocked validates a 'focus ... where' filter for the given path.
+// Callers must hold s.mu.
+func (s *Server) checkFocusFilterLocked(path, filterExpr string) string {
+	for _, file := range s.scanResult.Files {
+		if file.RelPath != path {
+			continue
+		}
+		if file.Type != types.TypePCAP {
+			return fmt.Sprintf("⚠️  'where' filters apply to packet captures only; %s is not one.", path)
+		}
+		if _, err := analyzer.ParsePCAPFilter(filterExpr); err != nil {
+			return fmt.Sprintf("⚠️  %v", err)
+		}
+		return ""
+	}
+	return fmt.Sprintf("⚠️  Could not find %s. Use 'files' to list available files.", path)
+}
+
+// focusChanged restricts the active files to those reported by git for the given scope
+func (s *Server) focusChanged(args string) string {
+	scope, err := vcs.ParseScope(args)
+	if err != nil {
+		return fmt.Sprintf("❌ %v", err)
+	}
+
+	changed, err := vcs.ChangedFiles(s.directory, scope)
+	if err != nil {
+		return fmt.Sprintf("⚠️  Could not list %s: %v", scope, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.focusedPath = ""
+	s.focusFilter = ""
+	s.changeScope = &scope
+	s.changedFiles = changedFileSet(changed)
+	return fmt.Sprintf("🔀 Focus set to %s: %d changed, %d in scan.", scope, len(changed), len(s.getActiveFiles()))
+}
+
+// refreshChangeScopeLocked re-runs git for the active change scope after a rescan.
+// Callers must hold s.mu.
+func (s *Server) refreshChangeScopeLocked() string {
+	if s.changeScope == nil {
+		return ""
+	}
+
+	changed, err := vcs.ChangedFiles(s.directory, *s.changeScope)
+	if err != nil {
+		scope := s.changeScope
+		s.changeScope = nil
+		s.changedFiles = nil
+		return fmt.Sprintf("\n🔀 Could not refresh %s: %v. All files are now active.", scope, err)
+	}
+	s.changedFiles = changedFileSet(changed)
+	return fmt.Sprintf("\n🔀 Focus on %s refreshed: %d files", s.changeScope, len(s.getActiveFiles()))
+}
+
+func changedFileSet(paths []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(paths))
+	for _, path := range paths {
+		set[filepath.ToSlash(path)] = struct{}{}
+	}
+	return set
+}
+
 func (s *Server) getActiveFiles() []*types.FileInfo {
 	if s.scanResult == nil {
 		return nil
 	}
 
+	if s.focusedPath == "" && s.changeScope != nil {
+		var files []*types.FileInfo
+		for i := range s.scanResult.Files {
+			if _, ok := s.changedFiles[filepath.ToSlash(s.scanResult.Files[i].RelPath)]; ok {
+				files = append(files, &s.scanResult.Files[i])
+			}
+		}
+		return files
+	}
+
 	if s.focusedPath == "" {
 		files := make([]*types.FileInfo, 0, len(s.scanResult.Files))
 		for i := range s.scanResult.Files {
@@ -658,6 +882,24 @@ func (s *Server) processQuestion(ctx context.Context, question string, files []*
 	analyzerEngine := analyzer.NewAnalyzer(s.cfg)
 	effectiveQuestion := s.buildQuestionWithSessionPrompt(question)
 
+	s.mu.RLock()
+	focusFilter := s.focusFilter
+	// With 'focus changed', the unchanged part of the scan is named so the model knows it exists
+	if s.focusedPath == "" && s.changeScope != nil && s.scanResult != nil {
+		effectiveQuestion += analyzer.UnchangedFilesNote(s.scanResult.Files, files)
+	}
+	s.mu.RUnlock()
+	if focusFilter != "" {
+		filtered, err := analyzerEngine.FilterCaptures(files, focusFilter)
+		if err != nil {
+			return nil, err
+		}
+		files = filtered
+	}
+
+	// Findings computed while reading files (e.g. PCAP indicators) come first
+	findings := analyzer.CollectFindings(files)
+
 	s.progressMu.Lock()
 	progressCh := s.progressCh
 	s.progressMu.Unlock()
@@ -689,7 +931,9 @@ func (s *Server) processQuestion(ctx context.Context, question string, files []*
 		}
 	}
 
-	// Filter to readable files within token limit
+	// Filter to readable files within token limit; the analysis of a group's
+	// representative applies to its duplicates
+	files, _ = analyzer.SkipDuplicates(files, s.cfg.Agent.TokenLimit)
 	var validFiles []*types.FileInfo
 	for _, f := range files {
 		if f != nil && f.IsReadable && len(f.Content) > 0 && f.TokenCount <= s.cfg.Agent.TokenLimit {
@@ -730,7 +974,7 @@ func (s *Server) processQuestion(ctx context.Context, question string, files []*
 		if err != nil {
 			return fileResult{idx: idx, name: file.RelPath, err: err}
 		}
-		return fileResult{idx: idx, name: file.RelPath, response: resp.Response, thinking: resp.ThinkingContent, tokens: resp.TokensUsed}
+		return fileResult{idx: idx, name: file.RelPath, response: resp.Response + analyzerEngine.DuplicateNote([]*types.FileInfo{file}), thinking: resp.ThinkingContent, tokens: resp.TokensUsed}
 	}
 
 	results := make([]fileResult, len(validFiles))
@@ -827,8 +1071,16 @@ func (s *Server) processQuestion(ctx context.Context, question string, files []*
 		}
 	}
 
+	if len(findings) > 0 {
+		sb.WriteString("\n\n🔍 Findings:\n")
+		for i, finding := range findings {
+			sb.WriteString(fmt.Sprintf("   %d. [%s] %s\n", i+1, finding.Severity, finding.Description))
+		}
+	}
+
 	return &types.AnalysisResponse{
 		Response:   strings.TrimSpace(sb.String()),
+		Findings:   findings,
 		Model:      s.cfg.LLM.Model,
 		TokensUsed: totalTokens,
 		FileTokens: fileTokens,
@@ -864,6 +1116,7 @@ func (s *Server) saveSession(question string, resp *types.AnalysisResponse) {
 		Duration:   resp.Duration,
 		Files:      sessionlog.FilesFromTokens(nil, s.focusedPath),
 		Response:   resp.Response,
+		Findings:   resp.Findings,
 	}
 
 	if s.scanResult != nil {
@@ -936,6 +1189,12 @@ func (s *Server) performRescan() (*types.ScanResult, error) {
 		return nil, err
 	}
 
+	// Archive members become virtual files that go through the same filter
+	analyzerEngine.ExpandArchives(result, f.Evaluate)
+
+	// Duplicates are analyzed once, through their group's representative
+	analyzerEngine.GroupDuplicates(result)
+
 	result.Duration = time.Since(startTime)
 	return result, nil
 }